@@ -3,16 +3,26 @@ package main
 import (
 	"context"
 	"fmt"
+	"job-portal/cmd/rediss"
+	"job-portal/config"
 	"job-portal/internal/auth"
+	"job-portal/internal/authstate"
+	"job-portal/internal/cache"
 	"job-portal/internal/database"
 	"job-portal/internal/handlers"
+	"job-portal/internal/invalidation"
+	"job-portal/internal/ratelimit"
 	"job-portal/internal/repository"
+	"job-portal/internal/service"
+	"job-portal/internal/storage"
+	"job-portal/internal/tracing"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
@@ -26,6 +36,33 @@ func main() {
 }
 func startJobPortal() error {
 
+	// =========================================================================
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config %w", err)
+	}
+	// A generated secret differs between instances and restarts, only
+	// SQLite setups run a single short lived instance
+	if cfg.FileURLSecret == "" && cfg.DBDriver != database.SQLite {
+		return fmt.Errorf("FILE_URL_SECRET is required with the %s driver", cfg.DBDriver)
+	}
+
+	// =========================================================================
+	// Initialize tracing support
+	log.Info().Str("exporter", cfg.TraceExporter).Msg("main : Started : Initializing tracing support")
+	shutdownTracing, err := tracing.Init(context.Background(), cfg)
+	if err != nil {
+		return fmt.Errorf("initializing tracing %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error().Err(err).Msg("main: flushing traces")
+		}
+	}()
+
 	// =========================================================================
 	// Initialize authentication support
 	log.Info().Msg("main : Started : Initializing authentication support")
@@ -56,7 +93,12 @@ func startJobPortal() error {
 	// =========================================================================
 	// Start Database
 	log.Info().Msg("main : Started : Initializing db support")
-	db, err := database.Open()
+	db, err := database.Open(database.Config{
+		Driver:       cfg.DBDriver,
+		DSN:          cfg.DBDSN,
+		QueryTimeout: cfg.DBQueryTimeout,
+		SlowQuery:    cfg.DBSlowQuery,
+	})
 	if err != nil {
 		return fmt.Errorf("connecting to db %w", err)
 	}
@@ -80,13 +122,77 @@ func startJobPortal() error {
 	}
 	// repo := repository.NewRepoStore(ms)
 
-	// Initialize http service
+	// =========================================================================
+	// Initialize Redis, one client and connection pool shared by the cache,
+	// the otps and lockouts and the rate limits
+	log.Info().Str("addr", cfg.RedisAddr).Msg("main : Started : Initializing redis support")
+	rdb := rediss.NewClient(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB)
+	defer rdb.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	err = rdb.Ping(ctx).Err()
+	if err != nil {
+		// The API keeps working without Redis, rate limits fall back to
+		// memory and the cache only costs database queries
+		log.Error().Err(err).Msg("main: redis is not reachable")
+	}
+
+	// =========================================================================
+	// Initialize rate limiting
+	log.Info().Msg("main : Started : Initializing rate limiting")
+	limits, err := rateLimits(cfg, rdb)
+	if err != nil {
+		return fmt.Errorf("configuring rate limits %w", err)
+	}
+
+	// =========================================================================
+	// Initialize caching, every instance keeps short lived copies of the
+	// entries in Redis and evicts them when the bus reports a change
+	log.Info().Dur("local ttl", cfg.LocalCacheTTL).Msg("main : Started : Initializing cache")
+	bus := invalidation.NewBus(rdb)
+	var ch cache.Cache = cache.NewRedis(rdb)
+	if cfg.LocalCacheTTL > 0 {
+		layered := cache.NewLayered(cache.NewMemory(), ch, cfg.LocalCacheTTL)
+		ch = layered
+		listenCtx, stopListening := context.WithCancel(context.Background())
+		defer stopListening()
+		go bus.Listen(listenCtx, service.Evict(layered))
+	}
+
+	// =========================================================================
+	// Initialize file storage for uploaded images
+	log.Info().Str("dir", cfg.StorageDir).Msg("main : Started : Initializing file storage")
+	files, err := storage.NewLocal(cfg.StorageDir)
+	if err != nil {
+		return fmt.Errorf("initializing file storage %w", err)
+	}
+
+	// Initialize http service, the task workers stop once run returns
+	work, stopWork := context.WithCancel(context.Background())
+	defer stopWork()
+	h := handlers.API(a, ms, ch, authstate.NewRedis(rdb), handlers.Config{
+		RateLimits:         limits,
+		TrustedProxies:     cfg.TrustedProxies,
+		VerifiedLogin:      cfg.RequireVerifiedLogin,
+		VerifiedApply:      cfg.RequireVerifiedApply,
+		JobCacheTTL:        cfg.JobCacheTTL,
+		Bus:                bus,
+		ApplyWorkers:       cfg.ApplyWorkers,
+		TaskWorkers:        cfg.TaskWorkers,
+		Work:               work,
+		BulkThreshold:      cfg.BulkApplyThreshold,
+		Storage:            files,
+		FileURLSecret:      []byte(cfg.FileURLSecret),
+		FileURLTTL:         cfg.FileURLTTL,
+		MaxUploadBytes:     cfg.UploadMaxBytes,
+		HideUnverifiedJobs: cfg.HideUnverifiedJobs,
+	})
 	api := http.Server{
 		Addr:         ":8080",
 		ReadTimeout:  8000 * time.Second,
 		WriteTimeout: 800 * time.Second,
 		IdleTimeout:  800 * time.Second,
-		Handler:      handlers.API(a, ms),
+		Handler:      h,
 	}
 
 	// channel to store any errors while setting up the service
@@ -119,3 +225,35 @@ func startJobPortal() error {
 	return nil
 
 }
+
+// rateLimits shares the counters between instances through Redis and falls
+// back to in-memory counters while Redis can't be reached.
+func rateLimits(cfg *config.Config, rdb redis.Scripter) (handlers.RateLimits, error) {
+	rl := handlers.RateLimits{
+		Limiter: ratelimit.NewFallback(ratelimit.NewRedis(rdb), ratelimit.NewMemory()),
+	}
+	rules := []struct {
+		value string
+		rule  *ratelimit.Rule
+	}{
+		{cfg.RateLimitLoginIP, &rl.LoginIP},
+		{cfg.RateLimitLoginEmail, &rl.LoginEmail},
+		{cfg.RateLimitForgotPasswordIP, &rl.ForgotPasswordIP},
+		{cfg.RateLimitForgotPasswordEmail, &rl.ForgotPasswordEmail},
+		{cfg.RateLimitResetPasswordIP, &rl.ResetPasswordIP},
+		{cfg.RateLimitResetPasswordEmail, &rl.ResetPasswordEmail},
+		{cfg.RateLimitPublicIP, &rl.PublicIP},
+		{cfg.RateLimitVerifyIP, &rl.VerifyIP},
+		{cfg.RateLimitVerifyEmail, &rl.VerifyEmail},
+		{cfg.RateLimitLoginMFAUser, &rl.LoginMFAUser},
+		{cfg.RateLimitApplyUser, &rl.ApplyUser},
+	}
+	for _, r := range rules {
+		parsed, err := ratelimit.ParseRule(r.value)
+		if err != nil {
+			return handlers.RateLimits{}, err
+		}
+		*r.rule = parsed
+	}
+	return rl, nil
+}
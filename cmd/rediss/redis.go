@@ -1,12 +1,22 @@
 package rediss
 
-import "github.com/redis/go-redis/v9"
+import (
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
 
-func RedisClient() *redis.Client {
+// NewClient returns a client with its own connection pool. It is created
+// once in main and shared by everything that talks to Redis.
+func NewClient(addr, password string, db int) *redis.Client {
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     "my-redis-container:6379",
-		Password: "", // no password set
-		DB:       0,  // use default DB
+		Addr:     addr,
+		Password: password,
+		DB:       db,
 	})
+	// Commands issued with a traced context show up as child spans
+	if err := redisotel.InstrumentTracing(rdb); err != nil {
+		log.Error().Err(err).Msg("instrumenting redis client")
+	}
 	return rdb
 }
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,11 +15,153 @@ type Config struct {
 	IdleTimeout  int    `mapstructure:"IDLE_TIMEOUT"`
 	PrivateKey   string `mapstructure:"PRIVATE_KEY"`
 	PublicKey    string `mapstructure:"PUBLIC_KEY"`
+
+	// ServiceName is reported as the service.name resource on exported spans.
+	ServiceName string `mapstructure:"SERVICE_NAME"`
+	// TraceExporter selects where spans are sent: otlp, stdout, file or none.
+	TraceExporter string `mapstructure:"TRACE_EXPORTER"`
+	// OTLPEndpoint is the host:port of the OTLP/HTTP collector.
+	OTLPEndpoint string `mapstructure:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	// OTLPInsecure disables TLS towards the collector.
+	OTLPInsecure bool `mapstructure:"OTEL_EXPORTER_OTLP_INSECURE"`
+	// TraceFile is the path spans are written to when TraceExporter is file.
+	TraceFile string `mapstructure:"TRACE_FILE"`
+
+	// Rate limits are written as <limit>/<window>, e.g. 5/1m. An empty
+	// value or a zero limit disables that limit.
+	RateLimitLoginIP             string `mapstructure:"RATE_LIMIT_LOGIN_IP"`
+	RateLimitLoginEmail          string `mapstructure:"RATE_LIMIT_LOGIN_EMAIL"`
+	RateLimitForgotPasswordIP    string `mapstructure:"RATE_LIMIT_FORGOT_PASSWORD_IP"`
+	RateLimitForgotPasswordEmail string `mapstructure:"RATE_LIMIT_FORGOT_PASSWORD_EMAIL"`
+	RateLimitResetPasswordIP     string `mapstructure:"RATE_LIMIT_RESET_PASSWORD_IP"`
+	RateLimitResetPasswordEmail  string `mapstructure:"RATE_LIMIT_RESET_PASSWORD_EMAIL"`
+	RateLimitPublicIP            string `mapstructure:"RATE_LIMIT_PUBLIC_IP"`
+	RateLimitVerifyIP            string `mapstructure:"RATE_LIMIT_VERIFY_IP"`
+	RateLimitVerifyEmail         string `mapstructure:"RATE_LIMIT_VERIFY_EMAIL"`
+	RateLimitLoginMFAUser        string `mapstructure:"RATE_LIMIT_LOGIN_MFA_USER"`
+	RateLimitApplyUser           string `mapstructure:"RATE_LIMIT_APPLY_USER"`
+	// TrustedProxies are the addresses or CIDRs of the proxies whose
+	// X-Forwarded-For header is believed, comma separated. Empty trusts none
+	// and counts requests against the address of the connection.
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	// DBDriver is postgres or sqlite. SQLite needs no server and suits local
+	// development and tests, features only Postgres has fall back to plain
+	// SQL on it. The sqlite driver is cgo, builds need CGO_ENABLED=1 and a C
+	// compiler, the dockerfile links it statically.
+	DBDriver string `mapstructure:"DB_DRIVER"`
+	// DBDSN is the Postgres connection string, or the SQLite file path or
+	// :memory:. Empty uses the compose Postgres or a fresh in-memory SQLite.
+	DBDSN string `mapstructure:"DB_DSN"`
+	// DBQueryTimeout bounds every database statement, zero leaves them
+	// unbounded.
+	DBQueryTimeout time.Duration `mapstructure:"DB_QUERY_TIMEOUT"`
+	// DBSlowQuery is the duration above which statements are logged with the
+	// trace id of their request.
+	DBSlowQuery time.Duration `mapstructure:"DB_SLOW_QUERY"`
+
+	// Redis keeps the cache, otps, login lockouts and rate limit counters
+	// shared by every instance.
+	RedisAddr     string `mapstructure:"REDIS_ADDR"`
+	RedisPassword string `mapstructure:"REDIS_PASSWORD"`
+	RedisDB       int    `mapstructure:"REDIS_DB"`
+	// JobCacheTTL bounds how long a cached job outlives a lost invalidation.
+	JobCacheTTL time.Duration `mapstructure:"JOB_CACHE_TTL"`
+	// LocalCacheTTL is how long an instance keeps its own copy of cached
+	// jobs, changes on other instances evict it over Redis pub/sub. Zero
+	// reads every entry from Redis.
+	LocalCacheTTL time.Duration `mapstructure:"LOCAL_CACHE_TTL"`
+
+	// ApplyWorkers is how many jobs of one batch of applications are loaded
+	// and matched at the same time.
+	ApplyWorkers int `mapstructure:"APPLY_WORKERS"`
+	// TaskWorkers is how many batches of applications are processed in the
+	// background at the same time.
+	TaskWorkers int `mapstructure:"TASK_WORKERS"`
+	// BulkApplyThreshold is the largest batch of applications matched
+	// within the request, larger ones are processed in the background.
+	BulkApplyThreshold int `mapstructure:"BULK_APPLY_THRESHOLD"`
+
+	// RequireVerifiedLogin refuses logins until the email is verified.
+	RequireVerifiedLogin bool `mapstructure:"REQUIRE_VERIFIED_LOGIN"`
+	// RequireVerifiedApply refuses job applications until the email is verified.
+	RequireVerifiedApply bool `mapstructure:"REQUIRE_VERIFIED_APPLY"`
+	// HideUnverifiedJobs leaves the jobs of unverified companies out of the
+	// listings.
+	HideUnverifiedJobs bool `mapstructure:"HIDE_UNVERIFIED_JOBS"`
+
+	// StorageDir is the directory uploaded files are kept in.
+	StorageDir string `mapstructure:"STORAGE_DIR"`
+	// UploadMaxBytes is the largest file accepted by upload endpoints.
+	UploadMaxBytes int64 `mapstructure:"UPLOAD_MAX_BYTES"`
+	// FileURLSecret signs the links to uploaded files, every instance needs
+	// the same one. It can only be empty with SQLite, which generates one on
+	// start.
+	FileURLSecret string `mapstructure:"FILE_URL_SECRET"`
+	// FileURLTTL is how long links to uploaded files stay valid.
+	FileURLTTL time.Duration `mapstructure:"FILE_URL_TTL"`
+}
+
+// defaults registers every key with viper so that environment variables are
+// picked up by Unmarshal even when no .env file is present.
+var defaults = map[string]any{
+	"API_PORT":                    8080,
+	"READ_TIMEOUT":                8000,
+	"WRITE_TIMEOUT":               800,
+	"IDLE_TIMEOUT":                800,
+	"PRIVATE_KEY":                 "",
+	"PUBLIC_KEY":                  "",
+	"SERVICE_NAME":                "job-portal-api",
+	"TRACE_EXPORTER":              "none",
+	"OTEL_EXPORTER_OTLP_ENDPOINT": "localhost:4318",
+	"OTEL_EXPORTER_OTLP_INSECURE": true,
+	"TRACE_FILE":                  "traces.json",
+
+	"RATE_LIMIT_LOGIN_IP":              "20/1m",
+	"RATE_LIMIT_LOGIN_EMAIL":           "5/1m",
+	"RATE_LIMIT_FORGOT_PASSWORD_IP":    "10/15m",
+	"RATE_LIMIT_FORGOT_PASSWORD_EMAIL": "3/15m",
+	"RATE_LIMIT_RESET_PASSWORD_IP":     "10/15m",
+	"RATE_LIMIT_RESET_PASSWORD_EMAIL":  "5/15m",
+	"RATE_LIMIT_PUBLIC_IP":             "60/1m",
+	"RATE_LIMIT_VERIFY_IP":             "10/15m",
+	"RATE_LIMIT_VERIFY_EMAIL":          "5/15m",
+	"RATE_LIMIT_LOGIN_MFA_USER":        "5/1m",
+	"RATE_LIMIT_APPLY_USER":            "30/1m",
+	"TRUSTED_PROXIES":                  "",
+
+	"DB_DRIVER":        "postgres",
+	"DB_DSN":           "",
+	"DB_QUERY_TIMEOUT": "5s",
+	"DB_SLOW_QUERY":    "200ms",
+
+	"REDIS_ADDR":      "my-redis-container:6379",
+	"REDIS_PASSWORD":  "",
+	"REDIS_DB":        0,
+	"JOB_CACHE_TTL":   "1h",
+	"LOCAL_CACHE_TTL": "30s",
+
+	"APPLY_WORKERS":        8,
+	"TASK_WORKERS":         4,
+	"BULK_APPLY_THRESHOLD": 100,
+
+	"REQUIRE_VERIFIED_LOGIN": false,
+	"REQUIRE_VERIFIED_APPLY": false,
+	"HIDE_UNVERIFIED_JOBS":   false,
+
+	"STORAGE_DIR":      "uploads",
+	"UPLOAD_MAX_BYTES": 5 << 20,
+	"FILE_URL_SECRET":  "",
+	"FILE_URL_TTL":     "15m",
 }
 
 func LoadConfig() (*Config, error) {
 	var config Config
 
+	for k, v := range defaults {
+		viper.SetDefault(k, v)
+	}
+
 	viper.AutomaticEnv()
 	viper.SetConfigName(".env")
 	viper.SetConfigType("dotenv")
@@ -25,7 +169,12 @@ func LoadConfig() (*Config, error) {
 
 	err := viper.ReadInConfig()
 	if err != nil {
-		return nil, fmt.Errorf("error reading config file: %w", err)
+		// The container image ships without a .env file, in that case we rely
+		// on the environment and the defaults above.
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
 	}
 
 	err = viper.Unmarshal(&config)
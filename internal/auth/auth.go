@@ -12,6 +12,22 @@ type ctxKey int
 
 const Key ctxKey = 1
 
+// Audiences added to tokens next to the default one to carry what the user
+// is allowed to do.
+const (
+	// AdminAudience is added to tokens issued to admins.
+	AdminAudience = "admin"
+	// VerifiedAudience is added to tokens of users who verified their email.
+	VerifiedAudience = "verified"
+	// MFAPendingAudience marks a token issued after the password was checked
+	// but before the second factor was. It is the only audience of such a
+	// token, so it never passes for a full login.
+	MFAPendingAudience = "mfa_pending"
+	// MFAEnrollAudience is added to pending tokens of users who have to
+	// enroll an authenticator before they can log in.
+	MFAEnrollAudience = "mfa_enroll"
+)
+
 type Auth struct {
 	privateKey *rsa.PrivateKey
 	publicKey  *rsa.PublicKey
@@ -55,3 +71,35 @@ func (a *Auth) ValidateToken(token string) (jwt.RegisteredClaims, error) {
 	}
 	return c, nil
 }
+
+// IsAdmin reports whether the claims were issued to an admin.
+func IsAdmin(c jwt.RegisteredClaims) bool {
+	return hasAudience(c, AdminAudience)
+}
+
+// IsVerified reports whether the claims were issued to a user whose email is
+// verified.
+func IsVerified(c jwt.RegisteredClaims) bool {
+	return hasAudience(c, VerifiedAudience)
+}
+
+// IsMFAPending reports whether the claims still wait for a second factor.
+func IsMFAPending(c jwt.RegisteredClaims) bool {
+	return hasAudience(c, MFAPendingAudience)
+}
+
+// CanEnrollMFA reports whether the claims may be used to enroll an
+// authenticator: full logins always can, pending ones only when enrollment
+// is what blocks the login.
+func CanEnrollMFA(c jwt.RegisteredClaims) bool {
+	return !IsMFAPending(c) || hasAudience(c, MFAEnrollAudience)
+}
+
+func hasAudience(c jwt.RegisteredClaims, aud string) bool {
+	for _, a := range c.Audience {
+		if a == aud {
+			return true
+		}
+	}
+	return false
+}
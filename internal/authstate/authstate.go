@@ -0,0 +1,64 @@
+// Package authstate keeps the short lived state of authentication attempts,
+// the one time passwords sent by email and the counters of failed logins. It
+// lives in Redis when instances have to share it or in process memory for
+// tests and single instance setups.
+package authstate
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoOTP is returned for otps that were never issued or have expired.
+var ErrNoOTP = errors.New("no otp")
+
+// Policy locks a subject out for Base once it failed Limit times within
+// Window. Every further lockout within Memory doubles the duration up to
+// Max.
+type Policy struct {
+	Limit  int
+	Window time.Duration
+	Base   time.Duration
+	Max    time.Duration
+	Memory time.Duration
+}
+
+// Store keeps otps and lockouts. Every method is atomic, instances sharing a
+// store never let more attempts through than one instance would.
+type Store interface {
+	// StartCooldown starts a cooldown of d under key unless one is running
+	// and reports whether it did.
+	StartCooldown(ctx context.Context, key string, d time.Duration) (bool, error)
+	// PutOTP stores the hash of an otp under key for ttl, replacing the otp
+	// stored before together with its attempts.
+	PutOTP(ctx context.Context, key string, hash []byte, ttl time.Duration) error
+	// AttemptOTP counts an attempt against the otp under key and returns its
+	// hash with the attempts made so far, or ErrNoOTP.
+	AttemptOTP(ctx context.Context, key string) ([]byte, int, error)
+	// Delete removes the keys and returns how many of them existed.
+	Delete(ctx context.Context, keys ...string) (int, error)
+
+	// LockedFor returns how long subject stays locked, 0 when it isn't.
+	LockedFor(ctx context.Context, subject string) (time.Duration, error)
+	// RecordFailure counts a failure against subject and returns the lockout
+	// it triggered, 0 if the limit wasn't reached.
+	RecordFailure(ctx context.Context, subject string, p Policy) (time.Duration, error)
+	// ClearFailures forgets the failures of subject, earlier lockouts still
+	// count towards the backoff.
+	ClearFailures(ctx context.Context, subject string) error
+	// Unlock lifts the lockout of subject and resets its backoff.
+	Unlock(ctx context.Context, subject string) error
+}
+
+func lockKey(subject string) string {
+	return "login:lock:" + subject
+}
+
+func failuresKey(subject string) string {
+	return "login:failures:" + subject
+}
+
+func lockoutsKey(subject string) string {
+	return "login:lockouts:" + subject
+}
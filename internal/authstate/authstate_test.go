@@ -0,0 +1,99 @@
+package authstate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var policy = Policy{Limit: 2, Window: time.Minute, Base: time.Minute, Max: 3 * time.Minute, Memory: time.Hour}
+
+func testStore(t *testing.T, s Store, advance func(time.Duration)) {
+	ctx := context.Background()
+
+	ok, err := s.StartCooldown(ctx, "otp:cooldown", time.Minute)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	ok, err = s.StartCooldown(ctx, "otp:cooldown", time.Minute)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, ok)
+
+	// Replacing an otp resets its attempts
+	assert.Equal(t, nil, s.PutOTP(ctx, "otp", []byte("old"), 5*time.Minute))
+	_, _, err = s.AttemptOTP(ctx, "otp")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, s.PutOTP(ctx, "otp", []byte("hash"), 5*time.Minute))
+	hash, attempts, err := s.AttemptOTP(ctx, "otp")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hash", string(hash))
+	assert.Equal(t, 1, attempts)
+	_, attempts, _ = s.AttemptOTP(ctx, "otp")
+	assert.Equal(t, 2, attempts)
+
+	n, err := s.Delete(ctx, "otp", "missing")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, n)
+	_, _, err = s.AttemptOTP(ctx, "otp")
+	assert.Equal(t, ErrNoOTP, err)
+
+	// Otps and cooldowns expire
+	assert.Equal(t, nil, s.PutOTP(ctx, "otp", []byte("hash"), 5*time.Minute))
+	advance(5 * time.Minute)
+	_, _, err = s.AttemptOTP(ctx, "otp")
+	assert.Equal(t, ErrNoOTP, err)
+	ok, _ = s.StartCooldown(ctx, "otp:cooldown", time.Minute)
+	assert.Equal(t, true, ok)
+
+	// Every lockout doubles the next one up to the maximum
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		d, err := s.RecordFailure(ctx, "account:vishnu", policy)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, time.Duration(0), d)
+		d, err = s.RecordFailure(ctx, "account:vishnu", policy)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, want, d)
+		locked, err := s.LockedFor(ctx, "account:vishnu")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, want, locked)
+		advance(want)
+		locked, _ = s.LockedFor(ctx, "account:vishnu")
+		assert.Equal(t, time.Duration(0), locked)
+	}
+
+	// Failures outside the window don't add up
+	d, _ := s.RecordFailure(ctx, "ip:10.0.0.1", policy)
+	assert.Equal(t, time.Duration(0), d)
+	advance(time.Minute)
+	d, _ = s.RecordFailure(ctx, "ip:10.0.0.1", policy)
+	assert.Equal(t, time.Duration(0), d)
+
+	// Clearing failures keeps the backoff, unlocking resets it
+	assert.Equal(t, nil, s.ClearFailures(ctx, "account:vishnu"))
+	s.RecordFailure(ctx, "account:vishnu", policy)
+	d, _ = s.RecordFailure(ctx, "account:vishnu", policy)
+	assert.Equal(t, 3*time.Minute, d)
+	assert.Equal(t, nil, s.Unlock(ctx, "account:vishnu"))
+	locked, _ := s.LockedFor(ctx, "account:vishnu")
+	assert.Equal(t, time.Duration(0), locked)
+	s.RecordFailure(ctx, "account:vishnu", policy)
+	d, _ = s.RecordFailure(ctx, "account:vishnu", policy)
+	assert.Equal(t, time.Minute, d)
+}
+
+func TestMemory(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	testStore(t, m, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	testStore(t, NewRedis(rdb), mr.FastForward)
+}
@@ -0,0 +1,154 @@
+package authstate
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps otps and lockouts in process memory, every instance counts
+// attempts on its own.
+type Memory struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]*entry
+	calls   int
+}
+
+type entry struct {
+	hash []byte
+	// n is the number of attempts against an otp, or the failures or
+	// lockouts of a subject.
+	n       int
+	expires time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{now: time.Now, entries: map[string]*entry{}}
+}
+
+func (m *Memory) StartCooldown(_ context.Context, key string, d time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lookup(key) != nil {
+		return false, nil
+	}
+	m.set(key, &entry{}, d)
+	return true, nil
+}
+
+func (m *Memory) PutOTP(_ context.Context, key string, hash []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(key, &entry{hash: append([]byte(nil), hash...)}, ttl)
+	return nil
+}
+
+func (m *Memory) AttemptOTP(_ context.Context, key string) ([]byte, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.lookup(key)
+	if e == nil || e.hash == nil {
+		return nil, 0, ErrNoOTP
+	}
+	e.n++
+	return append([]byte(nil), e.hash...), e.n, nil
+}
+
+func (m *Memory) Delete(_ context.Context, keys ...string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int
+	for _, k := range keys {
+		if m.lookup(k) != nil {
+			n++
+		}
+		delete(m.entries, k)
+	}
+	return n, nil
+}
+
+func (m *Memory) LockedFor(_ context.Context, subject string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.lookup(lockKey(subject))
+	if e == nil {
+		return 0, nil
+	}
+	return e.expires.Sub(m.now()), nil
+}
+
+func (m *Memory) RecordFailure(_ context.Context, subject string, p Policy) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	failures := m.lookup(failuresKey(subject))
+	if failures == nil {
+		failures = &entry{}
+		m.set(failuresKey(subject), failures, p.Window)
+	}
+	failures.n++
+	if failures.n < p.Limit {
+		return 0, nil
+	}
+
+	lockouts := m.lookup(lockoutsKey(subject))
+	if lockouts == nil {
+		lockouts = &entry{}
+	}
+	lockouts.n++
+	m.set(lockoutsKey(subject), lockouts, p.Memory)
+	d := p.Base << (lockouts.n - 1)
+	if d > p.Max || d <= 0 {
+		d = p.Max
+	}
+	m.set(lockKey(subject), &entry{}, d)
+	delete(m.entries, failuresKey(subject))
+	return d, nil
+}
+
+func (m *Memory) ClearFailures(_ context.Context, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, failuresKey(subject))
+	return nil
+}
+
+func (m *Memory) Unlock(_ context.Context, subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, lockKey(subject))
+	delete(m.entries, failuresKey(subject))
+	delete(m.entries, lockoutsKey(subject))
+	return nil
+}
+
+// set stores e under key for ttl, it has to be called with mu held. Expired
+// entries nobody asks for again are dropped every so often.
+func (m *Memory) set(key string, e *entry, ttl time.Duration) {
+	now := m.now()
+	e.expires = now.Add(ttl)
+	m.entries[key] = e
+
+	m.calls++
+	if m.calls%1024 == 0 {
+		for k, e := range m.entries {
+			if !now.Before(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+	}
+}
+
+// lookup returns the live entry of key or nil, it has to be called with mu
+// held.
+func (m *Memory) lookup(key string) *entry {
+	e, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	if !m.now().Before(e.expires) {
+		delete(m.entries, key)
+		return nil
+	}
+	return e
+}
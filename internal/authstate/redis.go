@@ -0,0 +1,128 @@
+package authstate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// otpAttempt returns the stored hash and the attempt count after counting
+// this attempt, or nil once the otp expired. Doing both in one script keeps
+// an expiring otp from being recreated without a ttl.
+var otpAttempt = redis.NewScript(`
+local hash = redis.call('HGET', KEYS[1], 'hash')
+if not hash then
+	return false
+end
+return {hash, redis.call('HINCRBY', KEYS[1], 'attempts', 1)}
+`)
+
+// recordFailure counts a failure and locks the subject once the limit is
+// reached. It returns the lockout duration in milliseconds, 0 when the
+// subject is not locked by this failure.
+var recordFailure = redis.NewScript(`
+local failures = redis.call('INCR', KEYS[1])
+if failures == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+if failures < tonumber(ARGV[1]) then
+	return 0
+end
+
+local lockouts = redis.call('INCR', KEYS[3])
+redis.call('PEXPIRE', KEYS[3], ARGV[5])
+local d = tonumber(ARGV[3]) * 2 ^ (lockouts - 1)
+if d > tonumber(ARGV[4]) then
+	d = tonumber(ARGV[4])
+end
+redis.call('SET', KEYS[2], 1, 'PX', d)
+redis.call('DEL', KEYS[1])
+return d
+`)
+
+// Redis shares otps and lockouts between every instance of the API. The hash
+// of an otp and the attempts made against it live in one redis hash so that
+// both expire together.
+type Redis struct {
+	rdb redis.Cmdable
+}
+
+func NewRedis(rdb redis.Cmdable) *Redis {
+	return &Redis{rdb: rdb}
+}
+
+func (r *Redis) StartCooldown(ctx context.Context, key string, d time.Duration) (bool, error) {
+	ok, err := r.rdb.SetNX(ctx, key, 1, d).Result()
+	if err != nil {
+		return false, fmt.Errorf("setting cooldown: %w", err)
+	}
+	return ok, nil
+}
+
+func (r *Redis) PutOTP(ctx context.Context, key string, hash []byte, ttl time.Duration) error {
+	_, err := r.rdb.TxPipelined(ctx, func(tx redis.Pipeliner) error {
+		tx.Del(ctx, key)
+		tx.HSet(ctx, key, "hash", hash, "attempts", 0)
+		tx.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("storing otp: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) AttemptOTP(ctx context.Context, key string) ([]byte, int, error) {
+	res, err := otpAttempt.Run(ctx, r.rdb, []string{key}).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, 0, ErrNoOTP
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("counting otp attempt: %w", err)
+	}
+	hash, _ := res[0].(string)
+	attempts, _ := res[1].(int64)
+	return []byte(hash), int(attempts), nil
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) (int, error) {
+	n, err := r.rdb.Del(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("deleting %v: %w", keys, err)
+	}
+	return int(n), nil
+}
+
+func (r *Redis) LockedFor(ctx context.Context, subject string) (time.Duration, error) {
+	d, err := r.rdb.PTTL(ctx, lockKey(subject)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("reading lock of %s: %w", subject, err)
+	}
+	// PTTL reports missing keys with a negative duration
+	if d < 0 {
+		return 0, nil
+	}
+	return d, nil
+}
+
+func (r *Redis) RecordFailure(ctx context.Context, subject string, p Policy) (time.Duration, error) {
+	ms, err := recordFailure.Run(ctx, r.rdb,
+		[]string{failuresKey(subject), lockKey(subject), lockoutsKey(subject)},
+		p.Limit, p.Window.Milliseconds(), p.Base.Milliseconds(),
+		p.Max.Milliseconds(), p.Memory.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("counting failure of %s: %w", subject, err)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func (r *Redis) ClearFailures(ctx context.Context, subject string) error {
+	return r.rdb.Del(ctx, failuresKey(subject)).Err()
+}
+
+func (r *Redis) Unlock(ctx context.Context, subject string) error {
+	return r.rdb.Del(ctx, lockKey(subject), failuresKey(subject), lockoutsKey(subject)).Err()
+}
@@ -0,0 +1,26 @@
+// Package cache stores short lived values shared between requests, in Redis
+// when instances have to share them or in process memory for tests and
+// single instance setups.
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned for keys that don't exist or have expired.
+var ErrMiss = errors.New("cache miss")
+
+// Cache is a key value store with per key expiry.
+type Cache interface {
+	// Get returns the value stored under key or ErrMiss.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key, a ttl of 0 keeps it until it is deleted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the keys, missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+	// TTL returns how long key is kept, 0 when it doesn't expire and ErrMiss
+	// when it doesn't exist.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// testCache runs the same checks against every implementation, advance
+// moves the clock of the implementation forward.
+func testCache(t *testing.T, c Cache, advance func(time.Duration)) {
+	ctx := context.Background()
+
+	_, err := c.Get(ctx, "job:1")
+	assert.Equal(t, true, errors.Is(err, ErrMiss))
+	_, err = c.TTL(ctx, "job:1")
+	assert.Equal(t, true, errors.Is(err, ErrMiss))
+
+	err = c.Set(ctx, "job:1", []byte("java"), time.Minute)
+	assert.Equal(t, nil, err)
+	err = c.Set(ctx, "job:2", []byte("go"), 0)
+	assert.Equal(t, nil, err)
+
+	v, err := c.Get(ctx, "job:1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "java", string(v))
+	d, err := c.TTL(ctx, "job:1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, d > 59*time.Second && d <= time.Minute)
+	d, err = c.TTL(ctx, "job:2")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, time.Duration(0), d)
+
+	advance(time.Minute)
+	_, err = c.Get(ctx, "job:1")
+	assert.Equal(t, true, errors.Is(err, ErrMiss))
+	v, err = c.Get(ctx, "job:2")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "go", string(v))
+
+	err = c.Delete(ctx, "job:2", "job:3")
+	assert.Equal(t, nil, err)
+	_, err = c.Get(ctx, "job:2")
+	assert.Equal(t, true, errors.Is(err, ErrMiss))
+	assert.Equal(t, nil, c.Delete(ctx))
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	testCache(t, m, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	testCache(t, NewRedis(rdb), mr.FastForward)
+}
+
+func TestMemory_ValuesAreCopied(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	b := []byte("java")
+	assert.Equal(t, nil, m.Set(ctx, "job:1", b, 0))
+	b[0] = 'J'
+	v, _ := m.Get(ctx, "job:1")
+	assert.Equal(t, "java", string(v))
+}
+
+func TestLayered(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	m := NewMemory()
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	testCache(t, NewLayered(m, NewRedis(rdb), 30*time.Second), func(d time.Duration) {
+		now = now.Add(d)
+		mr.FastForward(d)
+	})
+}
+
+func TestLayered_Evict(t *testing.T) {
+	ctx := context.Background()
+	remote := NewMemory()
+	l := NewLayered(NewMemory(), remote, time.Minute)
+	assert.Equal(t, nil, l.Set(ctx, "job:1", []byte("java"), 0))
+
+	// Another instance changes the entry, the local copy is served until
+	// it is evicted
+	assert.Equal(t, nil, remote.Set(ctx, "job:1", []byte("go"), 0))
+	v, _ := l.Get(ctx, "job:1")
+	assert.Equal(t, "java", string(v))
+	l.Evict(ctx, "job:1")
+	v, _ = l.Get(ctx, "job:1")
+	assert.Equal(t, "go", string(v))
+
+	assert.Equal(t, nil, remote.Set(ctx, "job:1", []byte("rust"), 0))
+	l.Purge()
+	v, _ = l.Get(ctx, "job:1")
+	assert.Equal(t, "rust", string(v))
+}
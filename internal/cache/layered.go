@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Layered keeps a short lived copy of the entries of a shared cache in
+// process memory. Deletes on other instances don't reach the copies, they
+// have to be evicted through the invalidation bus or age out after localTTL.
+type Layered struct {
+	local    *Memory
+	remote   Cache
+	localTTL time.Duration
+}
+
+func NewLayered(local *Memory, remote Cache, localTTL time.Duration) *Layered {
+	return &Layered{local: local, remote: remote, localTTL: localTTL}
+}
+
+func (l *Layered) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := l.local.Get(ctx, key)
+	if err == nil {
+		return v, nil
+	}
+	v, err = l.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	// The remaining ttl of the entry isn't known, localTTL is short enough
+	_ = l.local.Set(ctx, key, v, l.localTTL)
+	return v, nil
+}
+
+func (l *Layered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := l.remote.Set(ctx, key, value, ttl)
+	if err != nil {
+		return err
+	}
+	return l.local.Set(ctx, key, value, l.ttl(ttl))
+}
+
+func (l *Layered) Delete(ctx context.Context, keys ...string) error {
+	// Evicted first so a failing remote doesn't leave the local copy behind
+	_ = l.local.Delete(ctx, keys...)
+	return l.remote.Delete(ctx, keys...)
+}
+
+func (l *Layered) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return l.remote.TTL(ctx, key)
+}
+
+// Evict drops the local copies of keys and leaves the shared entries alone.
+func (l *Layered) Evict(ctx context.Context, keys ...string) {
+	_ = l.local.Delete(ctx, keys...)
+}
+
+// Purge drops every local copy.
+func (l *Layered) Purge() {
+	l.local.Clear()
+}
+
+func (l *Layered) ttl(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < l.localTTL {
+		return ttl
+	}
+	return l.localTTL
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps values in process memory, every instance has its own.
+type Memory struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]entry
+	calls   int
+}
+
+type entry struct {
+	value []byte
+	// expires is zero for entries that are kept until deleted.
+	expires time.Time
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+func NewMemory() *Memory {
+	return &Memory{now: time.Now, entries: map[string]entry{}}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.lookup(key)
+	if !ok {
+		return nil, ErrMiss
+	}
+	// Callers may modify the returned slice
+	return append([]byte(nil), e.value...), nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	e := entry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+	m.entries[key] = e
+
+	// Expired entries nobody asks for again are dropped every so often
+	m.calls++
+	if m.calls%1024 == 0 {
+		for k, e := range m.entries {
+			if e.expired(now) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range keys {
+		delete(m.entries, k)
+	}
+	return nil
+}
+
+func (m *Memory) TTL(_ context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.lookup(key)
+	if !ok {
+		return 0, ErrMiss
+	}
+	if e.expires.IsZero() {
+		return 0, nil
+	}
+	return e.expires.Sub(m.now()), nil
+}
+
+// lookup returns the live entry of key, it has to be called with mu held.
+func (m *Memory) lookup(key string) (entry, bool) {
+	e, ok := m.entries[key]
+	if !ok {
+		return entry{}, false
+	}
+	if e.expired(m.now()) {
+		delete(m.entries, key)
+		return entry{}, false
+	}
+	return e, true
+}
+
+// Clear drops every entry.
+func (m *Memory) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = map[string]entry{}
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis shares the cache between every instance using the same server.
+type Redis struct {
+	rdb redis.Cmdable
+}
+
+func NewRedis(rdb redis.Cmdable) *Redis {
+	return &Redis{rdb: rdb}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := r.rdb.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s from cache: %w", key, err)
+	}
+	return b, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := r.rdb.Set(ctx, key, value, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("writing %s to cache: %w", key, err)
+	}
+	return nil
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	err := r.rdb.Del(ctx, keys...).Err()
+	if err != nil {
+		return fmt.Errorf("deleting from cache: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) TTL(ctx context.Context, key string) (time.Duration, error) {
+	d, err := r.rdb.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("reading ttl of %s: %w", key, err)
+	}
+	// PTTL reports missing keys with -2 and keys without expiry with -1
+	switch d {
+	case -2:
+		return 0, ErrMiss
+	case -1:
+		return 0, nil
+	}
+	return d, nil
+}
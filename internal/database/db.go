@@ -1,24 +1,85 @@
 package database
 
 import (
-	"job-portal/internal/models"
+	"fmt"
+	"time"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-func Open() (*gorm.DB, error) {
-	dsn := "host=postgres user=postgres password=admin dbname=postgres port=5432 sslmode=disable TimeZone=Asia/Shanghai"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// Supported drivers.
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+// DefaultDSN is the Postgres database of the compose setup.
+const DefaultDSN = "host=postgres user=postgres password=admin dbname=postgres port=5432 sslmode=disable TimeZone=Asia/Shanghai"
+
+// Config selects the database and tunes how queries are run and logged.
+type Config struct {
+	// Driver is postgres or sqlite, empty means postgres.
+	Driver string
+	// DSN is the connection string for Postgres and a file path or :memory:
+	// for SQLite. Empty means DefaultDSN or an in-memory database.
+	DSN string
+	// QueryTimeout bounds every statement, the deadline of the request wins
+	// when it is earlier. Zero leaves statements unbounded.
+	QueryTimeout time.Duration
+	// SlowQuery is the duration above which statements are logged as slow.
+	SlowQuery time.Duration
+}
+
+// Open connects to the database and migrates the schema.
+func Open(c Config) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch c.Driver {
+	case Postgres, "":
+		dsn := c.DSN
+		if dsn == "" {
+			dsn = DefaultDSN
+		}
+		dialector = postgres.Open(dsn)
+	case SQLite:
+		dialector = sqliteDialector{Dialector: sqlite.Dialector{DSN: sqliteDSN(c.DSN)}}
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", c.Driver)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
+		// Surface unique violations as gorm.ErrDuplicatedKey
+		TranslateError: true,
+		Logger:         queryLogger{slow: c.SlowQuery},
+	})
 	if err != nil {
 		return nil, err
 	}
+	if c.Driver == SQLite {
+		// SQLite takes one writer at a time and every connection to
+		// :memory: opens an empty database
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	err = db.Use(tracingPlugin{})
+	if err != nil {
+		return nil, err
+	}
+	if c.QueryTimeout > 0 {
+		err = db.Use(timeoutPlugin{timeout: c.QueryTimeout})
+		if err != nil {
+			return nil, err
+		}
+	}
 	// err = db.Migrator().DropTable(&models.User{}, &models.Company{}, &models.Job{})
 	// if err != nil {
 	// 	// If there is an error while migrating, log the error message and stop the program
 	// 	return nil, err
 	// }
-	err = db.Migrator().AutoMigrate(&models.User{}, &models.Company{}, &models.Job{})
+	err = Migrate(db)
 	if err != nil {
 		// If there is an error while migrating, log the error message and stop the program
 		return nil, err
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"job-portal/internal/models"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/go-playground/assert.v1"
+	"gorm.io/gorm"
+)
+
+func openSQLite(t *testing.T, dsn string) *gorm.DB {
+	db, err := Open(Config{Driver: SQLite, DSN: dsn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	return db
+}
+
+func TestOpen_SQLiteFile(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "job-portal.db")
+	db := openSQLite(t, dsn)
+	u := models.User{Name: "vishnu", Email: "vishnu@example.com"}
+	assert.Equal(t, nil, db.Create(&u).Error)
+
+	// Migrating an existing database keeps the data
+	db = openSQLite(t, dsn)
+	var got models.User
+	assert.Equal(t, nil, db.First(&got, u.ID).Error)
+	assert.Equal(t, "vishnu@example.com", got.Email)
+	// user_id is filled like the bigserial on Postgres
+	assert.Equal(t, got.ID, got.UserId)
+
+	// Foreign keys are enforced
+	err := db.Create(&models.Job{Title: "go developer", CompanyID: 42}).Error
+	assert.Equal(t, true, errors.Is(err, gorm.ErrForeignKeyViolated))
+}
+
+func TestOpen_UnknownDriver(t *testing.T) {
+	_, err := Open(Config{Driver: "mysql"})
+	assert.Equal(t, `unknown database driver "mysql"`, err.Error())
+}
+
+func TestUniqueEmails(t *testing.T) {
+	db := openSQLite(t, ":memory:")
+	// Go back to before emails were unique
+	assert.Equal(t, nil, db.Exec("DROP INDEX idx_users_email_lower").Error)
+
+	company := uint(7)
+	users := []models.User{
+		{Name: "first", Email: "Vishnu@Example.com"},
+		{Name: "verified", Email: " vishnu@example.com", Verified: true},
+		{Name: "admin", Email: "VISHNU@example.com", Admin: true, CompanyID: &company},
+		{Name: "other", Email: " Vikram@example.com "},
+	}
+	assert.Equal(t, nil, db.Create(&users).Error)
+
+	dups, err := uniqueEmails(db)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []DuplicateEmail{{Email: "vishnu@example.com", Kept: users[1].ID, Merged: []uint{users[0].ID, users[2].ID}}}, dups)
+
+	var live []models.User
+	assert.Equal(t, nil, db.Order("id").Find(&live).Error)
+	assert.Equal(t, 2, len(live))
+	assert.Equal(t, "verified", live[0].Name)
+	assert.Equal(t, true, live[0].Admin)
+	assert.Equal(t, company, *live[0].CompanyID)
+	assert.Equal(t, "vikram@example.com", live[1].Email)
+
+	// The index is back and running again does nothing
+	err = db.Create(&models.User{Name: "again", Email: "vishnu@example.com"}).Error
+	assert.Equal(t, true, errors.Is(err, gorm.ErrDuplicatedKey))
+	dups, err = uniqueEmails(db)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(dups))
+}
+
+// beforeCompanyOwners goes back to before companies had owners.
+func beforeCompanyOwners(t *testing.T, db *gorm.DB) {
+	assert.Equal(t, nil, db.Exec("DELETE FROM schema_migrations WHERE name = ?", "company_owners").Error)
+}
+
+func TestMigrate_CompanyOwners(t *testing.T) {
+	db := openSQLite(t, ":memory:")
+	beforeCompanyOwners(t, db)
+	coms := []models.Company{{Name: "tek"}, {Name: "infy"}}
+	assert.Equal(t, nil, db.Create(&coms).Error)
+	users := []models.User{
+		{Name: "first", Email: "first@example.com", CompanyID: &coms[0].ID},
+		{Name: "second", Email: "second@example.com", CompanyID: &coms[0].ID},
+	}
+	assert.Equal(t, nil, db.Create(&users).Error)
+
+	assert.Equal(t, nil, Migrate(db))
+	var got []models.Company
+	assert.Equal(t, nil, db.Order("id").Find(&got).Error)
+	assert.Equal(t, users[0].ID, *got[0].OwnerID)
+	// Companies without members keep no owner
+	assert.Equal(t, (*uint)(nil), got[1].OwnerID)
+
+	// The backfill ran, later starts don't give owners to companies
+	assert.Equal(t, nil, db.Model(&users[1]).Update("company_id", coms[1].ID).Error)
+	assert.Equal(t, nil, Migrate(db))
+	assert.Equal(t, nil, db.First(&got[1], coms[1].ID).Error)
+	assert.Equal(t, (*uint)(nil), got[1].OwnerID)
+}
+
+func TestMigrate_CompanyOwnersAfterMerging(t *testing.T) {
+	db := openSQLite(t, ":memory:")
+	assert.Equal(t, nil, db.Exec("DROP INDEX idx_users_email_lower").Error)
+	beforeCompanyOwners(t, db)
+	coms := []models.Company{{Name: "tek"}, {Name: "infy"}}
+	assert.Equal(t, nil, db.Create(&coms).Error)
+	// The first member of tek is merged into the verified account, which
+	// then owns it. infy already belongs to the merged account.
+	users := []models.User{
+		{Name: "first", Email: "vishnu@example.com", CompanyID: &coms[0].ID},
+		{Name: "verified", Email: "Vishnu@example.com", Verified: true},
+	}
+	assert.Equal(t, nil, db.Create(&users).Error)
+	assert.Equal(t, nil, db.Model(&coms[1]).Update("owner_id", users[0].ID).Error)
+
+	assert.Equal(t, nil, Migrate(db))
+	var got []models.Company
+	assert.Equal(t, nil, db.Order("id").Find(&got).Error)
+	assert.Equal(t, users[1].ID, *got[0].OwnerID)
+	assert.Equal(t, users[1].ID, *got[1].OwnerID)
+}
+
+func TestMigrate_LogsMergedEmails(t *testing.T) {
+	db := openSQLite(t, ":memory:")
+	assert.Equal(t, nil, db.Exec("DROP INDEX idx_users_email_lower").Error)
+	users := []models.User{{Name: "first", Email: "vishnu@example.com"}, {Name: "second", Email: "Vishnu@example.com"}}
+	assert.Equal(t, nil, db.Create(&users).Error)
+
+	var out bytes.Buffer
+	prev := log.Logger
+	log.Logger = zerolog.New(&out)
+	t.Cleanup(func() { log.Logger = prev })
+
+	assert.Equal(t, nil, Migrate(db))
+	var entry struct {
+		Email  string `json:"email"`
+		Kept   uint   `json:"kept"`
+		Merged []uint `json:"merged"`
+	}
+	assert.Equal(t, nil, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "vishnu@example.com", entry.Email)
+	assert.Equal(t, users[0].ID, entry.Kept)
+	assert.Equal(t, []uint{users[1].ID}, entry.Merged)
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"fmt"
+	"job-portal/internal/models"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Migrate brings the schema up to date.
+func Migrate(db *gorm.DB) error {
+	err := db.Migrator().AutoMigrate(&schemaMigration{}, &models.User{}, &models.Company{}, &models.Job{}, &models.AuditEvent{}, &models.RecoveryCode{},
+		&models.Task{}, &models.TaskItem{}, &models.CompanyMedia{}, &models.CompanyVerification{})
+	if err != nil {
+		return err
+	}
+	if db.Dialector.Name() == SQLite {
+		err = db.Exec(userIDTrigger).Error
+		if err != nil {
+			return fmt.Errorf("creating user id trigger: %w", err)
+		}
+	}
+	// Accounts are merged first so owners are picked among the kept ones
+	dups, err := uniqueEmails(db)
+	if err != nil {
+		return err
+	}
+	for _, d := range dups {
+		log.Warn().Str("email", d.Email).Uint("kept", d.Kept).Uints("merged", d.Merged).
+			Msg("merged accounts sharing an email")
+	}
+	err = runOnce(db, "company_owners", func(tx *gorm.DB) error {
+		return tx.Exec(companyOwners).Error
+	})
+	if err != nil {
+		return fmt.Errorf("setting company owners: %w", err)
+	}
+	return companySearch(db)
+}
+
+// schemaMigration records a data migration that ran.
+type schemaMigration struct {
+	Name  string `gorm:"primaryKey"`
+	RanAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// runOnce runs the data migration called name unless it is recorded as ran.
+// The record is written in the same transaction first, so instances starting
+// together wait for each other and only one of them runs it.
+func runOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&schemaMigration{Name: name, RanAt: time.Now()})
+		if res.Error != nil {
+			return fmt.Errorf("recording migration %s: %w", name, res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		return migrate(tx)
+	})
+}
+
+// companyOwners makes the first member of every company created before
+// companies had owners its owner. Companies without members stay without
+// one. It runs once, a company left without an owner later isn't given one.
+const companyOwners = "UPDATE companies SET owner_id = (SELECT min(id) FROM users " +
+	"WHERE users.company_id = companies.id AND users.deleted_at IS NULL) WHERE owner_id IS NULL"
+
+// companyNameTrigrams serves searches of company names by substring and by
+// similarity.
+const companyNameTrigrams = "CREATE INDEX IF NOT EXISTS idx_companies_name_trgm ON companies USING gin (lower(name) gin_trgm_ops)"
+
+// companySearch enables searching similar company names on Postgres.
+// Creating the extension needs rights the database user may lack, search
+// then only matches names starting with or containing the query, like it
+// does on SQLite.
+func companySearch(db *gorm.DB) error {
+	if db.Dialector.Name() != Postgres {
+		return nil
+	}
+	err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error
+	if err != nil {
+		log.Warn().Err(err).Msg("pg_trgm is unavailable, company search won't match similar names")
+		return nil
+	}
+	err = db.Exec(companyNameTrigrams).Error
+	if err != nil {
+		return fmt.Errorf("creating company name index: %w", err)
+	}
+	return nil
+}
+
+// DuplicateEmail reports accounts that shared an email before emails were
+// unique. Kept is the account the others were merged into.
+type DuplicateEmail struct {
+	Email  string
+	Kept   uint
+	Merged []uint
+}
+
+// uniqueEmailIndex only covers live accounts, merged duplicates are soft
+// deleted and keep their email.
+const uniqueEmailIndex = "CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email)) WHERE deleted_at IS NULL"
+
+// uniqueEmails merges accounts that share an email ignoring case and
+// surrounding spaces, normalizes every email and creates the unique index.
+// Running it again once the index exists does nothing. The merges are
+// reported once they are committed.
+func uniqueEmails(db *gorm.DB) ([]DuplicateEmail, error) {
+	var dups []DuplicateEmail
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var emails []string
+		err := tx.Raw("SELECT lower(trim(email)) FROM users WHERE deleted_at IS NULL " +
+			"GROUP BY lower(trim(email)) HAVING count(*) > 1").Scan(&emails).Error
+		if err != nil {
+			return fmt.Errorf("finding duplicate emails: %w", err)
+		}
+
+		for _, email := range emails {
+			d, err := mergeDuplicates(tx, email)
+			if err != nil {
+				return err
+			}
+			dups = append(dups, d)
+		}
+
+		err = tx.Exec("UPDATE users SET email = lower(trim(email)) WHERE email <> lower(trim(email))").Error
+		if err != nil {
+			return fmt.Errorf("normalizing emails: %w", err)
+		}
+		err = tx.Exec(uniqueEmailIndex).Error
+		if err != nil {
+			return fmt.Errorf("creating unique email index: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dups, nil
+}
+
+// mergeDuplicates keeps the verified account registered first, or the first
+// one when none is verified. Rights, the company membership and the companies
+// owned by the others carry over, then they are soft deleted together with
+// their recovery codes.
+func mergeDuplicates(tx *gorm.DB, email string) (DuplicateEmail, error) {
+	var users []models.User
+	err := tx.Where("lower(trim(email)) = ?", email).Order("verified DESC, created_at, id").Find(&users).Error
+	if err != nil {
+		return DuplicateEmail{}, fmt.Errorf("fetching accounts of %s: %w", email, err)
+	}
+
+	kept := users[0]
+	d := DuplicateEmail{Email: email, Kept: kept.ID}
+	for _, u := range users[1:] {
+		kept.Admin = kept.Admin || u.Admin
+		if kept.CompanyID == nil {
+			kept.CompanyID = u.CompanyID
+		}
+		d.Merged = append(d.Merged, u.ID)
+	}
+
+	err = tx.Model(&kept).Select("admin", "company_id").Updates(&kept).Error
+	if err != nil {
+		return DuplicateEmail{}, fmt.Errorf("merging accounts of %s: %w", email, err)
+	}
+	err = tx.Model(&models.Company{}).Where("owner_id IN ?", d.Merged).Update("owner_id", kept.ID).Error
+	if err != nil {
+		return DuplicateEmail{}, fmt.Errorf("moving companies of %s: %w", email, err)
+	}
+	err = tx.Where("user_id IN ?", d.Merged).Delete(&models.RecoveryCode{}).Error
+	if err != nil {
+		return DuplicateEmail{}, fmt.Errorf("dropping recovery codes of %s: %w", email, err)
+	}
+	err = tx.Delete(&models.User{}, d.Merged).Error
+	if err != nil {
+		return DuplicateEmail{}, fmt.Errorf("deleting duplicates of %s: %w", email, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"job-portal/internal/middleware"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const cancelKey = "timeout:cancel"
+
+// timeoutPlugin gives every statement its own deadline. Row statements are
+// left alone, their rows are read after the callbacks returned.
+type timeoutPlugin struct {
+	timeout time.Duration
+}
+
+func (timeoutPlugin) Name() string {
+	return "timeout"
+}
+
+func (p timeoutPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		name   string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("*").Register, cb.Create().After("*").Register},
+		{"query", cb.Query().Before("*").Register, cb.Query().After("*").Register},
+		{"update", cb.Update().Before("*").Register, cb.Update().After("*").Register},
+		{"delete", cb.Delete().Before("*").Register, cb.Delete().After("*").Register},
+		{"raw", cb.Raw().Before("*").Register, cb.Raw().After("*").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("timeout:before_"+h.name, p.before); err != nil {
+			return err
+		}
+		if err := h.after("timeout:after_"+h.name, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p timeoutPlugin) before(db *gorm.DB) {
+	ctx := db.Statement.Context
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= p.timeout {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	db.Statement.Context = ctx
+	db.InstanceSet(cancelKey, cancel)
+}
+
+func (timeoutPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(cancelKey)
+	if !ok {
+		return
+	}
+	if cancel, ok := v.(context.CancelFunc); ok {
+		cancel()
+	}
+}
+
+// queryLogger sends gorm's logs through zerolog. Slow statements carry the
+// trace id of the request that ran them.
+type queryLogger struct {
+	slow time.Duration
+}
+
+func (l queryLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (queryLogger) Info(ctx context.Context, msg string, args ...any) {
+	log.Info().Str("Trace Id", traceID(ctx)).Msgf(msg, args...)
+}
+
+func (queryLogger) Warn(ctx context.Context, msg string, args ...any) {
+	log.Warn().Str("Trace Id", traceID(ctx)).Msgf(msg, args...)
+}
+
+func (queryLogger) Error(ctx context.Context, msg string, args ...any) {
+	log.Error().Str("Trace Id", traceID(ctx)).Msgf(msg, args...)
+}
+
+func (l queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		log.Error().Err(err).Str("Trace Id", traceID(ctx)).Dur("elapsed", elapsed).
+			Str("sql", sql).Int64("rows", rows).Msg("query failed")
+	case l.slow > 0 && elapsed > l.slow:
+		sql, rows := fc()
+		log.Warn().Str("Trace Id", traceID(ctx)).Dur("elapsed", elapsed).
+			Str("sql", sql).Int64("rows", rows).Msg("slow query")
+	}
+}
+
+func traceID(ctx context.Context) string {
+	id, _ := ctx.Value(middleware.TraceIdKey).(string)
+	return id
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"net/url"
+	"strings"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// sqliteDialector runs the Postgres schema on SQLite for local development
+// and tests. SQLite only auto increments the primary key, so other auto
+// incremented columns are copied from it by a trigger, see userIDTrigger.
+type sqliteDialector struct {
+	sqlite.Dialector
+}
+
+func (d sqliteDialector) DataTypeOf(field *schema.Field) string {
+	if field.AutoIncrement && field != field.Schema.PrioritizedPrimaryField {
+		return "integer"
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+func (d sqliteDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return sqlite.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
+
+// sqliteDSN turns a file path or :memory: into a DSN that enforces foreign
+// keys like Postgres does.
+func sqliteDSN(dsn string) string {
+	if dsn == "" || dsn == ":memory:" {
+		dsn = "file::memory:"
+	}
+	if strings.Contains(dsn, "_foreign_keys") || strings.Contains(dsn, "_fk") {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + url.Values{"_foreign_keys": {"1"}}.Encode()
+}
+
+// userIDTrigger fills users.user_id, a bigserial on Postgres, with the id of
+// new rows.
+const userIDTrigger = `CREATE TRIGGER IF NOT EXISTS users_user_id AFTER INSERT ON users
+WHEN NEW.user_id IS NULL
+BEGIN
+	UPDATE users SET user_id = NEW.id WHERE id = NEW.id;
+END`
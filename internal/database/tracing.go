@@ -0,0 +1,80 @@
+package database
+
+import (
+	"errors"
+	"job-portal/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "otel:span"
+
+// tracingPlugin opens a client span around every query gorm runs. The span
+// becomes a child of whatever span is stored in the statement context, so
+// repository calls made with db.WithContext(ctx) join the request trace.
+// Queries run without the context of a request start a trace of their own.
+type tracingPlugin struct{}
+
+func (tracingPlugin) Name() string {
+	return "tracing"
+}
+
+func (p tracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		name   string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("tracing:before_"+h.name, p.before(h.name)); err != nil {
+			return err
+		}
+		if err := h.after("tracing:after_"+h.name, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tracingPlugin) before(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := tracing.Tracer().Start(db.Statement.Context, "gorm."+op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system", db.Dialector.Name())))
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func (tracingPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"job-portal/internal/auth"
+	"job-portal/internal/middleware"
+	"job-portal/internal/models"
+	"job-portal/internal/problem"
+	"job-portal/internal/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// UnlockAccount lets an admin lift a login lockout before it expires.
+func (h *handler) UnlockAccount(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	claims, ok := ctx.Value(auth.Key).(jwt.RegisteredClaims)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	var u models.Unlock
+	err := json.NewDecoder(c.Request.Body).Decode(&u)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
+		return
+	}
+
+	err = validation.Struct(u)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return
+	}
+
+	err = h.s.UnlockAccount(ctx, u, claims.Subject)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("unlocking account")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Message{Message: "account unlocked"})
+}
+
+// ListCompanyVerifications returns the company verification requests with
+// the status given in the query, those waiting for review by default.
+func (h *handler) ListCompanyVerifications(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+
+	status, given := c.GetQuery("status")
+	if !given {
+		status = models.VerificationPending
+	}
+	vs, err := h.s.ListCompanyVerifications(ctx, status)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("listing company verifications")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, vs)
+}
+
+// ApproveCompanyVerification gives the company of a request the verified
+// badge.
+func (h *handler) ApproveCompanyVerification(c *gin.Context) {
+	h.reviewCompanyVerification(c, true)
+}
+
+// RejectCompanyVerification turns a request down, the reason is mailed to
+// the owner.
+func (h *handler) RejectCompanyVerification(c *gin.Context) {
+	h.reviewCompanyVerification(c, false)
+}
+
+// reviewCompanyVerification decides a request, the body with the reason may
+// be left out when approving.
+func (h *handler) reviewCompanyVerification(c *gin.Context, approve bool) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	claims, ok := ctx.Value(auth.Key).(jwt.RegisteredClaims)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidID, "id must be an integer")
+		return
+	}
+
+	var review models.VerificationReview
+	err = json.NewDecoder(c.Request.Body).Decode(&review)
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
+		return
+	}
+	err = validation.Struct(review)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return
+	}
+
+	v, err := h.s.ReviewCompanyVerification(ctx, uint(id), approve, review.Reason, claims.Subject)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("reviewing company verification")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
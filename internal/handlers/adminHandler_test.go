@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"job-portal/internal/auth"
+	"job-portal/internal/middleware"
+	"job-portal/internal/models"
+	"job-portal/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func Test_handler_UnlockAccount(t *testing.T) {
+	newContext := func(body string) (*gin.Context, *httptest.ResponseRecorder) {
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		httpReq, _ := http.NewRequest(http.MethodPost, "http://google.com:8080", strings.NewReader(body))
+		ctx := httpReq.Context()
+		ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+		ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
+		c.Request = httpReq.WithContext(ctx)
+		return c, rr
+	}
+
+	tests := []struct {
+		name               string
+		setup              func() (*gin.Context, *httptest.ResponseRecorder, service.Service)
+		expectedStatusCode int
+		expectedResponse   string
+	}{
+		{
+			name: "missing trace id",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				rr := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(rr)
+				httpReq, _ := http.NewRequest(http.MethodPost, "http://google.com", nil)
+				c.Request = httpReq
+
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
+		},
+		{
+			name: "invalid request body",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newContext("invalid string request body")
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
+		},
+		{
+			name: "checking validator function",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newContext(`{"email":"vishnu@gmail.com","ip":"not an ip"}`)
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"ip","rule":"ip","message":"must be a valid ip address"}]}`,
+		},
+		{
+			name: "error while unlocking",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newContext(`{"email":"vishnu@gmail.com"}`)
+				mc := gomock.NewController(t)
+				ms := service.NewMockService(mc)
+				ms.EXPECT().UnlockAccount(c.Request.Context(), models.Unlock{Email: "vishnu@gmail.com"}, "42").
+					Return(errors.New("redis is down")).Times(1)
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error","trace_id":"693"}`,
+		},
+		{
+			name: "success",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newContext(`{"email":"vishnu@gmail.com","ip":"10.0.0.1"}`)
+				mc := gomock.NewController(t)
+				ms := service.NewMockService(mc)
+				ms.EXPECT().UnlockAccount(c.Request.Context(), models.Unlock{Email: "vishnu@gmail.com", IP: "10.0.0.1"}, "42").
+					Return(nil).Times(1)
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   `{"message":"account unlocked"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr, ms := tt.setup()
+			h := &handler{
+				s: ms,
+			}
+			h.UnlockAccount(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			assert.Equal(t, tt.expectedResponse, rr.Body.String())
+		})
+	}
+}
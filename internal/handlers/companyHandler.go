@@ -2,13 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"job-portal/internal/middleware"
 	"job-portal/internal/models"
+	"job-portal/internal/problem"
+	"job-portal/internal/validation"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator"
 	"github.com/rs/zerolog/log"
 )
 
@@ -17,52 +20,121 @@ func (h *handler) CreateCompany(c *gin.Context) {
 	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
 	if !ok {
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	uid, _, ok := userID(ctx)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
 		return
 	}
 	var newCom models.NewCompany
 	err := json.NewDecoder(c.Request.Body).Decode(&newCom)
 	if err != nil {
 		log.Error().Str("Trace Id", traceId).Msg("login first")
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
 		return
 	}
-	validate := validator.New()
-	err = validate.Struct(newCom)
+	err = validation.Struct(newCom)
 
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId).Send()
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": "Bad Request"})
+		abortWithValidation(c, traceId, err)
 		return
 	}
-	com, err := h.s.CreateCompany(ctx, newCom)
+	com, err := h.s.CreateCompany(ctx, newCom, uid)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, traceId, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, com)
 
 }
+
+// ViewCompany returns a page of the company list, X-Total-Count tells how
+// many companies match and Link points to the neighbouring pages.
 func (h *handler) ViewCompany(c *gin.Context) {
+	page, ok := h.companyPage(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, page.Companies)
+}
+
+// ViewPublicCompanies serves the same pages to the public careers directory,
+// it doesn't rely on claims and hides the owner and the MFA policy.
+func (h *handler) ViewPublicCompanies(c *gin.Context) {
+	page, ok := h.companyPage(c)
+	if !ok {
+		return
+	}
+	companies := make([]models.PublicCompany, 0, len(page.Companies))
+	for _, com := range page.Companies {
+		companies = append(companies, com.Public())
+	}
+	c.JSON(http.StatusOK, companies)
+}
+
+// companyPage loads the page the query asks for and sets the page headers,
+// it reports false once it answered the request with a problem.
+func (h *handler) companyPage(c *gin.Context) (models.CompanyPage, bool) {
 	ctx := c.Request.Context()
 	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
 	if !ok {
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
-		return
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return models.CompanyPage{}, false
 	}
-	data, err := h.s.ViewCompany(ctx)
+	var q models.CompanyQuery
+	err := c.ShouldBindQuery(&q)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeBadRequest, "query parameters are not valid")
+		return models.CompanyPage{}, false
+	}
+	err = validation.Struct(q)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return models.CompanyPage{}, false
+	}
+	page, err := h.s.ViewCompany(ctx, q)
 
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		abortWithError(c, traceId, err)
+		return models.CompanyPage{}, false
 	}
 
-	c.JSON(http.StatusOK, data)
+	setPageHeaders(c, page.Page, page.PerPage, page.Total)
+	return page, true
+}
+
+// setPageHeaders reports the number of matches in X-Total-Count and links
+// the previous and next page in Link, keeping the other query parameters.
+func setPageHeaders(c *gin.Context, page, perPage int, total int64) {
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	var links []string
+	link := func(p int, rel string) {
+		u := *c.Request.URL
+		q := u.Query()
+		q.Set("page", strconv.Itoa(p))
+		q.Set("per_page", strconv.Itoa(perPage))
+		u.RawQuery = q.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel))
+	}
+	if page > 1 {
+		link(page-1, "prev")
+	}
+	if int64(page)*int64(perPage) < total {
+		link(page+1, "next")
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
 }
 
 func (h *handler) GetCompanyById(c *gin.Context) {
@@ -70,7 +142,7 @@ func (h *handler) GetCompanyById(c *gin.Context) {
 	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
 	if !ok {
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 
@@ -79,7 +151,7 @@ func (h *handler) GetCompanyById(c *gin.Context) {
 	if err != nil {
 		// Handle invalid ID
 		log.Error().Err(err).Str("Trace Id", traceId)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request"})
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidID, "id must be an integer")
 		return
 	}
 
@@ -87,10 +159,116 @@ func (h *handler) GetCompanyById(c *gin.Context) {
 	company, err := h.s.GetCompanyInfoByID(ctx, cId)
 	if err != nil {
 		// Handle errors, e.g., company not found
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, traceId, err)
 		return
 	}
 
 	// Return the company data as JSON response
 	c.JSON(http.StatusOK, company)
 }
+
+// UpdateCompany replaces the profile of a company owned by the user.
+func (h *handler) UpdateCompany(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, uid, cId, ok := companyOwnerRequest(c)
+	if !ok {
+		return
+	}
+
+	var p models.CompanyProfile
+	err := json.NewDecoder(c.Request.Body).Decode(&p)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
+		return
+	}
+	err = validation.Struct(p)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return
+	}
+
+	com, err := h.s.UpdateCompany(ctx, cId, uid, p)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("updating company")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, com)
+}
+
+// PatchCompany changes the fields of the profile of a company owned by the
+// user that the body carries.
+func (h *handler) PatchCompany(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, uid, cId, ok := companyOwnerRequest(c)
+	if !ok {
+		return
+	}
+
+	var cp models.CompanyPatch
+	err := json.NewDecoder(c.Request.Body).Decode(&cp)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
+		return
+	}
+	err = validation.Struct(cp)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return
+	}
+
+	com, err := h.s.PatchCompany(ctx, cId, uid, cp)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("patching company")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, com)
+}
+
+// DeleteCompany deletes a company owned by the user with its jobs and media.
+func (h *handler) DeleteCompany(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, uid, cId, ok := companyOwnerRequest(c)
+	if !ok {
+		return
+	}
+
+	err := h.s.DeleteCompany(ctx, cId, uid)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("deleting company")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// companyOwnerRequest reads the trace id, the user and the company id of a
+// request changing a company. It aborts the request and returns false when
+// one is missing or invalid.
+func companyOwnerRequest(c *gin.Context) (string, uint, uint, bool) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return "", 0, 0, false
+	}
+	uid, _, ok := userID(ctx)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return "", 0, 0, false
+	}
+	cId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidID, "id must be an integer")
+		return "", 0, 0, false
+	}
+	return traceId, uid, uint(cId), true
+}
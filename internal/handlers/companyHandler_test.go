@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"job-portal/internal/auth"
 	"job-portal/internal/middleware"
 	"job-portal/internal/models"
 	"job-portal/internal/service"
@@ -13,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/mock/gomock"
 	"gopkg.in/go-playground/assert.v1"
 )
@@ -35,7 +37,22 @@ func Test_handler_CreateCompany(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"Internal Server Error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
+		},
+		{
+			name: "missing claims",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				rr := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(rr)
+				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", nil)
+				ctx := httpReq.Context()
+				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				c.Request = httpReq.WithContext(ctx)
+
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   `{"type":"urn:job-portal:error:unauthorized","title":"Unauthorized","status":401,"code":"unauthorized","trace_id":"693"}`,
 		},
 		{
 			name: "invalid request body",
@@ -46,6 +63,7 @@ func Test_handler_CreateCompany(t *testing.T) {
 				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", strings.NewReader(requestBody))
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
 				httpReq = httpReq.WithContext(ctx)
 				c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
 				c.Request = httpReq
@@ -53,7 +71,7 @@ func Test_handler_CreateCompany(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
 		},
 		{
 			name: "checking validator function",
@@ -64,6 +82,7 @@ func Test_handler_CreateCompany(t *testing.T) {
 				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
 				httpReq = httpReq.WithContext(ctx)
 				c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
 				c.Request = httpReq
@@ -71,7 +90,7 @@ func Test_handler_CreateCompany(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"name","rule":"required","message":"is required"},{"field":"location","rule":"required","message":"is required"}]}`,
 		},
 		{
 			name: "error while creating a company",
@@ -82,18 +101,19 @@ func Test_handler_CreateCompany(t *testing.T) {
 				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
 				httpReq = httpReq.WithContext(ctx)
 				c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
 				c.Request = httpReq
 
 				mc := gomock.NewController(t)
 				ms := service.NewMockService(mc)
-				ms.EXPECT().CreateCompany(c.Request.Context(), gomock.Any()).Return(models.Company{}, errors.New("error in creating company")).AnyTimes()
+				ms.EXPECT().CreateCompany(c.Request.Context(), gomock.Any(), uint(42)).Return(models.Company{}, errors.New("error in creating company")).AnyTimes()
 
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"error in creating company"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error","trace_id":"693"}`,
 		},
 		{
 			name: "sucessfully adding company",
@@ -104,18 +124,19 @@ func Test_handler_CreateCompany(t *testing.T) {
 				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
 				httpReq = httpReq.WithContext(ctx)
 				c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
 				c.Request = httpReq
 
 				mc := gomock.NewController(t)
 				ms := service.NewMockService(mc)
-				ms.EXPECT().CreateCompany(c.Request.Context(), gomock.Any()).Return(models.Company{}, nil).AnyTimes()
+				ms.EXPECT().CreateCompany(c.Request.Context(), gomock.Any(), uint(42)).Return(models.Company{}, nil).AnyTimes()
 
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusOK,
-			expectedResponse:   `{"CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","DeletedAt":null,"ID":0,"Name":"","Location":""}`,
+			expectedResponse:   `{"CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","DeletedAt":null,"ID":0,"Name":"","Location":"","require_mfa":false,"owner_id":null,"verified":false,"description":"","website":"","industry":"","size_band":"","founded_year":0,"social_links":{},"headquarters_id":null,"offices":null,"open_jobs":0}`,
 		},
 	}
 	for _, tt := range tests {
@@ -150,7 +171,7 @@ func Test_handler_ViewCompany(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"Internal Server Error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
 		},
 		{
 			name: "error while fectching companies",
@@ -164,12 +185,12 @@ func Test_handler_ViewCompany(t *testing.T) {
 				c.Request = httpReq
 				mc := gomock.NewController(t)
 				ms := service.NewMockService(mc)
-				ms.EXPECT().ViewCompany(c.Request.Context()).Return([]models.Company{}, errors.New("companies not found")).AnyTimes()
+				ms.EXPECT().ViewCompany(c.Request.Context(), models.CompanyQuery{}).Return(models.CompanyPage{}, errors.New("companies not found")).AnyTimes()
 
 				return c, rr, ms
 			},
-			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"companies not found"}`,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error","trace_id":"693"}`,
 		},
 		{
 			name: "sucessfully fetching companies",
@@ -183,7 +204,7 @@ func Test_handler_ViewCompany(t *testing.T) {
 				c.Request = httpReq
 				mc := gomock.NewController(t)
 				ms := service.NewMockService(mc)
-				ms.EXPECT().ViewCompany(c.Request.Context()).Return([]models.Company{}, nil).AnyTimes()
+				ms.EXPECT().ViewCompany(c.Request.Context(), models.CompanyQuery{}).Return(models.CompanyPage{Companies: []models.Company{}, Page: 1, PerPage: 20}, nil).AnyTimes()
 
 				return c, rr, ms
 			},
@@ -205,6 +226,84 @@ func Test_handler_ViewCompany(t *testing.T) {
 	}
 }
 
+func Test_handler_ViewCompany_Query(t *testing.T) {
+	verified := true
+	tests := []struct {
+		name               string
+		query              string
+		want               models.CompanyQuery
+		page               models.CompanyPage
+		expectedStatusCode int
+		expectedCode       string
+		expectedTotal      string
+		expectedLink       string
+	}{
+		{
+			name:               "not a boolean",
+			query:              "verified=maybe",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       "bad_request",
+		},
+		{
+			name:               "unknown sort",
+			query:              "sort=size",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       "validation_failed",
+		},
+		{
+			name:               "page too large",
+			query:              "per_page=1000",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       "validation_failed",
+		},
+		{
+			name:               "page too far",
+			query:              "page=9223372036854775807&per_page=100",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       "validation_failed",
+		},
+		{
+			name:               "middle page",
+			query:              "q=tek&verified=true&sort=-open_jobs&page=2&per_page=10",
+			want:               models.CompanyQuery{Q: "tek", Verified: &verified, Sort: "-open_jobs", Page: 2, PerPage: 10},
+			page:               models.CompanyPage{Companies: []models.Company{}, Page: 2, PerPage: 10, Total: 25},
+			expectedStatusCode: http.StatusOK,
+			expectedTotal:      "25",
+			expectedLink: `</api/companies?page=1&per_page=10&q=tek&sort=-open_jobs&verified=true>; rel="prev", ` +
+				`</api/companies?page=3&per_page=10&q=tek&sort=-open_jobs&verified=true>; rel="next"`,
+		},
+		{
+			name:               "only page",
+			want:               models.CompanyQuery{},
+			page:               models.CompanyPage{Companies: []models.Company{}, Page: 1, PerPage: 20, Total: 3},
+			expectedStatusCode: http.StatusOK,
+			expectedTotal:      "3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			rr := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rr)
+			httpReq := httptest.NewRequest(http.MethodGet, "/api/companies?"+tt.query, nil)
+			c.Request = httpReq.WithContext(context.WithValue(httpReq.Context(), middleware.TraceIdKey, "693"))
+			ms := service.NewMockService(gomock.NewController(t))
+			if tt.expectedStatusCode == http.StatusOK {
+				ms.EXPECT().ViewCompany(gomock.Any(), tt.want).Return(tt.page, nil)
+			}
+
+			h := &handler{s: ms}
+			h.ViewCompany(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			if tt.expectedCode != "" {
+				assert.Equal(t, true, strings.Contains(rr.Body.String(), `"code":"`+tt.expectedCode+`"`))
+			}
+			assert.Equal(t, tt.expectedTotal, rr.Header().Get("X-Total-Count"))
+			assert.Equal(t, tt.expectedLink, rr.Header().Get("Link"))
+		})
+	}
+}
+
 func Test_handler_GetCompanyById(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -223,7 +322,7 @@ func Test_handler_GetCompanyById(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"Internal Server Error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
 		},
 		{
 			name: "Invalid companyId",
@@ -240,7 +339,7 @@ func Test_handler_GetCompanyById(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_id","title":"Bad Request","status":400,"detail":"id must be an integer","code":"invalid_id","trace_id":"693"}`,
 		},
 		{
 			name: "error while fectching company details by companyId",
@@ -260,7 +359,27 @@ func Test_handler_GetCompanyById(t *testing.T) {
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"mock while fectching company"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error","trace_id":"693"}`,
+		},
+		{
+			name: "company not found",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				rr := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(rr)
+				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", nil)
+				ctx := httpReq.Context()
+				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				httpReq = httpReq.WithContext(ctx)
+				c.Params = append(c.Params, gin.Param{Key: "id", Value: "999"})
+				c.Request = httpReq
+				mc := gomock.NewController(t)
+				ms := service.NewMockService(mc)
+				ms.EXPECT().GetCompanyInfoByID(c.Request.Context(), 999).Return(models.Company{}, &service.Error{Kind: service.ErrNotFound, Code: service.CodeCompanyNotFound, Message: "company not found"})
+
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   `{"type":"urn:job-portal:error:company_not_found","title":"Not Found","status":404,"detail":"company not found","code":"company_not_found","trace_id":"693"}`,
 		},
 		{
 			name: "sucess while fectching company details by companyId",
@@ -280,7 +399,7 @@ func Test_handler_GetCompanyById(t *testing.T) {
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusOK,
-			expectedResponse:   `{"CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","DeletedAt":null,"ID":0,"Name":"","Location":""}`,
+			expectedResponse:   `{"CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","DeletedAt":null,"ID":0,"Name":"","Location":"","require_mfa":false,"owner_id":null,"verified":false,"description":"","website":"","industry":"","size_band":"","founded_year":0,"social_links":{},"headquarters_id":null,"offices":null,"open_jobs":0}`,
 		},
 	}
 	for _, tt := range tests {
@@ -296,3 +415,150 @@ func Test_handler_GetCompanyById(t *testing.T) {
 		})
 	}
 }
+
+func Test_handler_UpdateCompany(t *testing.T) {
+	owner := jwt.RegisteredClaims{Subject: "1"}
+	tests := []struct {
+		name               string
+		id                 string
+		body               string
+		serviceErr         error
+		expectedStatusCode int
+		expectedResponse   string
+	}{
+		{
+			name:               "invalid id",
+			id:                 "one",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_id","title":"Bad Request","status":400,"detail":"id must be an integer","code":"invalid_id","trace_id":"693"}`,
+		},
+		{
+			name:               "invalid profile",
+			id:                 "7",
+			body:               `{"name":"tek","location":"pune","size_band":"huge","website":"tek","office_ids":[0]}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"website","rule":"url","message":"must be a valid url"},{"field":"size_band","rule":"size_band","message":"must be one of 1-10, 11-50, 51-200, 201-500, 501-1000, 1001-5000, 5001+"},{"field":"office_ids[0]","rule":"gt","message":"must be greater than 0"}]}`,
+		},
+		{
+			name:               "not the owner",
+			id:                 "7",
+			body:               `{"name":"tek","location":"pune"}`,
+			serviceErr:         &service.Error{Kind: service.ErrForbidden, Code: service.CodeNotCompanyOwner, Message: "only the owner can change a company"},
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   `{"type":"urn:job-portal:error:not_company_owner","title":"Forbidden","status":403,"detail":"only the owner can change a company","code":"not_company_owner","trace_id":"693"}`,
+		},
+		{
+			name:               "success",
+			id:                 "7",
+			body:               `{"name":"tek","location":"pune","size_band":"11-50","office_ids":[2]}`,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr := newClaimsContext(tt.body, owner)
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: tt.id})
+			ms := service.NewMockService(gomock.NewController(t))
+			ms.EXPECT().UpdateCompany(c.Request.Context(), uint(7), uint(1), gomock.Any()).
+				Return(models.Company{ID: 7, Name: "tek", OpenJobs: 2}, tt.serviceErr).AnyTimes()
+
+			h := &handler{s: ms}
+			h.UpdateCompany(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			if tt.expectedResponse != "" {
+				assert.Equal(t, tt.expectedResponse, rr.Body.String())
+			} else {
+				assert.Equal(t, true, strings.Contains(rr.Body.String(), `"open_jobs":2`))
+			}
+		})
+	}
+}
+
+func Test_handler_PatchCompany(t *testing.T) {
+	tests := []struct {
+		name               string
+		body               string
+		expectedStatusCode int
+		expectedResponse   string
+	}{
+		{
+			name:               "invalid request body",
+			body:               "invalid string request body",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
+		},
+		{
+			name:               "invalid patch",
+			body:               `{"name":"","website":"tek"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"name","rule":"min","message":"must be at least 1"},{"field":"website","rule":"optional_url","message":"must be a valid url"}]}`,
+		},
+		{
+			name:               "clearing optional fields",
+			body:               `{"website":"","size_band":"","headquarters_id":0}`,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr := newClaimsContext(tt.body, jwt.RegisteredClaims{Subject: "1"})
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: "7"})
+			ms := service.NewMockService(gomock.NewController(t))
+			empty, noHQ := "", uint(0)
+			ms.EXPECT().PatchCompany(c.Request.Context(), uint(7), uint(1),
+				models.CompanyPatch{Website: &empty, SizeBand: &empty, HeadquartersID: &noHQ}).
+				Return(models.Company{ID: 7, Name: "tek"}, nil).AnyTimes()
+
+			h := &handler{s: ms}
+			h.PatchCompany(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				assert.Equal(t, true, strings.Contains(rr.Body.String(), `"Name":"tek"`))
+			} else {
+				assert.Equal(t, tt.expectedResponse, rr.Body.String())
+			}
+		})
+	}
+}
+
+func Test_handler_DeleteCompany(t *testing.T) {
+	tests := []struct {
+		name               string
+		claims             jwt.RegisteredClaims
+		serviceErr         error
+		expectedStatusCode int
+	}{
+		{
+			name:               "missing claims",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "missing company",
+			claims:             jwt.RegisteredClaims{Subject: "1"},
+			serviceErr:         &service.Error{Kind: service.ErrNotFound, Code: service.CodeCompanyNotFound},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "success",
+			claims:             jwt.RegisteredClaims{Subject: "1"},
+			expectedStatusCode: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr := newClaimsContext("", tt.claims)
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: "7"})
+			ms := service.NewMockService(gomock.NewController(t))
+			ms.EXPECT().DeleteCompany(c.Request.Context(), uint(7), uint(1)).Return(tt.serviceErr).AnyTimes()
+
+			h := &handler{s: ms}
+			h.DeleteCompany(c)
+			// The recorder only sees a status once a body is written
+			assert.Equal(t, tt.expectedStatusCode, c.Writer.Status())
+			assert.Equal(t, tt.expectedStatusCode != http.StatusNoContent, rr.Body.Len() > 0)
+		})
+	}
+}
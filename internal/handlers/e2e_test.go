@@ -0,0 +1,540 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"job-portal/internal/auth"
+	"job-portal/internal/mfa"
+	"job-portal/internal/models"
+	"job-portal/internal/problem"
+	"job-portal/internal/ratelimit"
+	"job-portal/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var e2eJob = models.NewJob{
+	Title: "go developer", Description: "builds services", Min_NP: 10, Max_NP: 60, Budget: 100000,
+	MinExp: 1, MaxExp: 5, JobLocations: []uint{1, 2}, TechnologyStack: []uint{1}, WorkModes: []uint{1},
+	Qualifications: []uint{1}, WorkShifts: []uint{1}, JobTypes: []uint{1},
+}
+
+// e2eApplication meets every criterion of e2eJob once JobId is set.
+var e2eApplication = models.JobApplication{
+	Name: "vikram", Email: "vikram@example.com", Age: 25, NoticePeriod: 30, Expect_salary: 50000,
+	JobLocations: []uint{1}, TechnologyStack: []uint{1}, WorkModes: []uint{1}, Experience: 2,
+	Qualifications: []uint{1}, WorkShifts: []uint{1}, JobTypes: []uint{1},
+}
+
+// postCompanyWithJob creates a company with e2eJob as the user of token.
+func postCompanyWithJob(ts *testServer, token string) (models.Company, models.Job) {
+	ts.t.Helper()
+	var com models.Company
+	ts.call(http.MethodPost, "/api/companies", token, models.NewCompany{Name: "tek", Location: "bangalore"}, http.StatusOK, &com)
+	var job models.Job
+	ts.call(http.MethodPost, fmt.Sprintf("/api/companies/%d/jobs", com.ID), token, e2eJob, http.StatusOK, &job)
+	return com, job
+}
+
+func problemOf(t *testing.T, ts *testServer, method, path, token string, body any, want int) problem.Problem {
+	t.Helper()
+	var p problem.Problem
+	ts.call(method, path, token, body, want, &p)
+	return p
+}
+
+func TestE2E_JobFlow(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	employer := ts.signUp("employer@example.com")
+	com, job := postCompanyWithJob(ts, employer)
+	assert.Equal(t, com.ID, job.CompanyID)
+
+	applicant := ts.signUp("vikram@example.com")
+	var companies []models.Company
+	ts.call(http.MethodGet, "/api/companies", applicant, nil, http.StatusOK, &companies)
+	assert.Equal(t, 1, len(companies))
+	var got models.Company
+	ts.call(http.MethodGet, fmt.Sprintf("/api/companies/%d", com.ID), applicant, nil, http.StatusOK, &got)
+	assert.Equal(t, "tek", got.Name)
+
+	var jobs []models.Job
+	ts.call(http.MethodGet, "/api/jobs", applicant, nil, http.StatusOK, &jobs)
+	assert.Equal(t, 1, len(jobs))
+	ts.call(http.MethodGet, fmt.Sprintf("/api/companies/%d/jobs", com.ID), applicant, nil, http.StatusOK, &jobs)
+	assert.Equal(t, 1, len(jobs))
+	var j models.Job
+	ts.call(http.MethodGet, fmt.Sprintf("/api/jobs/%d", job.ID), applicant, nil, http.StatusOK, &j)
+	assert.Equal(t, 2, len(j.JobLocations))
+
+	matching := e2eApplication
+	matching.JobId = int(job.ID)
+	tooExpensive := matching
+	tooExpensive.Name, tooExpensive.Expect_salary = "vishnu", 500000
+	unknownJob := matching
+	unknownJob.Name, unknownJob.JobId = "ravi", 42
+	var outcomes []models.ApplicationOutcome
+	ts.call(http.MethodPost, "/api/job/applications/", applicant,
+		[]models.JobApplication{matching, tooExpensive, unknownJob}, http.StatusOK, &outcomes)
+	assert.Equal(t, []models.ApplicationOutcome{
+		{Position: 0, Name: "vikram", Email: "vikram@example.com", Outcome: models.OutcomeMatched},
+		{Position: 1, Name: "vishnu", Email: "vikram@example.com", Outcome: models.OutcomeRejected},
+		{Position: 2, Name: "ravi", Email: "vikram@example.com", Outcome: models.OutcomeFailed, Error: "job not found"},
+	}, outcomes)
+
+	p := problemOf(t, ts, http.MethodGet, "/api/jobs/42", applicant, nil, http.StatusNotFound)
+	assert.Equal(t, service.CodeJobNotFound, p.Code)
+}
+
+func TestE2E_JobsOfOthersCompany(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	owner := ts.signUp("employer@example.com")
+	com, _ := postCompanyWithJob(ts, owner)
+
+	// Jobs posted under a company carry its badge, only the owner may post
+	other := ts.signUp("vikram@example.com")
+	path := fmt.Sprintf("/api/companies/%d/jobs", com.ID)
+	p := problemOf(t, ts, http.MethodPost, path, other, e2eJob, http.StatusForbidden)
+	assert.Equal(t, service.CodeNotCompanyOwner, p.Code)
+
+	var jobs []models.Job
+	ts.call(http.MethodGet, path, owner, nil, http.StatusOK, &jobs)
+	assert.Equal(t, 1, len(jobs))
+}
+
+func TestE2E_Unauthenticated(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	for _, token := range []string{"", "not-a-token"} {
+		p := problemOf(t, ts, http.MethodGet, "/api/jobs", token, nil, http.StatusUnauthorized)
+		assert.Equal(t, problem.CodeUnauthorized, p.Code)
+		assert.NotEqual(t, "", p.TraceId)
+	}
+
+	// A token signed with another key is refused as well
+	other := newTestServer(t, Config{})
+	token := other.signUp("vishnu@example.com")
+	problemOf(t, ts, http.MethodGet, "/api/jobs", token, nil, http.StatusUnauthorized)
+}
+
+func TestE2E_RateLimitsPerUser(t *testing.T) {
+	ts := newTestServer(t, Config{RateLimits: RateLimits{
+		Limiter:      ratelimit.NewMemory(),
+		LoginMFAUser: ratelimit.Rule{Limit: 1, Window: time.Minute},
+		ApplyUser:    ratelimit.Rule{Limit: 1, Window: time.Minute},
+	}})
+
+	// Every request comes from the same address, the users are counted apart
+	pending := ts.tokenOf("999", auth.MFAPendingAudience)
+	code := models.MFACode{Code: "123456"}
+	assert.Equal(t, http.StatusNotFound, ts.do(http.MethodPost, "/api/login/mfa", pending, code).Code)
+	rr := ts.do(http.MethodPost, "/api/login/mfa", pending, code)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Limit"))
+	other := ts.tokenOf("998", auth.MFAPendingAudience)
+	assert.Equal(t, http.StatusNotFound, ts.do(http.MethodPost, "/api/login/mfa", other, code).Code)
+
+	applicant := ts.signUp("vikram@example.com")
+	assert.Equal(t, http.StatusBadRequest, ts.do(http.MethodPost, "/api/job/applications/", applicant, nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, ts.do(http.MethodPost, "/api/job/applications/", applicant, nil).Code)
+	another := ts.signUp("vishnu@example.com")
+	assert.Equal(t, http.StatusBadRequest, ts.do(http.MethodPost, "/api/job/applications/", another, nil).Code)
+}
+
+func TestE2E_Registration(t *testing.T) {
+	ts := newTestServer(t, Config{VerifiedLogin: true})
+	const email = "vishnu@example.com"
+	ts.register(email, testPassword)
+
+	p := problemOf(t, ts, http.MethodPost, "/api/register", "",
+		models.NewUser{Name: "other", Email: "Vishnu@Example.com", Password: testPassword}, http.StatusConflict)
+	assert.Equal(t, service.CodeEmailExists, p.Code)
+
+	p = problemOf(t, ts, http.MethodPost, "/api/login", "", models.Login{Email: email, Password: testPassword}, http.StatusForbidden)
+	assert.Equal(t, service.CodeEmailNotVerified, p.Code)
+
+	p = problemOf(t, ts, http.MethodPost, "/api/verify", "", models.VerifyEmail{Email: email, Code: "000000x"}, http.StatusBadRequest)
+	assert.Equal(t, service.CodeInvalidOTP, p.Code)
+	ts.verify(email)
+	assert.NotEqual(t, "", ts.login(email, testPassword).Token)
+
+	p = problemOf(t, ts, http.MethodPost, "/api/login", "", models.Login{Email: email, Password: "Wrong#123"}, http.StatusUnauthorized)
+	assert.Equal(t, service.CodeInvalidCredentials, p.Code)
+}
+
+func TestE2E_LockoutIgnoresForwardedFor(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	ts.signUp("vishnu@example.com")
+	login := func(remoteAddr, forwardedFor, email, password string) int {
+		body := fmt.Sprintf(`{"email":%q,"password":%q}`, email, password)
+		req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		rr := httptest.NewRecorder()
+		ts.engine.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Enough failures to lock an address, each naming the victim as the
+	// client
+	for i := 0; i < 20; i++ {
+		code := login("192.0.2.1:1234", "203.0.113.7", fmt.Sprintf("user%d@example.com", i), "Wrong#123")
+		assert.Equal(t, http.StatusUnauthorized, code)
+	}
+
+	// The connection the failures came from is locked, the victim isn't
+	assert.Equal(t, http.StatusTooManyRequests, login("192.0.2.1:1234", "", "vishnu@example.com", testPassword))
+	assert.Equal(t, http.StatusOK, login("203.0.113.7:1234", "", "vishnu@example.com", testPassword))
+}
+
+func TestE2E_PasswordReset(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	const email = "vishnu@example.com"
+	claims, err := ts.auth.ValidateToken(ts.signUp(email))
+	assert.Equal(t, nil, err)
+	// A session that was open before the reset
+	claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	old, err := ts.auth.GenerateToken(claims)
+	assert.Equal(t, nil, err)
+	ts.call(http.MethodGet, "/api/jobs", old, nil, http.StatusOK, nil)
+
+	ts.call(http.MethodPost, "/api/forgetpassword/", "", models.ForgotPassword{Email: email}, http.StatusOK, nil)
+	reset := models.Reset{Otp: ts.code(email), Email: email, NewPassword: "Changed#123", ConfirmPassword: "Changed#123"}
+	ts.call(http.MethodPost, "/api/resetpassword/", "", reset, http.StatusOK, nil)
+
+	problemOf(t, ts, http.MethodGet, "/api/jobs", old, nil, http.StatusUnauthorized)
+	tkn := ts.login(email, "Changed#123")
+	ts.call(http.MethodGet, "/api/jobs", tkn.Token, nil, http.StatusOK, nil)
+	problemOf(t, ts, http.MethodPost, "/api/login", "", models.Login{Email: email, Password: testPassword}, http.StatusUnauthorized)
+	// The otp works once
+	p := problemOf(t, ts, http.MethodPost, "/api/resetpassword/", "", reset, http.StatusBadRequest)
+	assert.Equal(t, service.CodeInvalidOTP, p.Code)
+}
+
+func TestE2E_CompanyMFAPolicy(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	const email = "employer@example.com"
+	token := ts.signUp(email)
+	com, _ := postCompanyWithJob(ts, token)
+	required := true
+	path := fmt.Sprintf("/api/companies/%d/mfa", com.ID)
+	other := ts.signUp("vikram@example.com")
+	p := problemOf(t, ts, http.MethodPut, path, other, models.MFAPolicy{Required: &required}, http.StatusForbidden)
+	assert.Equal(t, service.CodeNotCompanyOwner, p.Code)
+	ts.call(http.MethodPut, path, token, models.MFAPolicy{Required: &required}, http.StatusOK, nil)
+
+	// Members have to enroll before they get a full token
+	tkn := ts.login(email, testPassword)
+	assert.Equal(t, true, tkn.MFAEnrollmentRequired)
+	problemOf(t, ts, http.MethodGet, "/api/jobs", tkn.Token, nil, http.StatusUnauthorized)
+
+	var e models.TOTPEnrollment
+	ts.call(http.MethodPost, "/api/mfa/totp", tkn.Token, nil, http.StatusOK, &e)
+	code, err := mfa.Code(e.Secret, mfa.Step(time.Now()))
+	assert.Equal(t, nil, err)
+	var rc models.RecoveryCodes
+	ts.call(http.MethodPost, "/api/mfa/totp/confirm", tkn.Token, models.MFACode{Code: code}, http.StatusOK, &rc)
+	ts.call(http.MethodGet, "/api/jobs", rc.Token, nil, http.StatusOK, nil)
+
+	// Later logins complete with the second factor
+	tkn = ts.login(email, testPassword)
+	assert.Equal(t, true, tkn.MFARequired)
+	assert.Equal(t, false, tkn.MFAEnrollmentRequired)
+	p = problemOf(t, ts, http.MethodPost, "/api/login/mfa", tkn.Token, models.MFACode{Code: "123456"}, http.StatusUnauthorized)
+	assert.Equal(t, service.CodeInvalidMFACode, p.Code)
+	var full models.Token
+	ts.call(http.MethodPost, "/api/login/mfa", tkn.Token, models.MFACode{Code: rc.Codes[0]}, http.StatusOK, &full)
+	ts.call(http.MethodGet, "/api/jobs", full.Token, nil, http.StatusOK, nil)
+}
+
+func TestE2E_VerifiedApply(t *testing.T) {
+	ts := newTestServer(t, Config{VerifiedApply: true})
+	ts.register("vikram@example.com", testPassword)
+	token := ts.login("vikram@example.com", testPassword).Token
+
+	p := problemOf(t, ts, http.MethodPost, "/api/job/applications/", token, []models.JobApplication{e2eApplication}, http.StatusForbidden)
+	assert.Equal(t, problem.CodeForbidden, p.Code)
+}
+
+func TestE2E_BulkApplications(t *testing.T) {
+	ts := newTestServer(t, Config{TaskWorkers: 1, BulkThreshold: 1})
+	employer := ts.signUp("employer@example.com")
+	_, job := postCompanyWithJob(ts, employer)
+
+	applicant := ts.signUp("vikram@example.com")
+	a := e2eApplication
+	a.JobId = int(job.ID)
+	var task models.Task
+	rr := ts.call(http.MethodPost, "/api/job/applications/", applicant, []models.JobApplication{a, a}, http.StatusAccepted, &task)
+	location := rr.Header().Get("Location")
+	assert.Equal(t, "/api/tasks/"+task.ID, location)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for task.Status != models.TaskDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("task is still %s", task.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		ts.call(http.MethodGet, location, applicant, nil, http.StatusOK, &task)
+	}
+	assert.Equal(t, 2, task.Matched)
+	assert.Equal(t, 2, len(task.Items))
+
+	// Tasks are only visible to the user who submitted them
+	other := ts.signUp("vishnu@example.com")
+	p := problemOf(t, ts, http.MethodGet, location, other, nil, http.StatusNotFound)
+	assert.Equal(t, service.CodeTaskNotFound, p.Code)
+}
+
+func TestE2E_CompanyProfile(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	owner := ts.signUp("employer@example.com")
+	com, job := postCompanyWithJob(ts, owner)
+	path := fmt.Sprintf("/api/companies/%d", com.ID)
+
+	hq := uint(1)
+	profile := models.CompanyProfile{
+		Name: "tek", Location: "bangalore", Description: "staffing", Website: "https://tek.example.com",
+		SizeBand: "5001+", FoundedYear: 1983, HeadquartersID: &hq, OfficeIDs: []uint{2},
+	}
+	var got models.Company
+	ts.call(http.MethodPut, path, owner, profile, http.StatusOK, &got)
+	assert.Equal(t, int64(1), got.OpenJobs)
+	// The locations of the job are shared with the company
+	ts.call(http.MethodGet, path, owner, nil, http.StatusOK, &got)
+	assert.Equal(t, job.JobLocations[0].ID, got.Headquarters.ID)
+	assert.Equal(t, "https://tek.example.com", got.Website)
+
+	other := ts.signUp("vikram@example.com")
+	p := problemOf(t, ts, http.MethodPatch, path, other, map[string]any{"name": "mine"}, http.StatusForbidden)
+	assert.Equal(t, service.CodeNotCompanyOwner, p.Code)
+	problemOf(t, ts, http.MethodDelete, path, other, nil, http.StatusForbidden)
+
+	var patched models.Company
+	ts.call(http.MethodPatch, path, owner, map[string]any{"website": "", "headquarters_id": 0}, http.StatusOK, &patched)
+	assert.Equal(t, "", patched.Website)
+	assert.Equal(t, (*models.Location)(nil), patched.Headquarters)
+	assert.Equal(t, 1983, patched.FoundedYear)
+
+	ts.call(http.MethodDelete, path, owner, nil, http.StatusNoContent, nil)
+	var jobs []models.Job
+	ts.call(http.MethodGet, "/api/jobs", other, nil, http.StatusOK, &jobs)
+	assert.Equal(t, 0, len(jobs))
+	problemOf(t, ts, http.MethodGet, fmt.Sprintf("/api/jobs/%d", job.ID), other, nil, http.StatusNotFound)
+	p = problemOf(t, ts, http.MethodPatch, path, owner, map[string]any{"name": "again"}, http.StatusNotFound)
+	assert.Equal(t, service.CodeCompanyNotFound, p.Code)
+}
+
+func TestE2E_NotFound(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	owner := ts.signUp("employer@example.com")
+	com, _ := postCompanyWithJob(ts, owner)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   any
+		code   string
+	}{
+		{name: "company", method: http.MethodGet, path: "/api/companies/999", token: owner, code: service.CodeCompanyNotFound},
+		{name: "jobs of a company", method: http.MethodGet, path: "/api/companies/999/jobs", token: owner, code: service.CodeCompanyNotFound},
+		{name: "posting a job", method: http.MethodPost, path: "/api/companies/999/jobs", token: owner, body: e2eJob, code: service.CodeCompanyNotFound},
+		{name: "company media", method: http.MethodGet, path: "/api/companies/999/media", token: owner, code: service.CodeCompanyNotFound},
+		{name: "job", method: http.MethodGet, path: "/api/jobs/999", token: owner, code: service.CodeJobNotFound},
+		{name: "user", method: http.MethodDelete, path: "/api/mfa/totp", token: ts.tokenOf("999"), body: models.MFACode{Code: "123456"}, code: service.CodeUserNotFound},
+		{name: "user completing a login", method: http.MethodPost, path: "/api/login/mfa", token: ts.tokenOf("999", auth.MFAPendingAudience),
+			body: models.MFACode{Code: "123456"}, code: service.CodeUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := problemOf(t, ts, tt.method, tt.path, tt.token, tt.body, http.StatusNotFound)
+			assert.Equal(t, tt.code, p.Code)
+		})
+	}
+
+	// A company without jobs is still found
+	var jobs []models.Job
+	var other models.Company
+	ts.call(http.MethodPost, "/api/companies", owner, models.NewCompany{Name: "wipro", Location: "pune"}, http.StatusOK, &other)
+	ts.call(http.MethodGet, fmt.Sprintf("/api/companies/%d/jobs", other.ID), owner, nil, http.StatusOK, &jobs)
+	assert.Equal(t, 0, len(jobs))
+
+	ts.call(http.MethodDelete, fmt.Sprintf("/api/companies/%d", com.ID), owner, nil, http.StatusNoContent, nil)
+	p := problemOf(t, ts, http.MethodGet, fmt.Sprintf("/api/companies/%d", com.ID), owner, nil, http.StatusNotFound)
+	assert.Equal(t, service.CodeCompanyNotFound, p.Code)
+	// A deleted company takes no new jobs
+	p = problemOf(t, ts, http.MethodPost, fmt.Sprintf("/api/companies/%d/jobs", com.ID), owner, e2eJob, http.StatusNotFound)
+	assert.Equal(t, service.CodeCompanyNotFound, p.Code)
+	ts.call(http.MethodGet, "/api/jobs", owner, nil, http.StatusOK, &jobs)
+	assert.Equal(t, 0, len(jobs))
+}
+
+// pngOf encodes a blank w x h image.
+func pngOf(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestE2E_CompanyMedia(t *testing.T) {
+	ts := newTestServer(t, Config{MaxUploadBytes: 64 << 10})
+	owner := ts.signUp("employer@example.com")
+	com, _ := postCompanyWithJob(ts, owner)
+	path := fmt.Sprintf("/api/companies/%d", com.ID)
+
+	rr := ts.upload(path+"/logo", owner, "logo.png", pngOf(t, 1024, 256))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var logo models.CompanyMedia
+	err := json.Unmarshal(rr.Body.Bytes(), &logo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "image/png", logo.ContentType)
+	assert.Equal(t, 1024, logo.Width)
+
+	// The links work without a token
+	rr = ts.do(http.MethodGet, logo.Thumbnails["small"], "", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	small, err := png.DecodeConfig(rr.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 128, small.Width)
+	assert.Equal(t, 32, small.Height)
+	p := problemOf(t, ts, http.MethodGet, strings.Replace(logo.URL, "signature=", "signature=x", 1), "", nil, http.StatusForbidden)
+	assert.Equal(t, service.CodeInvalidSignature, p.Code)
+
+	rr = ts.upload(path+"/photos", owner, "office.png", []byte("GIF89a not quite an image"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	rr = ts.upload(path+"/photos", owner, "office.png", make([]byte, 128<<10))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	other := ts.signUp("vikram@example.com")
+	rr = ts.upload(path+"/photos", other, "office.png", pngOf(t, 10, 10))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	// A new logo replaces the previous one and its files
+	rr = ts.upload(path+"/logo", owner, "logo.png", pngOf(t, 64, 64))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	rr = ts.upload(path+"/photos", owner, "office.png", pngOf(t, 800, 600))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var media []models.CompanyMedia
+	ts.call(http.MethodGet, path+"/media", other, nil, http.StatusOK, &media)
+	assert.Equal(t, 2, len(media))
+	assert.Equal(t, models.MediaLogo, media[0].Kind)
+	assert.Equal(t, 64, media[0].Width)
+	assert.Equal(t, models.MediaPhoto, media[1].Kind)
+	problemOf(t, ts, http.MethodGet, logo.URL, "", nil, http.StatusNotFound)
+
+	problemOf(t, ts, http.MethodDelete, fmt.Sprintf("%s/media/%d", path, media[1].ID), other, nil, http.StatusForbidden)
+	ts.call(http.MethodDelete, fmt.Sprintf("%s/media/%d", path, media[1].ID), owner, nil, http.StatusNoContent, nil)
+	problemOf(t, ts, http.MethodGet, media[1].URL, "", nil, http.StatusNotFound)
+	p = problemOf(t, ts, http.MethodDelete, fmt.Sprintf("%s/media/%d", path, media[1].ID), owner, nil, http.StatusNotFound)
+	assert.Equal(t, service.CodeMediaNotFound, p.Code)
+}
+
+func TestE2E_CompanyVerification(t *testing.T) {
+	ts := newTestServer(t, Config{HideUnverifiedJobs: true})
+	owner := ts.signUp("employer@example.com")
+	com, job := postCompanyWithJob(ts, owner)
+	path := fmt.Sprintf("/api/companies/%d", com.ID)
+	applicant := ts.signUp("vikram@example.com")
+	ts.register("admin@example.com", testPassword)
+	ts.verify("admin@example.com")
+	assert.Equal(t, nil, ts.repo.GrantAdmin(context.Background(), "admin@example.com"))
+	admin := ts.login("admin@example.com", testPassword).Token
+
+	// The jobs of unverified companies are hidden
+	var jobs []models.Job
+	ts.call(http.MethodGet, "/api/jobs", applicant, nil, http.StatusOK, &jobs)
+	assert.Equal(t, 0, len(jobs))
+	problemOf(t, ts, http.MethodGet, fmt.Sprintf("/api/jobs/%d", job.ID), applicant, nil, http.StatusNotFound)
+
+	p := problemOf(t, ts, http.MethodPost, path+"/verification", owner, models.VerificationRequest{Email: "hr@tek.example.com"}, http.StatusBadRequest)
+	assert.Equal(t, service.CodeWebsiteMissing, p.Code)
+	ts.call(http.MethodPatch, path, owner, map[string]any{"website": "https://www.tek.example.com"}, http.StatusOK, nil)
+	p = problemOf(t, ts, http.MethodPost, path+"/verification", owner, models.VerificationRequest{Email: "hr@gmail.com"}, http.StatusBadRequest)
+	assert.Equal(t, service.CodeEmailNotOnDomain, p.Code)
+	problemOf(t, ts, http.MethodPost, path+"/verification", applicant, models.VerificationRequest{Email: "hr@tek.example.com"}, http.StatusForbidden)
+
+	var v models.CompanyVerification
+	ts.call(http.MethodPost, path+"/verification", owner, models.VerificationRequest{Email: "hr@tek.example.com"}, http.StatusOK, &v)
+	assert.Equal(t, models.VerificationEmailSent, v.Status)
+	ts.call(http.MethodPost, path+"/verification/confirm", owner, models.VerificationCode{Code: ts.code("hr@tek.example.com")}, http.StatusOK, &v)
+	assert.Equal(t, models.VerificationPending, v.Status)
+
+	problemOf(t, ts, http.MethodGet, "/api/admin/verifications", owner, nil, http.StatusForbidden)
+	var pending []models.CompanyVerification
+	ts.call(http.MethodGet, "/api/admin/verifications", admin, nil, http.StatusOK, &pending)
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, v.ID, pending[0].ID)
+	review := fmt.Sprintf("/api/admin/verifications/%d", v.ID)
+	p = problemOf(t, ts, http.MethodPost, review+"/reject", admin, nil, http.StatusBadRequest)
+	assert.Equal(t, service.CodeReasonRequired, p.Code)
+	ts.call(http.MethodPost, review+"/approve", admin, nil, http.StatusOK, &v)
+	assert.Equal(t, models.VerificationApproved, v.Status)
+	p = problemOf(t, ts, http.MethodPost, review+"/reject", admin, models.VerificationReview{Reason: "too late"}, http.StatusConflict)
+	assert.Equal(t, service.CodeVerificationReviewed, p.Code)
+
+	// The badge shows on the company and its jobs, which are listed again
+	var got models.Company
+	ts.call(http.MethodGet, path, applicant, nil, http.StatusOK, &got)
+	assert.Equal(t, true, got.Verified)
+	ts.call(http.MethodGet, "/api/jobs", applicant, nil, http.StatusOK, &jobs)
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, true, jobs[0].Verified)
+	p = problemOf(t, ts, http.MethodPost, path+"/verification", owner, models.VerificationRequest{Email: "hr@tek.example.com"}, http.StatusConflict)
+	assert.Equal(t, service.CodeCompanyVerified, p.Code)
+
+	// Moving to another domain takes the badge away
+	ts.call(http.MethodPatch, path, owner, map[string]any{"website": "https://tek.io"}, http.StatusOK, &got)
+	assert.Equal(t, false, got.Verified)
+	problemOf(t, ts, http.MethodGet, fmt.Sprintf("/api/jobs/%d", job.ID), applicant, nil, http.StatusNotFound)
+}
+
+func TestE2E_CompanyDirectory(t *testing.T) {
+	ts := newTestServer(t, Config{})
+	owner := ts.signUp("employer@example.com")
+	for _, name := range []string{"Tek Systems", "infotek", "Infosys", "wipro"} {
+		ts.call(http.MethodPost, "/api/companies", owner, models.NewCompany{Name: name, Location: "bangalore"}, http.StatusOK, nil)
+	}
+
+	// The directory needs no token, the authenticated list is the same
+	problemOf(t, ts, http.MethodGet, "/api/companies", "", nil, http.StatusUnauthorized)
+	var companies []models.Company
+	rr := ts.call(http.MethodGet, "/api/public/companies?q=inf&per_page=1", "", nil, http.StatusOK, &companies)
+	assert.Equal(t, "2", rr.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</api/public/companies?page=2&per_page=1&q=inf>; rel="next"`, rr.Header().Get("Link"))
+	assert.Equal(t, 1, len(companies))
+	assert.Equal(t, "Infosys", companies[0].Name)
+	// The directory doesn't tell who owns a company or how its members log in
+	assert.Equal(t, false, strings.Contains(rr.Body.String(), "owner_id"))
+	assert.Equal(t, false, strings.Contains(rr.Body.String(), "require_mfa"))
+	rr = ts.call(http.MethodGet, "/api/companies?q=inf&per_page=1", owner, nil, http.StatusOK, nil)
+	assert.Equal(t, true, strings.Contains(rr.Body.String(), `"require_mfa":false`))
+	ts.call(http.MethodGet, "/api/companies?q=inf&page=2&per_page=1", owner, nil, http.StatusOK, &companies)
+	assert.Equal(t, "infotek", companies[0].Name)
+
+	rr = ts.call(http.MethodGet, "/api/public/companies?sort=-name", "", nil, http.StatusOK, &companies)
+	assert.Equal(t, "4", rr.Header().Get("X-Total-Count"))
+	assert.Equal(t, "", rr.Header().Get("Link"))
+	assert.Equal(t, "wipro", companies[0].Name)
+	ts.call(http.MethodGet, "/api/public/companies?verified=true", "", nil, http.StatusOK, &companies)
+	assert.Equal(t, 0, len(companies))
+	p := problemOf(t, ts, http.MethodGet, "/api/public/companies?sort=size", "", nil, http.StatusBadRequest)
+	assert.Equal(t, "sort", p.Errors[0].Field)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"job-portal/internal/problem"
+	"job-portal/internal/service"
+	"job-portal/internal/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abortWithError maps an error returned by the service layer to a problem
+// response. Errors that are not domain errors are reported as a generic 500
+// so that internal details never reach the client.
+func abortWithError(c *gin.Context, traceId string, err error) {
+	var se *service.Error
+	if !errors.As(err, &se) {
+		abortWithProblem(c, traceId, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(se, service.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(se, service.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(se, service.ErrValidation):
+		status = http.StatusBadRequest
+	case errors.Is(se, service.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(se, service.ErrUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.Is(se, service.ErrTooManyRequests):
+		status = http.StatusTooManyRequests
+	case errors.Is(se, service.ErrTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(se, service.ErrUnsupportedMedia):
+		status = http.StatusUnsupportedMediaType
+	}
+	abortWithProblem(c, traceId, status, se.Code, se.Message)
+}
+
+// abortWithValidation reports every invalid field of a request body.
+func abortWithValidation(c *gin.Context, traceId string, err error) {
+	p := problem.New(http.StatusBadRequest, problem.CodeValidation, "request failed validation", traceId)
+	var verrs validation.Errors
+	if errors.As(err, &verrs) {
+		p.Errors = verrs
+	}
+	problem.Abort(c, p)
+}
+
+func abortWithProblem(c *gin.Context, traceId string, status int, code, detail string) {
+	problem.Abort(c, problem.New(status, code, detail, traceId))
+}
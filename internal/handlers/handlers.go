@@ -1,24 +1,128 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
 	"job-portal/internal/auth"
+	"job-portal/internal/authstate"
+	"job-portal/internal/cache"
+	"job-portal/internal/invalidation"
 	"job-portal/internal/middleware"
+	"job-portal/internal/ratelimit"
 	"job-portal/internal/repository"
 	"job-portal/internal/service"
+	"job-portal/internal/storage"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 )
 
-func API(a *auth.Auth, c repository.Repository) *gin.Engine {
+// RateLimits configures the limits on the account endpoints that can be
+// abused without a token, and on the second factor and applications of each
+// user. A nil Limiter turns rate limiting off.
+type RateLimits struct {
+	Limiter ratelimit.Limiter
+
+	LoginIP             ratelimit.Rule
+	LoginEmail          ratelimit.Rule
+	ForgotPasswordIP    ratelimit.Rule
+	ForgotPasswordEmail ratelimit.Rule
+	ResetPasswordIP     ratelimit.Rule
+	ResetPasswordEmail  ratelimit.Rule
+	PublicIP            ratelimit.Rule
+	VerifyIP            ratelimit.Rule
+	VerifyEmail         ratelimit.Rule
+	// LoginMFAUser and ApplyUser count per user, the user is known from the
+	// token.
+	LoginMFAUser ratelimit.Rule
+	ApplyUser    ratelimit.Rule
+}
+
+// Config holds the policies of the API that are set from the environment.
+type Config struct {
+	RateLimits RateLimits
+	// TrustedProxies may set X-Forwarded-For, the client address of requests
+	// from anywhere else is the address of the connection. None are trusted
+	// by default, the header is then ignored.
+	TrustedProxies []string
+	// VerifiedLogin refuses logins until the user verified their email.
+	VerifiedLogin bool
+	// VerifiedApply refuses job applications from users who haven't
+	// verified their email.
+	VerifiedApply bool
+	// JobCacheTTL is how long jobs stay cached, zero keeps the default.
+	JobCacheTTL time.Duration
+	// Bus tells the other instances about changes of cached data, nil when
+	// only one instance runs.
+	Bus invalidation.Publisher
+	// ApplyWorkers is how many jobs of one batch of applications are
+	// matched at the same time.
+	ApplyWorkers int
+	// TaskWorkers process batches of applications in the background.
+	TaskWorkers int
+	// Work stops the task workers once it is done, nil keeps them running
+	// until the process exits.
+	Work context.Context
+	// Mailer sends the emails, nil sends them through SMTP.
+	Mailer service.Mailer
+	// BulkThreshold is the largest batch of applications matched within the
+	// request, zero matches every batch within the request.
+	BulkThreshold int
+	// Storage keeps uploaded files, uploads fail without it.
+	Storage storage.Storage
+	// FileURLSecret signs the links to stored files, every instance needs the
+	// same one. Without it links only work on the instance that made them
+	// until it restarts.
+	FileURLSecret []byte
+	// FileURLTTL is how long links to files stay valid, zero keeps the
+	// default.
+	FileURLTTL time.Duration
+	// MaxUploadBytes is the largest file accepted, zero keeps the default.
+	MaxUploadBytes int64
+	// HideUnverifiedJobs leaves the jobs of companies without the verified
+	// badge out of the listings.
+	HideUnverifiedJobs bool
+}
+
+// filesPath is where signed links to stored files point to.
+const filesPath = "/api/files/"
+
+// API wires the routes. ch and as are the shared stores created in main, as
+// keeps otps and login lockouts.
+func API(a *auth.Auth, c repository.Repository, ch cache.Cache, as authstate.Store, cfg Config) *gin.Engine {
 
 	r := gin.New()
+	// Rate limits and lockouts count per client address, a header anyone
+	// can send mustn't choose it
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Panic().Err(err).Msg("trusted proxies not set up")
+	}
 
-	m, err := middleware.NewMid(a)
-	s := service.NewServiceStore(c)
+	rl := cfg.RateLimits
+	secret := cfg.FileURLSecret
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+		log.Warn().Msg("no file url secret set, links to files break on restart")
+	}
+	maxUpload := cfg.MaxUploadBytes
+	if maxUpload <= 0 {
+		maxUpload = service.DefaultMaxUpload
+	}
+	s := service.NewServiceStore(c, ch, as, service.WithVerifiedLogin(cfg.VerifiedLogin),
+		service.WithJobCacheTTL(cfg.JobCacheTTL), service.WithInvalidation(cfg.Bus),
+		service.WithApplyWorkers(cfg.ApplyWorkers), service.WithTaskWorkers(cfg.TaskWorkers),
+		service.WithWorkContext(cfg.Work),
+		service.WithMailer(cfg.Mailer),
+		service.WithStorage(cfg.Storage, storage.NewSigner(secret, filesPath), cfg.FileURLTTL),
+		service.WithUploadLimit(maxUpload), service.WithHiddenUnverified(cfg.HideUnverifiedJobs))
+	m, err := middleware.NewMid(a, rl.Limiter, s.TokenRevoked)
 	h := handler{
-		a: a,
-		s: s,
+		a:             a,
+		s:             s,
+		bulkThreshold: cfg.BulkThreshold,
+		maxUpload:     maxUpload,
 	}
 
 	if err != nil {
@@ -28,17 +132,69 @@ func API(a *auth.Auth, c repository.Repository) *gin.Engine {
 	r.Use(m.Log(), gin.Recovery())
 
 	r.POST("/api/register", h.UserRegister)
-	r.POST("/api/login", h.UserLogin)
+	r.POST("/api/verify", m.RateLimit([]middleware.Limit{
+		{Name: "verify:ip", Rule: rl.VerifyIP, Key: middleware.ByIP},
+		{Name: "verify:email", Rule: rl.VerifyEmail, Key: middleware.ByEmail},
+	}, h.VerifyEmail))
+	r.POST("/api/verify/resend", m.RateLimit([]middleware.Limit{
+		{Name: "verifyresend:ip", Rule: rl.VerifyIP, Key: middleware.ByIP},
+		{Name: "verifyresend:email", Rule: rl.VerifyEmail, Key: middleware.ByEmail},
+	}, h.ResendVerification))
+	r.POST("/api/login", m.RateLimit([]middleware.Limit{
+		{Name: "login:ip", Rule: rl.LoginIP, Key: middleware.ByIP},
+		{Name: "login:email", Rule: rl.LoginEmail, Key: middleware.ByEmail},
+	}, h.UserLogin))
+	r.POST("/api/login/mfa", m.AuthenticatePending(m.RateLimit([]middleware.Limit{
+		{Name: "loginmfa:user", Rule: rl.LoginMFAUser, Key: middleware.ByUserID},
+	}, h.LoginMFA)))
+	r.POST("/api/mfa/totp", m.AuthenticateEnrollment(h.EnrollTOTP))
+	r.POST("/api/mfa/totp/confirm", m.AuthenticateEnrollment(h.ConfirmTOTP))
+	r.DELETE("/api/mfa/totp", m.Authenticate(h.DisableTOTP))
+	r.POST("/api/mfa/recovery-codes", m.Authenticate(h.RegenerateRecoveryCodes))
 	r.POST("/api/companies", m.Authenticate(h.CreateCompany))
 	r.GET("/api/companies", m.Authenticate(h.ViewCompany))
+	r.GET("/api/public/companies", m.RateLimit([]middleware.Limit{
+		{Name: "public:ip", Rule: rl.PublicIP, Key: middleware.ByIP},
+	}, h.ViewPublicCompanies))
 	r.GET("/api/companies/:id", m.Authenticate(h.GetCompanyById))
+	r.PUT("/api/companies/:id", m.Authenticate(h.UpdateCompany))
+	r.PATCH("/api/companies/:id", m.Authenticate(h.PatchCompany))
+	r.DELETE("/api/companies/:id", m.Authenticate(h.DeleteCompany))
+	r.PUT("/api/companies/:id/mfa", m.Authenticate(h.SetCompanyMFAPolicy))
+	r.POST("/api/companies/:id/verification", m.Authenticate(h.RequestCompanyVerification))
+	r.POST("/api/companies/:id/verification/confirm", m.Authenticate(h.ConfirmCompanyVerification))
+	r.GET("/api/companies/:id/verification", m.Authenticate(h.GetCompanyVerification))
+	r.POST("/api/companies/:id/logo", m.Authenticate(h.UploadCompanyLogo))
+	r.POST("/api/companies/:id/photos", m.Authenticate(h.UploadCompanyPhoto))
+	r.GET("/api/companies/:id/media", m.Authenticate(h.ListCompanyMedia))
+	r.DELETE("/api/companies/:id/media/:mediaId", m.Authenticate(h.DeleteCompanyMedia))
+	r.GET(filesPath+"*key", h.ServeFile)
 	r.POST("/api/companies/:id/jobs", m.Authenticate(h.AddJob))
 	r.GET("/api/jobs", m.Authenticate(h.ViewJobs))
 	r.GET("/api/jobs/:id", m.Authenticate(h.ViewJobById))
 	r.GET("/api/companies/:id/jobs", m.Authenticate(h.ViewJobByCompany))
-	r.POST("/api/job/applications/", m.Authenticate(h.ApplyForJob))
-	r.POST("/api/forgetpassword/", h.ForgotPassword)
-	r.POST("/api/resetpassword/", h.ResetPassword)
+	apply := h.ApplyForJob
+	if cfg.VerifiedApply {
+		apply = m.RequireVerified(apply)
+	}
+	r.POST("/api/job/applications/", m.Authenticate(m.RateLimit([]middleware.Limit{
+		{Name: "apply:user", Rule: rl.ApplyUser, Key: middleware.ByUserID},
+	}, apply)))
+	r.GET("/api/tasks/:id", m.Authenticate(h.GetTask))
+	r.POST("/api/forgetpassword/", m.RateLimit([]middleware.Limit{
+		{Name: "forgetpassword:ip", Rule: rl.ForgotPasswordIP, Key: middleware.ByIP},
+		{Name: "forgetpassword:email", Rule: rl.ForgotPasswordEmail, Key: middleware.ByEmail},
+	}, h.ForgotPassword))
+	r.POST("/api/resetpassword/", m.RateLimit([]middleware.Limit{
+		{Name: "resetpassword:ip", Rule: rl.ResetPasswordIP, Key: middleware.ByIP},
+		{Name: "resetpassword:email", Rule: rl.ResetPasswordEmail, Key: middleware.ByEmail},
+	}, h.ResetPassword))
+	r.POST("/api/admin/unlock", m.Authenticate(m.RequireAdmin(h.UnlockAccount)))
+	r.GET("/api/admin/verifications", m.Authenticate(m.RequireAdmin(h.ListCompanyVerifications)))
+	r.POST("/api/admin/verifications/:id/approve", m.Authenticate(m.RequireAdmin(h.ApproveCompanyVerification)))
+	r.POST("/api/admin/verifications/:id/reject", m.Authenticate(m.RequireAdmin(h.RejectCompanyVerification)))
+	r.GET("/openapi.json", h.OpenAPI)
+	r.GET("/docs", h.Docs)
 	// Return the prepared Gin engine
 	return r
 }
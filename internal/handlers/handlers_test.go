@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"job-portal/internal/auth"
+	"job-portal/internal/authstate"
+	"job-portal/internal/cache"
+	"job-portal/internal/ratelimit"
+	"job-portal/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func newTestAuth(t *testing.T) *auth.Auth {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := auth.NewAuth(key, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func newTestEngine(t *testing.T, cfg Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	mc := gomock.NewController(t)
+	return API(newTestAuth(t), repository.NewMockRepository(mc), cache.NewMemory(), authstate.NewMemory(), cfg)
+}
+
+func TestAPI_RateLimitsLogin(t *testing.T) {
+	r := newTestEngine(t, Config{RateLimits: RateLimits{
+		Limiter:    ratelimit.NewMemory(),
+		LoginIP:    ratelimit.Rule{Limit: 3, Window: time.Minute},
+		LoginEmail: ratelimit.Rule{Limit: 2, Window: time.Minute},
+	}})
+
+	login := func(email string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		// The body fails validation so the request never reaches the service
+		req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"email":"`+email+`"}`))
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := login("a@b.com")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", rr.Header().Get("RateLimit-Policy"))
+
+	// The email limit is case insensitive
+	rr = login("A@B.com")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+
+	rr = login("a@b.com")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, true, strings.Contains(rr.Body.String(), `"code":"rate_limited"`))
+
+	// Every attempt counts against the address, so another email from it is
+	// now refused as well
+	rr = login("c@d.com")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "3", rr.Header().Get("RateLimit-Limit"))
+}
+
+func TestAPI_RateLimitsIgnoreSpoofedForwardedFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		want    []int
+	}{
+		{
+			name: "untrusted header",
+			want: []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusTooManyRequests},
+		},
+		{
+			// httptest requests come from 192.0.2.1
+			name:    "trusted proxy",
+			proxies: []string{"192.0.2.0/24"},
+			want:    []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusBadRequest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestEngine(t, Config{TrustedProxies: tt.proxies, RateLimits: RateLimits{
+				Limiter: ratelimit.NewMemory(),
+				LoginIP: ratelimit.Rule{Limit: 2, Window: time.Minute},
+			}})
+			for i, want := range tt.want {
+				rr := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{}`))
+				// Every attempt claims to come from another address
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+				r.ServeHTTP(rr, req)
+				assert.Equal(t, want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestAPI_RateLimitsVerify(t *testing.T) {
+	r := newTestEngine(t, Config{RateLimits: RateLimits{
+		Limiter:     ratelimit.NewMemory(),
+		VerifyIP:    ratelimit.Rule{Limit: 10, Window: time.Minute},
+		VerifyEmail: ratelimit.Rule{Limit: 1, Window: time.Minute},
+	}})
+	for _, path := range []string{"/api/verify", "/api/verify/resend"} {
+		codes := make([]int, 0, 2)
+		for i := 0; i < 2; i++ {
+			rr := httptest.NewRecorder()
+			// The body fails validation so the request never reaches the service
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"email":"vishnu"}`))
+			r.ServeHTTP(rr, req)
+			codes = append(codes, rr.Code)
+		}
+		assert.Equal(t, []int{http.StatusBadRequest, http.StatusTooManyRequests}, codes)
+	}
+}
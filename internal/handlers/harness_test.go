@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"job-portal/internal/auth"
+	"job-portal/internal/authstate"
+	"job-portal/internal/cache"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"job-portal/internal/storage"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// testServer runs the engine built by API with real auth on in-memory
+// dependencies, so requests pass routing, middleware and services like they
+// do in production.
+type testServer struct {
+	t      *testing.T
+	engine *gin.Engine
+	auth   *auth.Auth
+	repo   *repository.Memory
+	redis  *miniredis.Miniredis
+
+	mu    sync.Mutex
+	mails []sentMail
+}
+
+type sentMail struct {
+	to, subject, body string
+}
+
+func newTestServer(t *testing.T, cfg Config) *testServer {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	ts := &testServer{t: t, auth: newTestAuth(t), repo: repository.NewMemory(), redis: mr}
+	cfg.Mailer = ts.sendMail
+	if cfg.Work == nil {
+		work, stop := context.WithCancel(context.Background())
+		t.Cleanup(stop)
+		cfg.Work = work
+	}
+	if cfg.Storage == nil {
+		files, err := storage.NewLocal(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Storage = files
+	}
+	ts.engine = API(ts.auth, ts.repo, cache.NewMemory(), authstate.NewRedis(rdb), cfg)
+	return ts
+}
+
+func (ts *testServer) sendMail(to, subject, body string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.mails = append(ts.mails, sentMail{to: to, subject: subject, body: body})
+	return nil
+}
+
+var codePattern = regexp.MustCompile(`\d{6}`)
+
+// code returns the code of the last mail sent to email.
+func (ts *testServer) code(email string) string {
+	ts.t.Helper()
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for i := len(ts.mails) - 1; i >= 0; i-- {
+		m := ts.mails[i]
+		if m.to == email {
+			if c := codePattern.FindString(m.body); c != "" {
+				return c
+			}
+		}
+	}
+	ts.t.Fatalf("no code was mailed to %s", email)
+	return ""
+}
+
+// do sends a request, body is sent as is when it is a string and as json
+// otherwise. An empty token sends no Authorization header.
+func (ts *testServer) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = strings.NewReader(b)
+	default:
+		buf, err := json.Marshal(b)
+		if err != nil {
+			ts.t.Fatal(err)
+		}
+		r = strings.NewReader(string(buf))
+	}
+	req := httptest.NewRequest(method, path, r)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	ts.engine.ServeHTTP(rr, req)
+	return rr
+}
+
+// upload posts data as the file field of a multipart form.
+func (ts *testServer) upload(path, token, filename string, data []byte) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	_, _ = fw.Write(data)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	ts.engine.ServeHTTP(rr, req)
+	return rr
+}
+
+// call sends a request like do, fails the test unless the response has the
+// status want and decodes the response into out when it isn't nil.
+func (ts *testServer) call(method, path, token string, body any, want int, out any) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	rr := ts.do(method, path, token, body)
+	if rr.Code != want {
+		ts.t.Fatalf("%s %s: status %d, want %d: %s", method, path, rr.Code, want, rr.Body.String())
+	}
+	if out != nil {
+		err := json.Unmarshal(rr.Body.Bytes(), out)
+		if err != nil {
+			ts.t.Fatalf("%s %s: decoding %s: %v", method, path, rr.Body.String(), err)
+		}
+	}
+	return rr
+}
+
+func (ts *testServer) register(email, password string) models.User {
+	ts.t.Helper()
+	var u models.User
+	ts.call(http.MethodPost, "/api/register", "", models.NewUser{Name: "vishnu", Email: email, Password: password}, http.StatusOK, &u)
+	return u
+}
+
+// verify confirms email with the code mailed to it.
+func (ts *testServer) verify(email string) {
+	ts.t.Helper()
+	ts.call(http.MethodPost, "/api/verify", "", models.VerifyEmail{Email: email, Code: ts.code(email)}, http.StatusOK, nil)
+}
+
+func (ts *testServer) login(email, password string) models.Token {
+	ts.t.Helper()
+	var tkn models.Token
+	ts.call(http.MethodPost, "/api/login", "", models.Login{Email: email, Password: password}, http.StatusOK, &tkn)
+	return tkn
+}
+
+// signUp registers and verifies a user and returns a token of theirs.
+func (ts *testServer) signUp(email string) string {
+	ts.t.Helper()
+	ts.register(email, testPassword)
+	ts.verify(email)
+	return ts.login(email, testPassword).Token
+}
+
+// tokenOf signs a token for the subject with the audiences without a login,
+// whether or not such a user exists.
+func (ts *testServer) tokenOf(subject string, aud ...string) string {
+	ts.t.Helper()
+	tkn, err := ts.auth.GenerateToken(jwt.RegisteredClaims{
+		Subject:   subject,
+		Audience:  aud,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return tkn
+}
+
+const testPassword = "Secret#123"
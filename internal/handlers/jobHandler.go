@@ -2,53 +2,45 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"job-portal/internal/middleware"
 	"job-portal/internal/models"
+	"job-portal/internal/problem"
+	"job-portal/internal/validation"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator"
 	"github.com/rs/zerolog/log"
 )
 
 func (h *handler) AddJob(c *gin.Context) {
 
 	ctx := c.Request.Context()
-	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
-
+	traceId, uid, cId, ok := companyOwnerRequest(c)
 	if !ok {
-		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText((http.StatusInternalServerError))})
-		return
-	}
-	cIdstr := c.Param("id")
-	cId, err := strconv.Atoi(cIdstr)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": http.StatusText((http.StatusBadRequest))})
 		return
 	}
 	var newJob models.NewJob
-	err = json.NewDecoder(c.Request.Body).Decode(&newJob)
+	err := json.NewDecoder(c.Request.Body).Decode(&newJob)
 	if err != nil {
 		log.Info().Msg("error while converting request body to json")
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
 		return
 	}
-	validate := validator.New()
-	err = validate.Struct(newJob)
+	err = validation.Struct(newJob)
 
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId).Msg("validation failed")
-		c.AbortWithStatusJSON(http.StatusBadRequest,
-			gin.H{"error": "Bad Request"})
+		abortWithValidation(c, traceId, err)
 		return
 	}
 
-	job, err := h.s.CreateJob(ctx, newJob, cId)
+	job, err := h.s.CreateJob(ctx, newJob, int(cId), uid)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId).Msg("error while adding job")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, traceId, err)
 		return
 	}
 
@@ -62,7 +54,7 @@ func (h *handler) ViewJobs(c *gin.Context) {
 	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
 	if !ok {
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 
@@ -70,7 +62,7 @@ func (h *handler) ViewJobs(c *gin.Context) {
 
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, traceId, err)
 		return
 	}
 
@@ -83,7 +75,7 @@ func (h *handler) ViewJobById(c *gin.Context) {
 	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
 	if !ok {
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 
@@ -91,7 +83,7 @@ func (h *handler) ViewJobById(c *gin.Context) {
 	jId, err := strconv.Atoi(id)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request"})
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidID, "id must be an integer")
 		return
 	}
 
@@ -99,7 +91,7 @@ func (h *handler) ViewJobById(c *gin.Context) {
 	job, err := h.s.GetJobInfoByID(ctx, jId)
 	if err != nil {
 		// Handle errors, e.g., company not found
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, traceId, err)
 		return
 	}
 
@@ -112,14 +104,14 @@ func (h *handler) ViewJobByCompany(c *gin.Context) {
 	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
 	if !ok {
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 	id := c.Param("id")
 	cId, err := strconv.Atoi(id)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request"})
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidID, "id must be an integer")
 		return
 	}
 
@@ -127,7 +119,7 @@ func (h *handler) ViewJobByCompany(c *gin.Context) {
 	jobs, err := h.s.ViewJobByCompanyId(ctx, cId)
 	if err != nil {
 		// Handle errors, e.g., company not found
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, traceId, err)
 		return
 	}
 
@@ -141,7 +133,7 @@ func (h *handler) ApplyForJob(c *gin.Context) {
 
 	if !ok {
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 
@@ -156,24 +148,83 @@ func (h *handler) ApplyForJob(c *gin.Context) {
 	err := json.NewDecoder(c.Request.Body).Decode(&Applications)
 	if err != nil {
 		log.Info().Msg("error while converting request body to JSON")
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
 
 		return
 	}
 	var valid_applications []models.JobApplication
-	validate := validator.New()
-	for _, a := range Applications {
-		if err := validate.Struct(a); err != nil {
+	var verrs validation.Errors
+	for i, a := range Applications {
+		if err := validation.Struct(a); err != nil {
 			log.Error().Err(err).Str("Trace Id", traceId).Msgf("validation failed for an application %s", a.Name)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Request"})
-			return
+			var ferrs validation.Errors
+			if !errors.As(err, &ferrs) {
+				abortWithValidation(c, traceId, err)
+				return
+			}
+			verrs = append(verrs, ferrs.WithPrefix(fmt.Sprintf("[%d].", i))...)
+			continue
 		}
 		valid_applications = append(valid_applications, a)
 	}
-	users, err := h.s.ApplyJob(valid_applications)
+	if len(verrs) > 0 {
+		abortWithValidation(c, traceId, verrs)
+		return
+	}
+	if h.bulkThreshold > 0 && len(valid_applications) > h.bulkThreshold {
+		h.submitApplications(c, traceId, valid_applications)
+		return
+	}
+	users, err := h.s.ApplyJob(ctx, valid_applications)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, traceId, err)
 		return
 	}
 	c.JSON(http.StatusOK, users)
 }
+
+// submitApplications queues a large batch as a task and responds with the
+// task to poll.
+func (h *handler) submitApplications(c *gin.Context, traceId string, applications []models.JobApplication) {
+	ctx := c.Request.Context()
+	uid, _, ok := userID(ctx)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+	task, err := h.s.SubmitApplications(ctx, uid, applications)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("submitting applications")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.Header("Location", "/api/tasks/"+task.ID)
+	c.JSON(http.StatusAccepted, task)
+}
+
+// GetTask reports the progress of a task and the outcomes of the
+// applications processed so far.
+func (h *handler) GetTask(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	uid, _, ok := userID(ctx)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	task, err := h.s.GetTask(ctx, c.Param("id"), uid)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("fetching task")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
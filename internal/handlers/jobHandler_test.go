@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"job-portal/internal/auth"
 	"job-portal/internal/middleware"
 	"job-portal/internal/models"
 	"job-portal/internal/service"
@@ -13,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/mock/gomock"
 	"gopkg.in/go-playground/assert.v1"
 )
@@ -36,7 +38,7 @@ func Test_handler_ViewJobByCompany(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"Internal Server Error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
 		},
 		{
 			name: "Invalid companyId",
@@ -53,7 +55,7 @@ func Test_handler_ViewJobByCompany(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_id","title":"Bad Request","status":400,"detail":"id must be an integer","code":"invalid_id","trace_id":"693"}`,
 		},
 		{
 			name: "error while fectching job details by companyId",
@@ -73,7 +75,27 @@ func Test_handler_ViewJobByCompany(t *testing.T) {
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"mock service error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error","trace_id":"693"}`,
+		},
+		{
+			name: "company not found",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				rr := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(rr)
+				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", nil)
+				ctx := httpReq.Context()
+				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				httpReq = httpReq.WithContext(ctx)
+				c.Params = append(c.Params, gin.Param{Key: "id", Value: "999"})
+				c.Request = httpReq
+				mc := gomock.NewController(t)
+				ms := service.NewMockService(mc)
+				ms.EXPECT().ViewJobByCompanyId(c.Request.Context(), 999).Return([]models.Job{}, &service.Error{Kind: service.ErrNotFound, Code: service.CodeCompanyNotFound, Message: "company not found"})
+
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   `{"type":"urn:job-portal:error:company_not_found","title":"Not Found","status":404,"detail":"company not found","code":"company_not_found","trace_id":"693"}`,
 		},
 		{
 			name: "sucess while fectching job details by companyId",
@@ -128,7 +150,7 @@ func Test_handler_ViewJobById(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"Internal Server Error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
 		},
 		{
 			name: "Invalid jobId",
@@ -145,7 +167,7 @@ func Test_handler_ViewJobById(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_id","title":"Bad Request","status":400,"detail":"id must be an integer","code":"invalid_id","trace_id":"693"}`,
 		},
 		{
 			name: "error while fectching job details by jobId",
@@ -165,7 +187,27 @@ func Test_handler_ViewJobById(t *testing.T) {
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"mock service error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error","trace_id":"693"}`,
+		},
+		{
+			name: "job not found",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				rr := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(rr)
+				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", nil)
+				ctx := httpReq.Context()
+				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				httpReq = httpReq.WithContext(ctx)
+				c.Params = append(c.Params, gin.Param{Key: "id", Value: "693"})
+				c.Request = httpReq
+				mc := gomock.NewController(t)
+				ms := service.NewMockService(mc)
+				ms.EXPECT().GetJobInfoByID(c.Request.Context(), gomock.Any()).Return(models.Job{}, &service.Error{Kind: service.ErrNotFound, Code: service.CodeJobNotFound, Message: "job not found"}).AnyTimes()
+
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   `{"type":"urn:job-portal:error:job_not_found","title":"Not Found","status":404,"detail":"job not found","code":"job_not_found","trace_id":"693"}`,
 		},
 		{
 			name: "sucess while fectching job details by jobId",
@@ -185,7 +227,7 @@ func Test_handler_ViewJobById(t *testing.T) {
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusOK,
-			expectedResponse:   `{"CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","DeletedAt":null,"ID":0,"Title":"","Description":"","CompanyID":0,"Min_NP":0,"Max_NP":0,"Budget":0,"JobLocations":null,"TechnologyStack":null,"WorkModes":null,"MinExp":0,"MaxExp":0,"Qualifications":null,"Shifts":null,"JobTypes":null}`,
+			expectedResponse:   `{"CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","DeletedAt":null,"ID":0,"Title":"","Description":"","CompanyID":0,"Min_NP":0,"Max_NP":0,"Budget":0,"JobLocations":null,"TechnologyStack":null,"WorkModes":null,"MinExp":0,"MaxExp":0,"Qualifications":null,"Shifts":null,"JobTypes":null,"verified":false}`,
 		},
 	}
 	for _, tt := range tests {
@@ -221,7 +263,7 @@ func Test_handler_AddJob(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"Internal Server Error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
 		},
 		{
 			name: "Invalid CompanyId",
@@ -231,6 +273,7 @@ func Test_handler_AddJob(t *testing.T) {
 				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", nil)
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
 				httpReq = httpReq.WithContext(ctx)
 				c.Params = append(c.Params, gin.Param{Key: "id", Value: "vishnu"})
 				c.Request = httpReq
@@ -238,7 +281,7 @@ func Test_handler_AddJob(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_id","title":"Bad Request","status":400,"detail":"id must be an integer","code":"invalid_id","trace_id":"693"}`,
 		},
 		{
 			name: "invalid request body",
@@ -249,6 +292,7 @@ func Test_handler_AddJob(t *testing.T) {
 				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", strings.NewReader(requestBody))
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
 				httpReq = httpReq.WithContext(ctx)
 				c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
 				c.Request = httpReq
@@ -256,7 +300,7 @@ func Test_handler_AddJob(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
 		},
 		{
 			name: "checking validator function",
@@ -267,6 +311,40 @@ func Test_handler_AddJob(t *testing.T) {
 				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
+				httpReq = httpReq.WithContext(ctx)
+				c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
+				c.Request = httpReq
+
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"title","rule":"required","message":"is required"},{"field":"description","rule":"required","message":"is required"},{"field":"min_np","rule":"required","message":"is required"},{"field":"max_np","rule":"required","message":"is required"},{"field":"budget","rule":"required","message":"is required"},{"field":"job_location","rule":"required","message":"is required"},{"field":"technology_stack","rule":"required","message":"is required"},{"field":"work_mode","rule":"required","message":"is required"},{"field":"min_exp","rule":"required","message":"is required"},{"field":"max_exp","rule":"required","message":"is required"},{"field":"qualification","rule":"required","message":"is required"},{"field":"work_shift","rule":"required","message":"is required"},{"field":"job_type","rule":"required","message":"is required"}]}`,
+		},
+		{
+			name: "cross field validation",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				rr := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(rr)
+				requestBody := []byte(`{
+					"title": "software developer",
+					"description": "backend",
+					"min_np": 30,
+					"max_np": 10,
+					"budget": -5,
+					"job_location": [1],
+					"technology_stack": [1],
+					"work_mode": [1],
+					"min_exp": 5,
+					"max_exp": 2,
+					"qualification": [1],
+					"work_shift": [1],
+					"job_type": [1]
+				}`)
+				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
+				ctx := httpReq.Context()
+				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
 				httpReq = httpReq.WithContext(ctx)
 				c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
 				c.Request = httpReq
@@ -274,7 +352,7 @@ func Test_handler_AddJob(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"max_np","rule":"gtefield","message":"must be greater than or equal to min_np"},{"field":"budget","rule":"gt","message":"must be greater than 0"},{"field":"max_exp","rule":"gtefield","message":"must be greater than or equal to min_exp"}]}`,
 		},
 		{
 			name: "error while adding job",
@@ -299,18 +377,19 @@ func Test_handler_AddJob(t *testing.T) {
 				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
 				httpReq = httpReq.WithContext(ctx)
 				c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
 				c.Request = httpReq
 
 				mc := gomock.NewController(t)
 				ms := service.NewMockService(mc)
-				ms.EXPECT().CreateJob(c.Request.Context(), gomock.Any(), gomock.Any()).Return(models.Job{}, errors.New("error in adding job")).AnyTimes()
+				ms.EXPECT().CreateJob(c.Request.Context(), gomock.Any(), 1, uint(42)).Return(models.Job{}, errors.New("error in adding job")).AnyTimes()
 
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"error in adding job"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error","trace_id":"693"}`,
 		},
 		{
 			name: "sucessfully adding job",
@@ -335,18 +414,19 @@ func Test_handler_AddJob(t *testing.T) {
 				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				ctx = context.WithValue(ctx, auth.Key, jwt.RegisteredClaims{Subject: "42"})
 				httpReq = httpReq.WithContext(ctx)
 				c.Params = append(c.Params, gin.Param{Key: "id", Value: "1"})
 				c.Request = httpReq
 
 				mc := gomock.NewController(t)
 				ms := service.NewMockService(mc)
-				ms.EXPECT().CreateJob(c.Request.Context(), gomock.Any(), gomock.Any()).Return(models.Job{}, nil).AnyTimes()
+				ms.EXPECT().CreateJob(c.Request.Context(), gomock.Any(), 1, uint(42)).Return(models.Job{}, nil).AnyTimes()
 
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusOK,
-			expectedResponse:   `{"CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","DeletedAt":null,"ID":0,"Title":"","Description":"","CompanyID":0,"Min_NP":0,"Max_NP":0,"Budget":0,"JobLocations":null,"TechnologyStack":null,"WorkModes":null,"MinExp":0,"MaxExp":0,"Qualifications":null,"Shifts":null,"JobTypes":null}`,
+			expectedResponse:   `{"CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","DeletedAt":null,"ID":0,"Title":"","Description":"","CompanyID":0,"Min_NP":0,"Max_NP":0,"Budget":0,"JobLocations":null,"TechnologyStack":null,"WorkModes":null,"MinExp":0,"MaxExp":0,"Qualifications":null,"Shifts":null,"JobTypes":null,"verified":false}`,
 		},
 	}
 	for _, tt := range tests {
@@ -381,7 +461,7 @@ func Test_handler_ViewJobs(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"Internal Server Error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
 		},
 		{
 			name: "error while fectching jobs",
@@ -400,7 +480,7 @@ func Test_handler_ViewJobs(t *testing.T) {
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"jobs not found"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error","trace_id":"693"}`,
 		},
 		{
 			name: "sucess while fectching jobs",
@@ -454,24 +534,23 @@ func Test_handler_ApplyForJob(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"Internal Server Error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
 		},
 		{
-			name: "Invalid jobId",
+			name: "empty request body",
 			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
 				rr := httptest.NewRecorder()
 				c, _ := gin.CreateTestContext(rr)
-				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", nil)
+				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", http.NoBody)
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
 				httpReq = httpReq.WithContext(ctx)
-				c.Params = append(c.Params, gin.Param{Key: "id", Value: "vishnu"})
 				c.Request = httpReq
 
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
 		},
 		{
 			name: "checking decode function",
@@ -489,10 +568,10 @@ func Test_handler_ApplyForJob(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
 		},
 		{
-			name: "checking validator function",
+			name: "wrong field type",
 			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
 				rr := httptest.NewRecorder()
 				c, _ := gin.CreateTestContext(rr)
@@ -534,9 +613,40 @@ func Test_handler_ApplyForJob(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
 		},
 
+		{
+			name: "checking validator function",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				rr := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(rr)
+				requestBody := []byte(`[{
+					"name": "vishnu",
+					"email": "not-an-email",
+					"age": 30,
+					"job_Id": 1,
+					"notice_period": 1,
+					"expect_salary": 500000,
+					"job_location": [1, 2],
+					"technology_stack": [1,2],
+					"work_mode": [1,2],
+					"experience": 8,
+					"qualification": [1,2,3],
+					"work_shift": [1,2],
+					"job_type": [1,2]
+				  }]`)
+				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
+				ctx := httpReq.Context()
+				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				httpReq = httpReq.WithContext(ctx)
+				c.Request = httpReq
+
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"[0].email","rule":"email","message":"must be a valid email address"}]}`,
+		},
 		{
 			name: "error while applying job",
 			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
@@ -546,6 +656,7 @@ func Test_handler_ApplyForJob(t *testing.T) {
 					"name": "vishnu",
 					"email": "vishnu@example.com",
 					"age": 30,
+					"job_Id": 1,
 					"notice_period": 1,
 					"expect_salary": 500000,
 					"job_location": [1, 2],
@@ -560,6 +671,7 @@ func Test_handler_ApplyForJob(t *testing.T) {
 					"name": "vishnu",
 					"email": "vishnu@example.com",
 					"age": 30,
+					"job_Id": 1,
 					"notice_period": 1,
 					"expect_salary": 500000,
 					"job_location": [1, 2],
@@ -579,12 +691,12 @@ func Test_handler_ApplyForJob(t *testing.T) {
 
 				mc := gomock.NewController(t)
 				ms := service.NewMockService(mc)
-				ms.EXPECT().ApplyJob(gomock.Any()).Return([]models.Applicant{}, errors.New("error in adding job"))
+				ms.EXPECT().ApplyJob(gomock.Any(), gomock.Any()).Return(nil, errors.New("error in adding job"))
 
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"error in adding job"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error","trace_id":"693"}`,
 		},
 		{
 			name: "sucess while applying job",
@@ -595,6 +707,7 @@ func Test_handler_ApplyForJob(t *testing.T) {
 					"name": "vishnu",
 					"email": "vishnu@example.com",
 					"age": 30,
+					"job_Id": 1,
 					"notice_period": 1,
 					"expect_salary": 500000,
 					"job_location": [1, 2],
@@ -609,6 +722,7 @@ func Test_handler_ApplyForJob(t *testing.T) {
 					"name": "vishnu",
 					"email": "vishnu@example.com",
 					"age": 30,
+					"job_Id": 1,
 					"notice_period": 1,
 					"expect_salary": 500000,
 					"job_location": [1, 2],
@@ -628,12 +742,15 @@ func Test_handler_ApplyForJob(t *testing.T) {
 
 				mc := gomock.NewController(t)
 				ms := service.NewMockService(mc)
-				ms.EXPECT().ApplyJob(gomock.Any()).Return([]models.Applicant{}, nil)
+				ms.EXPECT().ApplyJob(gomock.Any(), gomock.Any()).Return([]models.ApplicationOutcome{
+					{Position: 0, Name: "vishnu", Email: "vishnu@gmail.com", Outcome: models.OutcomeMatched},
+					{Position: 1, Name: "ravi", Email: "ravi@gmail.com", Outcome: models.OutcomeFailed, Error: "job not found"},
+				}, nil)
 
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusOK,
-			expectedResponse:   `[]`,
+			expectedResponse:   `[{"index":0,"name":"vishnu","email":"vishnu@gmail.com","outcome":"matched"},{"index":1,"name":"ravi","email":"ravi@gmail.com","outcome":"failed","error":"job not found"}]`,
 		},
 	}
 	for _, tt := range tests {
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"job-portal/internal/middleware"
+	"job-portal/internal/models"
+	"job-portal/internal/problem"
+	"job-portal/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// multipartOverhead is what the boundaries and part headers of a form with a
+// single file may add to the size of the file.
+const multipartOverhead = 64 << 10
+
+// UploadCompanyLogo replaces the logo of a company owned by the user.
+func (h *handler) UploadCompanyLogo(c *gin.Context) {
+	h.uploadCompanyMedia(c, models.MediaLogo)
+}
+
+// UploadCompanyPhoto adds a photo to a company owned by the user.
+func (h *handler) UploadCompanyPhoto(c *gin.Context) {
+	h.uploadCompanyMedia(c, models.MediaPhoto)
+}
+
+// uploadCompanyMedia reads the image from the file field of a multipart
+// form. The service sniffs its content, the declared type is ignored.
+func (h *handler) uploadCompanyMedia(c *gin.Context, kind string) {
+	ctx := c.Request.Context()
+	traceId, uid, cId, ok := companyOwnerRequest(c)
+	if !ok {
+		return
+	}
+
+	// The service checks the size of the file, this stops reading huge
+	// bodies early
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxUpload+multipartOverhead)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			abortWithProblem(c, traceId, http.StatusRequestEntityTooLarge, service.CodeFileTooLarge, "the upload is too large")
+			return
+		}
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request must be a multipart form with a file field")
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("opening upload")
+		abortWithProblem(c, traceId, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	defer f.Close()
+
+	m, err := h.s.UploadCompanyMedia(ctx, cId, uid, kind, f)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("uploading company media")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusCreated, m)
+}
+
+// ListCompanyMedia returns the logo and photos of a company.
+func (h *handler) ListCompanyMedia(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	cId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidID, "id must be an integer")
+		return
+	}
+
+	media, err := h.s.ListCompanyMedia(ctx, uint(cId))
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("listing company media")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, media)
+}
+
+// DeleteCompanyMedia deletes an image of a company owned by the user.
+func (h *handler) DeleteCompanyMedia(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, uid, cId, ok := companyOwnerRequest(c)
+	if !ok {
+		return
+	}
+	mId, err := strconv.ParseUint(c.Param("mediaId"), 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidID, "id must be an integer")
+		return
+	}
+
+	err = h.s.DeleteCompanyMedia(ctx, cId, uid, uint(mId))
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("deleting company media")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ServeFile streams a stored file to whoever holds a valid signed link, no
+// token is needed so the links work in img tags.
+func (h *handler) ServeFile(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	obj, err := h.s.OpenFile(ctx, key, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Str("key", key).Msg("serving file")
+		abortWithError(c, traceId, err)
+		return
+	}
+	defer obj.Body.Close()
+	c.DataFromReader(http.StatusOK, obj.Size, obj.ContentType, obj.Body, map[string]string{
+		// The link expires, copies must not outlive it by much
+		"Cache-Control":          "private, max-age=300",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"job-portal/internal/models"
+	"job-portal/internal/service"
+	"job-portal/internal/storage"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// multipartBody returns a form with data as its file field and the content
+// type to send it with.
+func multipartBody(t *testing.T, data []byte) (string, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write(data)
+	mw.Close()
+	return body.String(), mw.FormDataContentType()
+}
+
+func Test_handler_UploadCompanyLogo(t *testing.T) {
+	tests := []struct {
+		name               string
+		claims             jwt.RegisteredClaims
+		file               []byte
+		plain              bool
+		setup              func(ms *service.MockService)
+		expectedStatusCode int
+		expectedCode       string
+	}{
+		{
+			name:               "missing claims",
+			file:               []byte("logo"),
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "not a multipart form",
+			claims:             jwt.RegisteredClaims{Subject: "1"},
+			file:               []byte("logo"),
+			plain:              true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       "invalid_body",
+		},
+		{
+			name:               "body too large",
+			claims:             jwt.RegisteredClaims{Subject: "1"},
+			file:               make([]byte, 2*multipartOverhead),
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+			expectedCode:       service.CodeFileTooLarge,
+		},
+		{
+			name:   "unsupported image",
+			claims: jwt.RegisteredClaims{Subject: "1"},
+			file:   []byte("logo"),
+			setup: func(ms *service.MockService) {
+				ms.EXPECT().UploadCompanyMedia(gomock.Any(), uint(7), uint(1), models.MediaLogo, gomock.Any()).
+					Return(models.CompanyMedia{}, &service.Error{Kind: service.ErrUnsupportedMedia, Code: service.CodeUnsupportedMedia})
+			},
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+			expectedCode:       service.CodeUnsupportedMedia,
+		},
+		{
+			name:   "success",
+			claims: jwt.RegisteredClaims{Subject: "1"},
+			file:   []byte("logo"),
+			setup: func(ms *service.MockService) {
+				ms.EXPECT().UploadCompanyMedia(gomock.Any(), uint(7), uint(1), models.MediaLogo, gomock.Any()).
+					DoAndReturn(func(_ any, _, _ uint, _ string, r io.Reader) (models.CompanyMedia, error) {
+						data, _ := io.ReadAll(r)
+						return models.CompanyMedia{ID: 3, Kind: models.MediaLogo, Size: int64(len(data))}, nil
+					})
+			},
+			expectedStatusCode: http.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			body, contentType := multipartBody(t, tt.file)
+			if tt.plain {
+				body, contentType = string(tt.file), "image/png"
+			}
+			c, rr := newClaimsContext(body, tt.claims)
+			c.Request.Header.Set("Content-Type", contentType)
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: "7"})
+			ms := service.NewMockService(gomock.NewController(t))
+			if tt.setup != nil {
+				tt.setup(ms)
+			}
+
+			h := &handler{s: ms, maxUpload: multipartOverhead}
+			h.UploadCompanyLogo(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			if tt.expectedCode != "" {
+				assert.Equal(t, true, strings.Contains(rr.Body.String(), `"code":"`+tt.expectedCode+`"`))
+			}
+			if tt.expectedStatusCode == http.StatusCreated {
+				assert.Equal(t, `{"id":3,"created_at":"0001-01-01T00:00:00Z","company_id":0,"kind":"logo","content_type":"","size":4,"width":0,"height":0,"url":""}`, rr.Body.String())
+			}
+		})
+	}
+}
+
+func Test_handler_ServeFile(t *testing.T) {
+	tests := []struct {
+		name               string
+		obj                storage.Object
+		serviceErr         error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "invalid signature",
+			serviceErr:         &service.Error{Kind: service.ErrForbidden, Code: service.CodeInvalidSignature},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "missing file",
+			serviceErr:         &service.Error{Kind: service.ErrNotFound, Code: service.CodeFileNotFound},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "success",
+			obj:                storage.Object{Body: io.NopCloser(strings.NewReader("png")), Size: 3, ContentType: "image/png"},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "png",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr := newClaimsContext("", jwt.RegisteredClaims{})
+			c.Request.URL.RawQuery = "expires=100&signature=abc"
+			c.Params = append(c.Params, gin.Param{Key: "key", Value: "/companies/7/logo.png"})
+			ms := service.NewMockService(gomock.NewController(t))
+			ms.EXPECT().OpenFile(c.Request.Context(), "companies/7/logo.png", "100", "abc").Return(tt.obj, tt.serviceErr)
+
+			h := &handler{s: ms}
+			h.ServeFile(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, rr.Body.String())
+				assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+				assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+			}
+		})
+	}
+}
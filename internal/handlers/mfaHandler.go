@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"job-portal/internal/auth"
+	"job-portal/internal/middleware"
+	"job-portal/internal/models"
+	"job-portal/internal/problem"
+	"job-portal/internal/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// LoginMFA completes a login that waits for a second factor and responds
+// with a full token.
+func (h *handler) LoginMFA(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	uid, _, ok := userID(ctx)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+	code, ok := decodeMFACode(c, traceId)
+	if !ok {
+		return
+	}
+
+	claims, err := h.s.CompleteMFALogin(ctx, uid, code.Code, c.ClientIP())
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("completing mfa login")
+		abortWithError(c, traceId, err)
+		return
+	}
+
+	var tkn models.Token
+	tkn.Token, err = h.a.GenerateToken(claims)
+	if err != nil {
+		log.Error().Err(err).Msg("generating token")
+		abortWithProblem(c, traceId, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	c.JSON(http.StatusOK, tkn)
+}
+
+// EnrollTOTP hands out a new secret and its provisioning URI.
+func (h *handler) EnrollTOTP(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	uid, _, ok := userID(ctx)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	e, err := h.s.EnrollTOTP(ctx, uid)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("enrolling totp")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
+
+// ConfirmTOTP activates the enrolled authenticator and responds with the
+// recovery codes. A login that was blocked until the enrollment gets its
+// full token in the same response.
+func (h *handler) ConfirmTOTP(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	uid, current, ok := userID(ctx)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+	code, ok := decodeMFACode(c, traceId)
+	if !ok {
+		return
+	}
+
+	rc, claims, err := h.s.ConfirmTOTP(ctx, uid, code.Code)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("confirming totp")
+		abortWithError(c, traceId, err)
+		return
+	}
+	if auth.IsMFAPending(current) {
+		rc.Token, err = h.a.GenerateToken(claims)
+		if err != nil {
+			log.Error().Err(err).Msg("generating token")
+			abortWithProblem(c, traceId, http.StatusInternalServerError, problem.CodeInternal, "")
+			return
+		}
+	}
+	c.JSON(http.StatusOK, rc)
+}
+
+// DisableTOTP removes the second factor of the user.
+func (h *handler) DisableTOTP(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	uid, _, ok := userID(ctx)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+	code, ok := decodeMFACode(c, traceId)
+	if !ok {
+		return
+	}
+
+	err := h.s.DisableTOTP(ctx, uid, code.Code, c.ClientIP())
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("disabling totp")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Message{Message: "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user.
+func (h *handler) RegenerateRecoveryCodes(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	uid, _, ok := userID(ctx)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+	code, ok := decodeMFACode(c, traceId)
+	if !ok {
+		return
+	}
+
+	rc, err := h.s.RegenerateRecoveryCodes(ctx, uid, code.Code, c.ClientIP())
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("regenerating recovery codes")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, rc)
+}
+
+// SetCompanyMFAPolicy lets members of a company require MFA for all members.
+func (h *handler) SetCompanyMFAPolicy(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	uid, _, ok := userID(ctx)
+	if !ok {
+		log.Error().Str("Trace Id", traceId).Msg("claims missing from context")
+		abortWithProblem(c, traceId, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+	cId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidID, "id must be an integer")
+		return
+	}
+
+	var p models.MFAPolicy
+	err = json.NewDecoder(c.Request.Body).Decode(&p)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
+		return
+	}
+	err = validation.Struct(p)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return
+	}
+
+	err = h.s.SetCompanyMFAPolicy(ctx, uint(cId), uid, *p.Required)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("setting mfa policy")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// decodeMFACode reads the code from the request body, it aborts the request
+// and returns false when the body is invalid.
+func decodeMFACode(c *gin.Context, traceId string) (models.MFACode, bool) {
+	var code models.MFACode
+	err := json.NewDecoder(c.Request.Body).Decode(&code)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
+		return models.MFACode{}, false
+	}
+	err = validation.Struct(code)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return models.MFACode{}, false
+	}
+	return code, true
+}
+
+// userID returns the id of the user the token of the request was issued to,
+// together with the claims of the token.
+func userID(ctx context.Context) (uint, jwt.RegisteredClaims, bool) {
+	claims, ok := ctx.Value(auth.Key).(jwt.RegisteredClaims)
+	if !ok {
+		return 0, jwt.RegisteredClaims{}, false
+	}
+	id, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, jwt.RegisteredClaims{}, false
+	}
+	return uint(id), claims, true
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"job-portal/internal/auth"
+	"job-portal/internal/authstate"
+	"job-portal/internal/cache"
+	"job-portal/internal/middleware"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"job-portal/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func newClaimsContext(body string, claims jwt.RegisteredClaims) (*gin.Context, *httptest.ResponseRecorder) {
+	rr := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rr)
+	httpReq, _ := http.NewRequest(http.MethodPost, "http://google.com:8080", strings.NewReader(body))
+	ctx := httpReq.Context()
+	ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+	ctx = context.WithValue(ctx, auth.Key, claims)
+	c.Request = httpReq.WithContext(ctx)
+	return c, rr
+}
+
+func TestAPI_PendingTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a := newTestAuth(t)
+	rp := repository.NewMockRepository(gomock.NewController(t))
+	// The password of the user was never reset
+	rp.EXPECT().GetUserByID(gomock.Any(), uint(1)).Return(models.User{}, nil).AnyTimes()
+	r := API(a, rp, cache.NewMemory(), authstate.NewMemory(), Config{})
+
+	token := func(aud ...string) string {
+		tkn, err := a.GenerateToken(jwt.RegisteredClaims{
+			Subject:   "1",
+			Audience:  aud,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tkn
+	}
+	full := token("students")
+	pending := token(auth.MFAPendingAudience)
+	enroll := token(auth.MFAPendingAudience, auth.MFAEnrollAudience)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		// Invalid bodies fail before reaching the service, so 400 means the
+		// token was accepted
+		{name: "pending token on a regular route", method: http.MethodGet, path: "/api/companies/x", token: pending, wantStatus: http.StatusUnauthorized},
+		{name: "pending token completes the login", method: http.MethodPost, path: "/api/login/mfa", token: pending, wantStatus: http.StatusBadRequest},
+		{name: "full token can't complete a login", method: http.MethodPost, path: "/api/login/mfa", token: full, wantStatus: http.StatusUnauthorized},
+		{name: "pending token can't enroll", method: http.MethodPost, path: "/api/mfa/totp/confirm", token: pending, wantStatus: http.StatusUnauthorized},
+		{name: "enrollment token can enroll", method: http.MethodPost, path: "/api/mfa/totp/confirm", token: enroll, wantStatus: http.StatusBadRequest},
+		{name: "full token can enroll", method: http.MethodPost, path: "/api/mfa/totp/confirm", token: full, wantStatus: http.StatusBadRequest},
+		{name: "enrollment token can't disable mfa", method: http.MethodDelete, path: "/api/mfa/totp", token: enroll, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("invalid"))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			r.ServeHTTP(rr, req)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
+
+func Test_handler_ConfirmTOTP(t *testing.T) {
+	tests := []struct {
+		name      string
+		claims    jwt.RegisteredClaims
+		wantToken bool
+	}{
+		{
+			name:   "enrolled from settings",
+			claims: jwt.RegisteredClaims{Subject: "1"},
+		},
+		{
+			name:      "enrolled to complete a login",
+			claims:    jwt.RegisteredClaims{Subject: "1", Audience: jwt.ClaimStrings{auth.MFAPendingAudience, auth.MFAEnrollAudience}},
+			wantToken: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr := newClaimsContext(`{"code":"123456"}`, tt.claims)
+			ms := service.NewMockService(gomock.NewController(t))
+			ms.EXPECT().ConfirmTOTP(c.Request.Context(), uint(1), "123456").
+				Return(models.RecoveryCodes{Codes: []string{"abcde-fghjk"}}, jwt.RegisteredClaims{Subject: "1"}, nil)
+
+			h := &handler{s: ms, a: newTestAuth(t)}
+			h.ConfirmTOTP(c)
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, true, strings.HasPrefix(rr.Body.String(), `{"recovery_codes":["abcde-fghjk"]`))
+			assert.Equal(t, tt.wantToken, strings.Contains(rr.Body.String(), `"token":`))
+		})
+	}
+}
+
+func Test_handler_DisableTOTP_UserNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, rr := newClaimsContext(`{"code":"123456"}`, jwt.RegisteredClaims{Subject: "999"})
+	ms := service.NewMockService(gomock.NewController(t))
+	ms.EXPECT().DisableTOTP(c.Request.Context(), uint(999), "123456", gomock.Any()).
+		Return(&service.Error{Kind: service.ErrNotFound, Code: service.CodeUserNotFound, Message: "user not found"})
+
+	h := &handler{s: ms}
+	h.DisableTOTP(c)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, `{"type":"urn:job-portal:error:user_not_found","title":"Not Found","status":404,"detail":"user not found","code":"user_not_found","trace_id":"693"}`, rr.Body.String())
+}
+
+func Test_handler_SetCompanyMFAPolicy(t *testing.T) {
+	tests := []struct {
+		name               string
+		setup              func() (*gin.Context, *httptest.ResponseRecorder, service.Service)
+		expectedStatusCode int
+		expectedResponse   string
+	}{
+		{
+			name: "invalid company id",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newClaimsContext(`{"required":true}`, jwt.RegisteredClaims{Subject: "1"})
+				c.Params = append(c.Params, gin.Param{Key: "id", Value: "abc"})
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_id","title":"Bad Request","status":400,"detail":"id must be an integer","code":"invalid_id","trace_id":"693"}`,
+		},
+		{
+			name: "checking validator function",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newClaimsContext(`{}`, jwt.RegisteredClaims{Subject: "1"})
+				c.Params = append(c.Params, gin.Param{Key: "id", Value: "7"})
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"required","rule":"required","message":"is required"}]}`,
+		},
+		{
+			name: "not the owner",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newClaimsContext(`{"required":true}`, jwt.RegisteredClaims{Subject: "1"})
+				c.Params = append(c.Params, gin.Param{Key: "id", Value: "7"})
+				ms := service.NewMockService(gomock.NewController(t))
+				ms.EXPECT().SetCompanyMFAPolicy(c.Request.Context(), uint(7), uint(1), true).
+					Return(&service.Error{Kind: service.ErrForbidden, Code: service.CodeNotCompanyOwner, Message: "only the owner can change a company"})
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedResponse:   `{"type":"urn:job-portal:error:not_company_owner","title":"Forbidden","status":403,"detail":"only the owner can change a company","code":"not_company_owner","trace_id":"693"}`,
+		},
+		{
+			name: "policy turned off",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newClaimsContext(`{"required":false}`, jwt.RegisteredClaims{Subject: "1"})
+				c.Params = append(c.Params, gin.Param{Key: "id", Value: "7"})
+				ms := service.NewMockService(gomock.NewController(t))
+				ms.EXPECT().SetCompanyMFAPolicy(c.Request.Context(), uint(7), uint(1), false).Return(nil)
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   `{"required":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr, ms := tt.setup()
+			h := &handler{
+				s: ms,
+			}
+			h.SetCompanyMFAPolicy(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			assert.Equal(t, tt.expectedResponse, rr.Body.String())
+		})
+	}
+}
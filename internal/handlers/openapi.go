@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"job-portal/internal/models"
+	"job-portal/internal/openapi"
+	"job-portal/internal/problem"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routes documents every endpoint registered in API. A route missing from
+// this list makes TestAPI_RoutesDocumented fail.
+var routes = []openapi.Route{
+	{Method: http.MethodPost, Path: "/api/register", Tag: "users", Summary: "Register a new user",
+		Request: models.NewUser{}, Response: models.User{},
+		Errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/verify", Tag: "users", Summary: "Confirm an email with the emailed code",
+		Request: models.VerifyEmail{}, Response: models.Message{},
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/verify/resend", Tag: "users", Summary: "Email a new verification code",
+		Request: models.ResendVerification{}, Response: models.Message{},
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/login", Tag: "users", Summary: "Log in and receive a token",
+		Request: models.Login{}, Response: models.Token{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/login/mfa", Tag: "users", Summary: "Complete a login with a TOTP or recovery code", Auth: true,
+		Request: models.MFACode{}, Response: models.Token{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusForbidden, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/mfa/totp", Tag: "users", Summary: "Start enrolling an authenticator app", Auth: true,
+		Response: models.TOTPEnrollment{},
+		Errors:   []int{http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/mfa/totp/confirm", Tag: "users", Summary: "Confirm the authenticator and receive recovery codes", Auth: true,
+		Request: models.MFACode{}, Response: models.RecoveryCodes{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+	{Method: http.MethodDelete, Path: "/api/mfa/totp", Tag: "users", Summary: "Turn off two-factor authentication", Auth: true,
+		Request: models.MFACode{}, Response: models.Message{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/mfa/recovery-codes", Tag: "users", Summary: "Replace the recovery codes", Auth: true,
+		Request: models.MFACode{}, Response: models.RecoveryCodes{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/forgetpassword/", Tag: "users", Summary: "Email a password reset otp",
+		Request: models.ForgotPassword{}, Response: models.Message{},
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/resetpassword/", Tag: "users", Summary: "Reset a password with an otp",
+		Request: models.Reset{}, Response: models.Message{},
+		Errors: []int{http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError}},
+
+	{Method: http.MethodPost, Path: "/api/companies", Tag: "companies", Summary: "Create a company", Auth: true,
+		Request: models.NewCompany{}, Response: models.Company{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict, http.StatusInternalServerError}},
+	{Method: http.MethodGet, Path: "/api/companies", Tag: "companies", Summary: "Search and page through companies, X-Total-Count and Link describe the pages", Auth: true,
+		Query: models.CompanyQuery{}, Response: []models.Company{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError}},
+	{Method: http.MethodGet, Path: "/api/public/companies", Tag: "companies", Summary: "The company list for the public careers directory",
+		Query: models.CompanyQuery{}, Response: []models.PublicCompany{},
+		Errors: []int{http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodGet, Path: "/api/companies/:id", Tag: "companies", Summary: "Get a company", Auth: true,
+		Response: models.Company{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError}},
+	{Method: http.MethodPut, Path: "/api/companies/:id", Tag: "companies", Summary: "Replace the profile of a company you own", Auth: true,
+		Request: models.CompanyProfile{}, Response: models.Company{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+	{Method: http.MethodPatch, Path: "/api/companies/:id", Tag: "companies", Summary: "Change some fields of the profile of a company you own", Auth: true,
+		Request: models.CompanyPatch{}, Response: models.Company{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+	{Method: http.MethodDelete, Path: "/api/companies/:id", Tag: "companies", Summary: "Delete a company you own with its jobs", Auth: true,
+		Status: http.StatusNoContent,
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+	{Method: http.MethodPut, Path: "/api/companies/:id/mfa", Tag: "companies", Summary: "Require two-factor authentication for members", Auth: true,
+		Request: models.MFAPolicy{}, Response: models.MFAPolicy{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/companies/:id/verification", Tag: "companies", Summary: "Email a verification code to an address on the domain of the company website", Auth: true,
+		Request: models.VerificationRequest{}, Response: models.CompanyVerification{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/companies/:id/verification/confirm", Tag: "companies", Summary: "Confirm the verification code, the request then waits for an admin", Auth: true,
+		Request: models.VerificationCode{}, Response: models.CompanyVerification{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodGet, Path: "/api/companies/:id/verification", Tag: "companies", Summary: "Show the latest verification request of a company you own", Auth: true,
+		Response: models.CompanyVerification{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/companies/:id/logo", Tag: "companies", Summary: "Upload the logo of a company you own, replacing the previous one", Auth: true,
+		Request: mediaUpload{}, RequestType: "multipart/form-data", Response: models.CompanyMedia{}, Status: http.StatusCreated,
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/companies/:id/photos", Tag: "companies", Summary: "Add an office photo to a company you own", Auth: true,
+		Request: mediaUpload{}, RequestType: "multipart/form-data", Response: models.CompanyMedia{}, Status: http.StatusCreated,
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusInternalServerError}},
+	{Method: http.MethodGet, Path: "/api/companies/:id/media", Tag: "companies", Summary: "List the logo and photos of a company with signed links", Auth: true,
+		Response: []models.CompanyMedia{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError}},
+	{Method: http.MethodDelete, Path: "/api/companies/:id/media/:mediaId", Tag: "companies", Summary: "Delete an image of a company you own", Auth: true,
+		Status: http.StatusNoContent,
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/companies/:id/jobs", Tag: "jobs", Summary: "Post a job for a company you own", Auth: true,
+		Request: models.NewJob{}, Response: models.Job{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+	{Method: http.MethodGet, Path: "/api/companies/:id/jobs", Tag: "jobs", Summary: "List the jobs of a company", Auth: true,
+		Response: []models.Job{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError}},
+
+	{Method: http.MethodGet, Path: "/api/jobs", Tag: "jobs", Summary: "List jobs", Auth: true,
+		Response: []models.Job{},
+		Errors:   []int{http.StatusUnauthorized, http.StatusInternalServerError}},
+	{Method: http.MethodGet, Path: "/api/jobs/:id", Tag: "jobs", Summary: "Get a job", Auth: true,
+		Response: models.Job{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/job/applications/", Tag: "jobs", Summary: "Match applications against job criteria, large batches answer 202 with a task", Auth: true,
+		Request: []models.JobApplication{}, Response: []models.ApplicationOutcome{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusInternalServerError}},
+	{Method: http.MethodGet, Path: "/api/tasks/:id", Tag: "jobs", Summary: "Get the progress of a batch of applications", Auth: true,
+		Response: models.Task{},
+		Errors:   []int{http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError}},
+
+	{Method: http.MethodPost, Path: "/api/admin/unlock", Tag: "admin", Summary: "Lift a login lockout", Auth: true,
+		Request: models.Unlock{}, Response: models.Message{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError}},
+	{Method: http.MethodGet, Path: "/api/admin/verifications", Tag: "admin", Summary: "List company verification requests, those waiting for review unless a status is given", Auth: true,
+		Response: []models.CompanyVerification{},
+		Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/admin/verifications/:id/approve", Tag: "admin", Summary: "Approve a company verification request", Auth: true,
+		Request: models.VerificationReview{}, Response: models.CompanyVerification{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+	{Method: http.MethodPost, Path: "/api/admin/verifications/:id/reject", Tag: "admin", Summary: "Reject a company verification request with a reason for the owner", Auth: true,
+		Request: models.VerificationReview{}, Response: models.CompanyVerification{},
+		Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+
+	{Method: http.MethodGet, Path: filesPath + "*key", Tag: "files", Summary: "Download a file with a signed link",
+		Response: openapi.File{}, ContentType: "application/octet-stream",
+		Errors: []int{http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+
+	{Method: http.MethodGet, Path: "/openapi.json", Tag: "docs", Summary: "This OpenAPI document",
+		Response: map[string]any{}},
+	{Method: http.MethodGet, Path: "/docs", Tag: "docs", Summary: "Interactive API documentation",
+		Response: "", ContentType: "text/html"},
+}
+
+// mediaUpload is the multipart form the upload endpoints read.
+type mediaUpload struct {
+	File openapi.File `json:"file" validate:"required"`
+}
+
+var (
+	specOnce sync.Once
+	spec     openapi.Document
+)
+
+// Spec returns the OpenAPI document describing the routes registered in API.
+func Spec() openapi.Document {
+	specOnce.Do(func() {
+		b := openapi.NewBuilder(openapi.Info{
+			Title:       "Job Portal API",
+			Version:     "1.0.0",
+			Description: "Companies post jobs, applicants are matched against the job criteria.",
+		}, problem.Problem{})
+		for _, r := range routes {
+			b.Add(r)
+		}
+		spec = b.Document()
+	})
+	return spec
+}
+
+func (h *handler) OpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, Spec())
+}
+
+func (h *handler) Docs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsHTML)
+}
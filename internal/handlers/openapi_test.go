@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"job-portal/internal/openapi"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestAPI_RoutesDocumented(t *testing.T) {
+	r := newTestEngine(t, Config{})
+	spec := Spec()
+
+	registered := map[string]bool{}
+	for _, route := range r.Routes() {
+		registered[route.Method+" "+route.Path] = true
+		if !spec.Has(route.Method, route.Path) {
+			t.Errorf("route %s %s is not described in the OpenAPI spec", route.Method, route.Path)
+		}
+	}
+	for _, route := range routes {
+		if !registered[route.Method+" "+route.Path] {
+			t.Errorf("spec describes %s %s which is not registered", route.Method, route.Path)
+		}
+	}
+}
+
+func TestAPI_ServesOpenAPI(t *testing.T) {
+	r := newTestEngine(t, Config{})
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var doc openapi.Document
+	err := json.Unmarshal(rr.Body.Bytes(), &doc)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+
+	// Request schemas are derived from the model structs
+	job := doc.Components.Schemas["NewJob"]
+	if job == nil || job.Properties["min_np"] == nil {
+		t.Fatalf("NewJob schema missing or incomplete: %+v", job)
+	}
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/docs", nil)
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+}
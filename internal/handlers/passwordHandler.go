@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"job-portal/internal/middleware"
 	"job-portal/internal/models"
+	"job-portal/internal/problem"
+	"job-portal/internal/validation"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator"
 	"github.com/rs/zerolog/log"
 )
 
@@ -16,40 +17,37 @@ func (h *handler) ForgotPassword(c *gin.Context) {
 	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
 	if !ok {
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 
-	// Define a new struct for login data
-	var login struct {
-		Email string `json:"email" validate:"required,email"`
-	}
+	// Define a new variable for the email to send the otp to
+	var login models.ForgotPassword
 
 	// Attempt to decode JSON from the request body into the login variable
 	err := json.NewDecoder(c.Request.Body).Decode(&login)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
 		return
 	}
 
-	// Create a new validator and validate the login variable
-	validate := validator.New()
-	err = validate.Struct(login)
+	// Validate the login variable
+	err = validation.Struct(login)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId).Send()
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Bad Request"})
+		abortWithValidation(c, traceId, err)
 		return
 	}
 
 	// Attempt to authenticate the user with the email
-	_, err = h.s.CheckEmail(login.Email)
+	_, err = h.s.CheckEmail(ctx, login.Email)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId).Send()
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "given email is not registered with job portal"})
+		abortWithError(c, traceId, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "otp sucessfully sent to registred email"})
+	c.JSON(http.StatusOK, models.Message{Message: "otp sucessfully sent to registred email"})
 
 }
 
@@ -58,7 +56,7 @@ func (h *handler) ResetPassword(c *gin.Context) {
 	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
 	if !ok {
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 	var login models.Reset
@@ -66,29 +64,24 @@ func (h *handler) ResetPassword(c *gin.Context) {
 	err := json.NewDecoder(c.Request.Body).Decode(&login)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
 		return
 	}
 
-	// Create a new validator and validate the login variable
-	validate := validator.New()
-	err = validate.Struct(login)
+	// Validate the login variable
+	err = validation.Struct(login)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId).Send()
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Bad Request"})
-		return
-	}
-	if login.NewPassword != login.ConfirmPassword {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "newpassword and confirmpassword are not equal"})
+		abortWithValidation(c, traceId, err)
 		return
 	}
 	// Attempt to authenticate the user with the email
-	_, err = h.s.UpdatePassword(login)
+	_, err = h.s.UpdatePassword(ctx, login)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId).Send()
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "error while resetting the newpassword"})
+		abortWithError(c, traceId, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "sucessfully reset the password"})
+	c.JSON(http.StatusOK, models.Message{Message: "sucessfully reset the password"})
 
 }
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"job-portal/internal/models"
+	"job-portal/internal/service"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+const application = `{"name":"vishnu","email":"vishnu@gmail.com","age":25,"job_Id":1,"notice_period":30,"expect_salary":500000,
+	"job_location":[1],"technology_stack":[1],"work_mode":[1],"experience":2,"qualification":[1],"work_shift":[1],"job_type":[1]}`
+
+func Test_handler_ApplyForJobBulk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, rr := newClaimsContext("["+application+","+application+"]", jwt.RegisteredClaims{Subject: "1"})
+	ms := service.NewMockService(gomock.NewController(t))
+	ms.EXPECT().SubmitApplications(c.Request.Context(), uint(1), gomock.Len(2)).
+		Return(models.Task{ID: "3f1c", Status: models.TaskPending, Total: 2}, nil)
+
+	h := &handler{s: ms, bulkThreshold: 1}
+	h.ApplyForJob(c)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/api/tasks/3f1c", rr.Header().Get("Location"))
+	assert.Equal(t, `{"id":"3f1c","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z","status":"pending","total":2,"processed":0,"matched":0,"failed":0}`, rr.Body.String())
+}
+
+func Test_handler_GetTask(t *testing.T) {
+	tests := []struct {
+		name               string
+		task               models.Task
+		err                error
+		expectedStatusCode int
+		expectedResponse   string
+	}{
+		{
+			name:               "task of another user",
+			err:                &service.Error{Kind: service.ErrNotFound, Code: service.CodeTaskNotFound, Message: "task not found"},
+			expectedStatusCode: http.StatusNotFound,
+			expectedResponse:   `{"type":"urn:job-portal:error:task_not_found","title":"Not Found","status":404,"detail":"task not found","code":"task_not_found","trace_id":"693"}`,
+		},
+		{
+			name: "running task",
+			task: models.Task{ID: "3f1c", Status: models.TaskRunning, Total: 2, Processed: 1, Failed: 1,
+				Items: []models.TaskItem{{ApplicationOutcome: models.ApplicationOutcome{Position: 0, Name: "vishnu", Email: "vishnu@gmail.com", Outcome: models.OutcomeFailed, Error: "job not found"}}}},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   `{"id":"3f1c","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z","status":"running","total":2,"processed":1,"matched":0,"failed":1,"items":[{"index":0,"name":"vishnu","email":"vishnu@gmail.com","outcome":"failed","error":"job not found"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr := newClaimsContext("", jwt.RegisteredClaims{Subject: "1"})
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: "3f1c"})
+			ms := service.NewMockService(gomock.NewController(t))
+			ms.EXPECT().GetTask(c.Request.Context(), "3f1c", uint(1)).Return(tt.task, tt.err)
+
+			h := &handler{s: ms}
+			h.GetTask(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			assert.Equal(t, tt.expectedResponse, rr.Body.String())
+		})
+	}
+}
@@ -5,17 +5,24 @@ import (
 	"job-portal/internal/auth"
 	"job-portal/internal/middleware"
 	"job-portal/internal/models"
+	"job-portal/internal/problem"
 	"job-portal/internal/service"
+	"job-portal/internal/validation"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator"
 	"github.com/rs/zerolog/log"
 )
 
 type handler struct {
 	a *auth.Auth
 	s service.Service
+	// bulkThreshold is the largest batch of applications matched within the
+	// request, larger ones become tasks. Zero matches every batch in the
+	// request.
+	bulkThreshold int
+	// maxUpload is the largest file accepted by upload endpoints.
+	maxUpload int64
 }
 
 func (h *handler) UserRegister(c *gin.Context) {
@@ -24,7 +31,7 @@ func (h *handler) UserRegister(c *gin.Context) {
 	if !ok {
 		// If the traceId isn't found in the request, log an error and return
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 
@@ -36,17 +43,16 @@ func (h *handler) UserRegister(c *gin.Context) {
 	if err != nil {
 		// If there is an error in decoding, log the error and return
 		log.Error().Err(err).Str("Trace Id", traceId)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
 		return
 	}
 
-	// Create a new validator and validate the NewUser variable
-	validate := validator.New()
-	err = validate.Struct(nu)
+	// Validate the NewUser variable
+	err = validation.Struct(nu)
 	if err != nil {
 		// If validation fails, log the error and return
 		log.Error().Err(err).Str("Trace Id", traceId).Send()
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Bad Request"})
+		abortWithValidation(c, traceId, err)
 		return
 	}
 
@@ -54,7 +60,7 @@ func (h *handler) UserRegister(c *gin.Context) {
 	usr, err := h.s.CreateUser(ctx, nu)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId).Msg("user signup problem")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "user signup failed"})
+		abortWithError(c, traceId, err)
 		return
 	}
 
@@ -68,53 +74,51 @@ func (h *handler) UserLogin(c *gin.Context) {
 	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
 	if !ok {
 		log.Error().Msg("traceId missing from context")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 
-	// Define a new struct for login data
-	var login struct {
-		Email    string `json:"email" validate:"required,email"`
-		Password string `json:"password" validate:"required"`
-	}
+	// Define a new variable for login data
+	var login models.Login
 
 	// Attempt to decode JSON from the request body into the login variable
 	err := json.NewDecoder(c.Request.Body).Decode(&login)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": http.StatusText(http.StatusBadRequest)})
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
 		return
 	}
 
-	// Create a new validator and validate the login variable
-	validate := validator.New()
-	err = validate.Struct(login)
+	// Validate the login variable
+	err = validation.Struct(login)
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId).Send()
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Bad Request"})
+		abortWithValidation(c, traceId, err)
 		return
 	}
 
 	// Attempt to authenticate the user with the email and password
-	claims, err := h.s.Authenticate(ctx, login.Email, login.Password)
+	claims, err := h.s.Authenticate(ctx, login.Email, login.Password, c.ClientIP())
 	if err != nil {
 		log.Error().Err(err).Str("Trace Id", traceId).Send()
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "login failed"})
+		abortWithError(c, traceId, err)
 		return
 	}
 
-	// Define a new struct for the token
-	var tkn struct {
-		Token string `json:"token"`
-	}
+	// Define a new variable for the token
+	var tkn models.Token
 
 	// Generate a new token and put it in the Token field of the token struct
 	tkn.Token, err = h.a.GenerateToken(claims)
 	if err != nil {
 		log.Error().Err(err).Msg("generating token")
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"msg": http.StatusText(http.StatusInternalServerError)})
+		abortWithProblem(c, traceId, http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
+	// A pending token only allows completing the login with a second
+	// factor, or enrolling one first
+	tkn.MFARequired = auth.IsMFAPending(claims)
+	tkn.MFAEnrollmentRequired = tkn.MFARequired && auth.CanEnrollMFA(claims)
 
 	// If everything goes right, respond with the token
 	c.JSON(http.StatusOK, tkn)
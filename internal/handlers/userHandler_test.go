@@ -35,7 +35,7 @@ func Test_handler_UserRegister(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"Internal Server Error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
 		},
 		{
 			name: "invalid request body",
@@ -53,7 +53,7 @@ func Test_handler_UserRegister(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
 		},
 		{
 			name: "checking validator function",
@@ -70,10 +70,10 @@ func Test_handler_UserRegister(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"name","rule":"required","message":"is required"},{"field":"email","rule":"required","message":"is required"},{"field":"password","rule":"required","message":"is required"}]}`,
 		},
 		{
-			name: "error while adding user",
+			name: "weak password",
 			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
 				rr := httptest.NewRecorder()
 				c, _ := gin.CreateTestContext(rr)
@@ -84,6 +84,23 @@ func Test_handler_UserRegister(t *testing.T) {
 				httpReq = httpReq.WithContext(ctx)
 				c.Request = httpReq
 
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"password","rule":"password","message":"must be at least 8 characters and contain an upper case letter, a lower case letter and a digit"}]}`,
+		},
+		{
+			name: "error while adding user",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				rr := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(rr)
+				requestBody := []byte(`{"name": "vishnu", "email":"vishnu@gmail.com", "password":"Secret123"}`)
+				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
+				ctx := httpReq.Context()
+				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				httpReq = httpReq.WithContext(ctx)
+				c.Request = httpReq
+
 				mc := gomock.NewController(t)
 				ms := service.NewMockService(mc)
 				ms.EXPECT().CreateUser(c.Request.Context(), gomock.Any()).Return(models.User{}, errors.New("error in adding job")).AnyTimes()
@@ -91,14 +108,36 @@ func Test_handler_UserRegister(t *testing.T) {
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"user signup failed"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error","trace_id":"693"}`,
+		},
+		{
+			name: "email already registered",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				rr := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(rr)
+				requestBody := []byte(`{"name": "vishnu", "email":"Vishnu@gmail.com", "password":"Secret123"}`)
+				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
+				ctx := httpReq.Context()
+				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
+				httpReq = httpReq.WithContext(ctx)
+				c.Request = httpReq
+
+				mc := gomock.NewController(t)
+				ms := service.NewMockService(mc)
+				ms.EXPECT().CreateUser(c.Request.Context(), gomock.Any()).Return(models.User{},
+					&service.Error{Kind: service.ErrConflict, Code: service.CodeEmailExists, Message: "an account with this email already exists"}).AnyTimes()
+
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedResponse:   `{"type":"urn:job-portal:error:email_exists","title":"Conflict","status":409,"detail":"an account with this email already exists","code":"email_exists","trace_id":"693"}`,
 		},
 		{
 			name: "sucessfully adding user",
 			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
 				rr := httptest.NewRecorder()
 				c, _ := gin.CreateTestContext(rr)
-				requestBody := []byte(`{"name": "vishnu", "email":"vishnu@gmail.com", "password":"123456"}`)
+				requestBody := []byte(`{"name": "vishnu", "email":"vishnu@gmail.com", "password":"Secret123"}`)
 				httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
 				ctx := httpReq.Context()
 				ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
@@ -112,7 +151,7 @@ func Test_handler_UserRegister(t *testing.T) {
 				return c, rr, ms
 			},
 			expectedStatusCode: http.StatusOK,
-			expectedResponse:   `{"ID":0,"CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","DeletedAt":null,"UserId":0,"name":"","email":""}`,
+			expectedResponse:   `{"ID":0,"CreatedAt":"0001-01-01T00:00:00Z","UpdatedAt":"0001-01-01T00:00:00Z","DeletedAt":null,"UserId":0,"name":"","email":"","verified":false,"company_id":null,"mfa_enabled":false}`,
 		},
 	}
 	for _, tt := range tests {
@@ -147,7 +186,7 @@ func Test_handler_UserLogin(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusInternalServerError,
-			expectedResponse:   `{"error":"Internal Server Error"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
 		},
 		{
 			name: "invalid request body",
@@ -165,7 +204,7 @@ func Test_handler_UserLogin(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
 		},
 		{
 			name: "checking validator function",
@@ -182,14 +221,14 @@ func Test_handler_UserLogin(t *testing.T) {
 				return c, rr, nil
 			},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedResponse:   `{"error":"Bad Request"}`,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"email","rule":"required","message":"is required"},{"field":"password","rule":"required","message":"is required"}]}`,
 		},
 		// {
 		// 	name: "error while athenticating user",
 		// 	setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
 		// 		rr := httptest.NewRecorder()
 		// 		c, _ := gin.CreateTestContext(rr)
-		// 		requestBody := []byte(`{"email":"vishnu@gmail.com", "password":"123456"}`)
+		// 		requestBody := []byte(`{"email":"vishnu@gmail.com", "password":"Secret123"}`)
 		// 		httpReq, _ := http.NewRequest(http.MethodGet, "http://google.com:8080", bytes.NewBuffer(requestBody))
 		// 		ctx := httpReq.Context()
 		// 		ctx = context.WithValue(ctx, middleware.TraceIdKey, "693")
@@ -198,7 +237,7 @@ func Test_handler_UserLogin(t *testing.T) {
 
 		// 		mc := gomock.NewController(t)
 		// 		ms := service.NewMockService(mc)
-		// 		ms.EXPECT().Authenticate(c.Request.Context(), gomock.Any(), gomock.Any())(jwt.RegisteredClaims{}, errors.New("")).AnyTimes()
+		// 		ms.EXPECT().Authenticate(c.Request.Context(), gomock.Any(), gomock.Any(), gomock.Any())(jwt.RegisteredClaims{}, errors.New("")).AnyTimes()
 
 		// 		return c, rr, nil
 		// 	},
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"job-portal/internal/models"
+	"job-portal/internal/problem"
+	"job-portal/internal/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestCompanyVerification mails a code to an address on the domain of the
+// website of a company owned by the user.
+func (h *handler) RequestCompanyVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, uid, cId, ok := companyOwnerRequest(c)
+	if !ok {
+		return
+	}
+
+	var vr models.VerificationRequest
+	err := json.NewDecoder(c.Request.Body).Decode(&vr)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
+		return
+	}
+	err = validation.Struct(vr)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return
+	}
+
+	v, err := h.s.RequestCompanyVerification(ctx, cId, uid, vr.Email)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("requesting company verification")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// ConfirmCompanyVerification checks the mailed code, the request then waits
+// for an admin.
+func (h *handler) ConfirmCompanyVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, uid, cId, ok := companyOwnerRequest(c)
+	if !ok {
+		return
+	}
+
+	var vc models.VerificationCode
+	err := json.NewDecoder(c.Request.Body).Decode(&vc)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
+		return
+	}
+	err = validation.Struct(vc)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return
+	}
+
+	v, err := h.s.ConfirmCompanyVerification(ctx, cId, uid, vc.Code)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("confirming company verification")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// GetCompanyVerification returns the latest verification request of a
+// company owned by the user.
+func (h *handler) GetCompanyVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, uid, cId, ok := companyOwnerRequest(c)
+	if !ok {
+		return
+	}
+
+	v, err := h.s.GetCompanyVerification(ctx, cId, uid)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("fetching company verification")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"job-portal/internal/models"
+	"job-portal/internal/service"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func Test_handler_RequestCompanyVerification(t *testing.T) {
+	tests := []struct {
+		name               string
+		claims             jwt.RegisteredClaims
+		body               string
+		setup              func(ms *service.MockService)
+		expectedStatusCode int
+		expectedResponse   string
+	}{
+		{
+			name:               "missing claims",
+			body:               `{"email":"hr@acme.com"}`,
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedResponse:   `{"type":"urn:job-portal:error:unauthorized","title":"Unauthorized","status":401,"code":"unauthorized","trace_id":"693"}`,
+		},
+		{
+			name:               "invalid request body",
+			claims:             jwt.RegisteredClaims{Subject: "1"},
+			body:               "not json",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
+		},
+		{
+			name:               "checking validator function",
+			claims:             jwt.RegisteredClaims{Subject: "1"},
+			body:               `{"email":"hr"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"email","rule":"email","message":"must be a valid email address"}]}`,
+		},
+		{
+			name:   "email not on the domain",
+			claims: jwt.RegisteredClaims{Subject: "1"},
+			body:   `{"email":"hr@gmail.com"}`,
+			setup: func(ms *service.MockService) {
+				ms.EXPECT().RequestCompanyVerification(gomock.Any(), uint(7), uint(1), "hr@gmail.com").
+					Return(models.CompanyVerification{}, &service.Error{Kind: service.ErrValidation, Code: service.CodeEmailNotOnDomain, Message: "the email has to be an address on acme.com"})
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:email_not_on_domain","title":"Bad Request","status":400,"detail":"the email has to be an address on acme.com","code":"email_not_on_domain","trace_id":"693"}`,
+		},
+		{
+			name:   "success",
+			claims: jwt.RegisteredClaims{Subject: "1"},
+			body:   `{"email":"hr@acme.com"}`,
+			setup: func(ms *service.MockService) {
+				ms.EXPECT().RequestCompanyVerification(gomock.Any(), uint(7), uint(1), "hr@acme.com").
+					Return(models.CompanyVerification{ID: 3, CompanyID: 7, RequestedBy: 1, Email: "hr@acme.com", Status: models.VerificationEmailSent}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   `{"id":3,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z","company_id":7,"requested_by":1,"email":"hr@acme.com","status":"email_sent"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr := newClaimsContext(tt.body, tt.claims)
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: "7"})
+			ms := service.NewMockService(gomock.NewController(t))
+			if tt.setup != nil {
+				tt.setup(ms)
+			}
+
+			h := &handler{s: ms}
+			h.RequestCompanyVerification(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			assert.Equal(t, tt.expectedResponse, rr.Body.String())
+		})
+	}
+}
+
+func Test_handler_ReviewCompanyVerification(t *testing.T) {
+	tests := []struct {
+		name               string
+		id                 string
+		body               string
+		approve            bool
+		setup              func(ms *service.MockService)
+		expectedStatusCode int
+		expectedCode       string
+	}{
+		{
+			name:               "invalid id",
+			id:                 "abc",
+			approve:            true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       "invalid_id",
+		},
+		{
+			name:               "invalid request body",
+			id:                 "3",
+			body:               "not json",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       "invalid_body",
+		},
+		{
+			name:               "reason too long",
+			id:                 "3",
+			body:               `{"reason":"` + strings.Repeat("a", 501) + `"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       "validation_failed",
+		},
+		{
+			name:    "approve without a body",
+			id:      "3",
+			approve: true,
+			setup: func(ms *service.MockService) {
+				ms.EXPECT().ReviewCompanyVerification(gomock.Any(), uint(3), true, "", "42").
+					Return(models.CompanyVerification{ID: 3, Status: models.VerificationApproved}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "reject",
+			id:   "3",
+			body: `{"reason":"the website belongs to someone else"}`,
+			setup: func(ms *service.MockService) {
+				ms.EXPECT().ReviewCompanyVerification(gomock.Any(), uint(3), false, "the website belongs to someone else", "42").
+					Return(models.CompanyVerification{ID: 3, Status: models.VerificationRejected}, nil)
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "already reviewed",
+			id:   "3",
+			body: `{"reason":"no"}`,
+			setup: func(ms *service.MockService) {
+				ms.EXPECT().ReviewCompanyVerification(gomock.Any(), uint(3), false, "no", "42").
+					Return(models.CompanyVerification{}, &service.Error{Kind: service.ErrConflict, Code: service.CodeVerificationReviewed})
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedCode:       service.CodeVerificationReviewed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr := newClaimsContext(tt.body, jwt.RegisteredClaims{Subject: "42"})
+			c.Params = append(c.Params, gin.Param{Key: "id", Value: tt.id})
+			ms := service.NewMockService(gomock.NewController(t))
+			if tt.setup != nil {
+				tt.setup(ms)
+			}
+
+			h := &handler{s: ms}
+			if tt.approve {
+				h.ApproveCompanyVerification(c)
+			} else {
+				h.RejectCompanyVerification(c)
+			}
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			if tt.expectedCode != "" {
+				assert.Equal(t, true, strings.Contains(rr.Body.String(), `"code":"`+tt.expectedCode+`"`))
+			}
+		})
+	}
+}
+
+func Test_handler_ListCompanyVerifications(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  url.Values
+		status string
+	}{
+		{name: "waiting for review by default", status: models.VerificationPending},
+		{name: "every status", query: url.Values{"status": {""}}, status: ""},
+		{name: "rejected", query: url.Values{"status": {"rejected"}}, status: models.VerificationRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr := newClaimsContext("", jwt.RegisteredClaims{Subject: "42"})
+			c.Request.URL.RawQuery = tt.query.Encode()
+			ms := service.NewMockService(gomock.NewController(t))
+			ms.EXPECT().ListCompanyVerifications(gomock.Any(), tt.status).Return([]models.CompanyVerification{}, nil)
+
+			h := &handler{s: ms}
+			h.ListCompanyVerifications(c)
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, `[]`, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"job-portal/internal/middleware"
+	"job-portal/internal/models"
+	"job-portal/internal/problem"
+	"job-portal/internal/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// VerifyEmail confirms the email of an account with the code sent on signup.
+func (h *handler) VerifyEmail(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+
+	var v models.VerifyEmail
+	err := json.NewDecoder(c.Request.Body).Decode(&v)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
+		return
+	}
+
+	err = validation.Struct(v)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return
+	}
+
+	err = h.s.VerifyEmail(ctx, v)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("verifying email")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Message{Message: "email verified"})
+}
+
+// ResendVerification emails a new verification code.
+func (h *handler) ResendVerification(c *gin.Context) {
+	ctx := c.Request.Context()
+	traceId, ok := ctx.Value(middleware.TraceIdKey).(string)
+	if !ok {
+		log.Error().Msg("traceId missing from context")
+		abortWithProblem(c, "", http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+
+	var rv models.ResendVerification
+	err := json.NewDecoder(c.Request.Body).Decode(&rv)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithProblem(c, traceId, http.StatusBadRequest, problem.CodeInvalidBody, "request body is not valid json")
+		return
+	}
+
+	err = validation.Struct(rv)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Send()
+		abortWithValidation(c, traceId, err)
+		return
+	}
+
+	err = h.s.ResendVerification(ctx, rv.Email)
+	if err != nil {
+		log.Error().Err(err).Str("Trace Id", traceId).Msg("resending verification code")
+		abortWithError(c, traceId, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.Message{Message: "verification code sent"})
+}
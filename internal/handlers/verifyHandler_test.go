@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"job-portal/internal/middleware"
+	"job-portal/internal/models"
+	"job-portal/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func newVerifyContext(body string) (*gin.Context, *httptest.ResponseRecorder) {
+	rr := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rr)
+	httpReq, _ := http.NewRequest(http.MethodPost, "http://google.com:8080", strings.NewReader(body))
+	ctx := context.WithValue(httpReq.Context(), middleware.TraceIdKey, "693")
+	c.Request = httpReq.WithContext(ctx)
+	return c, rr
+}
+
+func Test_handler_VerifyEmail(t *testing.T) {
+	tests := []struct {
+		name               string
+		setup              func() (*gin.Context, *httptest.ResponseRecorder, service.Service)
+		expectedStatusCode int
+		expectedResponse   string
+	}{
+		{
+			name: "missing trace id",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				rr := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(rr)
+				httpReq, _ := http.NewRequest(http.MethodPost, "http://google.com", nil)
+				c.Request = httpReq
+
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedResponse:   `{"type":"urn:job-portal:error:internal_error","title":"Internal Server Error","status":500,"code":"internal_error"}`,
+		},
+		{
+			name: "invalid request body",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newVerifyContext("invalid string request body")
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_body","title":"Bad Request","status":400,"detail":"request body is not valid json","code":"invalid_body","trace_id":"693"}`,
+		},
+		{
+			name: "checking validator function",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newVerifyContext(`{"email":"vishnu@gmail.com"}`)
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"code","rule":"required","message":"is required"}]}`,
+		},
+		{
+			name: "wrong code",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newVerifyContext(`{"email":"vishnu@gmail.com","code":"123456"}`)
+				mc := gomock.NewController(t)
+				ms := service.NewMockService(mc)
+				ms.EXPECT().VerifyEmail(c.Request.Context(), models.VerifyEmail{Email: "vishnu@gmail.com", Code: "123456"}).
+					Return(&service.Error{Kind: service.ErrValidation, Code: service.CodeInvalidOTP, Message: "otp is invalid or has expired"}).Times(1)
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:invalid_otp","title":"Bad Request","status":400,"detail":"otp is invalid or has expired","code":"invalid_otp","trace_id":"693"}`,
+		},
+		{
+			name: "success",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newVerifyContext(`{"email":"vishnu@gmail.com","code":"123456"}`)
+				mc := gomock.NewController(t)
+				ms := service.NewMockService(mc)
+				ms.EXPECT().VerifyEmail(c.Request.Context(), models.VerifyEmail{Email: "vishnu@gmail.com", Code: "123456"}).
+					Return(nil).Times(1)
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   `{"message":"email verified"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr, ms := tt.setup()
+			h := &handler{
+				s: ms,
+			}
+			h.VerifyEmail(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			assert.Equal(t, tt.expectedResponse, rr.Body.String())
+		})
+	}
+}
+
+func Test_handler_ResendVerification(t *testing.T) {
+	tests := []struct {
+		name               string
+		setup              func() (*gin.Context, *httptest.ResponseRecorder, service.Service)
+		expectedStatusCode int
+		expectedResponse   string
+	}{
+		{
+			name: "checking validator function",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newVerifyContext(`{"email":"vishnu"}`)
+				return c, rr, nil
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedResponse:   `{"type":"urn:job-portal:error:validation_failed","title":"Bad Request","status":400,"detail":"request failed validation","code":"validation_failed","trace_id":"693","errors":[{"field":"email","rule":"email","message":"must be a valid email address"}]}`,
+		},
+		{
+			name: "already verified",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newVerifyContext(`{"email":"vishnu@gmail.com"}`)
+				mc := gomock.NewController(t)
+				ms := service.NewMockService(mc)
+				ms.EXPECT().ResendVerification(c.Request.Context(), "vishnu@gmail.com").
+					Return(&service.Error{Kind: service.ErrConflict, Code: service.CodeEmailVerified, Message: "email is already verified"}).Times(1)
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedResponse:   `{"type":"urn:job-portal:error:email_already_verified","title":"Conflict","status":409,"detail":"email is already verified","code":"email_already_verified","trace_id":"693"}`,
+		},
+		{
+			name: "success",
+			setup: func() (*gin.Context, *httptest.ResponseRecorder, service.Service) {
+				c, rr := newVerifyContext(`{"email":"vishnu@gmail.com"}`)
+				mc := gomock.NewController(t)
+				ms := service.NewMockService(mc)
+				ms.EXPECT().ResendVerification(c.Request.Context(), "vishnu@gmail.com").Return(nil).Times(1)
+				return c, rr, ms
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponse:   `{"message":"verification code sent"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, rr, ms := tt.setup()
+			h := &handler{
+				s: ms,
+			}
+			h.ResendVerification(c)
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			assert.Equal(t, tt.expectedResponse, rr.Body.String())
+		})
+	}
+}
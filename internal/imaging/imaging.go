@@ -0,0 +1,106 @@
+// Package imaging checks uploaded images and scales them into thumbnails.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// Formats of uploaded images, named like the image package names them.
+const (
+	PNG  = "png"
+	JPEG = "jpeg"
+	WebP = "webp"
+)
+
+var (
+	// ErrUnsupported is returned for content that isn't a PNG, JPEG or WebP
+	// image, whatever the client claimed.
+	ErrUnsupported = errors.New("unsupported image format")
+	// ErrTooLarge is returned for images with more pixels than allowed,
+	// they would take too much memory to decode.
+	ErrTooLarge = errors.New("image dimensions too large")
+)
+
+// Sniff returns the format of an image from its first bytes.
+func Sniff(head []byte) (string, error) {
+	switch http.DetectContentType(head) {
+	case "image/png":
+		return PNG, nil
+	case "image/jpeg":
+		return JPEG, nil
+	case "image/webp":
+		return WebP, nil
+	}
+	return "", ErrUnsupported
+}
+
+// ContentType is the media type of a format.
+func ContentType(format string) string {
+	return "image/" + format
+}
+
+// Decode sniffs and decodes an image, refusing images of more than maxPixels
+// before decoding them.
+func Decode(data []byte, maxPixels int) (image.Image, string, error) {
+	format, err := Sniff(data)
+	if err != nil {
+		return nil, "", err
+	}
+	decodeConfig, decode := png.DecodeConfig, png.Decode
+	switch format {
+	case JPEG:
+		decodeConfig, decode = jpeg.DecodeConfig, jpeg.Decode
+	case WebP:
+		decodeConfig, decode = webp.DecodeConfig, webp.Decode
+	}
+
+	cfg, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, "", ErrTooLarge
+	}
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	return img, format, nil
+}
+
+// Fit scales img down to fit a size x size square keeping its aspect ratio.
+// Smaller images are returned as they are.
+func Fit(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	if w >= h {
+		w, h = size, max(1, h*size/w)
+	} else {
+		w, h = max(1, w*size/h), size
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// Encode writes img as a JPEG when the original was one and as a PNG
+// otherwise, there is no WebP encoder.
+func Encode(w io.Writer, img image.Image, format string) (string, error) {
+	if format == JPEG {
+		return JPEG, jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+	return PNG, png.Encode(w, img)
+}
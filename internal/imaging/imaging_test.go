@@ -0,0 +1,86 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func encoded(t *testing.T, w, h int, format string) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, color.White)
+	var buf bytes.Buffer
+	var err error
+	if format == JPEG {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantFormat string
+		wantErr    error
+	}{
+		{name: "png", data: encoded(t, 40, 20, PNG), wantFormat: PNG},
+		{name: "jpeg", data: encoded(t, 40, 20, JPEG), wantFormat: JPEG},
+		{name: "too many pixels", data: encoded(t, 200, 100, PNG), wantErr: ErrTooLarge},
+		{name: "gif", data: []byte("GIF89a\x01\x00\x01\x00"), wantErr: ErrUnsupported},
+		{name: "html", data: []byte("<html><script>alert(1)</script>"), wantErr: ErrUnsupported},
+		// A valid header with a broken body
+		{name: "truncated png", data: encoded(t, 40, 20, PNG)[:40], wantErr: ErrUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, format, err := Decode(tt.data, 10_000)
+			assert.Equal(t, true, errors.Is(err, tt.wantErr))
+			assert.Equal(t, tt.wantFormat, format)
+			if tt.wantErr == nil {
+				assert.Equal(t, image.Rect(0, 0, 40, 20), img.Bounds())
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{w: 1024, h: 256, size: 128, wantW: 128, wantH: 32},
+		{w: 300, h: 600, size: 128, wantW: 64, wantH: 128},
+		{w: 1000, h: 1, size: 100, wantW: 100, wantH: 1},
+		{w: 50, h: 80, size: 128, wantW: 50, wantH: 80},
+	}
+	for _, tt := range tests {
+		got := Fit(image.NewRGBA(image.Rect(0, 0, tt.w, tt.h)), tt.size).Bounds()
+		assert.Equal(t, tt.wantW, got.Dx())
+		assert.Equal(t, tt.wantH, got.Dy())
+	}
+}
+
+func TestEncode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for _, format := range []string{PNG, JPEG, WebP} {
+		var buf bytes.Buffer
+		got, err := Encode(&buf, img, format)
+		assert.Equal(t, nil, err)
+		sniffed, err := Sniff(buf.Bytes())
+		assert.Equal(t, nil, err)
+		assert.Equal(t, got, sniffed)
+	}
+}
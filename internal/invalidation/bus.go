@@ -0,0 +1,129 @@
+// Package invalidation tells every instance of the API that shared data
+// changed so they evict their in-process copies.
+package invalidation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// Channel is the Redis pub/sub channel events are published on.
+const Channel = "job-portal:invalidation"
+
+// Types of the events.
+const (
+	// Job events carry the id of the job and of its company.
+	Job = "job"
+	// Company events carry the id of the company.
+	Company = "company"
+	// Taxonomy events are published when locations, technologies,
+	// qualifications, shifts or work modes change, they affect every job.
+	Taxonomy = "taxonomy"
+	// All is never published, Listen hands it out after reconnecting
+	// because events published in the meantime were lost.
+	All = "all"
+)
+
+// Event describes what changed.
+type Event struct {
+	Type      string `json:"type"`
+	ID        int    `json:"id,omitempty"`
+	CompanyID int    `json:"company_id,omitempty"`
+}
+
+// Publisher announces changes to every instance.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Client is the part of the Redis client the bus needs.
+type Client interface {
+	Publish(ctx context.Context, channel string, message interface{}) *redis.IntCmd
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// Bus publishes and receives events over Redis pub/sub.
+type Bus struct {
+	rdb Client
+	// retry is the longest wait between attempts to resubscribe.
+	retry time.Duration
+}
+
+func NewBus(rdb Client) *Bus {
+	return &Bus{rdb: rdb, retry: 30 * time.Second}
+}
+
+func (b *Bus) Publish(ctx context.Context, e Event) error {
+	msg, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	err = b.rdb.Publish(ctx, Channel, msg).Err()
+	if err != nil {
+		return fmt.Errorf("publishing %s event: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Listen calls handle for every event, including the ones published by this
+// instance, until ctx is done. A lost connection is retried with a growing
+// delay and handle gets an All event once it is subscribed again.
+func (b *Bus) Listen(ctx context.Context, handle func(Event)) {
+	wait := 100 * time.Millisecond
+	for first := true; ; first = false {
+		subscribed, err := b.listen(ctx, handle, !first)
+		if ctx.Err() != nil {
+			return
+		}
+		if subscribed {
+			wait = 100 * time.Millisecond
+		}
+		log.Error().Err(err).Dur("retry", wait).Msg("invalidation bus disconnected")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, b.retry)
+	}
+}
+
+// listen runs one subscription, it reports whether the subscription was
+// confirmed before it failed.
+func (b *Bus) listen(ctx context.Context, handle func(Event), resumed bool) (bool, error) {
+	ps := b.rdb.Subscribe(ctx, Channel)
+	defer ps.Close()
+	// Reads block regardless of ctx, closing the subscription ends them
+	stop := context.AfterFunc(ctx, func() { ps.Close() })
+	defer stop()
+
+	// Receive waits for the confirmation so nothing published after it is
+	// missed
+	_, err := ps.Receive(ctx)
+	if err != nil {
+		return false, err
+	}
+	if resumed {
+		log.Info().Msg("invalidation bus reconnected")
+		handle(Event{Type: All})
+	}
+
+	for {
+		msg, err := ps.ReceiveMessage(ctx)
+		if err != nil {
+			return true, err
+		}
+		var e Event
+		err = json.Unmarshal([]byte(msg.Payload), &e)
+		if err != nil {
+			log.Error().Err(err).Str("payload", msg.Payload).Msg("decoding invalidation event")
+			continue
+		}
+		handle(e)
+	}
+}
@@ -0,0 +1,70 @@
+package invalidation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func next(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+// publish retries until the subscription is in place, events published
+// before it are lost.
+func publish(t *testing.T, b *Bus, mr *miniredis.Miniredis, e Event) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(mr.PubSubChannels(Channel)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("not subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, nil, b.Publish(context.Background(), e))
+}
+
+func TestBus(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	b := NewBus(rdb)
+	b.retry = 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	events := make(chan Event, 10)
+	go func() {
+		b.Listen(ctx, func(e Event) { events <- e })
+		close(done)
+	}()
+
+	publish(t, b, mr, Event{Type: Job, ID: 1, CompanyID: 7})
+	assert.Equal(t, Event{Type: Job, ID: 1, CompanyID: 7}, next(t, events))
+
+	// Events published while disconnected are lost, so everything is
+	// evicted once the subscription is back
+	mr.Close()
+	assert.Equal(t, nil, mr.Restart())
+	assert.Equal(t, Event{Type: All}, next(t, events))
+	publish(t, b, mr, Event{Type: Company, ID: 7})
+	assert.Equal(t, Event{Type: Company, ID: 7}, next(t, events))
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Listen didn't return")
+	}
+}
@@ -0,0 +1,94 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// rfcSecret is the SHA1 seed of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B lists 8 digit codes, the last 6 digits are the
+	// 6 digit code for the same step
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+
+	code, _ := Code(rfcSecret, step)
+	got, ok := Validate(rfcSecret, code, now)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, step, got)
+
+	// Codes of the neighbouring steps are accepted for clock drift
+	code, _ = Code(rfcSecret, step-1)
+	got, ok = Validate(rfcSecret, code, now)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, step-1, got)
+
+	code, _ = Code(rfcSecret, step+2)
+	_, ok = Validate(rfcSecret, code, now)
+	assert.Equal(t, false, ok)
+
+	_, ok = Validate(rfcSecret, "12345", now)
+	assert.Equal(t, false, ok)
+}
+
+func TestNewSecret(t *testing.T) {
+	secret, err := NewSecret()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 32, len(secret))
+
+	code, err := Code(secret, Step(time.Now()))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, IsCode(code))
+}
+
+func TestProvisioningURI(t *testing.T) {
+	u, err := url.Parse(ProvisioningURI("Job Portal", "vishnu@gmail.com", "JBSWY3DPEHPK3PXP"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Job Portal:vishnu@gmail.com", u.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	assert.Equal(t, "Job Portal", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 10, len(codes))
+
+	seen := map[string]bool{}
+	for _, c := range codes {
+		assert.Equal(t, 11, len(c))
+		assert.Equal(t, byte('-'), c[5])
+		assert.Equal(t, false, IsCode(c))
+		assert.Equal(t, c, NormalizeRecoveryCode(" "+c+" "))
+		seen[c] = true
+	}
+	assert.Equal(t, 10, len(seen))
+}
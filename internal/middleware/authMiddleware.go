@@ -4,15 +4,37 @@ import (
 	"context"
 	"errors"
 	"job-portal/internal/auth"
+	"job-portal/internal/problem"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/rs/zerolog/log"
 )
 
-// Authenticate is a method that defines a Middleware function for gin HTTP framework
+// Authenticate is a method that defines a Middleware function for gin HTTP framework.
+// Tokens still waiting for a second factor are refused.
 func (m *Mid) Authenticate(next gin.HandlerFunc) gin.HandlerFunc {
+	return m.authenticate(func(c jwt.RegisteredClaims) bool { return !auth.IsMFAPending(c) },
+		"complete the login with your second factor", next)
+}
+
+// AuthenticatePending only accepts tokens issued after the password step of
+// a login that still waits for a second factor.
+func (m *Mid) AuthenticatePending(next gin.HandlerFunc) gin.HandlerFunc {
+	return m.authenticate(auth.IsMFAPending, "token is not waiting for a second factor", next)
+}
+
+// AuthenticateEnrollment accepts full tokens and pending tokens of users who
+// have to enroll an authenticator before they can log in.
+func (m *Mid) AuthenticateEnrollment(next gin.HandlerFunc) gin.HandlerFunc {
+	return m.authenticate(auth.CanEnrollMFA, "complete the login with your second factor", next)
+}
+
+// authenticate validates the bearer token and lets it through when accept
+// approves of its claims, refused claims get a 401 with msg.
+func (m *Mid) authenticate(accept func(jwt.RegisteredClaims) bool, msg string, next gin.HandlerFunc) gin.HandlerFunc {
 	// This middleware function is returned
 	return func(c *gin.Context) {
 		// We get the current request context
@@ -29,7 +51,7 @@ func (m *Mid) Authenticate(next gin.HandlerFunc) gin.HandlerFunc {
 			log.Error().Msg("trace id not present in the context")
 
 			// Sending error response using gin context
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": http.StatusText(http.StatusInternalServerError)})
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternal, "", ""))
 			return
 		}
 
@@ -42,7 +64,7 @@ func (m *Mid) Authenticate(next gin.HandlerFunc) gin.HandlerFunc {
 			// If the header format doesn't match required format, log and send an error
 			err := errors.New("expected authorization header format: Bearer <token>")
 			log.Error().Err(err).Str("Trace Id", traceId).Send()
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, err.Error(), traceId))
 			return
 		}
 
@@ -51,10 +73,31 @@ func (m *Mid) Authenticate(next gin.HandlerFunc) gin.HandlerFunc {
 		// If there is an error, log it and return an Unauthorized error message
 		if err != nil {
 			log.Error().Err(err).Str("Trace Id", traceId).Send()
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": http.StatusText(http.StatusUnauthorized)})
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "token is invalid or expired", traceId))
 			return
 		}
 
+		if !accept(claims) {
+			log.Error().Str("Trace Id", traceId).Str("subject", claims.Subject).Msg(msg)
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, msg, traceId))
+			return
+		}
+
+		// Tokens issued before a password reset stop working
+		if m.revoked != nil {
+			revoked, err := m.revoked(ctx, claims)
+			if err != nil {
+				log.Error().Err(err).Str("Trace Id", traceId).Msg("checking token revocation")
+				problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternal, "", traceId))
+				return
+			}
+			if revoked {
+				log.Error().Str("Trace Id", traceId).Str("subject", claims.Subject).Msg("token was revoked")
+				problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "token is invalid or expired", traceId))
+				return
+			}
+		}
+
 		// If the token is valid, then add it to the context
 		ctx = context.WithValue(ctx, auth.Key, claims)
 
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"job-portal/internal/auth"
+	"job-portal/internal/problem"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// RequireAdmin only lets tokens issued to admins through, it has to run
+// inside Authenticate.
+func (m *Mid) RequireAdmin(next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		traceId, _ := ctx.Value(TraceIdKey).(string)
+
+		claims, ok := ctx.Value(auth.Key).(jwt.RegisteredClaims)
+		if !ok || !auth.IsAdmin(claims) {
+			log.Error().Str("Trace Id", traceId).Str("subject", claims.Subject).Msg("admin route called without admin rights")
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeForbidden, "admin rights required", traceId))
+			return
+		}
+		next(c)
+	}
+}
+
+// RequireVerified only lets users with a verified email through, it has to
+// run inside Authenticate.
+func (m *Mid) RequireVerified(next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		traceId, _ := ctx.Value(TraceIdKey).(string)
+
+		claims, ok := ctx.Value(auth.Key).(jwt.RegisteredClaims)
+		if !ok || !auth.IsVerified(claims) {
+			log.Error().Str("Trace Id", traceId).Str("subject", claims.Subject).Msg("unverified user blocked")
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeForbidden, "verify your email first", traceId))
+			return
+		}
+		next(c)
+	}
+}
@@ -2,22 +2,54 @@ package middleware
 
 import (
 	"context"
+	"job-portal/internal/tracing"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type key string
 
 const TraceIdKey key = "1"
 
+// TraceIdHeader carries the trace id back to the caller next to traceparent.
+const TraceIdHeader = "X-Trace-Id"
+
 func (m *Mid) Log() gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		traceId := uuid.NewString()
+		// Continue the caller's trace when a traceparent header was sent
+		propagator := otel.GetTextMapPropagator()
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		spanName := c.FullPath()
+		if spanName == "" {
+			spanName = c.Request.URL.Path
+		}
+		ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("http.route", c.FullPath()),
+			))
+		defer span.End()
 
-		ctx := c.Request.Context()
+		traceId := span.SpanContext().TraceID().String()
+		if !span.SpanContext().HasTraceID() {
+			// No tracer provider is installed, fall back to a random id
+			traceId = uuid.NewString()
+		}
+
+		propagator.Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+		c.Header(TraceIdHeader, traceId)
 
 		ctx = context.WithValue(ctx, TraceIdKey, traceId)
 
@@ -27,11 +59,19 @@ func (m *Mid) Log() gin.HandlerFunc {
 		log.Info().Str("Trace Id", traceId).Str("Method", c.Request.Method).
 			Str("URL Path", c.Request.URL.Path).Msg("request started")
 		// After the request is processed by the next handler, logs the info again with status code
-		defer log.Info().Str("Trace Id", traceId).Str("Method", c.Request.Method).
-			Str("URL Path", c.Request.URL.Path).
-			Int("status Code", c.Writer.Status()).Msg("Request processing completed")
+		defer func() {
+			log.Info().Str("Trace Id", traceId).Str("Method", c.Request.Method).
+				Str("URL Path", c.Request.URL.Path).
+				Int("status Code", c.Writer.Status()).Msg("Request processing completed")
+		}()
 
 		//we use c.Next only when we are using r.Use() method to assign middlewares
 		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
 	}
 }
@@ -1,8 +1,12 @@
 package middleware
 
 import (
+	"context"
 	"errors"
 	"job-portal/internal/auth"
+	"job-portal/internal/ratelimit"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 type Mid struct {
@@ -10,13 +14,24 @@ type Mid struct {
 	// It's important to note that 'a'
 	//is a pointer because we want to refer to the original 'Auth' object and not a COPY of it.
 	a *auth.Auth
+
+	// rl counts requests for RateLimit, nil disables rate limiting.
+	rl ratelimit.Limiter
+
+	// revoked refuses tokens that were revoked before they expired, nil
+	// accepts every valid token.
+	revoked Revoked
 }
 
-// NewMid is a function which takes an 'Auth' object pointer
-// and returns a Mid instance and an error.
+// Revoked reports whether a token with valid claims was revoked.
+type Revoked func(ctx context.Context, c jwt.RegisteredClaims) (bool, error)
+
+// NewMid is a function which takes an 'Auth' object pointer, the
+// limiter used by RateLimit and the check for revoked tokens and returns a
+// Mid instance and an error.
 // Purpose of this function is to initialize
 // and return a new instance of 'Mid' structure.
-func NewMid(a *auth.Auth) (Mid, error) {
+func NewMid(a *auth.Auth, rl ratelimit.Limiter, revoked Revoked) (Mid, error) {
 	// It first checks if 'a' is nil
 	// 'a' should not be nil because 'nil' indicates that the 'Auth' object does not exist.
 	if a == nil {
@@ -25,5 +40,5 @@ func NewMid(a *auth.Auth) (Mid, error) {
 	}
 	//If 'a' is not 'nil', a new 'Mid' instance is returned with 'a' as a field.
 	// A nil error is returned, indicating that there were no issues with the initialization.
-	return Mid{a: a}, nil
+	return Mid{a: a, rl: rl, revoked: revoked}, nil
 }
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"job-portal/internal/auth"
+	"job-portal/internal/problem"
+	"job-portal/internal/ratelimit"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// KeyFunc identifies who a request is counted against. An empty key skips
+// the limit for that request.
+type KeyFunc func(c *gin.Context) string
+
+// Limit is one rule attached to a route, Name keeps the counters of
+// different routes and keys apart.
+type Limit struct {
+	Name string
+	Rule ratelimit.Rule
+	Key  KeyFunc
+}
+
+// maxPeekBody bounds how much of a request body ByEmail reads.
+const maxPeekBody = 1 << 20
+
+// ByIP counts requests per client address, X-Forwarded-For only counts
+// when it was set by a trusted proxy.
+func ByIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ByEmail counts requests per email field of the JSON body. The body is
+// restored afterwards so the handler can decode it again.
+func ByEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekBody))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var v struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &v) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(v.Email))
+}
+
+// ByUserID counts requests per authenticated user, it has to run inside
+// one of the Authenticate middlewares.
+func ByUserID(c *gin.Context) string {
+	claims, ok := c.Request.Context().Value(auth.Key).(jwt.RegisteredClaims)
+	if !ok {
+		return ""
+	}
+	return claims.Subject
+}
+
+// RateLimit counts the request against every limit before calling next and
+// answers 429 once any of them is exhausted. The RateLimit-* headers report
+// the limit closest to being exhausted.
+func (m *Mid) RateLimit(limits []Limit, next gin.HandlerFunc) gin.HandlerFunc {
+	if m.rl == nil {
+		return next
+	}
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		traceId, _ := ctx.Value(TraceIdKey).(string)
+
+		var (
+			tightest ratelimit.Result
+			policy   ratelimit.Rule
+			found    bool
+		)
+		for _, l := range limits {
+			if !l.Rule.Enabled() {
+				continue
+			}
+			key := l.Key(c)
+			if key == "" {
+				continue
+			}
+
+			res, err := m.rl.Allow(ctx, "ratelimit:"+l.Name+":"+key, l.Rule)
+			if err != nil {
+				// Failing open keeps the endpoint usable when the store is down
+				log.Error().Err(err).Str("Trace Id", traceId).Str("limit", l.Name).Msg("rate limit check failed")
+				continue
+			}
+			if !found || !res.Allowed || (tightest.Allowed && res.Remaining < tightest.Remaining) {
+				tightest, policy, found = res, l.Rule, true
+			}
+			if !res.Allowed {
+				break
+			}
+		}
+
+		if found {
+			setRateLimitHeaders(c, tightest, policy)
+		}
+		if found && !tightest.Allowed {
+			retry := seconds(tightest.Reset)
+			c.Header("Retry-After", strconv.Itoa(retry))
+			log.Warn().Str("Trace Id", traceId).Str("client", c.ClientIP()).Msg("rate limit exceeded")
+			problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited,
+				fmt.Sprintf("too many requests, retry in %d seconds", retry), traceId))
+			return
+		}
+
+		next(c)
+	}
+}
+
+func setRateLimitHeaders(c *gin.Context, res ratelimit.Result, rule ratelimit.Rule) {
+	c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rule.Limit, seconds(rule.Window)))
+}
+
+// seconds rounds up so clients never retry before the window has moved.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Audit event types.
+const (
+	AuditLoginSucceeded  = "login_succeeded"
+	AuditLoginFailed     = "login_failed"
+	AuditLoginBlocked    = "login_blocked"
+	AuditAccountLocked   = "account_locked"
+	AuditIPLocked        = "ip_locked"
+	AuditAccountUnlocked = "account_unlocked"
+	AuditMFAEnabled      = "mfa_enabled"
+	AuditMFADisabled     = "mfa_disabled"
+	AuditMFAFailed       = "mfa_failed"
+	AuditRecoveryUsed    = "recovery_code_used"
+	AuditMFAPolicy       = "mfa_policy_changed"
+	AuditPasswordReset   = "password_reset"
+	AuditCompanyVerified = "company_verified"
+	AuditCompanyRejected = "company_verification_rejected"
+	AuditCompanyRevoked  = "company_verification_revoked"
+)
+
+// AuditEvent records a security relevant event. Rows are only ever inserted.
+type AuditEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	Event     string    `json:"event" gorm:"index;not null"`
+	Email     string    `json:"email" gorm:"index"`
+	IP        string    `json:"ip"`
+	// ActorID is the subject of the token that triggered the event, empty
+	// for anonymous requests.
+	ActorID string `json:"actor_id"`
+	Detail  string `json:"detail"`
+}
@@ -1,19 +1,245 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Company struct {
 	gorm.Model
 	ID       uint   `gorm:"primaryKey;autoIncrement"`
 	Name     string `gorm:"unique"`
 	Location string
+	// RequireMFA forces every member to log in with a second factor.
+	RequireMFA bool `json:"require_mfa" gorm:"not null;default:false"`
+	// OwnerID is the user who created the company, only the owner may change
+	// or delete its profile.
+	OwnerID *uint `json:"owner_id" gorm:"index"`
+	// Verified is set once an admin approved a verification request of the
+	// owner, changing the name or the website revokes it.
+	Verified   bool       `json:"verified" gorm:"not null;default:false"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+
+	Description string `json:"description"`
+	Website     string `json:"website"`
+	Industry    string `json:"industry"`
+	// SizeBand is one of CompanySizeBands, empty when unknown.
+	SizeBand    string      `json:"size_band"`
+	FoundedYear int         `json:"founded_year"`
+	SocialLinks SocialLinks `json:"social_links" gorm:"embedded;embeddedPrefix:social_"`
+	// Headquarters and Offices refer to the Location taxonomy shared with
+	// jobs.
+	HeadquartersID *uint      `json:"headquarters_id"`
+	Headquarters   *Location  `json:"headquarters,omitempty"`
+	Offices        []Location `json:"offices" gorm:"many2many:company_offices"`
+	// OpenJobs is the number of live jobs of the company, it is counted on
+	// every read and never stored.
+	OpenJobs int64 `json:"open_jobs" gorm:"-"`
 
 	//Users []User // Relationship: A company can have multiple users
 	Jobs []Job `json:"-"` // Relationship: A company can have multiple jobs
 }
 
+// PublicCompany is a company as the public careers directory shows it, it
+// leaves out the owner and the login policy of its members.
+type PublicCompany struct {
+	ID         uint       `json:"ID"`
+	CreatedAt  time.Time  `json:"CreatedAt"`
+	Name       string     `json:"Name"`
+	Location   string     `json:"Location"`
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+
+	Description    string      `json:"description"`
+	Website        string      `json:"website"`
+	Industry       string      `json:"industry"`
+	SizeBand       string      `json:"size_band"`
+	FoundedYear    int         `json:"founded_year"`
+	SocialLinks    SocialLinks `json:"social_links"`
+	HeadquartersID *uint       `json:"headquarters_id"`
+	Headquarters   *Location   `json:"headquarters,omitempty"`
+	Offices        []Location  `json:"offices"`
+	OpenJobs       int64       `json:"open_jobs"`
+}
+
+// Public returns the fields of the company the public directory may show.
+func (c Company) Public() PublicCompany {
+	return PublicCompany{
+		ID:             c.ID,
+		CreatedAt:      c.CreatedAt,
+		Name:           c.Name,
+		Location:       c.Location,
+		Verified:       c.Verified,
+		VerifiedAt:     c.VerifiedAt,
+		Description:    c.Description,
+		Website:        c.Website,
+		Industry:       c.Industry,
+		SizeBand:       c.SizeBand,
+		FoundedYear:    c.FoundedYear,
+		SocialLinks:    c.SocialLinks,
+		HeadquartersID: c.HeadquartersID,
+		Headquarters:   c.Headquarters,
+		Offices:        c.Offices,
+		OpenJobs:       c.OpenJobs,
+	}
+}
+
+// CompanySizeBands are the accepted head count ranges of a company.
+var CompanySizeBands = []string{"1-10", "11-50", "51-200", "201-500", "501-1000", "1001-5000", "5001+"}
+
+// SocialLinks are the profiles of a company on other sites.
+type SocialLinks struct {
+	LinkedIn string `json:"linkedin,omitempty" validate:"omitempty,url"`
+	Twitter  string `json:"twitter,omitempty" validate:"omitempty,url"`
+	GitHub   string `json:"github,omitempty" validate:"omitempty,url"`
+	Facebook string `json:"facebook,omitempty" validate:"omitempty,url"`
+}
+
 type NewCompany struct {
-	Name     string `gorm:"unique" ;json:"name" ;validate:"required"`
+	Name     string `json:"name" validate:"required"`
 	Location string `json:"location" validate:"required"`
 	Jobs     []Job
 }
+
+// CompanyProfile replaces every editable field of a company. Locations are
+// referenced by their ids.
+type CompanyProfile struct {
+	Name           string      `json:"name" validate:"required"`
+	Location       string      `json:"location" validate:"required"`
+	Description    string      `json:"description" validate:"max=5000"`
+	Website        string      `json:"website" validate:"omitempty,url"`
+	Industry       string      `json:"industry" validate:"max=100"`
+	SizeBand       string      `json:"size_band" validate:"size_band"`
+	FoundedYear    int         `json:"founded_year" validate:"omitempty,min=1800"`
+	SocialLinks    SocialLinks `json:"social_links"`
+	HeadquartersID *uint       `json:"headquarters_id" validate:"omitempty,gt=0"`
+	OfficeIDs      []uint      `json:"office_ids" validate:"dive,gt=0"`
+}
+
+// CompanyPatch changes the fields it carries and keeps the others. Empty
+// strings clear optional fields, a headquarters_id of 0 removes the
+// headquarters and social_links replaces every link.
+type CompanyPatch struct {
+	Name           *string      `json:"name" validate:"omitempty,min=1"`
+	Location       *string      `json:"location" validate:"omitempty,min=1"`
+	Description    *string      `json:"description" validate:"omitempty,max=5000"`
+	Website        *string      `json:"website" validate:"omitempty,optional_url"`
+	Industry       *string      `json:"industry" validate:"omitempty,max=100"`
+	SizeBand       *string      `json:"size_band" validate:"omitempty,size_band"`
+	FoundedYear    *int         `json:"founded_year" validate:"omitempty,min=1800"`
+	SocialLinks    *SocialLinks `json:"social_links"`
+	HeadquartersID *uint        `json:"headquarters_id"`
+	OfficeIDs      *[]uint      `json:"office_ids" validate:"omitempty,dive,gt=0"`
+}
+
+// Profile returns the editable fields of c.
+func (c Company) Profile() CompanyProfile {
+	p := CompanyProfile{
+		Name:           c.Name,
+		Location:       c.Location,
+		Description:    c.Description,
+		Website:        c.Website,
+		Industry:       c.Industry,
+		SizeBand:       c.SizeBand,
+		FoundedYear:    c.FoundedYear,
+		SocialLinks:    c.SocialLinks,
+		HeadquartersID: c.HeadquartersID,
+		OfficeIDs:      []uint{},
+	}
+	for _, o := range c.Offices {
+		p.OfficeIDs = append(p.OfficeIDs, o.ID)
+	}
+	return p
+}
+
+// Apply returns p with the fields carried by the patch replaced.
+func (cp CompanyPatch) Apply(p CompanyProfile) CompanyProfile {
+	set(&p.Name, cp.Name)
+	set(&p.Location, cp.Location)
+	set(&p.Description, cp.Description)
+	set(&p.Website, cp.Website)
+	set(&p.Industry, cp.Industry)
+	set(&p.SizeBand, cp.SizeBand)
+	set(&p.FoundedYear, cp.FoundedYear)
+	set(&p.SocialLinks, cp.SocialLinks)
+	set(&p.OfficeIDs, cp.OfficeIDs)
+	if cp.HeadquartersID != nil {
+		p.HeadquartersID = nil
+		if *cp.HeadquartersID != 0 {
+			hq := *cp.HeadquartersID
+			p.HeadquartersID = &hq
+		}
+	}
+	return p
+}
+
+func set[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+// CompanySorts are the orders of the company list, a leading minus reverses
+// them.
+var CompanySorts = []string{"name", "-name", "created", "-created", "open_jobs", "-open_jobs"}
+
+// CompanyQuery filters, sorts and pages the company list. Q matches names
+// starting with or containing it, and similar names where the database can
+// tell. Location and Industry match ignoring case. Page is bounded so the
+// offset stays a query the database can answer.
+type CompanyQuery struct {
+	Q        string `json:"q" form:"q" validate:"max=100"`
+	Location string `json:"location" form:"location" validate:"max=100"`
+	Industry string `json:"industry" form:"industry" validate:"max=100"`
+	Verified *bool  `json:"verified" form:"verified"`
+	// Sort is one of CompanySorts. Without it the best matches of Q come
+	// first, or the oldest companies when there is no Q.
+	Sort    string `json:"sort" form:"sort" validate:"omitempty,oneof=name -name created -created open_jobs -open_jobs"`
+	Page    int    `json:"page" form:"page" validate:"gte=0,lte=100000"`
+	PerPage int    `json:"per_page" form:"per_page" validate:"gte=0,lte=100"`
+}
+
+// Offset is the number of companies on the pages before Page.
+func (q CompanyQuery) Offset() int {
+	if q.Page < 1 {
+		return 0
+	}
+	return (q.Page - 1) * q.PerPage
+}
+
+// CompanyPage is one page of the company list, Total counts the companies
+// on every page.
+type CompanyPage struct {
+	Companies []Company
+	Page      int
+	PerPage   int
+	Total     int64
+}
+
+// Kinds of company media. A company has at most one logo.
+const (
+	MediaLogo  = "logo"
+	MediaPhoto = "photo"
+)
+
+// CompanyMedia is an image uploaded for a company page. The original and its
+// thumbnails live in the file storage, the links to them are signed on
+// every read and expire.
+type CompanyMedia struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt   time.Time `json:"created_at"`
+	CompanyID   uint      `json:"company_id" gorm:"index;not null"`
+	Company     Company   `json:"-"`
+	Kind        string    `json:"kind" gorm:"not null"`
+	Key         string    `json:"-" gorm:"not null"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+
+	// URL links to the original, Thumbnails to the scaled copies by size
+	// name.
+	URL        string            `json:"url" gorm:"-"`
+	Thumbnails map[string]string `json:"thumbnails,omitempty" gorm:"-"`
+}
@@ -55,6 +55,9 @@ type Job struct {
 	Qualifications  []Qualification `gorm:"many2many:qualification_jobs"`
 	Shifts          []Shift         `gorm:"many2many:shift_jobs"`
 	JobTypes        []JobType       `gorm:"many2many:jobtype_jobs"`
+	// Verified is the badge of the company posting the job, it is read from
+	// the company and never stored.
+	Verified bool `json:"verified" gorm:"-"`
 }
 
 type NewJob struct {
@@ -62,13 +65,13 @@ type NewJob struct {
 	Description     string `json:"description" validate:"required"`
 	CompanyID       uint
 	Min_NP          int    `json:"min_np" validate:"required"`
-	Max_NP          int    `json:"max_np" validate:"required"`
-	Budget          int    `json:"budget" validate:"required"`
+	Max_NP          int    `json:"max_np" validate:"required,gtefield=Min_NP"`
+	Budget          int    `json:"budget" validate:"required,gt=0"`
 	JobLocations    []uint `json:"job_location" validate:"required"`
 	TechnologyStack []uint `json:"technology_stack" validate:"required"`
 	WorkModes       []uint `json:"work_mode" validate:"required"`
 	MinExp          int    `json:"min_exp" validate:"required"`
-	MaxExp          int    `json:"max_exp" validate:"required"`
+	MaxExp          int    `json:"max_exp" validate:"required,gtefield=MinExp"`
 	Qualifications  []uint `json:"qualification" validate:"required"`
 	WorkShifts      []uint `json:"work_shift" validate:"required"`
 	JobTypes        []uint `json:"job_type" validate:"required"`
@@ -76,7 +79,7 @@ type NewJob struct {
 
 type JobApplication struct {
 	Name            string `json:"name" validate:"required"`
-	Email           string `json:"email" validate:"required"`
+	Email           string `json:"email" validate:"required,email"`
 	Age             int    `json:"age" validate:"required"`
 	JobId           int    `json:"job_Id" validate:"required"`
 	NoticePeriod    int    `json:"notice_period" validate:"required"`
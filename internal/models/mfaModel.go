@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single use code that replaces a TOTP code when the
+// authenticator is lost. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint `gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time
+	UserID    uint   `gorm:"index:idx_recovery_user_hash;not null"`
+	Hash      string `gorm:"index:idx_recovery_user_hash;not null"`
+	UsedAt    *time.Time
+}
+
+// MFACode carries a TOTP code or a recovery code.
+type MFACode struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// TOTPEnrollment is handed out when enrolling an authenticator, the
+// provisioning URI is usually shown as a QR code.
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodes are shown once, only their hashes are kept. Token is set
+// when the enrollment completed a login that was blocked until the user
+// enrolled.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+	Token string   `json:"token,omitempty"`
+}
+
+// MFAPolicy sets whether a company forces its members to use MFA.
+type MFAPolicy struct {
+	Required *bool `json:"required" validate:"required"`
+}
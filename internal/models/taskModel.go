@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Task statuses.
+const (
+	TaskPending = "pending"
+	TaskRunning = "running"
+	TaskDone    = "done"
+	TaskFailed  = "failed"
+)
+
+// Outcomes of matching an application.
+const (
+	OutcomeMatched  = "matched"
+	OutcomeRejected = "rejected"
+	OutcomeFailed   = "failed"
+)
+
+// Task is a batch of job applications processed in the background. The
+// applications are kept so unfinished tasks resume after a restart.
+type Task struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:36"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uint      `json:"-" gorm:"index;not null"`
+	Status    string    `json:"status" gorm:"index;not null"`
+	Total     int       `json:"total"`
+	Processed int       `json:"processed"`
+	Matched   int       `json:"matched"`
+	Failed    int       `json:"failed"`
+	// Error explains why a task failed as a whole.
+	Error        string     `json:"error,omitempty"`
+	Applications []byte     `json:"-"`
+	Items        []TaskItem `json:"items,omitempty" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+	// Owner is the instance running the task. Nobody else runs it until
+	// LeaseUntil, the owner extends the lease while it makes progress.
+	Owner      string     `json:"-" gorm:"size:36"`
+	LeaseUntil *time.Time `json:"-"`
+}
+
+// ApplicationOutcome is the result of matching one application.
+type ApplicationOutcome struct {
+	// Position is the index of the application in the submitted batch.
+	Position int    `json:"index" gorm:"uniqueIndex:idx_task_item_position"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Outcome  string `json:"outcome"`
+	// Error explains why an application couldn't be matched.
+	Error string `json:"error,omitempty"`
+}
+
+// TaskItem is the outcome of one application of a task.
+type TaskItem struct {
+	ID                 uint   `json:"-" gorm:"primaryKey;autoIncrement"`
+	TaskID             string `json:"-" gorm:"size:36;uniqueIndex:idx_task_item_position;not null"`
+	ApplicationOutcome `gorm:"embedded"`
+}
@@ -1,6 +1,11 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
@@ -8,17 +13,85 @@ type User struct {
 	Name         string `json:"name"`
 	Email        string `json:"email"`
 	PasswordHash string `json:"-"`
+	// PasswordChangedAt is when the password was last reset, tokens issued
+	// before are refused.
+	PasswordChangedAt *time.Time `json:"-"`
+	// Verified is set once the user confirmed the code emailed on signup.
+	Verified bool `json:"verified" gorm:"not null;default:false"`
+	// Admin is granted directly in the database, tokens of admins carry the
+	// admin audience.
+	Admin bool `json:"-" gorm:"not null;default:false"`
+	// CompanyID is the company the user is a member of, users become members
+	// of the companies they create.
+	CompanyID *uint `json:"company_id"`
+
+	// MFAEnabled is set once a TOTP enrollment was confirmed.
+	MFAEnabled bool `json:"mfa_enabled" gorm:"not null;default:false"`
+	// TOTPSecret is the confirmed shared secret, TOTPPendingSecret the one
+	// handed out by an enrollment that wasn't confirmed yet.
+	TOTPSecret        string `json:"-"`
+	TOTPPendingSecret string `json:"-"`
+	// TOTPLastStep is the time step of the last accepted code, codes of
+	// earlier steps are refused so they can't be replayed.
+	TOTPLastStep int64 `json:"-" gorm:"not null;default:0"`
+}
+
+// NormalizeEmail returns the form emails are stored and looked up in. The
+// database additionally keeps a unique index on lower(email) so two accounts
+// can never share an email.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 type NewUser struct {
 	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+type Login struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
 }
 
+// Token is returned by a login. When MFARequired is set the token only
+// allows completing the login with a second factor, MFAEnrollmentRequired
+// additionally allows enrolling one first.
+type Token struct {
+	Token                 string `json:"token"`
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+}
+
+type Message struct {
+	Message string `json:"message"`
+}
+
+type ForgotPassword struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
 type Reset struct {
 	Otp             string `json:"otp" validate:"required"`
 	Email           string `json:"email" validate:"required,email"`
-	NewPassword     string `json:"new_password" validate:"required"`
-	ConfirmPassword string `json:"confirm_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,password"`
+	ConfirmPassword string `json:"confirm_password" validate:"required,eqfield=NewPassword"`
+}
+
+// Unlock lifts the lockout of an account and optionally of the address the
+// failed logins came from.
+type Unlock struct {
+	Email string `json:"email" validate:"required,email"`
+	IP    string `json:"ip" validate:"omitempty,ip"`
+}
+
+// VerifyEmail confirms an email with the code sent to it.
+type VerifyEmail struct {
+	Email string `json:"email" validate:"required,email"`
+	Code  string `json:"code" validate:"required"`
+}
+
+// ResendVerification asks for a new verification code.
+type ResendVerification struct {
+	Email string `json:"email" validate:"required,email"`
 }
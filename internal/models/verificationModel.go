@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Statuses of a company verification. The owner first proves an address on
+// the domain of the company website, then an admin reviews the request.
+const (
+	VerificationEmailSent = "email_sent"
+	VerificationPending   = "pending"
+	VerificationApproved  = "approved"
+	VerificationRejected  = "rejected"
+)
+
+// CompanyVerification is a request of an owner to have a company verified.
+// Only the latest request of a company counts.
+type CompanyVerification struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CompanyID uint      `json:"company_id" gorm:"index;not null"`
+	Company   Company   `json:"-"`
+	// RequestedBy is the owner who asked, Email the address on the domain of
+	// the website the code was sent to.
+	RequestedBy     uint       `json:"requested_by" gorm:"not null"`
+	Email           string     `json:"email" gorm:"not null"`
+	Status          string     `json:"status" gorm:"index;not null"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// ReviewedBy is the subject of the admin who approved or rejected the
+	// request, Reason what they told the owner.
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+}
+
+// VerificationRequest starts the verification of a company.
+type VerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// VerificationCode confirms the address of a verification request.
+type VerificationCode struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// VerificationReview carries the reason of an admin, it is required to
+// reject a request.
+type VerificationReview struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
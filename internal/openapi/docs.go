@@ -0,0 +1,9 @@
+package openapi
+
+import _ "embed"
+
+// DocsHTML is a self contained page that renders the document served at
+// openapi.json next to it and lets developers try requests from the browser.
+//
+//go:embed docs.html
+var DocsHTML []byte
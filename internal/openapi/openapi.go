@@ -0,0 +1,233 @@
+package openapi
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Version of the OpenAPI specification the documents conform to.
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps a lower case HTTP method to its operation.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type SecurityRequirement map[string][]string
+
+// Route describes one endpoint registered on the gin engine. Path uses the
+// gin syntax (/api/jobs/:id), Request and Response are zero values of the
+// types sent and returned, nil when there is no body. RequestType and
+// ContentType are the media types of the bodies, JSON when empty. The form
+// tagged fields of the struct Query are the query parameters.
+type Route struct {
+	Method      string
+	Path        string
+	Summary     string
+	Tag         string
+	Auth        bool
+	Query       any
+	Request     any
+	RequestType string
+	Response    any
+	Status      int
+	ContentType string
+	Errors      []int
+}
+
+// Builder assembles a Document from a list of routes, collecting the schemas
+// of every model it encounters under components.
+type Builder struct {
+	doc     Document
+	schemas *registry
+	problem any
+}
+
+// NewBuilder starts a document. problemType is the body returned for every
+// error status listed on a route.
+func NewBuilder(info Info, problemType any) *Builder {
+	b := &Builder{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   map[string]PathItem{},
+			Components: Components{
+				SecuritySchemes: map[string]SecurityScheme{
+					"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				},
+			},
+		},
+		schemas: newRegistry(),
+		problem: problemType,
+	}
+	return b
+}
+
+// Add documents a route.
+func (b *Builder) Add(r Route) {
+	path, params := convertPath(r.Path)
+
+	op := &Operation{
+		Summary:     r.Summary,
+		OperationID: operationID(r.Method, r.Path),
+		Parameters:  params,
+		Responses:   map[string]Response{},
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+	if r.Query != nil {
+		op.Parameters = append(op.Parameters, b.schemas.queryParameters(r.Query)...)
+	}
+	if r.Auth {
+		op.Security = []SecurityRequirement{{"bearerAuth": {}}}
+	}
+	if r.Request != nil {
+		ct := r.RequestType
+		if ct == "" {
+			ct = "application/json"
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{ct: {Schema: b.schemas.schemaFor(r.Request)}},
+		}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = 200
+	}
+	ok := Response{Description: "Success"}
+	if r.Response != nil {
+		ct := r.ContentType
+		if ct == "" {
+			ct = "application/json"
+		}
+		ok.Content = map[string]MediaType{ct: {Schema: b.schemas.schemaFor(r.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = ok
+
+	for _, code := range r.Errors {
+		op.Responses[strconv.Itoa(code)] = Response{
+			Description: "Error",
+			Content: map[string]MediaType{
+				"application/problem+json": {Schema: b.schemas.schemaFor(b.problem)},
+			},
+		}
+	}
+
+	item, exists := b.doc.Paths[path]
+	if !exists {
+		item = PathItem{}
+		b.doc.Paths[path] = item
+	}
+	item[strings.ToLower(r.Method)] = op
+}
+
+// Document returns the assembled specification.
+func (b *Builder) Document() Document {
+	doc := b.doc
+	doc.Components.Schemas = b.schemas.components
+	return doc
+}
+
+// Has reports whether method and the gin style path are documented.
+func (d Document) Has(method, ginPath string) bool {
+	path, _ := convertPath(ginPath)
+	item, ok := d.Paths[path]
+	if !ok {
+		return false
+	}
+	_, ok = item[strings.ToLower(method)]
+	return ok
+}
+
+// convertPath turns /api/jobs/:id into /api/jobs/{id} and returns the path
+// parameters it found.
+func convertPath(p string) (string, []Parameter) {
+	segs := strings.Split(p, "/")
+	var params []Parameter
+	for i, s := range segs {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			name := s[1:]
+			segs[i] = "{" + name + "}"
+			schema := &Schema{Type: "string"}
+			if name == "id" {
+				schema = &Schema{Type: "integer"}
+			}
+			params = append(params, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   schema,
+			})
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+func operationID(method, path string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(method))
+	for _, s := range strings.Split(path, "/") {
+		s = strings.TrimLeft(s, ":*")
+		if s == "" || s == "api" {
+			continue
+		}
+		sb.WriteString(strings.ToUpper(s[:1]) + s[1:])
+	}
+	return sb.String()
+}
@@ -0,0 +1,186 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// File stands for the content of a file in a multipart form or a response
+// that isn't JSON.
+type File struct{}
+
+var (
+	fileType      = reflect.TypeOf(File{})
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// registry derives JSON schemas from Go types following the encoding/json
+// rules, named struct types are stored once under components.
+type registry struct {
+	components map[string]*Schema
+}
+
+func newRegistry() *registry {
+	return &registry{components: map[string]*Schema{}}
+}
+
+func (r *registry) schemaFor(v any) *Schema {
+	return r.schema(reflect.TypeOf(v))
+}
+
+func (r *registry) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case fileType:
+		return &Schema{Type: "string", Format: "binary"}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	}
+	if t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		// Custom encodings can't be derived, leave the schema open
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := r.components[name]; !ok {
+			// Reserve the name first so recursive types terminate
+			r.components[name] = &Schema{}
+			*r.components[name] = *r.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// object builds the schema of a struct. Fields of embedded structs are
+// promoted unless a shallower field already uses the same JSON name, which
+// mirrors how encoding/json resolves them.
+func (r *registry) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	var embedded []reflect.StructField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, skip := fieldName(f)
+		if skip {
+			continue
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" && f.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, f)
+			continue
+		}
+		s.Properties[name] = r.schema(f.Type)
+		if isRequired(f) {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	for _, f := range embedded {
+		inner := r.object(f.Type)
+		for name, p := range inner.Properties {
+			if _, ok := s.Properties[name]; !ok {
+				s.Properties[name] = p
+			}
+		}
+		s.Required = append(s.Required, inner.Required...)
+	}
+	return s
+}
+
+// queryParameters lists the fields of a struct with a form tag, the tag
+// gin binds query parameters by.
+func (r *registry) queryParameters(v any) []Parameter {
+	t := reflect.TypeOf(v)
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.SplitN(f.Tag.Get("form"), ",", 2)[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "query",
+			Required: isRequired(f),
+			Schema:   r.schema(f.Type),
+		})
+	}
+	return params
+}
+
+func fieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	name := strings.SplitN(tag, ",", 2)[0]
+	if name == "-" {
+		return "", true
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, false
+}
+
+func isRequired(f reflect.StructField) bool {
+	for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaName qualifies the type with its package so models.Job and a
+// handler local Job type never collide.
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg == "" || pkg == "models" {
+		return t.Name()
+	}
+	return pkg + "." + t.Name()
+}
@@ -0,0 +1,58 @@
+package problem
+
+import (
+	"job-portal/internal/validation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type defined by RFC 7807 for problem details.
+const ContentType = "application/problem+json"
+
+// Generic codes used when a failure is not tied to a specific domain error.
+const (
+	CodeBadRequest   = "bad_request"
+	CodeInvalidBody  = "invalid_body"
+	CodeInvalidID    = "invalid_id"
+	CodeValidation   = "validation_failed"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeRateLimited  = "rate_limited"
+	CodeInternal     = "internal_error"
+)
+
+// Problem is the RFC 7807 body every error response is rendered as. Code is
+// a stable identifier clients can switch on, TraceId ties the response to
+// the server logs and spans.
+type Problem struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Status  int    `json:"status"`
+	Detail  string `json:"detail,omitempty"`
+	Code    string `json:"code"`
+	TraceId string `json:"trace_id,omitempty"`
+
+	// Errors lists the offending fields of a validation_failed problem.
+	Errors validation.Errors `json:"errors,omitempty"`
+}
+
+// New builds a problem for the given status and code.
+func New(status int, code, detail, traceId string) Problem {
+	return Problem{
+		Type:    "urn:job-portal:error:" + code,
+		Title:   http.StatusText(status),
+		Status:  status,
+		Detail:  detail,
+		Code:    code,
+		TraceId: traceId,
+	}
+}
+
+// Abort stops the handler chain and writes p as problem+json.
+func Abort(c *gin.Context, p Problem) {
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
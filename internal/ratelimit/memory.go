@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps a sliding window log per key in process memory. Limits are
+// per instance, which makes it suitable for tests and as a fallback.
+type Memory struct {
+	mu    sync.Mutex
+	now   func() time.Time
+	keys  map[string]*window
+	calls int
+}
+
+type window struct {
+	hits []time.Time
+	size time.Duration
+}
+
+func NewMemory() *Memory {
+	return &Memory{now: time.Now, keys: map[string]*window{}}
+}
+
+func (m *Memory) Allow(_ context.Context, key string, r Rule) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	w, ok := m.keys[key]
+	if !ok {
+		w = &window{}
+		m.keys[key] = w
+	}
+	w.size = r.Window
+	hits := prune(w.hits, now.Add(-r.Window))
+
+	res := Result{Limit: r.Limit}
+	if len(hits) < r.Limit {
+		hits = append(hits, now)
+		res.Allowed = true
+		res.Remaining = r.Limit - len(hits)
+	}
+	if len(hits) > 0 {
+		res.Reset = hits[0].Add(r.Window).Sub(now)
+	}
+	w.hits = hits
+
+	// Keys that stopped receiving traffic are dropped every so often so the
+	// map doesn't grow with every address that ever made a request.
+	m.calls++
+	if m.calls%1024 == 0 {
+		m.sweep(now)
+	}
+	return res, nil
+}
+
+func (m *Memory) sweep(now time.Time) {
+	for k, w := range m.keys {
+		if len(w.hits) == 0 || now.Sub(w.hits[len(w.hits)-1]) > w.size {
+			delete(m.keys, k)
+		}
+	}
+}
+
+// prune drops the hits that happened at or before cutoff.
+func prune(hits []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Rule allows Limit requests in any Window long period.
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseRule reads a rule written as "<limit>/<window>", e.g. "5/1m".
+// An empty string or a zero limit disables the rule.
+func ParseRule(s string) (Rule, error) {
+	if strings.TrimSpace(s) == "" {
+		return Rule{}, nil
+	}
+	limit, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("rate limit %q: expected <limit>/<window>", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || n < 0 {
+		return Rule{}, fmt.Errorf("rate limit %q: invalid limit", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || d <= 0 {
+		return Rule{}, fmt.Errorf("rate limit %q: invalid window", s)
+	}
+	return Rule{Limit: n, Window: d}, nil
+}
+
+// Enabled reports whether the rule limits anything.
+func (r Rule) Enabled() bool {
+	return r.Limit > 0 && r.Window > 0
+}
+
+// Result describes the state of a key after a request was counted.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the oldest request in the window expires and
+	// a slot frees up again.
+	Reset time.Duration
+}
+
+// Limiter counts requests per key over a sliding window.
+type Limiter interface {
+	Allow(ctx context.Context, key string, r Rule) (Result, error)
+}
+
+// fallbackCooldown is how long Fallback stops asking a failed primary, so
+// requests don't each wait for a connection timeout while Redis is down.
+const fallbackCooldown = 10 * time.Second
+
+// Fallback uses the primary limiter and switches to the secondary one while
+// the primary fails, so an unreachable Redis degrades to per instance limits
+// instead of disabling rate limiting.
+type Fallback struct {
+	primary   Limiter
+	secondary Limiter
+	now       func() time.Time
+
+	mu        sync.Mutex
+	downUntil time.Time
+}
+
+func NewFallback(primary, secondary Limiter) *Fallback {
+	return &Fallback{primary: primary, secondary: secondary, now: time.Now}
+}
+
+func (f *Fallback) Allow(ctx context.Context, key string, r Rule) (Result, error) {
+	f.mu.Lock()
+	down := f.now().Before(f.downUntil)
+	f.mu.Unlock()
+
+	if !down {
+		res, err := f.primary.Allow(ctx, key, r)
+		if err == nil {
+			return res, nil
+		}
+		log.Warn().Err(err).Msg("rate limiter unavailable, using in-memory fallback")
+		f.mu.Lock()
+		f.downUntil = f.now().Add(fallbackCooldown)
+		f.mu.Unlock()
+	}
+	return f.secondary.Allow(ctx, key, r)
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    Rule
+		wantErr bool
+	}{
+		{name: "limit per minute", value: "5/1m", want: Rule{Limit: 5, Window: time.Minute}},
+		{name: "spaces are ignored", value: " 10 / 15m ", want: Rule{Limit: 10, Window: 15 * time.Minute}},
+		{name: "empty disables", value: "", want: Rule{}},
+		{name: "missing window", value: "5", wantErr: true},
+		{name: "bad limit", value: "x/1m", wantErr: true},
+		{name: "bad window", value: "5/soon", wantErr: true},
+		{name: "negative window", value: "5/-1m", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRule(tt.value)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// clock is advanced by hand so the window can slide without sleeping.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func testLimiter(t *testing.T, l Limiter, c *clock) {
+	ctx := context.Background()
+	rule := Rule{Limit: 2, Window: time.Minute}
+
+	res, err := l.Allow(ctx, "k", rule)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute}, res)
+
+	c.t = c.t.Add(20 * time.Second)
+	res, err = l.Allow(ctx, "k", rule)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 40 * time.Second}, res)
+
+	res, err = l.Allow(ctx, "k", rule)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Result{Allowed: false, Limit: 2, Remaining: 0, Reset: 40 * time.Second}, res)
+
+	// Other keys have their own window
+	res, err = l.Allow(ctx, "other", rule)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, res.Allowed)
+
+	// The first request falls out of the window, the second one still counts
+	c.t = c.t.Add(41 * time.Second)
+	res, err = l.Allow(ctx, "k", rule)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 19 * time.Second}, res)
+
+	res, err = l.Allow(ctx, "k", rule)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, res.Allowed)
+}
+
+func TestMemory_Allow(t *testing.T) {
+	c := &clock{t: time.Unix(1700000000, 0)}
+	m := NewMemory()
+	m.now = c.now
+	testLimiter(t, m, c)
+}
+
+func TestRedis_Allow(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	c := &clock{t: time.Unix(1700000000, 0)}
+	r := NewRedis(rdb)
+	r.now = c.now
+	testLimiter(t, r, c)
+
+	// Idle keys expire with the window
+	assert.Equal(t, true, mr.Exists("k"))
+	mr.FastForward(2 * time.Minute)
+	assert.Equal(t, false, mr.Exists("k"))
+}
+
+type failing struct{ calls int }
+
+func (f *failing) Allow(context.Context, string, Rule) (Result, error) {
+	f.calls++
+	return Result{}, errors.New("connection refused")
+}
+
+func TestFallback_Allow(t *testing.T) {
+	c := &clock{t: time.Unix(1700000000, 0)}
+	primary := &failing{}
+	f := NewFallback(primary, NewMemory())
+	f.now = c.now
+	rule := Rule{Limit: 1, Window: time.Minute}
+
+	res, err := f.Allow(context.Background(), "k", rule)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, res.Allowed)
+
+	// The secondary keeps counting while the primary is skipped
+	res, err = f.Allow(context.Background(), "k", rule)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, false, res.Allowed)
+	assert.Equal(t, 1, primary.calls)
+
+	c.t = c.t.Add(fallbackCooldown)
+	_, _ = f.Allow(context.Background(), "k", rule)
+	assert.Equal(t, 2, primary.calls)
+}
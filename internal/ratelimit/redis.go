@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindow keeps one sorted set entry per request scored by its time in
+// milliseconds. Entries older than the window are trimmed before counting so
+// the check and the insert happen atomically on the server.
+var slidingWindow = redis.NewScript(`
+local key    = KEYS[1]
+local now    = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit  = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = 0
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
+
+// Redis shares the counters between every instance of the API.
+type Redis struct {
+	rdb redis.Scripter
+	now func() time.Time
+}
+
+func NewRedis(rdb redis.Scripter) *Redis {
+	return &Redis{rdb: rdb, now: time.Now}
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	now := r.now().UnixMilli()
+	// The member only has to be unique, the score carries the time
+	member := strconv.FormatInt(now, 10) + "-" + uuid.NewString()
+
+	vals, err := slidingWindow.Run(ctx, r.rdb, []string{key},
+		now, rule.Window.Milliseconds(), rule.Limit, member).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit %s: %w", key, err)
+	}
+	if len(vals) != 3 {
+		return Result{}, fmt.Errorf("rate limit %s: unexpected reply %v", key, vals)
+	}
+
+	res := Result{
+		Allowed: vals[0] == 1,
+		Limit:   rule.Limit,
+		Reset:   time.Duration(vals[2]) * time.Millisecond,
+	}
+	if res.Allowed {
+		res.Remaining = rule.Limit - int(vals[1])
+	}
+	return res, nil
+}
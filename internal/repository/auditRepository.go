@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+	"job-portal/internal/models"
+)
+
+// RecordAuditEvent appends an event to the audit log.
+func (s *Conn) RecordAuditEvent(ctx context.Context, e models.AuditEvent) error {
+	return s.db.WithContext(ctx).Create(&e).Error
+}
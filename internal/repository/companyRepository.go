@@ -2,47 +2,282 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"job-portal/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func (s *Conn) CreateC(ctx context.Context, nc models.NewCompany) (models.Company, error) {
+// CreateC creates a company and makes the user creating it a member, unless
+// the user already belongs to a company.
+func (s *Conn) CreateC(ctx context.Context, nc models.NewCompany, userID uint) (models.Company, error) {
 
 	com := models.Company{
 		Name:     nc.Name,
 		Location: nc.Location,
+		OwnerID:  &userID,
 		//CompanyId: nc.CompanyID,
 		Jobs: nc.Jobs,
 	}
-	tx := s.db.WithContext(ctx).Create(&com)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&com).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ? AND company_id IS NULL", userID).
+			Update("company_id", com.ID).Error
+	})
+	if err != nil {
+		return models.Company{}, err
+	}
 
-	if tx.Error != nil {
+	return com, nil
+}
 
-		return models.Company{}, tx.Error
+// SearchCompanies returns the page of live companies the query asks for and
+// how many match it on every page. A PerPage of zero returns every match.
+func (s *Conn) SearchCompanies(ctx context.Context, q models.CompanyQuery) ([]models.Company, int64, error) {
+	term := strings.ToLower(strings.TrimSpace(q.Q))
+	filter := func(db *gorm.DB) *gorm.DB {
+		db = db.Model(&models.Company{})
+		if term != "" {
+			contains := "%" + escapeLike(term) + "%"
+			if s.trigram {
+				db = db.Where("(lower(name) LIKE ? ESCAPE '\\' OR lower(name) % ?)", contains, term)
+			} else {
+				db = db.Where("lower(name) LIKE ? ESCAPE '\\'", contains)
+			}
+		}
+		if q.Location != "" {
+			db = db.Where("lower(location) = ?", strings.ToLower(q.Location))
+		}
+		if q.Industry != "" {
+			db = db.Where("lower(industry) = ?", strings.ToLower(q.Industry))
+		}
+		if q.Verified != nil {
+			db = db.Where("verified = ?", *q.Verified)
+		}
+		return db
 	}
 
-	return com, nil
+	var total int64
+	err := filter(s.db.WithContext(ctx)).Count(&total).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("counting companies: %w", err)
+	}
+	com := []models.Company{}
+	tx := withLocations(filter(s.db.WithContext(ctx))).Clauses(s.companyOrder(q.Sort, term))
+	if q.PerPage > 0 {
+		tx = tx.Limit(q.PerPage).Offset(q.Offset())
+	}
+	err = tx.Find(&com).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("searching companies: %w", err)
+	}
+	err = countOpenJobs(s.db.WithContext(ctx), com)
+	if err != nil {
+		return nil, 0, err
+	}
+	return com, total, nil
 }
 
-func (s *Conn) ViewCompanies() ([]models.Company, error) {
-	var com []models.Company
-	err := s.db.Find(&com).Error
+// openJobsSQL counts the live jobs of the company of the row.
+const openJobsSQL = "(SELECT count(*) FROM jobs WHERE jobs.company_id = companies.id AND jobs.deleted_at IS NULL)"
 
+// companyOrder sorts the company list, ties go to the oldest company.
+// Without a sort names starting with the term come first, then the most
+// similar names.
+func (s *Conn) companyOrder(sort string, term string) clause.OrderBy {
+	if sort == "" && term != "" {
+		prefix := escapeLike(term) + "%"
+		if s.trigram {
+			return clause.OrderBy{Expression: clause.Expr{
+				SQL:  "CASE WHEN lower(name) LIKE ? ESCAPE '\\' THEN 0 ELSE 1 END, similarity(lower(name), ?) DESC, lower(name), id",
+				Vars: []any{prefix, term},
+			}}
+		}
+		return clause.OrderBy{Expression: clause.Expr{
+			SQL:  "CASE WHEN lower(name) LIKE ? ESCAPE '\\' THEN 0 ELSE 1 END, lower(name), id",
+			Vars: []any{prefix},
+		}}
+	}
+	dir := ""
+	if strings.HasPrefix(sort, "-") {
+		dir = " DESC"
+	}
+	order := "id"
+	switch strings.TrimPrefix(sort, "-") {
+	case "name":
+		order = "lower(name)" + dir + ", id"
+	case "created":
+		order = "created_at" + dir + ", id" + dir
+	case "open_jobs":
+		order = openJobsSQL + dir + ", id"
+	}
+	return clause.OrderBy{Expression: clause.Expr{SQL: order}}
+}
+
+// escapeLike makes the wildcards of LIKE in s match themselves, the
+// patterns using it escape with a backslash.
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+// GetCompanyByID fetches a company by its id, a missing company is
+// ErrNotFound.
+func (s *Conn) GetCompanyByID(ctx context.Context, uid int) (models.Company, error) {
+
+	var com models.Company
+	tx := withLocations(s.db.WithContext(ctx)).Where("ID = ?", uid)
+	err := tx.First(&com).Error
 	if err != nil {
-		return []models.Company{}, err
+		return models.Company{}, fmt.Errorf("fetching company %d: %w", uid, notFound(err))
 	}
+	coms := []models.Company{com}
+	err = countOpenJobs(s.db.WithContext(ctx), coms)
+	if err != nil {
+		return models.Company{}, fmt.Errorf("fetching company %d: %w", uid, err)
+	}
+	return coms[0], nil
 
-	return com, nil
 }
 
-func (s *Conn) GetCompanyByID(uid int) (models.Company, error) {
+func (s *Conn) LockCompany(ctx context.Context, id uint) error {
+	err := s.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").Where("id = ?", id).First(&models.Company{}).Error
+	if err != nil {
+		return fmt.Errorf("locking company %d: %w", id, notFound(err))
+	}
+	return nil
+}
 
+// withLocations preloads the headquarters and offices of companies, offices
+// in the order of their ids.
+func withLocations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Headquarters").Preload("Offices", func(db *gorm.DB) *gorm.DB {
+		return db.Order("locations.id")
+	})
+}
+
+// countOpenJobs sets OpenJobs of every company with one query.
+func countOpenJobs(db *gorm.DB, com []models.Company) error {
+	if len(com) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(com))
+	for i, c := range com {
+		ids[i] = c.ID
+	}
+	var counts []struct {
+		CompanyID uint
+		Count     int64
+	}
+	err := db.Model(&models.Job{}).Select("company_id, count(*) AS count").
+		Where("company_id IN ?", ids).Group("company_id").Scan(&counts).Error
+	if err != nil {
+		return fmt.Errorf("counting open jobs: %w", err)
+	}
+	open := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		open[c.CompanyID] = c.Count
+	}
+	for i := range com {
+		com[i].OpenJobs = open[com[i].ID]
+	}
+	return nil
+}
+
+// UpdateCompany replaces the profile of a live company. Locations that
+// don't exist yet are created, like they are for jobs.
+func (s *Conn) UpdateCompany(ctx context.Context, id uint, p models.CompanyProfile) (models.Company, error) {
 	var com models.Company
-	tx := s.db.Where("ID = ?", uid)
-	err := tx.Find(&com).Error
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ?", id).First(&com).Error
+		if err != nil {
+			return notFound(err)
+		}
+
+		offices := make([]models.Location, 0, len(p.OfficeIDs))
+		for _, lId := range p.OfficeIDs {
+			offices = append(offices, models.Location{ID: lId})
+		}
+		if p.HeadquartersID != nil {
+			err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Location{ID: *p.HeadquartersID}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		com.Name = p.Name
+		com.Location = p.Location
+		com.Description = p.Description
+		com.Website = p.Website
+		com.Industry = p.Industry
+		com.SizeBand = p.SizeBand
+		com.FoundedYear = p.FoundedYear
+		com.SocialLinks = p.SocialLinks
+		com.HeadquartersID = p.HeadquartersID
+		err = tx.Omit(clause.Associations).Save(&com).Error
+		if err != nil {
+			return err
+		}
+		if len(offices) == 0 {
+			return tx.Model(&com).Association("Offices").Clear()
+		}
+		return tx.Model(&com).Association("Offices").Replace(offices)
+	})
 	if err != nil {
-		return models.Company{}, errors.New("company not found")
+		return models.Company{}, fmt.Errorf("updating company %d: %w", id, err)
 	}
-	return com, nil
+	return s.GetCompanyByID(ctx, int(id))
+}
+
+// DeleteCompany soft deletes a company with its jobs, its members are free
+// to join or create another company. The name of the company stays taken.
+// Its media records are deleted and returned, their files stay in the
+// storage.
+func (s *Conn) DeleteCompany(ctx context.Context, id uint) ([]models.CompanyMedia, error) {
+	var media []models.CompanyMedia
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ?", id).Delete(&models.Company{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("deleting company %d: %w", id, ErrNotFound)
+		}
+		err := tx.Where("company_id = ?", id).Delete(&models.Job{}).Error
+		if err != nil {
+			return fmt.Errorf("deleting jobs of company %d: %w", id, err)
+		}
+		// Uploads lock the company row, so none slips in after this read
+		err = tx.Where("company_id = ?", id).Order("id").Find(&media).Error
+		if err != nil {
+			return fmt.Errorf("fetching media of company %d: %w", id, err)
+		}
+		err = tx.Where("company_id = ?", id).Delete(&models.CompanyMedia{}).Error
+		if err != nil {
+			return fmt.Errorf("deleting media of company %d: %w", id, err)
+		}
+		return tx.Model(&models.User{}).Where("company_id = ?", id).Update("company_id", nil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return media, nil
+}
 
+// SetCompanyMFA sets whether the members of a company have to use MFA.
+func (s *Conn) SetCompanyMFA(ctx context.Context, companyID uint, required bool) error {
+	tx := s.db.WithContext(ctx).Model(&models.Company{}).Where("id = ?", companyID).Update("require_mfa", required)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return fmt.Errorf("updating company %d: %w", companyID, ErrNotFound)
+	}
+	return nil
 }
@@ -0,0 +1,712 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"job-portal/internal/database"
+	"job-portal/internal/models"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/go-playground/assert.v1"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// contractRepo is a repository under test. softDelete soft deletes the
+// record of model's type with the given id, the interface has no way to.
+type contractRepo struct {
+	Repository
+	softDelete func(t *testing.T, model any, id uint)
+}
+
+// testRepositoryContract checks the behavior every Repository shares,
+// newRepo returns an empty repository.
+func testRepositoryContract(t *testing.T, newRepo func(t *testing.T) contractRepo) {
+	ctx := context.Background()
+	register := func(t *testing.T, r Repository, email string) models.User {
+		u, err := r.CreateU(ctx, models.NewUser{Name: "vishnu", Email: email, Password: "Secret#123"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	// Missing records are ErrNotFound, which callers of gorm still recognize
+	isNotFound := func(err error) bool {
+		return errors.Is(err, ErrNotFound) && errors.Is(err, gorm.ErrRecordNotFound)
+	}
+
+	t.Run("users", func(t *testing.T) {
+		r := newRepo(t)
+		u := register(t, r, " Vishnu@Example.com")
+		assert.Equal(t, "vishnu@example.com", u.Email)
+		assert.NotEqual(t, uint(0), u.ID)
+
+		_, err := r.CreateU(ctx, models.NewUser{Name: "other", Email: "VISHNU@example.com", Password: "Secret#123"})
+		assert.Equal(t, true, errors.Is(err, gorm.ErrDuplicatedKey))
+
+		got, err := r.GetUserByEmail(ctx, "VISHNU@example.com ")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, u.ID, got.ID)
+		got, err = r.GetUserByID(ctx, u.ID)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, u.Email, got.Email)
+
+		ok, err := r.CheckUserEmail(ctx, "vishnu@example.com")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, ok)
+
+		claims, err := r.AuthenticateUser(ctx, "vishnu@example.com", "Secret#123")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, UserClaims(got).Subject, claims.Subject)
+		_, err = r.AuthenticateUser(ctx, "vishnu@example.com", "wrong")
+		assert.Equal(t, bcrypt.ErrMismatchedHashAndPassword, err)
+
+		ok, err = r.UpdateUserPassword(ctx, models.Reset{Email: "vishnu@example.com", NewPassword: "Changed#123"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, ok)
+		_, err = r.AuthenticateUser(ctx, "vishnu@example.com", "Changed#123")
+		assert.Equal(t, nil, err)
+		got, _ = r.GetUserByID(ctx, u.ID)
+		assert.Equal(t, true, got.PasswordChangedAt != nil)
+
+		assert.Equal(t, nil, r.MarkUserVerified(ctx, "Vishnu@example.com"))
+		got, _ = r.GetUserByID(ctx, u.ID)
+		assert.Equal(t, true, got.Verified)
+	})
+
+	t.Run("missing users", func(t *testing.T) {
+		r := newRepo(t)
+		const email = "nobody@example.com"
+
+		// Some methods wrap the error, none hides it
+		_, err := r.GetUserByEmail(ctx, email)
+		assert.Equal(t, true, isNotFound(err))
+		_, err = r.GetUserByID(ctx, 42)
+		assert.Equal(t, true, isNotFound(err))
+		ok, err := r.CheckUserEmail(ctx, email)
+		assert.Equal(t, false, ok)
+		assert.Equal(t, ErrNotFound, err)
+		_, err = r.AuthenticateUser(ctx, email, "Secret#123")
+		assert.Equal(t, ErrNotFound, err)
+		ok, err = r.UpdateUserPassword(ctx, models.Reset{Email: email, NewPassword: "Changed#123"})
+		assert.Equal(t, false, ok)
+		assert.Equal(t, ErrNotFound, err)
+		assert.Equal(t, true, isNotFound(r.MarkUserVerified(ctx, email)))
+		assert.Equal(t, true, isNotFound(r.SetTOTPPendingSecret(ctx, 42, "secret")))
+		assert.Equal(t, true, isNotFound(r.EnableTOTP(ctx, 42, "secret", 1, nil)))
+		assert.Equal(t, true, isNotFound(r.DisableTOTP(ctx, 42)))
+		ok, err = r.AdvanceTOTPStep(ctx, 42, 1)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, false, ok)
+	})
+
+	t.Run("soft deleted users", func(t *testing.T) {
+		r := newRepo(t)
+		u := register(t, r, "vishnu@example.com")
+		r.softDelete(t, &models.User{}, u.ID)
+
+		_, err := r.GetUserByID(ctx, u.ID)
+		assert.Equal(t, true, isNotFound(err))
+		_, err = r.GetUserByEmail(ctx, u.Email)
+		assert.Equal(t, true, isNotFound(err))
+		_, err = r.AuthenticateUser(ctx, u.Email, "Secret#123")
+		assert.Equal(t, ErrNotFound, err)
+		assert.Equal(t, true, isNotFound(r.MarkUserVerified(ctx, u.Email)))
+		assert.Equal(t, true, isNotFound(r.SetTOTPPendingSecret(ctx, u.ID, "secret")))
+
+		// The email is free again
+		again := register(t, r, u.Email)
+		assert.NotEqual(t, u.ID, again.ID)
+	})
+
+	t.Run("companies", func(t *testing.T) {
+		r := newRepo(t)
+		u := register(t, r, "vishnu@example.com")
+		c, err := r.CreateC(ctx, models.NewCompany{Name: "tek", Location: "bangalore"}, u.ID)
+		assert.Equal(t, nil, err)
+		assert.NotEqual(t, uint(0), c.ID)
+
+		// The creator becomes a member, but only of the first company
+		other, err := r.CreateC(ctx, models.NewCompany{Name: "infy", Location: "mysore"}, u.ID)
+		assert.Equal(t, nil, err)
+		got, _ := r.GetUserByID(ctx, u.ID)
+		assert.Equal(t, c.ID, *got.CompanyID)
+
+		_, err = r.CreateC(ctx, models.NewCompany{Name: "tek", Location: "pune"}, u.ID)
+		assert.Equal(t, true, errors.Is(err, gorm.ErrDuplicatedKey))
+
+		com, err := r.GetCompanyByID(ctx, int(c.ID))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "bangalore", com.Location)
+		all, total, err := r.SearchCompanies(ctx, models.CompanyQuery{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2, len(all))
+		assert.Equal(t, int64(2), total)
+
+		assert.Equal(t, nil, r.SetCompanyMFA(ctx, c.ID, true))
+		com, _ = r.GetCompanyByID(ctx, int(c.ID))
+		assert.Equal(t, true, com.RequireMFA)
+
+		r.softDelete(t, &models.Company{}, other.ID)
+		all, total, _ = r.SearchCompanies(ctx, models.CompanyQuery{})
+		assert.Equal(t, 1, len(all))
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, true, isNotFound(r.SetCompanyMFA(ctx, other.ID, true)))
+		// The name stays taken
+		_, err = r.CreateC(ctx, models.NewCompany{Name: "infy", Location: "mysore"}, u.ID)
+		assert.Equal(t, true, errors.Is(err, gorm.ErrDuplicatedKey))
+	})
+
+	t.Run("missing companies", func(t *testing.T) {
+		r := newRepo(t)
+		com, err := r.GetCompanyByID(ctx, 42)
+		assert.Equal(t, true, isNotFound(err))
+		assert.Equal(t, uint(0), com.ID)
+		assert.Equal(t, true, isNotFound(r.SetCompanyMFA(ctx, 42, true)))
+		_, err = r.UpdateCompany(ctx, 42, models.CompanyProfile{Name: "tek", Location: "pune"})
+		assert.Equal(t, true, isNotFound(err))
+		_, err = r.DeleteCompany(ctx, 42)
+		assert.Equal(t, true, isNotFound(err))
+		assert.Equal(t, true, isNotFound(r.LockCompany(ctx, 42)))
+		all, total, err := r.SearchCompanies(ctx, models.CompanyQuery{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 0, len(all))
+		assert.Equal(t, int64(0), total)
+	})
+
+	t.Run("company profiles", func(t *testing.T) {
+		r := newRepo(t)
+		u := register(t, r, "vishnu@example.com")
+		c, _ := r.CreateC(ctx, models.NewCompany{Name: "tek", Location: "bangalore"}, u.ID)
+		assert.Equal(t, u.ID, *c.OwnerID)
+		other, _ := r.CreateC(ctx, models.NewCompany{Name: "infy", Location: "mysore"}, u.ID)
+		_, err := r.CreateJ(ctx, models.NewJob{Title: "go developer", JobLocations: []uint{1}}, int(c.ID))
+		assert.Equal(t, nil, err)
+		_, err = r.CreateJ(ctx, models.NewJob{Title: "java developer", JobLocations: []uint{1}}, int(c.ID))
+		assert.Equal(t, nil, err)
+
+		hq := uint(1)
+		p := models.CompanyProfile{
+			Name: "tek systems", Location: "bangalore", Description: "staffing", Website: "https://tek.example.com",
+			Industry: "it services", SizeBand: "5001+", FoundedYear: 1983,
+			SocialLinks:    models.SocialLinks{LinkedIn: "https://linkedin.com/company/tek"},
+			HeadquartersID: &hq, OfficeIDs: []uint{2, 3, 2},
+		}
+		com, err := r.UpdateCompany(ctx, c.ID, p)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "tek systems", com.Name)
+		assert.Equal(t, int64(2), com.OpenJobs)
+		assert.Equal(t, hq, com.Headquarters.ID)
+		// Locations are referenced once and created when missing
+		assert.Equal(t, 2, len(com.Offices))
+
+		com, err = r.GetCompanyByID(ctx, int(c.ID))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, p.SocialLinks, com.SocialLinks)
+		assert.Equal(t, 1983, com.FoundedYear)
+		assert.Equal(t, []uint{2, 3}, com.Profile().OfficeIDs)
+		assert.Equal(t, int64(2), com.OpenJobs)
+		all, _, _ := r.SearchCompanies(ctx, models.CompanyQuery{Sort: "created"})
+		assert.Equal(t, int64(2), all[0].OpenJobs)
+		assert.Equal(t, int64(0), all[1].OpenJobs)
+
+		// Clearing the optional fields
+		com, err = r.UpdateCompany(ctx, c.ID, models.CompanyProfile{Name: "tek systems", Location: "pune"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, (*models.Location)(nil), com.Headquarters)
+		assert.Equal(t, 0, len(com.Offices))
+		assert.Equal(t, "", com.Website)
+
+		_, err = r.UpdateCompany(ctx, c.ID, models.CompanyProfile{Name: "infy", Location: "pune"})
+		assert.Equal(t, true, errors.Is(err, gorm.ErrDuplicatedKey))
+
+		// Deleting takes the jobs along and frees the members
+		_, err = r.DeleteCompany(ctx, c.ID)
+		assert.Equal(t, nil, err)
+		_, err = r.GetCompanyByID(ctx, int(c.ID))
+		assert.Equal(t, true, isNotFound(err))
+		jobs, _ := r.ViewJobs(ctx)
+		assert.Equal(t, 0, len(jobs))
+		got, _ := r.GetUserByID(ctx, u.ID)
+		assert.Equal(t, (*uint)(nil), got.CompanyID)
+		_, err = r.DeleteCompany(ctx, c.ID)
+		assert.Equal(t, true, isNotFound(err))
+		_, err = r.UpdateCompany(ctx, c.ID, p)
+		assert.Equal(t, true, isNotFound(err))
+		all, _, _ = r.SearchCompanies(ctx, models.CompanyQuery{})
+		assert.Equal(t, 1, len(all))
+		assert.Equal(t, other.ID, all[0].ID)
+	})
+
+	t.Run("company locks", func(t *testing.T) {
+		r := newRepo(t)
+		u := register(t, r, "vishnu@example.com")
+		c, _ := r.CreateC(ctx, models.NewCompany{Name: "tek", Location: "bangalore"}, u.ID)
+
+		// Each change reads the profile and writes it back with one field
+		// changed, the lock keeps the second from writing a stale read
+		change := func(set func(p *models.CompanyProfile)) error {
+			return r.WithTx(ctx, func(tx Repository) error {
+				err := tx.LockCompany(ctx, c.ID)
+				if err != nil {
+					return err
+				}
+				com, err := tx.GetCompanyByID(ctx, int(c.ID))
+				if err != nil {
+					return err
+				}
+				p := com.Profile()
+				time.Sleep(20 * time.Millisecond)
+				set(&p)
+				_, err = tx.UpdateCompany(ctx, c.ID, p)
+				return err
+			})
+		}
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs[0] = change(func(p *models.CompanyProfile) { p.Website = "https://tek.example.com" })
+		}()
+		go func() {
+			defer wg.Done()
+			errs[1] = change(func(p *models.CompanyProfile) { p.Industry = "software" })
+		}()
+		wg.Wait()
+		assert.Equal(t, []error{nil, nil}, errs)
+		com, err := r.GetCompanyByID(ctx, int(c.ID))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "https://tek.example.com", com.Website)
+		assert.Equal(t, "software", com.Industry)
+	})
+
+	t.Run("company search", func(t *testing.T) {
+		r := newRepo(t)
+		u := register(t, r, "vishnu@example.com")
+		names := []string{"Tek Systems", "infotek", "Infosys", "acme_labs", "acme labs", "wipro"}
+		com := make([]models.Company, len(names))
+		for i, name := range names {
+			com[i], _ = r.CreateC(ctx, models.NewCompany{Name: name, Location: "bangalore"}, u.ID)
+		}
+		_, err := r.CreateJ(ctx, models.NewJob{Title: "go developer", JobLocations: []uint{1}}, int(com[2].ID))
+		assert.Equal(t, nil, err)
+		_, err = r.UpdateCompany(ctx, com[5].ID, models.CompanyProfile{Name: "wipro", Location: "Pune", Industry: "IT services"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, nil, r.SetCompanyVerified(ctx, com[1].ID, true))
+		r.softDelete(t, &models.Company{}, com[0].ID)
+		ids := func(cs []models.Company) []uint {
+			out := []uint{}
+			for _, c := range cs {
+				out = append(out, c.ID)
+			}
+			return out
+		}
+
+		// Names starting with the term come first
+		got, total, err := r.SearchCompanies(ctx, models.CompanyQuery{Q: " INF"})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, int64(2), total)
+		assert.Equal(t, []uint{com[2].ID, com[1].ID}, ids(got))
+		assert.Equal(t, int64(1), got[0].OpenJobs)
+		// Wildcards match themselves
+		got, _, _ = r.SearchCompanies(ctx, models.CompanyQuery{Q: "_"})
+		assert.Equal(t, []uint{com[3].ID}, ids(got))
+
+		got, _, _ = r.SearchCompanies(ctx, models.CompanyQuery{Location: "PUNE", Industry: "it services"})
+		assert.Equal(t, []uint{com[5].ID}, ids(got))
+		verified := true
+		got, _, _ = r.SearchCompanies(ctx, models.CompanyQuery{Verified: &verified})
+		assert.Equal(t, []uint{com[1].ID}, ids(got))
+
+		got, total, _ = r.SearchCompanies(ctx, models.CompanyQuery{Sort: "name", Page: 2, PerPage: 2})
+		assert.Equal(t, int64(5), total)
+		assert.Equal(t, []uint{com[2].ID, com[1].ID}, ids(got))
+		got, _, _ = r.SearchCompanies(ctx, models.CompanyQuery{Sort: "-created", Page: 3, PerPage: 2})
+		assert.Equal(t, []uint{com[1].ID}, ids(got))
+		got, _, _ = r.SearchCompanies(ctx, models.CompanyQuery{Sort: "-open_jobs", PerPage: 2})
+		assert.Equal(t, []uint{com[2].ID, com[1].ID}, ids(got))
+		got, total, _ = r.SearchCompanies(ctx, models.CompanyQuery{Page: 4, PerPage: 2})
+		assert.Equal(t, int64(5), total)
+		assert.Equal(t, 0, len(got))
+	})
+
+	t.Run("company media", func(t *testing.T) {
+		r := newRepo(t)
+		u := register(t, r, "vishnu@example.com")
+		c, _ := r.CreateC(ctx, models.NewCompany{Name: "tek", Location: "bangalore"}, u.ID)
+		other, _ := r.CreateC(ctx, models.NewCompany{Name: "infy", Location: "mysore"}, u.ID)
+		logo, err := r.AddCompanyMedia(ctx, models.CompanyMedia{CompanyID: c.ID, Kind: models.MediaLogo,
+			Key: "companies/1/logo.png", ContentType: "image/png", Size: 42, Width: 8, Height: 4})
+		assert.Equal(t, nil, err)
+		assert.NotEqual(t, uint(0), logo.ID)
+		photo, _ := r.AddCompanyMedia(ctx, models.CompanyMedia{CompanyID: c.ID, Kind: models.MediaPhoto, Key: "companies/1/office.jpg"})
+		_, _ = r.AddCompanyMedia(ctx, models.CompanyMedia{CompanyID: other.ID, Kind: models.MediaLogo, Key: "companies/2/logo.png"})
+		_, err = r.AddCompanyMedia(ctx, models.CompanyMedia{CompanyID: 42, Kind: models.MediaLogo, Key: "companies/42/logo.png"})
+		assert.Equal(t, true, errors.Is(err, gorm.ErrForeignKeyViolated))
+
+		media, err := r.CompanyMedia(ctx, c.ID)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2, len(media))
+		assert.Equal(t, "companies/1/logo.png", media[0].Key)
+		assert.Equal(t, 8, media[0].Width)
+
+		// Media are only deleted through their company
+		_, err = r.DeleteCompanyMedia(ctx, other.ID, photo.ID)
+		assert.Equal(t, true, isNotFound(err))
+		deleted, err := r.DeleteCompanyMedia(ctx, c.ID, photo.ID)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "companies/1/office.jpg", deleted.Key)
+		media, _ = r.CompanyMedia(ctx, c.ID)
+		assert.Equal(t, 1, len(media))
+		_, err = r.DeleteCompanyMedia(ctx, c.ID, photo.ID)
+		assert.Equal(t, true, isNotFound(err))
+
+		// Deleting the company takes its media along and returns them
+		deletedMedia, err := r.DeleteCompany(ctx, c.ID)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 1, len(deletedMedia))
+		assert.Equal(t, "companies/1/logo.png", deletedMedia[0].Key)
+		media, _ = r.CompanyMedia(ctx, c.ID)
+		assert.Equal(t, 0, len(media))
+		media, _ = r.CompanyMedia(ctx, other.ID)
+		assert.Equal(t, 1, len(media))
+	})
+
+	t.Run("company verification", func(t *testing.T) {
+		r := newRepo(t)
+		u := register(t, r, "vishnu@example.com")
+		c, _ := r.CreateC(ctx, models.NewCompany{Name: "tek", Location: "bangalore"}, u.ID)
+		other, _ := r.CreateC(ctx, models.NewCompany{Name: "infy", Location: "mysore"}, u.ID)
+		_, err := r.CreateJ(ctx, models.NewJob{Title: "go developer", JobLocations: []uint{1}}, int(c.ID))
+		assert.Equal(t, nil, err)
+		_, err = r.LatestCompanyVerification(ctx, c.ID)
+		assert.Equal(t, true, isNotFound(err))
+
+		first, err := r.SaveCompanyVerification(ctx, models.CompanyVerification{CompanyID: c.ID, RequestedBy: u.ID,
+			Email: "hr@tek.example.com", Status: models.VerificationEmailSent})
+		assert.Equal(t, nil, err)
+		assert.NotEqual(t, uint(0), first.ID)
+		first.Status = models.VerificationRejected
+		first.Reason = "not the real tek"
+		_, err = r.SaveCompanyVerification(ctx, first)
+		assert.Equal(t, nil, err)
+		second, _ := r.SaveCompanyVerification(ctx, models.CompanyVerification{CompanyID: c.ID, RequestedBy: u.ID,
+			Email: "ceo@tek.example.com", Status: models.VerificationPending})
+		_, _ = r.SaveCompanyVerification(ctx, models.CompanyVerification{CompanyID: other.ID, RequestedBy: u.ID,
+			Email: "hr@infy.example.com", Status: models.VerificationPending})
+		_, err = r.SaveCompanyVerification(ctx, models.CompanyVerification{CompanyID: 42, Email: "a@b.c", Status: models.VerificationPending})
+		assert.Equal(t, true, errors.Is(err, gorm.ErrForeignKeyViolated))
+
+		latest, err := r.LatestCompanyVerification(ctx, c.ID)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, second.ID, latest.ID)
+		got, err := r.GetCompanyVerification(ctx, first.ID)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "not the real tek", got.Reason)
+		_, err = r.GetCompanyVerification(ctx, 42)
+		assert.Equal(t, true, isNotFound(err))
+		pending, err := r.CompanyVerifications(ctx, models.VerificationPending)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2, len(pending))
+		assert.Equal(t, second.ID, pending[0].ID)
+		all, _ := r.CompanyVerifications(ctx, "")
+		assert.Equal(t, 3, len(all))
+
+		// The badge shows on the company and its jobs
+		assert.Equal(t, nil, r.SetCompanyVerified(ctx, c.ID, true))
+		com, _ := r.GetCompanyByID(ctx, int(c.ID))
+		assert.Equal(t, true, com.Verified)
+		assert.NotEqual(t, (*time.Time)(nil), com.VerifiedAt)
+		jobs, _ := r.ViewJobById(ctx, int(c.ID))
+		assert.Equal(t, true, jobs[0].Verified)
+		j, _ := r.GetJobById(ctx, int(jobs[0].ID))
+		assert.Equal(t, true, j.Verified)
+		j, _ = r.CreateJ(ctx, models.NewJob{Title: "java developer", JobLocations: []uint{1}}, int(c.ID))
+		assert.Equal(t, true, j.Verified)
+		com, _ = r.UpdateCompany(ctx, c.ID, models.CompanyProfile{Name: "tek", Location: "pune"})
+		assert.Equal(t, true, com.Verified)
+
+		assert.Equal(t, nil, r.SetCompanyVerified(ctx, c.ID, false))
+		com, _ = r.GetCompanyByID(ctx, int(c.ID))
+		assert.Equal(t, false, com.Verified)
+		assert.Equal(t, (*time.Time)(nil), com.VerifiedAt)
+		jobs, _ = r.ViewJobs(ctx)
+		assert.Equal(t, false, jobs[0].Verified)
+		assert.Equal(t, true, isNotFound(r.SetCompanyVerified(ctx, 42, true)))
+	})
+
+	t.Run("jobs", func(t *testing.T) {
+		r := newRepo(t)
+		u := register(t, r, "vishnu@example.com")
+		c, _ := r.CreateC(ctx, models.NewCompany{Name: "tek", Location: "bangalore"}, u.ID)
+		other, _ := r.CreateC(ctx, models.NewCompany{Name: "infy", Location: "mysore"}, u.ID)
+		nj := models.NewJob{
+			Title: "go developer", Description: "services", Min_NP: 10, Max_NP: 30, Budget: 1000, MinExp: 1, MaxExp: 3,
+			JobLocations: []uint{1, 2}, TechnologyStack: []uint{3}, WorkModes: []uint{1}, Qualifications: []uint{2},
+			WorkShifts: []uint{1}, JobTypes: []uint{1},
+		}
+		j, err := r.CreateJ(ctx, nj, int(c.ID))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, c.ID, j.CompanyID)
+		_, err = r.CreateJ(ctx, models.NewJob{Title: "java developer", JobLocations: []uint{2}}, int(other.ID))
+		assert.Equal(t, nil, err)
+
+		got, err := r.GetJobById(ctx, int(j.ID))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "go developer", got.Title)
+		assert.Equal(t, 2, len(got.JobLocations))
+		assert.Equal(t, uint(3), got.TechnologyStack[0].ID)
+		assert.Equal(t, uint(2), got.Qualifications[0].ID)
+		assert.Equal(t, 1, len(got.JobTypes))
+
+		all, err := r.ViewJobs(ctx)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2, len(all))
+		jobs, err := r.ViewJobById(ctx, int(c.ID))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 1, len(jobs))
+		assert.Equal(t, 1, len(jobs[0].Shifts))
+
+		r.softDelete(t, &models.Job{}, j.ID)
+		_, err = r.GetJobById(ctx, int(j.ID))
+		assert.Equal(t, true, isNotFound(err))
+		jobs, _ = r.ViewJobById(ctx, int(c.ID))
+		assert.Equal(t, 0, len(jobs))
+		all, _ = r.ViewJobs(ctx)
+		assert.Equal(t, 1, len(all))
+	})
+
+	t.Run("missing jobs", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.GetJobById(ctx, 42)
+		assert.Equal(t, true, isNotFound(err))
+		jobs, err := r.ViewJobById(ctx, 42)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 0, len(jobs))
+		// Jobs belong to an existing company
+		_, err = r.CreateJ(ctx, models.NewJob{Title: "go developer"}, 42)
+		assert.Equal(t, true, errors.Is(err, gorm.ErrForeignKeyViolated))
+	})
+
+	t.Run("mfa", func(t *testing.T) {
+		r := newRepo(t)
+		u := register(t, r, "vishnu@example.com")
+		assert.Equal(t, nil, r.SetTOTPPendingSecret(ctx, u.ID, "pending"))
+		assert.Equal(t, nil, r.EnableTOTP(ctx, u.ID, "pending", 10, []string{"a", "b"}))
+		got, _ := r.GetUserByID(ctx, u.ID)
+		assert.Equal(t, true, got.MFAEnabled)
+		assert.Equal(t, "pending", got.TOTPSecret)
+		assert.Equal(t, "", got.TOTPPendingSecret)
+
+		ok, _ := r.AdvanceTOTPStep(ctx, u.ID, 10)
+		assert.Equal(t, false, ok)
+		ok, _ = r.AdvanceTOTPStep(ctx, u.ID, 11)
+		assert.Equal(t, true, ok)
+
+		ok, _ = r.UseRecoveryCode(ctx, u.ID, "a")
+		assert.Equal(t, true, ok)
+		ok, _ = r.UseRecoveryCode(ctx, u.ID, "a")
+		assert.Equal(t, false, ok)
+
+		assert.Equal(t, nil, r.ReplaceRecoveryCodes(ctx, u.ID, []string{"c"}))
+		ok, _ = r.UseRecoveryCode(ctx, u.ID, "b")
+		assert.Equal(t, false, ok)
+
+		assert.Equal(t, nil, r.DisableTOTP(ctx, u.ID))
+		got, _ = r.GetUserByID(ctx, u.ID)
+		assert.Equal(t, false, got.MFAEnabled)
+		ok, _ = r.UseRecoveryCode(ctx, u.ID, "c")
+		assert.Equal(t, false, ok)
+		assert.Equal(t, nil, r.RecordAuditEvent(ctx, models.AuditEvent{Event: models.AuditMFADisabled, Email: u.Email}))
+	})
+
+	t.Run("tasks", func(t *testing.T) {
+		r := newRepo(t)
+		older, err := r.CreateTask(ctx, models.Task{ID: "0b6e0c52-8a1f-4c5d-9a40-3f1d4e2b7c01", UserID: 1,
+			Status: models.TaskPending, Total: 3, CreatedAt: time.Now().Add(-time.Minute)})
+		assert.Equal(t, nil, err)
+		task, err := r.CreateTask(ctx, models.Task{ID: "0b6e0c52-8a1f-4c5d-9a40-3f1d4e2b7c02", UserID: 1,
+			Status: models.TaskPending, Total: 3, Applications: []byte("[]")})
+		assert.Equal(t, nil, err)
+		_, err = r.CreateTask(ctx, models.Task{ID: task.ID, Status: models.TaskPending})
+		assert.Equal(t, true, errors.Is(err, gorm.ErrDuplicatedKey))
+
+		item := func(pos int, outcome string) models.TaskItem {
+			return models.TaskItem{ApplicationOutcome: models.ApplicationOutcome{Position: pos, Outcome: outcome}}
+		}
+		err = r.RecordTaskItems(ctx, task.ID, []models.TaskItem{item(1, models.OutcomeMatched), item(0, models.OutcomeFailed)})
+		assert.Equal(t, nil, err)
+		err = r.RecordTaskItems(ctx, task.ID, []models.TaskItem{item(1, models.OutcomeRejected)})
+		assert.Equal(t, true, errors.Is(err, gorm.ErrDuplicatedKey))
+		err = r.RecordTaskItems(ctx, task.ID, []models.TaskItem{item(2, models.OutcomeRejected)})
+		assert.Equal(t, nil, err)
+
+		got, err := r.GetTask(ctx, task.ID)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, models.TaskRunning, got.Status)
+		assert.Equal(t, 3, got.Processed)
+		assert.Equal(t, 1, got.Matched)
+		assert.Equal(t, 1, got.Failed)
+		assert.Equal(t, "[]", string(got.Applications))
+		assert.Equal(t, 3, len(got.Items))
+		for i, it := range got.Items {
+			assert.Equal(t, i, it.Position)
+		}
+
+		unfinished, err := r.UnfinishedTasks(ctx)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2, len(unfinished))
+		assert.Equal(t, older.ID, unfinished[0].ID)
+		// The rescan doesn't load the applications
+		assert.Equal(t, 0, len(unfinished[1].Applications))
+
+		// Only one instance holds a task until its lease ends
+		ok, err := r.ClaimTask(ctx, older.ID, "a", time.Now().Add(time.Minute))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, ok)
+		ok, _ = r.ClaimTask(ctx, older.ID, "b", time.Now().Add(time.Minute))
+		assert.Equal(t, false, ok)
+		ok, _ = r.ExtendTask(ctx, older.ID, "b", time.Now().Add(time.Minute))
+		assert.Equal(t, false, ok)
+		ok, err = r.ExtendTask(ctx, older.ID, "a", time.Now().Add(-time.Second))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, ok)
+		ok, _ = r.ClaimTask(ctx, older.ID, "b", time.Now().Add(time.Minute))
+		assert.Equal(t, true, ok)
+		got, _ = r.GetTask(ctx, older.ID)
+		assert.Equal(t, models.TaskRunning, got.Status)
+		assert.Equal(t, "b", got.Owner)
+		ok, _ = r.ExtendTask(ctx, older.ID, "a", time.Now().Add(time.Minute))
+		assert.Equal(t, false, ok)
+
+		assert.Equal(t, nil, r.FinishTask(ctx, task.ID, models.TaskFailed, "stopped"))
+		ok, _ = r.ClaimTask(ctx, task.ID, "a", time.Now().Add(time.Minute))
+		assert.Equal(t, false, ok)
+		got, _ = r.GetTask(ctx, task.ID)
+		assert.Equal(t, "stopped", got.Error)
+		unfinished, _ = r.UnfinishedTasks(ctx)
+		assert.Equal(t, 1, len(unfinished))
+	})
+
+	t.Run("missing tasks", func(t *testing.T) {
+		r := newRepo(t)
+		const id = "0b6e0c52-8a1f-4c5d-9a40-3f1d4e2b7c09"
+		_, err := r.GetTask(ctx, id)
+		assert.Equal(t, true, isNotFound(err))
+		err = r.RecordTaskItems(ctx, id, []models.TaskItem{{}})
+		assert.Equal(t, true, errors.Is(err, gorm.ErrForeignKeyViolated))
+		assert.Equal(t, nil, r.FinishTask(ctx, id, models.TaskDone, ""))
+	})
+
+	t.Run("transactions", func(t *testing.T) {
+		r := newRepo(t)
+		failed := errors.New("failed")
+		err := r.WithTx(ctx, func(tx Repository) error {
+			register(t, tx, "rolled@example.com")
+			return failed
+		})
+		assert.Equal(t, failed, err)
+		_, err = r.GetUserByEmail(ctx, "rolled@example.com")
+		assert.Equal(t, true, isNotFound(err))
+
+		err = r.WithTx(ctx, func(tx Repository) error {
+			register(t, tx, "outer@example.com")
+			_ = tx.WithTx(ctx, func(tx Repository) error {
+				register(t, tx, "inner@example.com")
+				return failed
+			})
+			return nil
+		})
+		assert.Equal(t, nil, err)
+		_, err = r.GetUserByEmail(ctx, "outer@example.com")
+		assert.Equal(t, nil, err)
+		_, err = r.GetUserByEmail(ctx, "inner@example.com")
+		assert.Equal(t, true, isNotFound(err))
+	})
+}
+
+func TestMemory_Contract(t *testing.T) {
+	testRepositoryContract(t, func(t *testing.T) contractRepo {
+		m := NewMemory()
+		return contractRepo{Repository: m, softDelete: func(t *testing.T, model any, id uint) {
+			deleted := gorm.DeletedAt{Time: time.Now(), Valid: true}
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			switch model.(type) {
+			case *models.User:
+				u := m.st.users[id]
+				u.DeletedAt = deleted
+				m.st.users[id] = u
+			case *models.Company:
+				c := m.st.companies[id]
+				c.DeletedAt = deleted
+				m.st.companies[id] = c
+			case *models.Job:
+				j := m.st.jobs[id]
+				j.DeletedAt = deleted
+				m.st.jobs[id] = j
+			default:
+				t.Fatalf("soft deleting %T", model)
+			}
+		}}
+	})
+}
+
+// TestConn_Contract runs against the Postgres database TEST_POSTGRES_DSN
+// points to. Every table in it is emptied.
+func TestConn_Contract(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN isn't set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = database.Migrate(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRepo(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testRepositoryContract(t, func(t *testing.T) contractRepo {
+		err := db.Exec("TRUNCATE users, companies, jobs, locations, technologies, work_modes, qualifications, " +
+			"shifts, job_types, audit_events, recovery_codes, tasks, task_items, company_media, company_verifications RESTART IDENTITY CASCADE").Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contractRepo{Repository: repo, softDelete: func(t *testing.T, model any, id uint) {
+			err := db.Delete(model, id).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+		}}
+	})
+}
+
+func TestConn_ContractSQLite(t *testing.T) {
+	testRepositoryContract(t, func(t *testing.T) contractRepo {
+		db, err := database.Open(database.Config{Driver: database.SQLite, DSN: ":memory:"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			sqlDB, _ := db.DB()
+			sqlDB.Close()
+		})
+		return contractRepo{Repository: &Conn{db: db}, softDelete: func(t *testing.T, model any, id uint) {
+			err := db.Delete(model, id).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+		}}
+	})
+}
@@ -2,8 +2,11 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"job-portal/internal/models"
+	"slices"
+
+	"gorm.io/gorm"
 )
 
 func (s *Conn) CreateJ(ctx context.Context, nj models.NewJob, cId int) (models.Job, error) {
@@ -48,16 +51,42 @@ func (s *Conn) CreateJ(ctx context.Context, nj models.NewJob, cId int) (models.J
 	tx := s.db.WithContext(ctx).Create(&job)
 
 	if tx.Error != nil {
-		return models.Job{}, errors.New("creation of job failed")
+		return models.Job{}, fmt.Errorf("creating job for company %d: %w", cId, tx.Error)
+	}
+	jobs := []models.Job{job}
+	err := markVerified(s.db.WithContext(ctx), jobs)
+	if err != nil {
+		return models.Job{}, err
 	}
 
-	return job, nil
+	return jobs[0], nil
 }
 
-func (s *Conn) ViewJobs() ([]models.Job, error) {
+// markVerified copies the verified badge of their companies onto jobs with
+// one query.
+func markVerified(db *gorm.DB, jobs []models.Job) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(jobs))
+	for i, j := range jobs {
+		ids[i] = j.CompanyID
+	}
+	var verified []uint
+	err := db.Model(&models.Company{}).Where("id IN ? AND verified", ids).Pluck("id", &verified).Error
+	if err != nil {
+		return fmt.Errorf("reading verified companies: %w", err)
+	}
+	for i := range jobs {
+		jobs[i].Verified = slices.Contains(verified, jobs[i].CompanyID)
+	}
+	return nil
+}
+
+func (s *Conn) ViewJobs(ctx context.Context) ([]models.Job, error) {
 	var jobs []models.Job
 
-	err := s.db.
+	err := s.db.WithContext(ctx).
 		Preload("JobLocations").
 		Preload("TechnologyStack").
 		Preload("WorkModes").
@@ -69,13 +98,17 @@ func (s *Conn) ViewJobs() ([]models.Job, error) {
 	if err != nil {
 		return []models.Job{}, err
 	}
+	err = markVerified(s.db.WithContext(ctx), jobs)
+	if err != nil {
+		return []models.Job{}, err
+	}
 
 	return jobs, nil
 }
 
-func (s *Conn) GetJobById(jId int) (models.Job, error) {
+func (s *Conn) GetJobById(ctx context.Context, jId int) (models.Job, error) {
 	var job models.Job
-	tx := s.db.
+	tx := s.db.WithContext(ctx).
 		Preload("JobLocations").
 		Preload("TechnologyStack").
 		Preload("WorkModes").
@@ -85,15 +118,20 @@ func (s *Conn) GetJobById(jId int) (models.Job, error) {
 		Where("ID = ?", jId)
 	err := tx.First(&job).Error
 	if err != nil {
-		return models.Job{}, errors.New("job not found")
+		return models.Job{}, fmt.Errorf("fetching job %d: %w", jId, notFound(err))
+	}
+	jobs := []models.Job{job}
+	err = markVerified(s.db.WithContext(ctx), jobs)
+	if err != nil {
+		return models.Job{}, fmt.Errorf("fetching job %d: %w", jId, err)
 	}
-	return job, nil
+	return jobs[0], nil
 }
 
-func (s *Conn) ViewJobById(cId int) ([]models.Job, error) {
+func (s *Conn) ViewJobById(ctx context.Context, cId int) ([]models.Job, error) {
 	var jobs []models.Job
 
-	tx := s.db.
+	tx := s.db.WithContext(ctx).
 		Preload("JobLocations").
 		Preload("TechnologyStack").
 		Preload("WorkModes").
@@ -103,25 +141,12 @@ func (s *Conn) ViewJobById(cId int) ([]models.Job, error) {
 	err := tx.Find(&jobs).Error
 
 	if err != nil {
-		return []models.Job{}, errors.New("no jobs for that company")
+		return []models.Job{}, fmt.Errorf("fetching jobs of company %d: %w", cId, err)
 	}
-
-	return jobs, nil
-}
-
-func (s *Conn) Process(jId int) (models.Job, error) {
-	var job models.Job
-	tx := s.db.
-		Preload("JobLocations").
-		Preload("TechnologyStack").
-		Preload("WorkModes").
-		Preload("Qualifications").
-		Preload("Shifts").
-		Preload("JobTypes").
-		Where("ID = ?", jId)
-	err := tx.First(&job).Error
+	err = markVerified(s.db.WithContext(ctx), jobs)
 	if err != nil {
-		return models.Job{}, err
+		return []models.Job{}, err
 	}
-	return job, nil
+
+	return jobs, nil
 }
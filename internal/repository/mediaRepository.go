@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"job-portal/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func (s *Conn) AddCompanyMedia(ctx context.Context, m models.CompanyMedia) (models.CompanyMedia, error) {
+	err := s.db.WithContext(ctx).Omit("Company").Create(&m).Error
+	if err != nil {
+		return models.CompanyMedia{}, fmt.Errorf("storing media of company %d: %w", m.CompanyID, err)
+	}
+	return m, nil
+}
+
+// CompanyMedia returns the media of a company in the order they were
+// uploaded.
+func (s *Conn) CompanyMedia(ctx context.Context, companyID uint) ([]models.CompanyMedia, error) {
+	media := []models.CompanyMedia{}
+	err := s.db.WithContext(ctx).Where("company_id = ?", companyID).Order("id").Find(&media).Error
+	if err != nil {
+		return nil, fmt.Errorf("fetching media of company %d: %w", companyID, err)
+	}
+	return media, nil
+}
+
+// DeleteCompanyMedia deletes a media record of a company and returns it, the
+// files stay in the storage.
+func (s *Conn) DeleteCompanyMedia(ctx context.Context, companyID uint, id uint) (models.CompanyMedia, error) {
+	var m models.CompanyMedia
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ? AND company_id = ?", id, companyID).First(&m).Error
+		if err != nil {
+			return notFound(err)
+		}
+		return tx.Delete(&m).Error
+	})
+	if err != nil {
+		return models.CompanyMedia{}, fmt.Errorf("deleting media %d: %w", id, err)
+	}
+	return m, nil
+}
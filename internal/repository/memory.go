@@ -0,0 +1,1169 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"job-portal/internal/models"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Memory keeps the records in process memory. It behaves like Conn on
+// Postgres, including soft deleted rows, the constraints of the schema and
+// the errors returned for missing records, so tests and local runs can use
+// it instead of a database.
+type Memory struct {
+	mu sync.RWMutex
+	st *memState
+}
+
+// memState holds the tables. Records are stored by value and never modified
+// in place, so a transaction can work on a shallow copy.
+type memState struct {
+	users map[uint]models.User
+	// Companies keep their offices as locations with only the id set, like
+	// jobs keep their associations.
+	companies map[uint]models.Company
+	media     map[uint]models.CompanyMedia
+	// verifications are the verification requests of companies.
+	verifications map[uint]models.CompanyVerification
+	// Jobs keep their associations as records with only the id set, they are
+	// resolved against the taxonomy tables on every read.
+	jobs           map[uint]models.Job
+	locations      map[uint]models.Location
+	technologies   map[uint]models.Technology
+	workModes      map[uint]models.WorkMode
+	qualifications map[uint]models.Qualification
+	shifts         map[uint]models.Shift
+	jobTypes       map[uint]models.JobType
+	audit          []models.AuditEvent
+	codes          []models.RecoveryCode
+	tasks          map[string]models.Task
+	// lastID is the last id handed out per table.
+	lastID map[string]uint
+}
+
+var _ Repository = (*Memory)(nil)
+
+func NewMemory() *Memory {
+	return &Memory{st: &memState{
+		users:          map[uint]models.User{},
+		companies:      map[uint]models.Company{},
+		media:          map[uint]models.CompanyMedia{},
+		verifications:  map[uint]models.CompanyVerification{},
+		jobs:           map[uint]models.Job{},
+		locations:      map[uint]models.Location{},
+		technologies:   map[uint]models.Technology{},
+		workModes:      map[uint]models.WorkMode{},
+		qualifications: map[uint]models.Qualification{},
+		shifts:         map[uint]models.Shift{},
+		jobTypes:       map[uint]models.JobType{},
+		tasks:          map[string]models.Task{},
+		lastID:         map[string]uint{},
+	}}
+}
+
+func (st *memState) clone() *memState {
+	return &memState{
+		users:          maps.Clone(st.users),
+		companies:      maps.Clone(st.companies),
+		media:          maps.Clone(st.media),
+		verifications:  maps.Clone(st.verifications),
+		jobs:           maps.Clone(st.jobs),
+		locations:      maps.Clone(st.locations),
+		technologies:   maps.Clone(st.technologies),
+		workModes:      maps.Clone(st.workModes),
+		qualifications: maps.Clone(st.qualifications),
+		shifts:         maps.Clone(st.shifts),
+		jobTypes:       maps.Clone(st.jobTypes),
+		audit:          slices.Clone(st.audit),
+		codes:          slices.Clone(st.codes),
+		tasks:          maps.Clone(st.tasks),
+		lastID:         maps.Clone(st.lastID),
+	}
+}
+
+func (st *memState) nextID(table string) uint {
+	st.lastID[table]++
+	return st.lastID[table]
+}
+
+// read runs fn with the tables locked for reading.
+func (m *Memory) read(ctx context.Context, fn func(st *memState) error) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return fn(m.st)
+}
+
+// write runs fn with the tables locked. fn checks everything that can fail
+// before it changes a table, so a failed call leaves no trace.
+func (m *Memory) write(ctx context.Context, fn func(st *memState) error) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return fn(m.st)
+}
+
+// WithTx runs fn on a copy of the tables that replaces them when fn returns
+// nil. Other calls wait until the transaction ends. Calling WithTx on the
+// repository handed to fn nests like a savepoint.
+func (m *Memory) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return m.write(ctx, func(st *memState) error {
+		tx := &Memory{st: st.clone()}
+		err := fn(tx)
+		if err != nil {
+			return err
+		}
+		m.st = tx.st
+		return nil
+	})
+}
+
+// live reports whether a record isn't soft deleted.
+func live(m gorm.Model) bool {
+	return !m.DeletedAt.Valid
+}
+
+// sortedByID returns the values of tab in the order of their ids.
+func sortedByID[T any](tab map[uint]T) []T {
+	ids := slices.Sorted(maps.Keys(tab))
+	vs := make([]T, len(ids))
+	for i, id := range ids {
+		vs[i] = tab[id]
+	}
+	return vs
+}
+
+func (st *memState) userByEmail(email string) (models.User, bool) {
+	email = models.NormalizeEmail(email)
+	for _, u := range sortedByID(st.users) {
+		if live(u.Model) && u.Email == email {
+			return u, true
+		}
+	}
+	return models.User{}, false
+}
+
+func (st *memState) liveUser(id uint) (models.User, bool) {
+	u, ok := st.users[id]
+	return u, ok && live(u.Model)
+}
+
+func (m *Memory) CreateU(ctx context.Context, nu models.NewUser) (models.User, error) {
+	hashedPass, err := bcrypt.GenerateFromPassword([]byte(nu.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, fmt.Errorf("generating password hash: %w", err)
+	}
+
+	u := models.User{
+		Name:         nu.Name,
+		Email:        models.NormalizeEmail(nu.Email),
+		PasswordHash: string(hashedPass),
+	}
+	err = m.write(ctx, func(st *memState) error {
+		// The unique email index only covers live accounts
+		_, taken := st.userByEmail(u.Email)
+		if taken {
+			return gorm.ErrDuplicatedKey
+		}
+		now := time.Now()
+		u.ID = st.nextID("users")
+		u.UserId = u.ID
+		u.CreatedAt, u.UpdatedAt = now, now
+		st.users[u.ID] = u
+		return nil
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+func (m *Memory) AuthenticateUser(ctx context.Context, email, password string) (jwt.RegisteredClaims, error) {
+	u, err := m.userByEmail(ctx, email)
+	if err != nil {
+		return jwt.RegisteredClaims{}, err
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
+	if err != nil {
+		return jwt.RegisteredClaims{}, err
+	}
+	return UserClaims(u), nil
+}
+
+// userByEmail returns the live user registered with email or ErrNotFound.
+func (m *Memory) userByEmail(ctx context.Context, email string) (models.User, error) {
+	var u models.User
+	err := m.read(ctx, func(st *memState) error {
+		var ok bool
+		u, ok = st.userByEmail(email)
+		if !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	return u, err
+}
+
+func (m *Memory) CheckUserEmail(ctx context.Context, email string) (bool, error) {
+	_, err := m.userByEmail(ctx, email)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *Memory) UpdateUserPassword(ctx context.Context, np models.Reset) (bool, error) {
+	_, err := m.userByEmail(ctx, np.Email)
+	if err != nil {
+		return false, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(np.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return false, err
+	}
+	err = m.write(ctx, func(st *memState) error {
+		u, ok := st.userByEmail(np.Email)
+		if !ok {
+			return ErrNotFound
+		}
+		now := time.Now()
+		u.PasswordHash = string(hashedPassword)
+		u.PasswordChangedAt = &now
+		u.UpdatedAt = now
+		st.users[u.ID] = u
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	u, err := m.userByEmail(ctx, email)
+	if err != nil {
+		return models.User{}, fmt.Errorf("fetching user %s: %w", email, err)
+	}
+	return u, nil
+}
+
+func (m *Memory) MarkUserVerified(ctx context.Context, email string) error {
+	return m.write(ctx, func(st *memState) error {
+		u, ok := st.userByEmail(email)
+		if !ok {
+			return fmt.Errorf("verifying user %s: %w", email, ErrNotFound)
+		}
+		u.Verified = true
+		u.UpdatedAt = time.Now()
+		st.users[u.ID] = u
+		return nil
+	})
+}
+
+// GrantAdmin makes the user registered with email an admin. Admins are
+// granted directly in the database, this stands in for that on Memory.
+func (m *Memory) GrantAdmin(ctx context.Context, email string) error {
+	return m.write(ctx, func(st *memState) error {
+		u, ok := st.userByEmail(email)
+		if !ok {
+			return fmt.Errorf("granting admin to %s: %w", email, ErrNotFound)
+		}
+		u.Admin = true
+		u.UpdatedAt = time.Now()
+		st.users[u.ID] = u
+		return nil
+	})
+}
+
+func (m *Memory) GetUserByID(ctx context.Context, id uint) (models.User, error) {
+	var u models.User
+	err := m.read(ctx, func(st *memState) error {
+		var ok bool
+		u, ok = st.liveUser(id)
+		if !ok {
+			return fmt.Errorf("fetching user %d: %w", id, ErrNotFound)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+// updateUser applies change to the live user with the given id.
+func (st *memState) updateUser(userID uint, change func(u *models.User)) error {
+	u, ok := st.liveUser(userID)
+	if !ok {
+		return fmt.Errorf("updating user %d: %w", userID, ErrNotFound)
+	}
+	change(&u)
+	u.UpdatedAt = time.Now()
+	st.users[u.ID] = u
+	return nil
+}
+
+func (m *Memory) SetTOTPPendingSecret(ctx context.Context, userID uint, secret string) error {
+	return m.write(ctx, func(st *memState) error {
+		return st.updateUser(userID, func(u *models.User) {
+			u.TOTPPendingSecret = secret
+		})
+	})
+}
+
+func (m *Memory) EnableTOTP(ctx context.Context, userID uint, secret string, step int64, recoveryHashes []string) error {
+	return m.write(ctx, func(st *memState) error {
+		err := st.updateUser(userID, func(u *models.User) {
+			u.MFAEnabled = true
+			u.TOTPSecret = secret
+			u.TOTPPendingSecret = ""
+			u.TOTPLastStep = step
+		})
+		if err != nil {
+			return err
+		}
+		st.replaceRecoveryCodes(userID, recoveryHashes)
+		return nil
+	})
+}
+
+func (m *Memory) DisableTOTP(ctx context.Context, userID uint) error {
+	return m.write(ctx, func(st *memState) error {
+		err := st.updateUser(userID, func(u *models.User) {
+			u.MFAEnabled = false
+			u.TOTPSecret = ""
+			u.TOTPPendingSecret = ""
+			u.TOTPLastStep = 0
+		})
+		if err != nil {
+			return err
+		}
+		st.replaceRecoveryCodes(userID, nil)
+		return nil
+	})
+}
+
+func (m *Memory) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	advanced := false
+	err := m.write(ctx, func(st *memState) error {
+		u, ok := st.liveUser(userID)
+		if !ok || u.TOTPLastStep >= step {
+			return nil
+		}
+		advanced = true
+		return st.updateUser(userID, func(u *models.User) {
+			u.TOTPLastStep = step
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	return advanced, nil
+}
+
+func (m *Memory) ReplaceRecoveryCodes(ctx context.Context, userID uint, hashes []string) error {
+	return m.write(ctx, func(st *memState) error {
+		st.replaceRecoveryCodes(userID, hashes)
+		return nil
+	})
+}
+
+func (m *Memory) UseRecoveryCode(ctx context.Context, userID uint, hash string) (bool, error) {
+	used := 0
+	err := m.write(ctx, func(st *memState) error {
+		now := time.Now()
+		for i, c := range st.codes {
+			if c.UserID == userID && c.Hash == hash && c.UsedAt == nil {
+				st.codes[i].UsedAt = &now
+				used++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return used == 1, nil
+}
+
+func (st *memState) replaceRecoveryCodes(userID uint, hashes []string) {
+	st.codes = slices.DeleteFunc(st.codes, func(c models.RecoveryCode) bool {
+		return c.UserID == userID
+	})
+	now := time.Now()
+	for _, h := range hashes {
+		st.codes = append(st.codes, models.RecoveryCode{
+			ID:        st.nextID("recovery_codes"),
+			CreatedAt: now,
+			UserID:    userID,
+			Hash:      h,
+		})
+	}
+}
+
+func (m *Memory) RecordAuditEvent(ctx context.Context, e models.AuditEvent) error {
+	return m.write(ctx, func(st *memState) error {
+		e.ID = st.nextID("audit_events")
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = time.Now()
+		}
+		st.audit = append(st.audit, e)
+		return nil
+	})
+}
+
+func (m *Memory) CreateC(ctx context.Context, nc models.NewCompany, userID uint) (models.Company, error) {
+	com := models.Company{
+		Name:     nc.Name,
+		Location: nc.Location,
+		OwnerID:  &userID,
+	}
+	err := m.write(ctx, func(st *memState) error {
+		if st.nameTaken(com.Name, 0) {
+			return gorm.ErrDuplicatedKey
+		}
+		now := time.Now()
+		com.ID = st.nextID("companies")
+		com.CreatedAt, com.UpdatedAt = now, now
+		st.companies[com.ID] = com
+		for _, j := range nc.Jobs {
+			j.CompanyID = com.ID
+			com.Jobs = append(com.Jobs, st.insertJob(j))
+		}
+
+		u, ok := st.liveUser(userID)
+		if ok && u.CompanyID == nil {
+			cId := com.ID
+			u.CompanyID = &cId
+			u.UpdatedAt = now
+			st.users[u.ID] = u
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Company{}, err
+	}
+	return com, nil
+}
+
+// nameTaken reports whether a company other than the one with id except
+// has the name. The unique name index covers soft deleted companies as well.
+func (st *memState) nameTaken(name string, except uint) bool {
+	for _, c := range st.companies {
+		if c.Name == name && c.ID != except {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCompany returns the company with its locations and open jobs.
+func (st *memState) loadCompany(c models.Company) models.Company {
+	c.Headquarters = nil
+	if c.HeadquartersID != nil {
+		l, ok := st.locations[*c.HeadquartersID]
+		if ok && live(l.Model) {
+			c.Headquarters = &l
+		}
+	}
+	c.Offices = preload(st.locations, c.Offices,
+		func(l models.Location) uint { return l.ID }, func(l models.Location) gorm.Model { return l.Model })
+	c.OpenJobs = 0
+	for _, j := range st.jobs {
+		if j.CompanyID == c.ID && live(j.Model) {
+			c.OpenJobs++
+		}
+	}
+	return c
+}
+
+// SearchCompanies matches names starting with or containing the query, like
+// Conn does without pg_trgm.
+func (m *Memory) SearchCompanies(ctx context.Context, q models.CompanyQuery) ([]models.Company, int64, error) {
+	term := strings.ToLower(strings.TrimSpace(q.Q))
+	com := []models.Company{}
+	err := m.read(ctx, func(st *memState) error {
+		for _, c := range sortedByID(st.companies) {
+			if !live(c.Model) || !strings.Contains(strings.ToLower(c.Name), term) ||
+				(q.Location != "" && !strings.EqualFold(c.Location, q.Location)) ||
+				(q.Industry != "" && !strings.EqualFold(c.Industry, q.Industry)) ||
+				(q.Verified != nil && c.Verified != *q.Verified) {
+				continue
+			}
+			com = append(com, st.loadCompany(c))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("searching companies: %w", err)
+	}
+	slices.SortStableFunc(com, companyOrder(q.Sort, term))
+
+	total := int64(len(com))
+	if q.PerPage > 0 {
+		from := min(q.Offset(), len(com))
+		com = com[from:min(from+q.PerPage, len(com))]
+	}
+	return com, total, nil
+}
+
+// companyOrder compares companies in the order Conn sorts them in, given
+// companies sorted by id.
+func companyOrder(sort string, term string) func(a, b models.Company) int {
+	name := func(c models.Company) string { return strings.ToLower(c.Name) }
+	desc := strings.HasPrefix(sort, "-")
+	reverse := func(n int) int {
+		if desc {
+			return -n
+		}
+		return n
+	}
+	switch strings.TrimPrefix(sort, "-") {
+	case "name":
+		return func(a, b models.Company) int { return reverse(cmp.Compare(name(a), name(b))) }
+	case "created":
+		return func(a, b models.Company) int {
+			return reverse(cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID)))
+		}
+	case "open_jobs":
+		return func(a, b models.Company) int { return reverse(cmp.Compare(a.OpenJobs, b.OpenJobs)) }
+	}
+	if sort == "" && term != "" {
+		prefixed := func(c models.Company) int {
+			if strings.HasPrefix(name(c), term) {
+				return 0
+			}
+			return 1
+		}
+		return func(a, b models.Company) int {
+			return cmp.Or(cmp.Compare(prefixed(a), prefixed(b)), cmp.Compare(name(a), name(b)))
+		}
+	}
+	return func(a, b models.Company) int { return 0 }
+}
+
+// GetCompanyByID wraps ErrNotFound when there is no live
+// company, like Conn does.
+// LockCompany only checks the company exists, transactions on Memory hold
+// the whole store.
+func (m *Memory) LockCompany(ctx context.Context, id uint) error {
+	err := m.read(ctx, func(st *memState) error {
+		c, ok := st.companies[id]
+		if !ok || !live(c.Model) {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("locking company %d: %w", id, err)
+	}
+	return nil
+}
+
+func (m *Memory) GetCompanyByID(ctx context.Context, uid int) (models.Company, error) {
+	var com models.Company
+	err := m.read(ctx, func(st *memState) error {
+		c, ok := st.companies[uint(uid)]
+		if !ok || !live(c.Model) {
+			return ErrNotFound
+		}
+		com = st.loadCompany(c)
+		return nil
+	})
+	if err != nil {
+		return models.Company{}, fmt.Errorf("fetching company %d: %w", uid, err)
+	}
+	return com, nil
+}
+
+func (m *Memory) SetCompanyMFA(ctx context.Context, companyID uint, required bool) error {
+	return m.write(ctx, func(st *memState) error {
+		c, ok := st.companies[companyID]
+		if !ok || !live(c.Model) {
+			return fmt.Errorf("updating company %d: %w", companyID, ErrNotFound)
+		}
+		c.RequireMFA = required
+		c.UpdatedAt = time.Now()
+		st.companies[c.ID] = c
+		return nil
+	})
+}
+
+func (m *Memory) UpdateCompany(ctx context.Context, id uint, p models.CompanyProfile) (models.Company, error) {
+	var com models.Company
+	err := m.write(ctx, func(st *memState) error {
+		c, ok := st.companies[id]
+		if !ok || !live(c.Model) {
+			return ErrNotFound
+		}
+		if st.nameTaken(p.Name, id) {
+			return gorm.ErrDuplicatedKey
+		}
+		offices := make([]models.Location, 0, len(p.OfficeIDs))
+		for _, lId := range p.OfficeIDs {
+			offices = append(offices, models.Location{ID: lId})
+		}
+		if p.HeadquartersID != nil {
+			upsert(st.locations, []models.Location{{ID: *p.HeadquartersID}}, func(l models.Location) uint { return l.ID })
+		}
+
+		c.Name = p.Name
+		c.Location = p.Location
+		c.Description = p.Description
+		c.Website = p.Website
+		c.Industry = p.Industry
+		c.SizeBand = p.SizeBand
+		c.FoundedYear = p.FoundedYear
+		c.SocialLinks = p.SocialLinks
+		c.HeadquartersID = p.HeadquartersID
+		c.Offices = upsert(st.locations, offices, func(l models.Location) uint { return l.ID })
+		c.UpdatedAt = time.Now()
+		st.companies[id] = c
+		com = st.loadCompany(c)
+		return nil
+	})
+	if err != nil {
+		return models.Company{}, fmt.Errorf("updating company %d: %w", id, err)
+	}
+	return com, nil
+}
+
+func (m *Memory) DeleteCompany(ctx context.Context, id uint) ([]models.CompanyMedia, error) {
+	var media []models.CompanyMedia
+	err := m.write(ctx, func(st *memState) error {
+		c, ok := st.companies[id]
+		if !ok || !live(c.Model) {
+			return fmt.Errorf("deleting company %d: %w", id, ErrNotFound)
+		}
+		deleted := gorm.DeletedAt{Time: time.Now(), Valid: true}
+		c.DeletedAt = deleted
+		st.companies[id] = c
+		for _, j := range st.jobs {
+			if j.CompanyID == id && live(j.Model) {
+				j.DeletedAt = deleted
+				st.jobs[j.ID] = j
+			}
+		}
+		for _, cm := range sortedByID(st.media) {
+			if cm.CompanyID == id {
+				media = append(media, cm)
+				delete(st.media, cm.ID)
+			}
+		}
+		for _, u := range st.users {
+			if live(u.Model) && u.CompanyID != nil && *u.CompanyID == id {
+				u.CompanyID = nil
+				u.UpdatedAt = deleted.Time
+				st.users[u.ID] = u
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+func (m *Memory) AddCompanyMedia(ctx context.Context, cm models.CompanyMedia) (models.CompanyMedia, error) {
+	err := m.write(ctx, func(st *memState) error {
+		// The foreign key ignores soft deletes
+		_, ok := st.companies[cm.CompanyID]
+		if !ok {
+			return gorm.ErrForeignKeyViolated
+		}
+		cm.ID = st.nextID("company_media")
+		if cm.CreatedAt.IsZero() {
+			cm.CreatedAt = time.Now()
+		}
+		st.media[cm.ID] = cm
+		return nil
+	})
+	if err != nil {
+		return models.CompanyMedia{}, fmt.Errorf("storing media of company %d: %w", cm.CompanyID, err)
+	}
+	return cm, nil
+}
+
+func (m *Memory) CompanyMedia(ctx context.Context, companyID uint) ([]models.CompanyMedia, error) {
+	media := []models.CompanyMedia{}
+	err := m.read(ctx, func(st *memState) error {
+		for _, cm := range sortedByID(st.media) {
+			if cm.CompanyID == companyID {
+				media = append(media, cm)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching media of company %d: %w", companyID, err)
+	}
+	return media, nil
+}
+
+func (m *Memory) DeleteCompanyMedia(ctx context.Context, companyID uint, id uint) (models.CompanyMedia, error) {
+	var cm models.CompanyMedia
+	err := m.write(ctx, func(st *memState) error {
+		var ok bool
+		cm, ok = st.media[id]
+		if !ok || cm.CompanyID != companyID {
+			return ErrNotFound
+		}
+		delete(st.media, id)
+		return nil
+	})
+	if err != nil {
+		return models.CompanyMedia{}, fmt.Errorf("deleting media %d: %w", id, err)
+	}
+	return cm, nil
+}
+
+func (m *Memory) SetCompanyVerified(ctx context.Context, companyID uint, verified bool) error {
+	return m.write(ctx, func(st *memState) error {
+		c, ok := st.companies[companyID]
+		if !ok || !live(c.Model) {
+			return fmt.Errorf("updating company %d: %w", companyID, ErrNotFound)
+		}
+		now := time.Now()
+		c.Verified = verified
+		c.VerifiedAt = nil
+		if verified {
+			c.VerifiedAt = &now
+		}
+		c.UpdatedAt = now
+		st.companies[c.ID] = c
+		return nil
+	})
+}
+
+func (m *Memory) SaveCompanyVerification(ctx context.Context, v models.CompanyVerification) (models.CompanyVerification, error) {
+	err := m.write(ctx, func(st *memState) error {
+		// The foreign key ignores soft deletes
+		_, ok := st.companies[v.CompanyID]
+		if !ok {
+			return gorm.ErrForeignKeyViolated
+		}
+		now := time.Now()
+		if v.ID == 0 {
+			v.ID = st.nextID("company_verifications")
+			v.CreatedAt = now
+		}
+		v.UpdatedAt = now
+		v.Company = models.Company{}
+		st.verifications[v.ID] = v
+		return nil
+	})
+	if err != nil {
+		return models.CompanyVerification{}, fmt.Errorf("storing verification of company %d: %w", v.CompanyID, err)
+	}
+	return v, nil
+}
+
+func (m *Memory) LatestCompanyVerification(ctx context.Context, companyID uint) (models.CompanyVerification, error) {
+	var v models.CompanyVerification
+	err := m.read(ctx, func(st *memState) error {
+		for _, cv := range st.verifications {
+			if cv.CompanyID == companyID && cv.ID > v.ID {
+				v = cv
+			}
+		}
+		if v.ID == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return models.CompanyVerification{}, fmt.Errorf("fetching verification of company %d: %w", companyID, err)
+	}
+	return v, nil
+}
+
+func (m *Memory) GetCompanyVerification(ctx context.Context, id uint) (models.CompanyVerification, error) {
+	var v models.CompanyVerification
+	err := m.read(ctx, func(st *memState) error {
+		var ok bool
+		v, ok = st.verifications[id]
+		if !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return models.CompanyVerification{}, fmt.Errorf("fetching verification %d: %w", id, err)
+	}
+	return v, nil
+}
+
+func (m *Memory) CompanyVerifications(ctx context.Context, status string) ([]models.CompanyVerification, error) {
+	vs := []models.CompanyVerification{}
+	err := m.read(ctx, func(st *memState) error {
+		for _, v := range sortedByID(st.verifications) {
+			if status == "" || v.Status == status {
+				vs = append(vs, v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching verifications: %w", err)
+	}
+	return vs, nil
+}
+
+func (m *Memory) CreateJ(ctx context.Context, nj models.NewJob, cId int) (models.Job, error) {
+	job := models.Job{
+		Title:       nj.Title,
+		Description: nj.Description,
+		CompanyID:   uint(cId),
+		Min_NP:      nj.Min_NP,
+		Max_NP:      nj.Max_NP,
+		Budget:      nj.Budget,
+		MinExp:      nj.MinExp,
+		MaxExp:      nj.MaxExp,
+	}
+	for _, id := range nj.JobLocations {
+		job.JobLocations = append(job.JobLocations, models.Location{ID: id})
+	}
+	for _, id := range nj.TechnologyStack {
+		job.TechnologyStack = append(job.TechnologyStack, models.Technology{ID: id})
+	}
+	for _, id := range nj.WorkModes {
+		job.WorkModes = append(job.WorkModes, models.WorkMode{ID: id})
+	}
+	for _, id := range nj.Qualifications {
+		job.Qualifications = append(job.Qualifications, models.Qualification{ID: id})
+	}
+	for _, id := range nj.WorkShifts {
+		job.Shifts = append(job.Shifts, models.Shift{ID: id})
+	}
+	for _, id := range nj.JobTypes {
+		job.JobTypes = append(job.JobTypes, models.JobType{ID: id})
+	}
+
+	err := m.write(ctx, func(st *memState) error {
+		// The foreign key ignores soft deletes
+		_, ok := st.companies[job.CompanyID]
+		if !ok {
+			return gorm.ErrForeignKeyViolated
+		}
+		job = st.insertJob(job)
+		job.Verified = st.companies[job.CompanyID].Verified
+		return nil
+	})
+	if err != nil {
+		return models.Job{}, fmt.Errorf("creating job for company %d: %w", cId, err)
+	}
+	return job, nil
+}
+
+// insertJob stores j and creates the taxonomy records it refers to that
+// don't exist yet, like gorm does for associations.
+func (st *memState) insertJob(j models.Job) models.Job {
+	now := time.Now()
+	j.ID = st.nextID("jobs")
+	j.CreatedAt, j.UpdatedAt = now, now
+	stored := j
+	stored.JobLocations = upsert(st.locations, j.JobLocations, func(l models.Location) uint { return l.ID })
+	stored.TechnologyStack = upsert(st.technologies, j.TechnologyStack, func(t models.Technology) uint { return t.ID })
+	stored.WorkModes = upsert(st.workModes, j.WorkModes, func(w models.WorkMode) uint { return w.ID })
+	stored.Qualifications = upsert(st.qualifications, j.Qualifications, func(q models.Qualification) uint { return q.ID })
+	stored.Shifts = upsert(st.shifts, j.Shifts, func(s models.Shift) uint { return s.ID })
+	stored.JobTypes = upsert(st.jobTypes, j.JobTypes, func(t models.JobType) uint { return t.ID })
+	st.jobs[j.ID] = stored
+	return j
+}
+
+// upsert adds the records of refs missing from tab and returns refs without
+// duplicates, the join tables hold every pair once.
+func upsert[T any](tab map[uint]T, refs []T, id func(T) uint) []T {
+	var out []T
+	seen := map[uint]bool{}
+	for _, r := range refs {
+		if seen[id(r)] {
+			continue
+		}
+		seen[id(r)] = true
+		_, ok := tab[id(r)]
+		if !ok {
+			tab[id(r)] = r
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// preload replaces the references in refs by the live records of tab.
+func preload[T any](tab map[uint]T, refs []T, id func(T) uint, model func(T) gorm.Model) []T {
+	out := []T{}
+	for _, r := range refs {
+		v, ok := tab[id(r)]
+		if ok && live(model(v)) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// loadJob returns the job with its associations and the badge of its
+// company.
+func (st *memState) loadJob(j models.Job) models.Job {
+	j.Verified = st.companies[j.CompanyID].Verified
+	j.JobLocations = preload(st.locations, j.JobLocations,
+		func(l models.Location) uint { return l.ID }, func(l models.Location) gorm.Model { return l.Model })
+	j.TechnologyStack = preload(st.technologies, j.TechnologyStack,
+		func(t models.Technology) uint { return t.ID }, func(t models.Technology) gorm.Model { return t.Model })
+	j.WorkModes = preload(st.workModes, j.WorkModes,
+		func(w models.WorkMode) uint { return w.ID }, func(w models.WorkMode) gorm.Model { return w.Model })
+	j.Qualifications = preload(st.qualifications, j.Qualifications,
+		func(q models.Qualification) uint { return q.ID }, func(q models.Qualification) gorm.Model { return q.Model })
+	j.Shifts = preload(st.shifts, j.Shifts,
+		func(s models.Shift) uint { return s.ID }, func(s models.Shift) gorm.Model { return s.Model })
+	j.JobTypes = preload(st.jobTypes, j.JobTypes,
+		func(t models.JobType) uint { return t.ID }, func(t models.JobType) gorm.Model { return t.Model })
+	return j
+}
+
+// findJobs returns the live jobs keep accepts with their associations.
+func (st *memState) findJobs(keep func(models.Job) bool) []models.Job {
+	jobs := []models.Job{}
+	for _, j := range sortedByID(st.jobs) {
+		if live(j.Model) && keep(j) {
+			jobs = append(jobs, st.loadJob(j))
+		}
+	}
+	return jobs
+}
+
+func (m *Memory) ViewJobs(ctx context.Context) ([]models.Job, error) {
+	var jobs []models.Job
+	err := m.read(ctx, func(st *memState) error {
+		jobs = st.findJobs(func(models.Job) bool { return true })
+		return nil
+	})
+	if err != nil {
+		return []models.Job{}, err
+	}
+	return jobs, nil
+}
+
+func (m *Memory) ViewJobById(ctx context.Context, cId int) ([]models.Job, error) {
+	var jobs []models.Job
+	err := m.read(ctx, func(st *memState) error {
+		jobs = st.findJobs(func(j models.Job) bool { return j.CompanyID == uint(cId) })
+		return nil
+	})
+	if err != nil {
+		return []models.Job{}, fmt.Errorf("fetching jobs of company %d: %w", cId, err)
+	}
+	return jobs, nil
+}
+
+func (m *Memory) GetJobById(ctx context.Context, jId int) (models.Job, error) {
+	var job models.Job
+	err := m.read(ctx, func(st *memState) error {
+		j, ok := st.jobs[uint(jId)]
+		if !ok || !live(j.Model) {
+			return ErrNotFound
+		}
+		job = st.loadJob(j)
+		return nil
+	})
+	if err != nil {
+		return models.Job{}, fmt.Errorf("fetching job %d: %w", jId, err)
+	}
+	return job, nil
+}
+
+func (m *Memory) CreateTask(ctx context.Context, t models.Task) (models.Task, error) {
+	err := m.write(ctx, func(st *memState) error {
+		_, ok := st.tasks[t.ID]
+		if ok {
+			return gorm.ErrDuplicatedKey
+		}
+		now := time.Now()
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = now
+		}
+		if t.UpdatedAt.IsZero() {
+			t.UpdatedAt = now
+		}
+		items, err := st.newTaskItems(t.ID, nil, t.Items)
+		if err != nil {
+			return err
+		}
+		t.Items = items
+		st.tasks[t.ID] = t
+		return nil
+	})
+	if err != nil {
+		return models.Task{}, fmt.Errorf("creating task: %w", err)
+	}
+	return t, nil
+}
+
+// newTaskItems returns items numbered and linked to the task, checking that
+// no position is taken by them or by existing.
+func (st *memState) newTaskItems(taskID string, existing, items []models.TaskItem) ([]models.TaskItem, error) {
+	taken := map[int]bool{}
+	for _, it := range existing {
+		taken[it.Position] = true
+	}
+	out := make([]models.TaskItem, len(items))
+	for i, it := range items {
+		if taken[it.Position] {
+			return nil, gorm.ErrDuplicatedKey
+		}
+		taken[it.Position] = true
+		it.ID = st.nextID("task_items")
+		it.TaskID = taskID
+		out[i] = it
+	}
+	return out, nil
+}
+
+func (m *Memory) GetTask(ctx context.Context, id string) (models.Task, error) {
+	var t models.Task
+	err := m.read(ctx, func(st *memState) error {
+		var ok bool
+		t, ok = st.tasks[id]
+		if !ok {
+			return ErrNotFound
+		}
+		t.Items = slices.SortedFunc(slices.Values(t.Items), func(a, b models.TaskItem) int {
+			return cmp.Compare(a.Position, b.Position)
+		})
+		return nil
+	})
+	if err != nil {
+		return models.Task{}, fmt.Errorf("fetching task %s: %w", id, err)
+	}
+	return t, nil
+}
+
+func (m *Memory) UnfinishedTasks(ctx context.Context) ([]models.Task, error) {
+	var tasks []models.Task
+	err := m.read(ctx, func(st *memState) error {
+		for _, t := range st.tasks {
+			if t.Status == models.TaskPending || t.Status == models.TaskRunning {
+				tasks = append(tasks, models.Task{ID: t.ID, CreatedAt: t.CreatedAt, Processed: t.Processed,
+					Owner: t.Owner, LeaseUntil: t.LeaseUntil})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching unfinished tasks: %w", err)
+	}
+	slices.SortFunc(tasks, func(a, b models.Task) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return tasks, nil
+}
+
+func (m *Memory) ClaimTask(ctx context.Context, id string, owner string, until time.Time) (bool, error) {
+	var ok bool
+	err := m.write(ctx, func(st *memState) error {
+		t, found := st.tasks[id]
+		if !found || (t.Status != models.TaskPending && t.Status != models.TaskRunning) ||
+			(t.LeaseUntil != nil && !t.LeaseUntil.Before(time.Now())) {
+			return nil
+		}
+		t.Owner = owner
+		t.LeaseUntil = &until
+		t.Status = models.TaskRunning
+		t.UpdatedAt = time.Now()
+		st.tasks[id] = t
+		ok = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("claiming task %s: %w", id, err)
+	}
+	return ok, nil
+}
+
+func (m *Memory) ExtendTask(ctx context.Context, id string, owner string, until time.Time) (bool, error) {
+	var ok bool
+	err := m.write(ctx, func(st *memState) error {
+		t, found := st.tasks[id]
+		if !found || t.Owner != owner || t.Status != models.TaskRunning {
+			return nil
+		}
+		t.LeaseUntil = &until
+		t.UpdatedAt = time.Now()
+		st.tasks[id] = t
+		ok = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("extending task %s: %w", id, err)
+	}
+	return ok, nil
+}
+
+func (m *Memory) RecordTaskItems(ctx context.Context, id string, items []models.TaskItem) error {
+	return m.write(ctx, func(st *memState) error {
+		t, ok := st.tasks[id]
+		if len(items) > 0 {
+			if !ok {
+				return fmt.Errorf("storing items of task %s: %w", id, gorm.ErrForeignKeyViolated)
+			}
+			added, err := st.newTaskItems(id, t.Items, items)
+			if err != nil {
+				return fmt.Errorf("storing items of task %s: %w", id, err)
+			}
+			// Never append to a slice another copy of the tables shares
+			t.Items = append(slices.Clip(t.Items), added...)
+		}
+		if !ok {
+			return nil
+		}
+		for _, it := range items {
+			switch it.Outcome {
+			case models.OutcomeMatched:
+				t.Matched++
+			case models.OutcomeFailed:
+				t.Failed++
+			}
+		}
+		t.Status = models.TaskRunning
+		t.Processed += len(items)
+		t.UpdatedAt = time.Now()
+		st.tasks[id] = t
+		return nil
+	})
+}
+
+func (m *Memory) FinishTask(ctx context.Context, id string, status string, reason string) error {
+	err := m.write(ctx, func(st *memState) error {
+		t, ok := st.tasks[id]
+		if !ok {
+			return nil
+		}
+		t.Status = status
+		t.Error = reason
+		t.UpdatedAt = time.Now()
+		st.tasks[id] = t
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("finishing task %s: %w", id, err)
+	}
+	return nil
+}
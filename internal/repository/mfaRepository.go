@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"job-portal/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SetTOTPPendingSecret stores the secret of an enrollment until it is
+// confirmed with a first code.
+func (s *Conn) SetTOTPPendingSecret(ctx context.Context, userID uint, secret string) error {
+	return s.updateUser(ctx, userID, map[string]any{"totp_pending_secret": secret})
+}
+
+// EnableTOTP activates secret for the user and replaces the recovery codes.
+func (s *Conn) EnableTOTP(ctx context.Context, userID uint, secret string, step int64, recoveryHashes []string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := (&Conn{db: tx}).updateUser(ctx, userID, map[string]any{
+			"mfa_enabled":         true,
+			"totp_secret":         secret,
+			"totp_pending_secret": "",
+			"totp_last_step":      step,
+		})
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, recoveryHashes)
+	})
+}
+
+// DisableTOTP removes the second factor and the recovery codes of a user.
+func (s *Conn) DisableTOTP(ctx context.Context, userID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := (&Conn{db: tx}).updateUser(ctx, userID, map[string]any{
+			"mfa_enabled":         false,
+			"totp_secret":         "",
+			"totp_pending_secret": "",
+			"totp_last_step":      0,
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// AdvanceTOTPStep records step as the last accepted one. It reports false
+// when a code of the same or a later step was accepted already, which makes
+// concurrent use of one code fail for all but one request.
+func (s *Conn) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	tx := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes drops the recovery codes of a user and stores new ones.
+func (s *Conn) ReplaceRecoveryCodes(ctx context.Context, userID uint, hashes []string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, hashes)
+	})
+}
+
+// UseRecoveryCode marks the unused recovery code of a user with the given
+// hash as used. It reports false when there is no such code, so a code can
+// be used only once even by concurrent requests.
+func (s *Conn) UseRecoveryCode(ctx context.Context, userID uint, hash string) (bool, error) {
+	tx := s.db.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected == 1, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, hashes []string) error {
+	err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	if err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	codes := make([]models.RecoveryCode, len(hashes))
+	for i, h := range hashes {
+		codes[i] = models.RecoveryCode{UserID: userID, Hash: h}
+	}
+	return tx.Create(&codes).Error
+}
+
+func (s *Conn) updateUser(ctx context.Context, userID uint, fields map[string]any) error {
+	tx := s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(fields)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return fmt.Errorf("updating user %d: %w", userID, ErrNotFound)
+	}
+	return nil
+}
@@ -3,32 +3,82 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"job-portal/internal/models"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
+// ErrNotFound is returned, wrapped, when the record asked for doesn't exist
+// or is soft deleted. It wraps gorm.ErrRecordNotFound.
+var ErrNotFound = fmt.Errorf("record not found: %w", gorm.ErrRecordNotFound)
+
+// notFound reports the missing records of gorm as ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, ErrNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
 type Conn struct {
 
 	// db is an instance of the SQLite database.
 	db *gorm.DB
+	// trigram is set when Postgres has pg_trgm, company search then matches
+	// similar names too.
+	trigram bool
 }
 
 //go:generate mockgen -source=repo.go -destination=repo_mock.go -package=repository
 type Repository interface {
+	// WithTx makes the calls fn issues on the repository it is handed
+	// atomic.
+	WithTx(ctx context.Context, fn func(Repository) error) error
 	CreateU(ctx context.Context, nu models.NewUser) (models.User, error)
 	AuthenticateUser(ctx context.Context, email string, password string) (jwt.RegisteredClaims, error)
 	CreateJ(ctx context.Context, nj models.NewJob, cId int) (models.Job, error)
-	ViewJobs() ([]models.Job, error)
-	GetJobById(jId int) (models.Job, error)
-	ViewJobById(cId int) ([]models.Job, error)
-	CreateC(ctx context.Context, nc models.NewCompany) (models.Company, error)
-	ViewCompanies() ([]models.Company, error)
-	GetCompanyByID(uid int) (models.Company, error)
-	Process(jId int) (models.Job, error)
-	CheckUserEmail(email string) (bool, error)
-	UpdateUserPassword(np models.Reset) (bool, error)
+	ViewJobs(ctx context.Context) ([]models.Job, error)
+	GetJobById(ctx context.Context, jId int) (models.Job, error)
+	ViewJobById(ctx context.Context, cId int) ([]models.Job, error)
+	CreateC(ctx context.Context, nc models.NewCompany, userID uint) (models.Company, error)
+	SearchCompanies(ctx context.Context, q models.CompanyQuery) ([]models.Company, int64, error)
+	GetCompanyByID(ctx context.Context, uid int) (models.Company, error)
+	// LockCompany holds the company row until the transaction it runs in
+	// ends, so changes read and written in it don't interleave.
+	LockCompany(ctx context.Context, id uint) error
+	UpdateCompany(ctx context.Context, id uint, p models.CompanyProfile) (models.Company, error)
+	DeleteCompany(ctx context.Context, id uint) ([]models.CompanyMedia, error)
+	AddCompanyMedia(ctx context.Context, m models.CompanyMedia) (models.CompanyMedia, error)
+	CompanyMedia(ctx context.Context, companyID uint) ([]models.CompanyMedia, error)
+	DeleteCompanyMedia(ctx context.Context, companyID uint, id uint) (models.CompanyMedia, error)
+	CheckUserEmail(ctx context.Context, email string) (bool, error)
+	UpdateUserPassword(ctx context.Context, np models.Reset) (bool, error)
+	RecordAuditEvent(ctx context.Context, e models.AuditEvent) error
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	MarkUserVerified(ctx context.Context, email string) error
+	GetUserByID(ctx context.Context, id uint) (models.User, error)
+	SetTOTPPendingSecret(ctx context.Context, userID uint, secret string) error
+	EnableTOTP(ctx context.Context, userID uint, secret string, step int64, recoveryHashes []string) error
+	DisableTOTP(ctx context.Context, userID uint) error
+	AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uint, hash string) (bool, error)
+	SetCompanyMFA(ctx context.Context, companyID uint, required bool) error
+	SetCompanyVerified(ctx context.Context, companyID uint, verified bool) error
+	SaveCompanyVerification(ctx context.Context, v models.CompanyVerification) (models.CompanyVerification, error)
+	LatestCompanyVerification(ctx context.Context, companyID uint) (models.CompanyVerification, error)
+	GetCompanyVerification(ctx context.Context, id uint) (models.CompanyVerification, error)
+	CompanyVerifications(ctx context.Context, status string) ([]models.CompanyVerification, error)
+	CreateTask(ctx context.Context, t models.Task) (models.Task, error)
+	GetTask(ctx context.Context, id string) (models.Task, error)
+	UnfinishedTasks(ctx context.Context) ([]models.Task, error)
+	ClaimTask(ctx context.Context, id string, owner string, until time.Time) (bool, error)
+	ExtendTask(ctx context.Context, id string, owner string, until time.Time) (bool, error)
+	RecordTaskItems(ctx context.Context, id string, items []models.TaskItem) error
+	FinishTask(ctx context.Context, id string, status string, reason string) error
 }
 
 // type RepoStore struct {
@@ -55,5 +105,12 @@ func NewRepo(db *gorm.DB) (Repository, error) {
 	if db == nil {
 		return nil, errors.New("db cannot be nil")
 	}
-	return &Conn{db: db}, nil
+	s := &Conn{db: db}
+	if db.Dialector.Name() == "postgres" {
+		err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')").Scan(&s.trigram).Error
+		if err != nil {
+			return nil, fmt.Errorf("looking for pg_trgm: %w", err)
+		}
+	}
+	return s, nil
 }
@@ -12,6 +12,7 @@ import (
 	context "context"
 	models "job-portal/internal/models"
 	reflect "reflect"
+	time "time"
 
 	jwt "github.com/golang-jwt/jwt/v5"
 	gomock "go.uber.org/mock/gomock"
@@ -40,6 +41,36 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddCompanyMedia mocks base method.
+func (m_2 *MockRepository) AddCompanyMedia(ctx context.Context, m models.CompanyMedia) (models.CompanyMedia, error) {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "AddCompanyMedia", ctx, m)
+	ret0, _ := ret[0].(models.CompanyMedia)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddCompanyMedia indicates an expected call of AddCompanyMedia.
+func (mr *MockRepositoryMockRecorder) AddCompanyMedia(ctx, m any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddCompanyMedia", reflect.TypeOf((*MockRepository)(nil).AddCompanyMedia), ctx, m)
+}
+
+// AdvanceTOTPStep mocks base method.
+func (m *MockRepository) AdvanceTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdvanceTOTPStep", ctx, userID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdvanceTOTPStep indicates an expected call of AdvanceTOTPStep.
+func (mr *MockRepositoryMockRecorder) AdvanceTOTPStep(ctx, userID, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdvanceTOTPStep", reflect.TypeOf((*MockRepository)(nil).AdvanceTOTPStep), ctx, userID, step)
+}
+
 // AuthenticateUser mocks base method.
 func (m *MockRepository) AuthenticateUser(ctx context.Context, email, password string) (jwt.RegisteredClaims, error) {
 	m.ctrl.T.Helper()
@@ -56,33 +87,78 @@ func (mr *MockRepositoryMockRecorder) AuthenticateUser(ctx, email, password any)
 }
 
 // CheckUserEmail mocks base method.
-func (m *MockRepository) CheckUserEmail(email string) (bool, error) {
+func (m *MockRepository) CheckUserEmail(ctx context.Context, email string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CheckUserEmail", email)
+	ret := m.ctrl.Call(m, "CheckUserEmail", ctx, email)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CheckUserEmail indicates an expected call of CheckUserEmail.
-func (mr *MockRepositoryMockRecorder) CheckUserEmail(email any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) CheckUserEmail(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckUserEmail", reflect.TypeOf((*MockRepository)(nil).CheckUserEmail), ctx, email)
+}
+
+// ClaimTask mocks base method.
+func (m *MockRepository) ClaimTask(ctx context.Context, id, owner string, until time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimTask", ctx, id, owner, until)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimTask indicates an expected call of ClaimTask.
+func (mr *MockRepositoryMockRecorder) ClaimTask(ctx, id, owner, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimTask", reflect.TypeOf((*MockRepository)(nil).ClaimTask), ctx, id, owner, until)
+}
+
+// CompanyMedia mocks base method.
+func (m *MockRepository) CompanyMedia(ctx context.Context, companyID uint) ([]models.CompanyMedia, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompanyMedia", ctx, companyID)
+	ret0, _ := ret[0].([]models.CompanyMedia)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompanyMedia indicates an expected call of CompanyMedia.
+func (mr *MockRepositoryMockRecorder) CompanyMedia(ctx, companyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompanyMedia", reflect.TypeOf((*MockRepository)(nil).CompanyMedia), ctx, companyID)
+}
+
+// CompanyVerifications mocks base method.
+func (m *MockRepository) CompanyVerifications(ctx context.Context, status string) ([]models.CompanyVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompanyVerifications", ctx, status)
+	ret0, _ := ret[0].([]models.CompanyVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompanyVerifications indicates an expected call of CompanyVerifications.
+func (mr *MockRepositoryMockRecorder) CompanyVerifications(ctx, status any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckUserEmail", reflect.TypeOf((*MockRepository)(nil).CheckUserEmail), email)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompanyVerifications", reflect.TypeOf((*MockRepository)(nil).CompanyVerifications), ctx, status)
 }
 
 // CreateC mocks base method.
-func (m *MockRepository) CreateC(ctx context.Context, nc models.NewCompany) (models.Company, error) {
+func (m *MockRepository) CreateC(ctx context.Context, nc models.NewCompany, userID uint) (models.Company, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateC", ctx, nc)
+	ret := m.ctrl.Call(m, "CreateC", ctx, nc, userID)
 	ret0, _ := ret[0].(models.Company)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateC indicates an expected call of CreateC.
-func (mr *MockRepositoryMockRecorder) CreateC(ctx, nc any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) CreateC(ctx, nc, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateC", reflect.TypeOf((*MockRepository)(nil).CreateC), ctx, nc)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateC", reflect.TypeOf((*MockRepository)(nil).CreateC), ctx, nc, userID)
 }
 
 // CreateJ mocks base method.
@@ -100,6 +176,21 @@ func (mr *MockRepositoryMockRecorder) CreateJ(ctx, nj, cId any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJ", reflect.TypeOf((*MockRepository)(nil).CreateJ), ctx, nj, cId)
 }
 
+// CreateTask mocks base method.
+func (m *MockRepository) CreateTask(ctx context.Context, t models.Task) (models.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTask", ctx, t)
+	ret0, _ := ret[0].(models.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTask indicates an expected call of CreateTask.
+func (mr *MockRepositoryMockRecorder) CreateTask(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTask", reflect.TypeOf((*MockRepository)(nil).CreateTask), ctx, t)
+}
+
 // CreateU mocks base method.
 func (m *MockRepository) CreateU(ctx context.Context, nu models.NewUser) (models.User, error) {
 	m.ctrl.T.Helper()
@@ -115,107 +206,441 @@ func (mr *MockRepositoryMockRecorder) CreateU(ctx, nu any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateU", reflect.TypeOf((*MockRepository)(nil).CreateU), ctx, nu)
 }
 
+// DeleteCompany mocks base method.
+func (m *MockRepository) DeleteCompany(ctx context.Context, id uint) ([]models.CompanyMedia, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCompany", ctx, id)
+	ret0, _ := ret[0].([]models.CompanyMedia)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteCompany indicates an expected call of DeleteCompany.
+func (mr *MockRepositoryMockRecorder) DeleteCompany(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCompany", reflect.TypeOf((*MockRepository)(nil).DeleteCompany), ctx, id)
+}
+
+// DeleteCompanyMedia mocks base method.
+func (m *MockRepository) DeleteCompanyMedia(ctx context.Context, companyID, id uint) (models.CompanyMedia, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCompanyMedia", ctx, companyID, id)
+	ret0, _ := ret[0].(models.CompanyMedia)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteCompanyMedia indicates an expected call of DeleteCompanyMedia.
+func (mr *MockRepositoryMockRecorder) DeleteCompanyMedia(ctx, companyID, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCompanyMedia", reflect.TypeOf((*MockRepository)(nil).DeleteCompanyMedia), ctx, companyID, id)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockRepository) DisableTOTP(ctx context.Context, userID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockRepositoryMockRecorder) DisableTOTP(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockRepository)(nil).DisableTOTP), ctx, userID)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockRepository) EnableTOTP(ctx context.Context, userID uint, secret string, step int64, recoveryHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, userID, secret, step, recoveryHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockRepositoryMockRecorder) EnableTOTP(ctx, userID, secret, step, recoveryHashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockRepository)(nil).EnableTOTP), ctx, userID, secret, step, recoveryHashes)
+}
+
+// ExtendTask mocks base method.
+func (m *MockRepository) ExtendTask(ctx context.Context, id, owner string, until time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtendTask", ctx, id, owner, until)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExtendTask indicates an expected call of ExtendTask.
+func (mr *MockRepositoryMockRecorder) ExtendTask(ctx, id, owner, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtendTask", reflect.TypeOf((*MockRepository)(nil).ExtendTask), ctx, id, owner, until)
+}
+
+// FinishTask mocks base method.
+func (m *MockRepository) FinishTask(ctx context.Context, id, status, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishTask", ctx, id, status, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishTask indicates an expected call of FinishTask.
+func (mr *MockRepositoryMockRecorder) FinishTask(ctx, id, status, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishTask", reflect.TypeOf((*MockRepository)(nil).FinishTask), ctx, id, status, reason)
+}
+
 // GetCompanyByID mocks base method.
-func (m *MockRepository) GetCompanyByID(uid int) (models.Company, error) {
+func (m *MockRepository) GetCompanyByID(ctx context.Context, uid int) (models.Company, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCompanyByID", uid)
+	ret := m.ctrl.Call(m, "GetCompanyByID", ctx, uid)
 	ret0, _ := ret[0].(models.Company)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetCompanyByID indicates an expected call of GetCompanyByID.
-func (mr *MockRepositoryMockRecorder) GetCompanyByID(uid any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetCompanyByID(ctx, uid any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompanyByID", reflect.TypeOf((*MockRepository)(nil).GetCompanyByID), uid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompanyByID", reflect.TypeOf((*MockRepository)(nil).GetCompanyByID), ctx, uid)
+}
+
+// GetCompanyVerification mocks base method.
+func (m *MockRepository) GetCompanyVerification(ctx context.Context, id uint) (models.CompanyVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCompanyVerification", ctx, id)
+	ret0, _ := ret[0].(models.CompanyVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCompanyVerification indicates an expected call of GetCompanyVerification.
+func (mr *MockRepositoryMockRecorder) GetCompanyVerification(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompanyVerification", reflect.TypeOf((*MockRepository)(nil).GetCompanyVerification), ctx, id)
 }
 
 // GetJobById mocks base method.
-func (m *MockRepository) GetJobById(jId int) (models.Job, error) {
+func (m *MockRepository) GetJobById(ctx context.Context, jId int) (models.Job, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetJobById", jId)
+	ret := m.ctrl.Call(m, "GetJobById", ctx, jId)
 	ret0, _ := ret[0].(models.Job)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetJobById indicates an expected call of GetJobById.
-func (mr *MockRepositoryMockRecorder) GetJobById(jId any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetJobById(ctx, jId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobById", reflect.TypeOf((*MockRepository)(nil).GetJobById), jId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobById", reflect.TypeOf((*MockRepository)(nil).GetJobById), ctx, jId)
 }
 
-// Process mocks base method.
-func (m *MockRepository) Process(jId int) (models.Job, error) {
+// GetTask mocks base method.
+func (m *MockRepository) GetTask(ctx context.Context, id string) (models.Task, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Process", jId)
-	ret0, _ := ret[0].(models.Job)
+	ret := m.ctrl.Call(m, "GetTask", ctx, id)
+	ret0, _ := ret[0].(models.Task)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Process indicates an expected call of Process.
-func (mr *MockRepositoryMockRecorder) Process(jId any) *gomock.Call {
+// GetTask indicates an expected call of GetTask.
+func (mr *MockRepositoryMockRecorder) GetTask(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Process", reflect.TypeOf((*MockRepository)(nil).Process), jId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTask", reflect.TypeOf((*MockRepository)(nil).GetTask), ctx, id)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockRepository) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", ctx, email)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockRepositoryMockRecorder) GetUserByEmail(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockRepository)(nil).GetUserByEmail), ctx, email)
+}
+
+// GetUserByID mocks base method.
+func (m *MockRepository) GetUserByID(ctx context.Context, id uint) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", ctx, id)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockRepositoryMockRecorder) GetUserByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockRepository)(nil).GetUserByID), ctx, id)
+}
+
+// LatestCompanyVerification mocks base method.
+func (m *MockRepository) LatestCompanyVerification(ctx context.Context, companyID uint) (models.CompanyVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LatestCompanyVerification", ctx, companyID)
+	ret0, _ := ret[0].(models.CompanyVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LatestCompanyVerification indicates an expected call of LatestCompanyVerification.
+func (mr *MockRepositoryMockRecorder) LatestCompanyVerification(ctx, companyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LatestCompanyVerification", reflect.TypeOf((*MockRepository)(nil).LatestCompanyVerification), ctx, companyID)
+}
+
+// LockCompany mocks base method.
+func (m *MockRepository) LockCompany(ctx context.Context, id uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockCompany", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockCompany indicates an expected call of LockCompany.
+func (mr *MockRepositoryMockRecorder) LockCompany(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockCompany", reflect.TypeOf((*MockRepository)(nil).LockCompany), ctx, id)
+}
+
+// MarkUserVerified mocks base method.
+func (m *MockRepository) MarkUserVerified(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkUserVerified", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkUserVerified indicates an expected call of MarkUserVerified.
+func (mr *MockRepositoryMockRecorder) MarkUserVerified(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkUserVerified", reflect.TypeOf((*MockRepository)(nil).MarkUserVerified), ctx, email)
+}
+
+// RecordAuditEvent mocks base method.
+func (m *MockRepository) RecordAuditEvent(ctx context.Context, e models.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAuditEvent", ctx, e)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAuditEvent indicates an expected call of RecordAuditEvent.
+func (mr *MockRepositoryMockRecorder) RecordAuditEvent(ctx, e any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAuditEvent", reflect.TypeOf((*MockRepository)(nil).RecordAuditEvent), ctx, e)
+}
+
+// RecordTaskItems mocks base method.
+func (m *MockRepository) RecordTaskItems(ctx context.Context, id string, items []models.TaskItem) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordTaskItems", ctx, id, items)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordTaskItems indicates an expected call of RecordTaskItems.
+func (mr *MockRepositoryMockRecorder) RecordTaskItems(ctx, id, items any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTaskItems", reflect.TypeOf((*MockRepository)(nil).RecordTaskItems), ctx, id, items)
+}
+
+// ReplaceRecoveryCodes mocks base method.
+func (m *MockRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, hashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRecoveryCodes", ctx, userID, hashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceRecoveryCodes indicates an expected call of ReplaceRecoveryCodes.
+func (mr *MockRepositoryMockRecorder) ReplaceRecoveryCodes(ctx, userID, hashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRecoveryCodes", reflect.TypeOf((*MockRepository)(nil).ReplaceRecoveryCodes), ctx, userID, hashes)
+}
+
+// SaveCompanyVerification mocks base method.
+func (m *MockRepository) SaveCompanyVerification(ctx context.Context, v models.CompanyVerification) (models.CompanyVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveCompanyVerification", ctx, v)
+	ret0, _ := ret[0].(models.CompanyVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveCompanyVerification indicates an expected call of SaveCompanyVerification.
+func (mr *MockRepositoryMockRecorder) SaveCompanyVerification(ctx, v any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveCompanyVerification", reflect.TypeOf((*MockRepository)(nil).SaveCompanyVerification), ctx, v)
+}
+
+// SearchCompanies mocks base method.
+func (m *MockRepository) SearchCompanies(ctx context.Context, q models.CompanyQuery) ([]models.Company, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchCompanies", ctx, q)
+	ret0, _ := ret[0].([]models.Company)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SearchCompanies indicates an expected call of SearchCompanies.
+func (mr *MockRepositoryMockRecorder) SearchCompanies(ctx, q any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchCompanies", reflect.TypeOf((*MockRepository)(nil).SearchCompanies), ctx, q)
+}
+
+// SetCompanyMFA mocks base method.
+func (m *MockRepository) SetCompanyMFA(ctx context.Context, companyID uint, required bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCompanyMFA", ctx, companyID, required)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCompanyMFA indicates an expected call of SetCompanyMFA.
+func (mr *MockRepositoryMockRecorder) SetCompanyMFA(ctx, companyID, required any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCompanyMFA", reflect.TypeOf((*MockRepository)(nil).SetCompanyMFA), ctx, companyID, required)
+}
+
+// SetCompanyVerified mocks base method.
+func (m *MockRepository) SetCompanyVerified(ctx context.Context, companyID uint, verified bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCompanyVerified", ctx, companyID, verified)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCompanyVerified indicates an expected call of SetCompanyVerified.
+func (mr *MockRepositoryMockRecorder) SetCompanyVerified(ctx, companyID, verified any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCompanyVerified", reflect.TypeOf((*MockRepository)(nil).SetCompanyVerified), ctx, companyID, verified)
+}
+
+// SetTOTPPendingSecret mocks base method.
+func (m *MockRepository) SetTOTPPendingSecret(ctx context.Context, userID uint, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTOTPPendingSecret", ctx, userID, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTOTPPendingSecret indicates an expected call of SetTOTPPendingSecret.
+func (mr *MockRepositoryMockRecorder) SetTOTPPendingSecret(ctx, userID, secret any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTOTPPendingSecret", reflect.TypeOf((*MockRepository)(nil).SetTOTPPendingSecret), ctx, userID, secret)
+}
+
+// UnfinishedTasks mocks base method.
+func (m *MockRepository) UnfinishedTasks(ctx context.Context) ([]models.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnfinishedTasks", ctx)
+	ret0, _ := ret[0].([]models.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnfinishedTasks indicates an expected call of UnfinishedTasks.
+func (mr *MockRepositoryMockRecorder) UnfinishedTasks(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnfinishedTasks", reflect.TypeOf((*MockRepository)(nil).UnfinishedTasks), ctx)
+}
+
+// UpdateCompany mocks base method.
+func (m *MockRepository) UpdateCompany(ctx context.Context, id uint, p models.CompanyProfile) (models.Company, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCompany", ctx, id, p)
+	ret0, _ := ret[0].(models.Company)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateCompany indicates an expected call of UpdateCompany.
+func (mr *MockRepositoryMockRecorder) UpdateCompany(ctx, id, p any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCompany", reflect.TypeOf((*MockRepository)(nil).UpdateCompany), ctx, id, p)
 }
 
 // UpdateUserPassword mocks base method.
-func (m *MockRepository) UpdateUserPassword(np models.Reset) (bool, error) {
+func (m *MockRepository) UpdateUserPassword(ctx context.Context, np models.Reset) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUserPassword", np)
+	ret := m.ctrl.Call(m, "UpdateUserPassword", ctx, np)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateUserPassword indicates an expected call of UpdateUserPassword.
-func (mr *MockRepositoryMockRecorder) UpdateUserPassword(np any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) UpdateUserPassword(ctx, np any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockRepository)(nil).UpdateUserPassword), np)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockRepository)(nil).UpdateUserPassword), ctx, np)
 }
 
-// ViewCompanies mocks base method.
-func (m *MockRepository) ViewCompanies() ([]models.Company, error) {
+// UseRecoveryCode mocks base method.
+func (m *MockRepository) UseRecoveryCode(ctx context.Context, userID uint, hash string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ViewCompanies")
-	ret0, _ := ret[0].([]models.Company)
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userID, hash)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ViewCompanies indicates an expected call of ViewCompanies.
-func (mr *MockRepositoryMockRecorder) ViewCompanies() *gomock.Call {
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockRepositoryMockRecorder) UseRecoveryCode(ctx, userID, hash any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ViewCompanies", reflect.TypeOf((*MockRepository)(nil).ViewCompanies))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockRepository)(nil).UseRecoveryCode), ctx, userID, hash)
 }
 
 // ViewJobById mocks base method.
-func (m *MockRepository) ViewJobById(cId int) ([]models.Job, error) {
+func (m *MockRepository) ViewJobById(ctx context.Context, cId int) ([]models.Job, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ViewJobById", cId)
+	ret := m.ctrl.Call(m, "ViewJobById", ctx, cId)
 	ret0, _ := ret[0].([]models.Job)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ViewJobById indicates an expected call of ViewJobById.
-func (mr *MockRepositoryMockRecorder) ViewJobById(cId any) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) ViewJobById(ctx, cId any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ViewJobById", reflect.TypeOf((*MockRepository)(nil).ViewJobById), cId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ViewJobById", reflect.TypeOf((*MockRepository)(nil).ViewJobById), ctx, cId)
 }
 
 // ViewJobs mocks base method.
-func (m *MockRepository) ViewJobs() ([]models.Job, error) {
+func (m *MockRepository) ViewJobs(ctx context.Context) ([]models.Job, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ViewJobs")
+	ret := m.ctrl.Call(m, "ViewJobs", ctx)
 	ret0, _ := ret[0].([]models.Job)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ViewJobs indicates an expected call of ViewJobs.
-func (mr *MockRepositoryMockRecorder) ViewJobs() *gomock.Call {
+func (mr *MockRepositoryMockRecorder) ViewJobs(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ViewJobs", reflect.TypeOf((*MockRepository)(nil).ViewJobs), ctx)
+}
+
+// WithTx mocks base method.
+func (m *MockRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockRepositoryMockRecorder) WithTx(ctx, fn any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ViewJobs", reflect.TypeOf((*MockRepository)(nil).ViewJobs))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockRepository)(nil).WithTx), ctx, fn)
 }
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"job-portal/internal/database"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/go-playground/assert.v1"
+	"gorm.io/gorm"
+)
+
+// The queries of a repository call made with the context of a request are
+// spans of the request trace.
+func TestConn_QueriesJoinRequestTrace(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	db, err := database.Open(database.Config{Driver: database.SQLite, DSN: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	r := &Conn{db: db}
+
+	ctx, request := tp.Tracer("test").Start(context.Background(), "GET /api/companies/:id")
+	_, err = r.GetCompanyByID(ctx, 42)
+	request.End()
+	assert.Equal(t, true, errors.Is(err, gorm.ErrRecordNotFound))
+
+	var queries int
+	for _, s := range sr.Ended() {
+		if !strings.HasPrefix(s.Name(), "gorm.") || s.SpanContext().TraceID() != request.SpanContext().TraceID() {
+			continue
+		}
+		queries++
+		assert.Equal(t, request.SpanContext().SpanID(), s.Parent().SpanID())
+	}
+	assert.Equal(t, true, queries > 0)
+}
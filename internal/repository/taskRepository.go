@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"job-portal/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateTask stores a new task together with its applications.
+func (s *Conn) CreateTask(ctx context.Context, t models.Task) (models.Task, error) {
+	err := s.db.WithContext(ctx).Create(&t).Error
+	if err != nil {
+		return models.Task{}, fmt.Errorf("creating task: %w", err)
+	}
+	return t, nil
+}
+
+// GetTask returns the task with its items in the order of the applications.
+func (s *Conn) GetTask(ctx context.Context, id string) (models.Task, error) {
+	var t models.Task
+	err := s.db.WithContext(ctx).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Where("id = ?", id).First(&t).Error
+	if err != nil {
+		return models.Task{}, fmt.Errorf("fetching task %s: %w", id, notFound(err))
+	}
+	return t, nil
+}
+
+// UnfinishedTasks returns the tasks that are pending or were interrupted,
+// oldest first. Only the id, the progress and the lease are loaded, not the
+// applications.
+func (s *Conn) UnfinishedTasks(ctx context.Context) ([]models.Task, error) {
+	var tasks []models.Task
+	err := s.db.WithContext(ctx).Select("id", "created_at", "processed", "owner", "lease_until").Where("status IN ?", []string{models.TaskPending, models.TaskRunning}).
+		Order("created_at").Find(&tasks).Error
+	if err != nil {
+		return nil, fmt.Errorf("fetching unfinished tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// ClaimTask makes owner the only instance running an unfinished task until
+// the lease ends. It reports false when the task finished or another
+// instance holds a lease that hasn't ended yet.
+func (s *Conn) ClaimTask(ctx context.Context, id string, owner string, until time.Time) (bool, error) {
+	res := s.db.WithContext(ctx).Model(&models.Task{}).
+		Where("id = ? AND status IN ?", id, []string{models.TaskPending, models.TaskRunning}).
+		Where("lease_until IS NULL OR lease_until < ?", time.Now()).
+		Updates(map[string]any{"owner": owner, "lease_until": until, "status": models.TaskRunning})
+	if res.Error != nil {
+		return false, fmt.Errorf("claiming task %s: %w", id, res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// ExtendTask moves the end of the lease owner holds on a running task. It
+// reports false when the task finished or another instance took it over.
+func (s *Conn) ExtendTask(ctx context.Context, id string, owner string, until time.Time) (bool, error) {
+	res := s.db.WithContext(ctx).Model(&models.Task{}).
+		Where("id = ? AND owner = ? AND status = ?", id, owner, models.TaskRunning).
+		Update("lease_until", until)
+	if res.Error != nil {
+		return false, fmt.Errorf("extending task %s: %w", id, res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// RecordTaskItems stores the outcomes of the next applications of a task and
+// advances its counters in the same transaction, so the counters always
+// match the stored items.
+func (s *Conn) RecordTaskItems(ctx context.Context, id string, items []models.TaskItem) error {
+	var matched, failed int
+	for i, it := range items {
+		items[i].TaskID = id
+		switch it.Outcome {
+		case models.OutcomeMatched:
+			matched++
+		case models.OutcomeFailed:
+			failed++
+		}
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(items) > 0 {
+			err := tx.Create(&items).Error
+			if err != nil {
+				return fmt.Errorf("storing items of task %s: %w", id, err)
+			}
+		}
+		err := tx.Model(&models.Task{}).Where("id = ?", id).Updates(map[string]any{
+			"status":    models.TaskRunning,
+			"processed": gorm.Expr("processed + ?", len(items)),
+			"matched":   gorm.Expr("matched + ?", matched),
+			"failed":    gorm.Expr("failed + ?", failed),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("updating task %s: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// FinishTask sets the final status of a task, reason explains a failure.
+func (s *Conn) FinishTask(ctx context.Context, id string, status string, reason string) error {
+	err := s.db.WithContext(ctx).Model(&models.Task{}).Where("id = ?", id).
+		Updates(map[string]any{"status": status, "error": reason}).Error
+	if err != nil {
+		return fmt.Errorf("finishing task %s: %w", id, err)
+	}
+	return nil
+}
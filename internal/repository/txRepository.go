@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// WithTx runs fn with a repository whose calls share one transaction. The
+// transaction commits when fn returns nil and rolls back when it returns an
+// error or panics, the panic is passed on. Calling WithTx on the repository
+// handed to fn nests a savepoint, so an inner failure the caller recovers
+// from only undoes the inner calls.
+func (s *Conn) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Conn{db: tx, trigram: s.trigram})
+	})
+}
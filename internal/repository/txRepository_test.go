@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"job-portal/internal/models"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTxTestConn(t *testing.T) *Conn {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One connection, every new one would open an empty database
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	err = db.AutoMigrate(&models.AuditEvent{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Conn{db: db}
+}
+
+func events(t *testing.T, s *Conn) []string {
+	var names []string
+	err := s.db.Model(&models.AuditEvent{}).Order("id").Pluck("event", &names).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestConn_WithTx(t *testing.T) {
+	ctx := context.Background()
+	record := func(r Repository, event string) error {
+		return r.RecordAuditEvent(ctx, models.AuditEvent{Event: event})
+	}
+
+	t.Run("commits", func(t *testing.T) {
+		s := newTxTestConn(t)
+		err := s.WithTx(ctx, func(r Repository) error {
+			return errors.Join(record(r, "a"), record(r, "b"))
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{"a", "b"}, events(t, s))
+	})
+
+	t.Run("rolls back on error", func(t *testing.T) {
+		s := newTxTestConn(t)
+		failed := errors.New("failed")
+		err := s.WithTx(ctx, func(r Repository) error {
+			_ = record(r, "a")
+			return failed
+		})
+		assert.Equal(t, failed, err)
+		assert.Equal(t, 0, len(events(t, s)))
+	})
+
+	t.Run("rolls back on panic", func(t *testing.T) {
+		s := newTxTestConn(t)
+		func() {
+			defer func() {
+				assert.Equal(t, "boom", recover())
+			}()
+			_ = s.WithTx(ctx, func(r Repository) error {
+				_ = record(r, "a")
+				panic("boom")
+			})
+		}()
+		assert.Equal(t, 0, len(events(t, s)))
+	})
+
+	t.Run("nested calls use savepoints", func(t *testing.T) {
+		s := newTxTestConn(t)
+		err := s.WithTx(ctx, func(r Repository) error {
+			_ = record(r, "outer")
+			// The caller recovers from the inner failure
+			_ = r.WithTx(ctx, func(r Repository) error {
+				_ = record(r, "inner")
+				return errors.New("failed")
+			})
+			return r.WithTx(ctx, func(r Repository) error {
+				return record(r, "second inner")
+			})
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{"outer", "second inner"}, events(t, s))
+	})
+}
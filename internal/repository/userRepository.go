@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"job-portal/internal/auth"
 	"job-portal/internal/models"
 	"strconv"
 	"time"
@@ -25,13 +26,14 @@ func (s *Conn) CreateU(ctx context.Context, nu models.NewUser) (models.User, err
 	// We prepare the User record.
 	u := models.User{
 		Name:         nu.Name,
-		Email:        nu.Email,
+		Email:        models.NormalizeEmail(nu.Email),
 		PasswordHash: string(hashedPass),
 		//CompanyID:    nu.CompanyID,
 	}
 
-	// We attempt to create the new User record in the database.
-	err = s.db.Create(&u).Error
+	// We attempt to create the new User record in the database. An email that
+	// is already taken fails with gorm.ErrDuplicatedKey.
+	err = s.db.WithContext(ctx).Create(&u).Error
 	if err != nil {
 		return models.User{}, err
 	}
@@ -47,9 +49,9 @@ func (s *Conn) AuthenticateUser(ctx context.Context, email, password string) (jw
 	// We attempt to find the User record where the email
 	// matches the provided email.
 	var u models.User
-	tx := s.db.Where("email = ?", email).First(&u)
+	tx := s.db.WithContext(ctx).Where("email = ?", models.NormalizeEmail(email)).First(&u)
 	if tx.Error != nil {
-		return jwt.RegisteredClaims{}, tx.Error
+		return jwt.RegisteredClaims{}, notFound(tx.Error)
 	}
 
 	// We check if the provided password matches the hashed password in the database.
@@ -59,21 +61,32 @@ func (s *Conn) AuthenticateUser(ctx context.Context, email, password string) (jw
 	}
 
 	// Successful authentication! Generate JWT claims.
-	c := jwt.RegisteredClaims{
+	return UserClaims(u), nil
+}
+
+// UserClaims returns the claims of a token issued to u. The audience carries
+// what the user is allowed to do next to the default audience.
+func UserClaims(u models.User) jwt.RegisteredClaims {
+	aud := jwt.ClaimStrings{"students"}
+	if u.Admin {
+		aud = append(aud, auth.AdminAudience)
+	}
+	if u.Verified {
+		aud = append(aud, auth.VerifiedAudience)
+	}
+	return jwt.RegisteredClaims{
 		Issuer:    "job-portal-api",
 		Subject:   strconv.FormatUint(uint64(u.ID), 10),
-		Audience:  jwt.ClaimStrings{"students"},
+		Audience:  aud,
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}
-
-	// And return those claims.
-	return c, nil
 }
-func (s *Conn) CheckUserEmail(email string) (bool, error) {
+
+func (s *Conn) CheckUserEmail(ctx context.Context, email string) (bool, error) {
 	// Query the database for a user with the specified email
 	var user models.User
-	result := s.db.Where("email = ?", email).First(&user)
+	result := s.db.WithContext(ctx).Where("email = ?", models.NormalizeEmail(email)).First(&user)
 
 	if result.Error == nil {
 		// If no error occurred, the user with the specified email exists
@@ -82,19 +95,19 @@ func (s *Conn) CheckUserEmail(email string) (bool, error) {
 
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		// If the error is ErrRecordNotFound, the user with the specified email does not exist
-		return false, result.Error
+		return false, ErrNotFound
 	}
 
 	// If there is an error other than ErrRecordNotFound, return the error
 	return false, result.Error
 }
 
-func (s *Conn) UpdateUserPassword(np models.Reset) (bool, error) {
+func (s *Conn) UpdateUserPassword(ctx context.Context, np models.Reset) (bool, error) {
 	var user models.User
-	result := s.db.Where("email = ?", np.Email).First(&user)
+	result := s.db.WithContext(ctx).Where("email = ?", models.NormalizeEmail(np.Email)).First(&user)
 
 	if result.Error != nil {
-		return false, result.Error
+		return false, notFound(result.Error)
 	}
 
 	// Hash the new password before updating it in the database
@@ -104,10 +117,44 @@ func (s *Conn) UpdateUserPassword(np models.Reset) (bool, error) {
 	}
 
 	// Update the user's password in the database
+	now := time.Now()
 	user.PasswordHash = string(hashedPassword)
-	if err := s.db.Save(&user).Error; err != nil {
+	user.PasswordChangedAt = &now
+	if err := s.db.WithContext(ctx).Save(&user).Error; err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
+
+// GetUserByEmail fetches the user registered with email.
+func (s *Conn) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	var u models.User
+	err := s.db.WithContext(ctx).Where("email = ?", models.NormalizeEmail(email)).First(&u).Error
+	if err != nil {
+		return models.User{}, fmt.Errorf("fetching user %s: %w", email, notFound(err))
+	}
+	return u, nil
+}
+
+// MarkUserVerified records that the user registered with email confirmed it.
+func (s *Conn) MarkUserVerified(ctx context.Context, email string) error {
+	tx := s.db.WithContext(ctx).Model(&models.User{}).Where("email = ?", models.NormalizeEmail(email)).Update("verified", true)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return fmt.Errorf("verifying user %s: %w", email, ErrNotFound)
+	}
+	return nil
+}
+
+// GetUserByID fetches a user by its id.
+func (s *Conn) GetUserByID(ctx context.Context, id uint) (models.User, error) {
+	var u models.User
+	err := s.db.WithContext(ctx).First(&u, id).Error
+	if err != nil {
+		return models.User{}, fmt.Errorf("fetching user %d: %w", id, notFound(err))
+	}
+	return u, nil
+}
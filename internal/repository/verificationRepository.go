@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"job-portal/internal/models"
+	"time"
+)
+
+// SetCompanyVerified sets the verified badge of a live company, the time it
+// was verified is kept while it is set.
+func (s *Conn) SetCompanyVerified(ctx context.Context, companyID uint, verified bool) error {
+	var at *time.Time
+	if verified {
+		now := time.Now()
+		at = &now
+	}
+	tx := s.db.WithContext(ctx).Model(&models.Company{}).Where("id = ?", companyID).
+		Updates(map[string]any{"verified": verified, "verified_at": at})
+	if tx.Error != nil {
+		return fmt.Errorf("updating company %d: %w", companyID, tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		return fmt.Errorf("updating company %d: %w", companyID, ErrNotFound)
+	}
+	return nil
+}
+
+// SaveCompanyVerification creates a verification request, or updates it when
+// it has an id.
+func (s *Conn) SaveCompanyVerification(ctx context.Context, v models.CompanyVerification) (models.CompanyVerification, error) {
+	err := s.db.WithContext(ctx).Omit("Company").Save(&v).Error
+	if err != nil {
+		return models.CompanyVerification{}, fmt.Errorf("storing verification of company %d: %w", v.CompanyID, err)
+	}
+	return v, nil
+}
+
+// LatestCompanyVerification returns the last verification request of a
+// company.
+func (s *Conn) LatestCompanyVerification(ctx context.Context, companyID uint) (models.CompanyVerification, error) {
+	var v models.CompanyVerification
+	err := s.db.WithContext(ctx).Where("company_id = ?", companyID).Order("id DESC").First(&v).Error
+	if err != nil {
+		return models.CompanyVerification{}, fmt.Errorf("fetching verification of company %d: %w", companyID, notFound(err))
+	}
+	return v, nil
+}
+
+func (s *Conn) GetCompanyVerification(ctx context.Context, id uint) (models.CompanyVerification, error) {
+	var v models.CompanyVerification
+	err := s.db.WithContext(ctx).Where("id = ?", id).First(&v).Error
+	if err != nil {
+		return models.CompanyVerification{}, fmt.Errorf("fetching verification %d: %w", id, notFound(err))
+	}
+	return v, nil
+}
+
+// CompanyVerifications returns the verification requests with the status,
+// every request when it is empty, oldest first.
+func (s *Conn) CompanyVerifications(ctx context.Context, status string) ([]models.CompanyVerification, error) {
+	vs := []models.CompanyVerification{}
+	tx := s.db.WithContext(ctx).Order("id")
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	err := tx.Find(&vs).Error
+	if err != nil {
+		return nil, fmt.Errorf("fetching verifications: %w", err)
+	}
+	return vs, nil
+}
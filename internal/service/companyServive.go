@@ -2,29 +2,176 @@ package service
 
 import (
 	"context"
+	"errors"
+	"job-portal/internal/invalidation"
 	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
 )
 
-func (r NewService) CreateCompany(ctx context.Context, ni models.NewCompany) (models.Company, error) {
-	c, err := r.rp.CreateC(ctx, ni)
+// CreateCompany creates a company, the user creating it becomes a member.
+func (r NewService) CreateCompany(ctx context.Context, ni models.NewCompany, userID uint) (models.Company, error) {
+	c, err := r.rp.CreateC(ctx, ni, userID)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return models.Company{}, newError(ErrConflict, CodeCompanyExists, "a company with this name already exists", err)
+	}
 	if err != nil {
 		return models.Company{}, err
 	}
 	return c, nil
 }
 
-func (r NewService) ViewCompany(ctx context.Context) ([]models.Company, error) {
-	c, err := r.rp.ViewCompanies()
+// DefaultCompaniesPerPage is the size of a page of the company list when
+// the query doesn't ask for one.
+const DefaultCompaniesPerPage = 20
+
+// ViewCompany returns the page of the company list the query asks for, the
+// first one by default.
+func (r NewService) ViewCompany(ctx context.Context, q models.CompanyQuery) (models.CompanyPage, error) {
+	q.Q = strings.TrimSpace(q.Q)
+	q.Page = max(q.Page, 1)
+	if q.PerPage <= 0 {
+		q.PerPage = DefaultCompaniesPerPage
+	}
+	c, total, err := r.rp.SearchCompanies(ctx, q)
 	if err != nil {
-		return []models.Company{}, err
+		return models.CompanyPage{}, err
 	}
-	return c, nil
+	return models.CompanyPage{Companies: c, Page: q.Page, PerPage: q.PerPage, Total: total}, nil
 }
 
 func (r NewService) GetCompanyInfoByID(ctx context.Context, uid int) (models.Company, error) {
-	c, err := r.rp.GetCompanyByID(uid)
+	c, err := r.rp.GetCompanyByID(ctx, uid)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Company{}, newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+	}
+	if err != nil {
+		return models.Company{}, err
+	}
+	return c, nil
+}
+
+// UpdateCompany replaces the profile of a company. Only its owner may.
+func (r NewService) UpdateCompany(ctx context.Context, companyID uint, userID uint, p models.CompanyProfile) (models.Company, error) {
+	return r.changeProfile(ctx, companyID, userID, func(models.Company) models.CompanyProfile {
+		return p
+	})
+}
+
+// PatchCompany changes the fields of the profile of a company the patch
+// carries. Only its owner may.
+func (r NewService) PatchCompany(ctx context.Context, companyID uint, userID uint, cp models.CompanyPatch) (models.Company, error) {
+	return r.changeProfile(ctx, companyID, userID, func(c models.Company) models.CompanyProfile {
+		return cp.Apply(c.Profile())
+	})
+}
+
+// changeProfile stores the profile built from the current company. The
+// company stays locked from reading to writing so concurrent patches don't
+// undo each other. A new name or website revokes the verification.
+func (r NewService) changeProfile(ctx context.Context, companyID uint, userID uint,
+	profile func(models.Company) models.CompanyProfile) (models.Company, error) {
+	var com models.Company
+	var revoked bool
+	var jobs []models.Job
+	err := r.inTx(ctx, func(r NewService) error {
+		err := r.rp.LockCompany(ctx, companyID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+		}
+		if err != nil {
+			return err
+		}
+		c, err := r.ownCompany(ctx, companyID, userID)
+		if err != nil {
+			return err
+		}
+		p := profile(c)
+		if p.FoundedYear > time.Now().Year() {
+			return newError(ErrValidation, CodeInvalidFoundedYear, "founded_year can't be in the future", nil)
+		}
+		com, err = r.rp.UpdateCompany(ctx, companyID, p)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return newError(ErrConflict, CodeCompanyExists, "a company with this name already exists", err)
+		}
+		if errors.Is(err, repository.ErrNotFound) {
+			return newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+		}
+		if err != nil {
+			return err
+		}
+		if c.Verified && (p.Name != c.Name || websiteDomain(p.Website) != websiteDomain(c.Website)) {
+			jobs, err = r.revokeVerification(ctx, c, userID)
+			if err != nil {
+				return err
+			}
+			revoked, com.Verified, com.VerifiedAt = true, false, nil
+		}
+		return nil
+	})
 	if err != nil {
 		return models.Company{}, err
 	}
+	if revoked {
+		r.companyJobsChanged(ctx, companyID, jobs)
+	}
+	return com, nil
+}
+
+// DeleteCompany deletes a company with its jobs and media. Only its owner
+// may. The files of the media are removed once the delete is committed.
+func (r NewService) DeleteCompany(ctx context.Context, companyID uint, userID uint) error {
+	var jobs []models.Job
+	var media []models.CompanyMedia
+	err := r.inTx(ctx, func(r NewService) error {
+		_, err := r.ownCompany(ctx, companyID, userID)
+		if err != nil {
+			return err
+		}
+		jobs, err = r.rp.ViewJobById(ctx, int(companyID))
+		if err != nil {
+			return err
+		}
+		media, err = r.rp.DeleteCompany(ctx, companyID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	r.companyJobsChanged(ctx, companyID, jobs)
+	for _, m := range media {
+		r.removeFiles(ctx, mediaKeys(m))
+	}
+	return nil
+}
+
+// companyJobsChanged drops the cached jobs of a company and its job list on
+// every instance.
+func (r NewService) companyJobsChanged(ctx context.Context, companyID uint, jobs []models.Job) {
+	for _, j := range jobs {
+		r.jobChanged(ctx, int(j.ID), int(companyID))
+	}
+	r.jobs.invalidate(ctx, 0, int(companyID))
+	r.publish(ctx, invalidation.Event{Type: invalidation.Company, ID: int(companyID)})
+}
+
+// ownCompany returns the company when the user owns it.
+func (r NewService) ownCompany(ctx context.Context, companyID uint, userID uint) (models.Company, error) {
+	c, err := r.rp.GetCompanyByID(ctx, int(companyID))
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Company{}, newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+	}
+	if err != nil {
+		return models.Company{}, err
+	}
+	if c.OwnerID == nil || *c.OwnerID != userID {
+		return models.Company{}, newError(ErrForbidden, CodeNotCompanyOwner, "only the owner can change a company", nil)
+	}
 	return c, nil
 }
@@ -1,14 +1,24 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"job-portal/internal/authstate"
+	"job-portal/internal/cache"
 	"job-portal/internal/models"
 	"job-portal/internal/repository"
+	"job-portal/internal/storage"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	gomock "go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+	"gorm.io/gorm"
 )
 
 func TestNewService_CreateCompany(t *testing.T) {
@@ -71,12 +81,12 @@ func TestNewService_CreateCompany(t *testing.T) {
 			mockRepo := repository.NewMockRepository(mc)
 
 			if tt.mockRepoResponse != nil {
-				mockRepo.EXPECT().CreateC(tt.args.ctx, tt.args.ni).Return(tt.mockRepoResponse()).AnyTimes()
+				mockRepo.EXPECT().CreateC(tt.args.ctx, tt.args.ni, uint(1)).Return(tt.mockRepoResponse()).AnyTimes()
 			}
 
-			s := NewServiceStore(mockRepo)
+			s := NewServiceStore(mockRepo, cache.NewMemory(), authstate.NewMemory())
 
-			got, err := s.CreateCompany(tt.args.ctx, tt.args.ni)
+			got, err := s.CreateCompany(tt.args.ctx, tt.args.ni, 1)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewService.CreateCompany() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -91,40 +101,50 @@ func TestNewService_CreateCompany(t *testing.T) {
 func TestNewService_ViewCompany(t *testing.T) {
 	type args struct {
 		ctx context.Context
+		q   models.CompanyQuery
 	}
 	tests := []struct {
 		name string
 		//r                NewService
-		want             []models.Company
+		want             models.CompanyPage
 		args             args
+		wantQuery        models.CompanyQuery
 		wantErr          bool
-		mockRepoResponse func() ([]models.Company, error)
+		mockRepoResponse func() ([]models.Company, int64, error)
 	}{
 		{
-			name:    "error from db",
-			want:    []models.Company{},
-			wantErr: true,
-			mockRepoResponse: func() ([]models.Company, error) {
-				return []models.Company{}, errors.New("test error")
+			name:      "error from db",
+			want:      models.CompanyPage{},
+			wantQuery: models.CompanyQuery{Page: 1, PerPage: DefaultCompaniesPerPage},
+			wantErr:   true,
+			mockRepoResponse: func() ([]models.Company, int64, error) {
+				return nil, 0, errors.New("test error")
 			},
 		},
 
 		{
 			name: "sucsess",
-			want: []models.Company{
-				models.Company{
-					Name:     "tcs",
-					Location: "banglore",
+			args: args{q: models.CompanyQuery{Q: " tc ", Sort: "name", Page: 2, PerPage: 1}},
+			want: models.CompanyPage{
+				Companies: []models.Company{
+					models.Company{
+						Name:     "tcs",
+						Location: "banglore",
+					},
 				},
+				Page:    2,
+				PerPage: 1,
+				Total:   2,
 			},
-			wantErr: false,
-			mockRepoResponse: func() ([]models.Company, error) {
+			wantQuery: models.CompanyQuery{Q: "tc", Sort: "name", Page: 2, PerPage: 1},
+			wantErr:   false,
+			mockRepoResponse: func() ([]models.Company, int64, error) {
 				return []models.Company{
 					models.Company{
 						Name:     "tcs",
 						Location: "banglore",
 					},
-				}, nil
+				}, 2, nil
 			},
 		},
 	}
@@ -136,12 +156,12 @@ func TestNewService_ViewCompany(t *testing.T) {
 			mockRepo := repository.NewMockRepository(mc)
 
 			if tt.mockRepoResponse != nil {
-				mockRepo.EXPECT().ViewCompanies().Return(tt.mockRepoResponse())
+				mockRepo.EXPECT().SearchCompanies(gomock.Any(), tt.wantQuery).Return(tt.mockRepoResponse())
 			}
 
-			s := NewServiceStore(mockRepo)
+			s := NewServiceStore(mockRepo, cache.NewMemory(), authstate.NewMemory())
 
-			got, err := s.ViewCompany(tt.args.ctx)
+			got, err := s.ViewCompany(tt.args.ctx, tt.args.q)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewService.ViewCompany() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -164,6 +184,7 @@ func TestNewService_GetCompanyInfoByID(t *testing.T) {
 		args             args
 		want             models.Company
 		wantErr          bool
+		wantCode         string
 		mockRepoResponse func() (models.Company, error)
 	}{
 		{
@@ -178,6 +199,18 @@ func TestNewService_GetCompanyInfoByID(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "missing company",
+			args: args{
+				uid: 999,
+			},
+			want: models.Company{},
+			mockRepoResponse: func() (models.Company, error) {
+				return models.Company{}, fmt.Errorf("fetching company 999: %w", repository.ErrNotFound)
+			},
+			wantErr:  true,
+			wantCode: CodeCompanyNotFound,
+		},
 
 		{
 			name: "success",
@@ -204,17 +237,147 @@ func TestNewService_GetCompanyInfoByID(t *testing.T) {
 			mockRepo := repository.NewMockRepository(mc)
 
 			if tt.mockRepoResponse != nil {
-				mockRepo.EXPECT().GetCompanyByID(tt.args.uid).Return(tt.mockRepoResponse()).AnyTimes()
+				mockRepo.EXPECT().GetCompanyByID(gomock.Any(), tt.args.uid).Return(tt.mockRepoResponse()).AnyTimes()
 			}
-			s := NewServiceStore(mockRepo)
+			s := NewServiceStore(mockRepo, cache.NewMemory(), authstate.NewMemory())
 			got, err := s.GetCompanyInfoByID(tt.args.ctx, tt.args.uid)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewService.GetCompanyInfoByID() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if errCode(err) != tt.wantCode {
+				t.Errorf("NewService.GetCompanyInfoByID() code = %v, wantCode %v", errCode(err), tt.wantCode)
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewService.GetCompanyInfoByID() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestNewService_PatchCompany(t *testing.T) {
+	owner, other := uint(1), uint(2)
+	hq := uint(3)
+	current := models.Company{
+		ID: 7, Name: "tek", Location: "bangalore", OwnerID: &owner, Website: "https://tek.example.com",
+		SizeBand: "11-50", HeadquartersID: &hq, Offices: []models.Location{{ID: 4}},
+	}
+	name, empty, noHQ, future := "tek systems", "", uint(0), time.Now().Year()+1
+	tests := []struct {
+		name     string
+		company  models.Company
+		patch    models.CompanyPatch
+		want     models.CompanyProfile
+		lockErr  error
+		repoErr  error
+		wantCode string
+	}{
+		{
+			name:    "changes the carried fields",
+			company: current,
+			patch:   models.CompanyPatch{Name: &name, Website: &empty, HeadquartersID: &noHQ},
+			want: models.CompanyProfile{Name: "tek systems", Location: "bangalore", SizeBand: "11-50",
+				OfficeIDs: []uint{4}},
+		},
+		{
+			name:     "not the owner",
+			company:  models.Company{ID: 7, Name: "tek", OwnerID: &other},
+			patch:    models.CompanyPatch{Name: &name},
+			wantCode: CodeNotCompanyOwner,
+		},
+		{
+			name:     "without owner",
+			company:  models.Company{ID: 7, Name: "tek"},
+			patch:    models.CompanyPatch{Name: &name},
+			wantCode: CodeNotCompanyOwner,
+		},
+		{
+			name:     "missing company",
+			patch:    models.CompanyPatch{Name: &name},
+			lockErr:  fmt.Errorf("locking company 7: %w", repository.ErrNotFound),
+			wantCode: CodeCompanyNotFound,
+		},
+		{
+			name:     "founded in the future",
+			company:  current,
+			patch:    models.CompanyPatch{FoundedYear: &future},
+			wantCode: CodeInvalidFoundedYear,
+		},
+		{
+			name:     "name taken",
+			company:  current,
+			patch:    models.CompanyPatch{Name: &name},
+			want:     models.CompanyProfile{Name: "tek systems"},
+			repoErr:  gorm.ErrDuplicatedKey,
+			wantCode: CodeCompanyExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mc := gomock.NewController(t)
+			ms := repository.NewMockRepository(mc)
+			s := &NewService{rp: ms}
+
+			expectTx(ms)
+			ms.EXPECT().LockCompany(ctx, uint(7)).Return(tt.lockErr)
+			if tt.lockErr == nil {
+				ms.EXPECT().GetCompanyByID(ctx, 7).Return(tt.company, nil)
+			}
+			if tt.want.Name != "" {
+				ms.EXPECT().UpdateCompany(ctx, uint(7), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ uint, p models.CompanyProfile) (models.Company, error) {
+						if tt.repoErr == nil {
+							assert.Equal(t, tt.want, p)
+						}
+						return models.Company{ID: 7, Name: p.Name}, tt.repoErr
+					})
+			}
+
+			com, err := s.PatchCompany(ctx, 7, owner, tt.patch)
+			assert.Equal(t, tt.wantCode, errCode(err))
+			if tt.wantCode == "" {
+				assert.Equal(t, "tek systems", com.Name)
+			}
+		})
+	}
+}
+
+func TestNewService_DeleteCompany(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	c := cache.NewMemory()
+	s := &NewService{rp: ms, jobs: newJobCache(c, 0)}
+	dir := t.TempDir()
+	files, err := storage.NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	WithStorage(files, storage.NewSigner([]byte("secret"), "/api/files/"), time.Minute)(s)
+	owner := uint(1)
+	logo := models.CompanyMedia{ID: 2, CompanyID: 7, Kind: models.MediaLogo, Key: "companies/7/logo.png"}
+	for _, key := range mediaKeys(logo) {
+		assert.Equal(t, nil, files.Put(ctx, key, bytes.NewReader([]byte("png")), "image/png"))
+	}
+
+	// The cached jobs and the stored media of the company go with it
+	assert.Equal(t, nil, c.Set(ctx, jobKey(5), []byte("{}"), time.Hour))
+	assert.Equal(t, nil, c.Set(ctx, companyJobsKey(7), []byte("[]"), time.Hour))
+	expectTx(ms)
+	ms.EXPECT().GetCompanyByID(ctx, 7).Return(models.Company{ID: 7, OwnerID: &owner}, nil).Times(2)
+	ms.EXPECT().ViewJobById(ctx, 7).Return([]models.Job{{ID: 5, CompanyID: 7}}, nil)
+	ms.EXPECT().DeleteCompany(ctx, uint(7)).Return([]models.CompanyMedia{logo}, nil)
+
+	assert.Equal(t, nil, s.DeleteCompany(ctx, 7, owner))
+	left, err := os.ReadDir(filepath.Join(dir, "companies", "7"))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(left))
+	_, err = c.Get(ctx, jobKey(5))
+	assert.Equal(t, true, errors.Is(err, cache.ErrMiss))
+	_, err = c.Get(ctx, companyJobsKey(7))
+	assert.Equal(t, true, errors.Is(err, cache.ErrMiss))
+
+	err = s.DeleteCompany(ctx, 7, 2)
+	assert.Equal(t, CodeNotCompanyOwner, errCode(err))
+}
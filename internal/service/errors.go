@@ -0,0 +1,85 @@
+package service
+
+import "errors"
+
+// Sentinel kinds every service error is classified under. Callers test for
+// them with errors.Is and never need to look at the message.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrTooManyRequests means the caller has to wait before trying again.
+	ErrTooManyRequests = errors.New("too many requests")
+	// ErrTooLarge and ErrUnsupportedMedia refuse uploaded files.
+	ErrTooLarge         = errors.New("too large")
+	ErrUnsupportedMedia = errors.New("unsupported media type")
+)
+
+// Stable, machine readable codes returned to API clients.
+const (
+	CodeJobNotFound          = "job_not_found"
+	CodeCompanyNotFound      = "company_not_found"
+	CodeEmailNotRegistered   = "email_not_registered"
+	CodeEmailExists          = "email_exists"
+	CodeCompanyExists        = "company_exists"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeInvalidOTP           = "invalid_otp"
+	CodeOTPLocked            = "otp_locked"
+	CodeOTPCooldown          = "otp_cooldown"
+	CodeAccountLocked        = "account_locked"
+	CodeEmailNotVerified     = "email_not_verified"
+	CodeEmailVerified        = "email_already_verified"
+	CodeUserNotFound         = "user_not_found"
+	CodeInvalidMFACode       = "invalid_mfa_code"
+	CodeMFALocked            = "mfa_locked"
+	CodeMFANotEnrolled       = "mfa_not_enrolled"
+	CodeMFAEnabled           = "mfa_already_enabled"
+	CodeMFARequired          = "mfa_required"
+	CodeNotCompanyOwner      = "not_company_owner"
+	CodeInvalidFoundedYear   = "invalid_founded_year"
+	CodeCompanyVerified      = "company_already_verified"
+	CodeWebsiteMissing       = "company_website_missing"
+	CodeEmailNotOnDomain     = "email_not_on_domain"
+	CodeVerificationNotFound = "verification_not_found"
+	CodeVerificationPending  = "verification_pending"
+	CodeVerificationReviewed = "verification_already_reviewed"
+	CodeReasonRequired       = "reason_required"
+	CodeInvalidStatus        = "invalid_status"
+	CodeFileTooLarge         = "file_too_large"
+	CodeUnsupportedMedia     = "unsupported_media_type"
+	CodeMediaNotFound        = "media_not_found"
+	CodeFileNotFound         = "file_not_found"
+	CodeInvalidSignature     = "invalid_signature"
+	CodeTaskNotFound         = "task_not_found"
+	CodeTaskQueueFull        = "task_queue_full"
+)
+
+// Error is a domain error returned by the service layer. Kind is one of the
+// sentinels above, Code identifies the exact failure and Message is safe to
+// show to clients. Err keeps the underlying cause for logging.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+func newError(kind error, code, message string, err error) *Error {
+	return &Error{Kind: kind, Code: code, Message: message, Err: err}
+}
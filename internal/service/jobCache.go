@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"job-portal/internal/cache"
+	"job-portal/internal/invalidation"
+	"job-portal/internal/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+)
+
+// jobCacheVersion is part of every key, bump it whenever the cached models
+// change shape so entries written by older builds are never decoded.
+const jobCacheVersion = "v2"
+
+// defaultJobCacheTTL bounds how stale an entry can get when an invalidation
+// is lost.
+const defaultJobCacheTTL = time.Hour
+
+func jobKey(jId int) string {
+	return "jobs:" + jobCacheVersion + ":job:" + strconv.Itoa(jId)
+}
+
+func companyJobsKey(cId int) string {
+	return "jobs:" + jobCacheVersion + ":company:" + strconv.Itoa(cId)
+}
+
+// jobCache reads jobs through the cache. Concurrent misses of the same key
+// share one database query.
+type jobCache struct {
+	c     cache.Cache
+	ttl   time.Duration
+	group singleflight.Group
+
+	mu sync.Mutex
+	// loading has an entry for every key being loaded, it turns true when
+	// the key is invalidated during the load. The loaded value may predate
+	// the change then and isn't cached.
+	loading map[string]bool
+}
+
+func newJobCache(c cache.Cache, ttl time.Duration) *jobCache {
+	if ttl <= 0 {
+		ttl = defaultJobCacheTTL
+	}
+	return &jobCache{c: c, ttl: ttl, loading: map[string]bool{}}
+}
+
+// job returns the job with its criteria, calling load on a miss.
+func (jc *jobCache) job(ctx context.Context, jId int, load func(context.Context, int) (models.Job, error)) (models.Job, error) {
+	var job models.Job
+	err := jc.get(ctx, jobKey(jId), &job, func(ctx context.Context) (any, error) {
+		return load(ctx, jId)
+	})
+	return job, err
+}
+
+// job reads a job through the cache. Every reader goes through the same
+// loader so the entry looks the same whoever filled it.
+func (r NewService) job(ctx context.Context, jId int) (models.Job, error) {
+	return r.jobs.job(ctx, jId, r.rp.GetJobById)
+}
+
+// companyJobs returns the jobs of a company, calling load on a miss.
+func (jc *jobCache) companyJobs(ctx context.Context, cId int, load func(context.Context, int) ([]models.Job, error)) ([]models.Job, error) {
+	var jobs []models.Job
+	err := jc.get(ctx, companyJobsKey(cId), &jobs, func(ctx context.Context) (any, error) {
+		return load(ctx, cId)
+	})
+	return jobs, err
+}
+
+// invalidate drops the cached job and the job list of its company. It is
+// called after every write to a job or its associations.
+func (jc *jobCache) invalidate(ctx context.Context, jId int, cId int) {
+	keys := []string{companyJobsKey(cId)}
+	if jId > 0 {
+		keys = append(keys, jobKey(jId))
+	}
+	jc.mu.Lock()
+	for _, k := range keys {
+		if _, ok := jc.loading[k]; ok {
+			jc.loading[k] = true
+		}
+	}
+	jc.mu.Unlock()
+	err := jc.c.Delete(ctx, keys...)
+	if err != nil {
+		// The entries expire on their own, until then reads are stale
+		log.Error().Err(err).Strs("keys", keys).Msg("invalidating cached jobs")
+	}
+}
+
+// jobChanged drops the cached job and the job list of its company on every
+// instance.
+func (r NewService) jobChanged(ctx context.Context, jId int, cId int) {
+	r.jobs.invalidate(ctx, jId, cId)
+	r.publish(ctx, invalidation.Event{Type: invalidation.Job, ID: jId, CompanyID: cId})
+}
+
+// publish announces a change to the other instances. Their local copies
+// expire soon anyway, so a failure is only logged.
+func (r NewService) publish(ctx context.Context, e invalidation.Event) {
+	if r.bus == nil {
+		return
+	}
+	err := r.bus.Publish(ctx, e)
+	if err != nil {
+		log.Error().Err(err).Str("type", e.Type).Msg("publishing invalidation")
+	}
+}
+
+// Evict returns the handler of the invalidation bus that drops the local
+// copies an event makes stale.
+func Evict(l *cache.Layered) func(invalidation.Event) {
+	return func(e invalidation.Event) {
+		ctx := context.Background()
+		switch e.Type {
+		case invalidation.Job:
+			l.Evict(ctx, jobKey(e.ID), companyJobsKey(e.CompanyID))
+		case invalidation.Company:
+			l.Evict(ctx, companyJobsKey(e.ID))
+		default:
+			// Taxonomy is embedded in every job and events may have been
+			// lost while disconnected
+			l.Purge()
+		}
+	}
+}
+
+// get decodes the entry under key into dst. On a miss load runs once for all
+// concurrent callers and its result is cached unless the key was invalidated
+// in the meantime. A failing cache only costs the query, errors of load are
+// never cached. A load racing a write on another instance may still cache
+// the old value until the ttl expires.
+func (jc *jobCache) get(ctx context.Context, key string, dst any, load func(context.Context) (any, error)) error {
+	b, err := jc.c.Get(ctx, key)
+	if err == nil {
+		err = json.Unmarshal(b, dst)
+		if err == nil {
+			return nil
+		}
+		log.Error().Err(err).Str("key", key).Msg("decoding cached entry")
+	} else if !errors.Is(err, cache.ErrMiss) {
+		log.Error().Err(err).Str("key", key).Msg("reading cache")
+	}
+
+	v, err, _ := jc.group.Do(key, func() (any, error) {
+		// The request that triggered the load may be cancelled while others
+		// still wait for the result
+		ctx := context.WithoutCancel(ctx)
+		jc.mu.Lock()
+		jc.loading[key] = false
+		jc.mu.Unlock()
+		defer func() {
+			jc.mu.Lock()
+			delete(jc.loading, key)
+			jc.mu.Unlock()
+		}()
+
+		v, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if jc.invalidated(key) {
+			return b, nil
+		}
+		err = jc.c.Set(ctx, key, b, jc.ttl)
+		if err != nil {
+			log.Error().Err(err).Str("key", key).Msg("writing cache")
+		}
+		// An invalidation between the check and the write may have deleted
+		// the key before it was written
+		if jc.invalidated(key) {
+			_ = jc.c.Delete(ctx, key)
+		}
+		return b, nil
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(v.([]byte), dst)
+}
+
+// invalidated reports whether key was invalidated since its load started.
+func (jc *jobCache) invalidated(key string) bool {
+	jc.mu.Lock()
+	defer jc.mu.Unlock()
+	return jc.loading[key]
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"job-portal/internal/authstate"
+	"job-portal/internal/cache"
+	"job-portal/internal/invalidation"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"sync"
+	"testing"
+	"time"
+
+	gomock "go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestNewService_JobCacheReadThrough(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	s := NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory())
+
+	ms.EXPECT().GetJobById(gomock.Any(), 1).Return(models.Job{ID: 1, Title: "go developer", CompanyID: 7}, nil).Times(1)
+	ms.EXPECT().ViewJobById(gomock.Any(), 7).Return([]models.Job{{ID: 1, Title: "go developer", CompanyID: 7}}, nil).Times(1)
+	for i := 0; i < 2; i++ {
+		job, err := s.GetJobInfoByID(ctx, 1)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "go developer", job.Title)
+
+		jobs, err := s.ViewJobByCompanyId(ctx, 7)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 1, len(jobs))
+	}
+
+	// Missing jobs aren't cached, a job created later is found
+	ms.EXPECT().GetJobById(gomock.Any(), 2).Return(models.Job{}, repository.ErrNotFound).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err := s.GetJobInfoByID(ctx, 2)
+		assert.Equal(t, CodeJobNotFound, errCode(err))
+	}
+}
+
+func TestNewService_JobCacheInvalidatedOnCreate(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	s := NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory())
+
+	gomock.InOrder(
+		ms.EXPECT().ViewJobById(gomock.Any(), 7).Return([]models.Job{{ID: 1, CompanyID: 7}}, nil),
+		ms.EXPECT().ViewJobById(gomock.Any(), 7).Return([]models.Job{{ID: 1, CompanyID: 7}, {ID: 2, CompanyID: 7}}, nil),
+	)
+	owner := uint(1)
+	expectTx(ms)
+	ms.EXPECT().LockCompany(ctx, uint(7)).Return(nil)
+	ms.EXPECT().GetCompanyByID(ctx, 7).Return(models.Company{ID: 7, OwnerID: &owner}, nil)
+	ms.EXPECT().CreateJ(ctx, models.NewJob{Title: "go developer"}, 7).Return(models.Job{ID: 2, CompanyID: 7}, nil)
+
+	jobs, err := s.ViewJobByCompanyId(ctx, 7)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(jobs))
+
+	_, err = s.CreateJob(ctx, models.NewJob{Title: "go developer"}, 7, 1)
+	assert.Equal(t, nil, err)
+
+	jobs, err = s.ViewJobByCompanyId(ctx, 7)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 2, len(jobs))
+}
+
+// recordingBus keeps the published events.
+type recordingBus struct {
+	events []invalidation.Event
+}
+
+func (b *recordingBus) Publish(_ context.Context, e invalidation.Event) error {
+	b.events = append(b.events, e)
+	return nil
+}
+
+func TestNewService_CreateJobPublishesInvalidation(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	bus := &recordingBus{}
+	s := NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory(), WithInvalidation(bus))
+
+	owner := uint(1)
+	expectTx(ms)
+	ms.EXPECT().LockCompany(ctx, uint(7)).Return(nil)
+	ms.EXPECT().GetCompanyByID(ctx, 7).Return(models.Company{ID: 7, OwnerID: &owner}, nil)
+	ms.EXPECT().CreateJ(ctx, models.NewJob{Title: "go developer"}, 7).Return(models.Job{ID: 2, CompanyID: 7}, nil)
+	_, err := s.CreateJob(ctx, models.NewJob{Title: "go developer"}, 7, 1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []invalidation.Event{{Type: invalidation.Job, ID: 2, CompanyID: 7}}, bus.events)
+}
+
+func TestEvict(t *testing.T) {
+	ctx := context.Background()
+	remote := cache.NewMemory()
+	l := cache.NewLayered(cache.NewMemory(), remote, time.Minute)
+	keys := []string{jobKey(1), companyJobsKey(7), jobKey(2)}
+	fill := func(v string) {
+		for _, k := range keys {
+			assert.Equal(t, nil, l.Set(ctx, k, []byte(v), 0))
+			// Another instance wrote a newer value
+			assert.Equal(t, nil, remote.Set(ctx, k, []byte(v+"-new"), 0))
+		}
+	}
+	get := func(key string) string {
+		v, _ := l.Get(ctx, key)
+		return string(v)
+	}
+
+	fill("a")
+	Evict(l)(invalidation.Event{Type: invalidation.Job, ID: 1, CompanyID: 7})
+	assert.Equal(t, "a-new", get(jobKey(1)))
+	assert.Equal(t, "a-new", get(companyJobsKey(7)))
+	assert.Equal(t, "a", get(jobKey(2)))
+
+	fill("b")
+	Evict(l)(invalidation.Event{Type: invalidation.Company, ID: 7})
+	assert.Equal(t, "b", get(jobKey(1)))
+	assert.Equal(t, "b-new", get(companyJobsKey(7)))
+
+	fill("c")
+	Evict(l)(invalidation.Event{Type: invalidation.All})
+	for _, k := range keys {
+		assert.Equal(t, "c-new", get(k))
+	}
+}
+
+func TestNewService_JobCacheSingleflight(t *testing.T) {
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	s := NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory())
+
+	// The first load blocks until every caller waits on the same key
+	release := make(chan struct{})
+	ms.EXPECT().GetJobById(gomock.Any(), 1).DoAndReturn(func(context.Context, int) (models.Job, error) {
+		<-release
+		return models.Job{ID: 1}, nil
+	}).Times(1)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.GetJobInfoByID(context.Background(), 1)
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Equal(t, nil, err)
+	}
+}
+
+func TestNewService_JobCacheInvalidatedDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	c := cache.NewMemory()
+	s := NewServiceStore(ms, c, authstate.NewMemory()).(*NewService)
+
+	// The job changes while the first load still runs, the old title it
+	// read mustn't be cached
+	loading, release := make(chan struct{}), make(chan struct{})
+	gomock.InOrder(
+		ms.EXPECT().GetJobById(gomock.Any(), 1).DoAndReturn(func(context.Context, int) (models.Job, error) {
+			close(loading)
+			<-release
+			return models.Job{ID: 1, Title: "go developer", CompanyID: 7}, nil
+		}),
+		ms.EXPECT().GetJobById(gomock.Any(), 1).Return(models.Job{ID: 1, Title: "golang developer", CompanyID: 7}, nil),
+	)
+
+	done := make(chan error)
+	go func() {
+		_, err := s.GetJobInfoByID(ctx, 1)
+		done <- err
+	}()
+	<-loading
+	s.jobChanged(ctx, 1, 7)
+	close(release)
+	assert.Equal(t, nil, <-done)
+
+	_, err := c.Get(ctx, jobKey(1))
+	assert.Equal(t, cache.ErrMiss, err)
+	job, err := s.GetJobInfoByID(ctx, 1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "golang developer", job.Title)
+	assert.Equal(t, 0, len(s.jobs.loading))
+}
+
+func TestJobCache_FailingCache(t *testing.T) {
+	jc := newJobCache(failingCache{}, 0)
+	loads := 0
+	for i := 0; i < 2; i++ {
+		job, err := jc.job(context.Background(), 1, func(_ context.Context, jId int) (models.Job, error) {
+			loads++
+			return models.Job{ID: uint(jId)}, nil
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, uint(1), job.ID)
+	}
+	assert.Equal(t, 2, loads)
+}
+
+// failingCache is a cache whose backend is down.
+type failingCache struct{}
+
+var errCacheDown = errors.New("cache down")
+
+func (failingCache) Get(context.Context, string) ([]byte, error) { return nil, errCacheDown }
+func (failingCache) Set(context.Context, string, []byte, time.Duration) error {
+	return errCacheDown
+}
+func (failingCache) Delete(context.Context, ...string) error { return errCacheDown }
+func (failingCache) TTL(context.Context, string) (time.Duration, error) {
+	return 0, errCacheDown
+}
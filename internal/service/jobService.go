@@ -2,47 +2,98 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"job-portal/cmd/rediss"
 	"job-portal/internal/models"
-	"strconv"
-	"sync"
-	"time"
+	"job-portal/internal/repository"
 
 	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
 )
 
-func (r NewService) CreateJob(ctx context.Context, nj models.NewJob, cId int) (models.Job, error) {
-	job, err := r.rp.CreateJ(ctx, nj, cId)
+// CreateJob posts a job under a company. Only its owner may, the job shows
+// the verified badge of the company. The company stays locked until the job
+// is stored, so a company deleted meanwhile gets no new jobs.
+func (r NewService) CreateJob(ctx context.Context, nj models.NewJob, cId int, userID uint) (models.Job, error) {
+	var job models.Job
+	err := r.inTx(ctx, func(r NewService) error {
+		err := r.rp.LockCompany(ctx, uint(cId))
+		if errors.Is(err, repository.ErrNotFound) {
+			return newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = r.ownCompany(ctx, uint(cId), userID)
+		if err != nil {
+			return err
+		}
+		job, err = r.rp.CreateJ(ctx, nj, cId)
+		return err
+	})
 	if err != nil {
 		return models.Job{}, err
 	}
+	r.jobChanged(ctx, int(job.ID), cId)
 	return job, nil
 }
 
 func (r NewService) ViewJob(ctx context.Context) ([]models.Job, error) {
-	jobs, err := r.rp.ViewJobs()
+	jobs, err := r.rp.ViewJobs(ctx)
 	if err != nil {
 		return []models.Job{}, err
 	}
-	return jobs, nil
+	return r.visibleJobs(jobs), nil
 }
 
 func (r NewService) GetJobInfoByID(ctx context.Context, jId int) (models.Job, error) {
-	job, err := r.rp.GetJobById(jId)
+	job, err := r.job(ctx, jId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Job{}, newError(ErrNotFound, CodeJobNotFound, "job not found", err)
+	}
 	if err != nil {
 		return models.Job{}, err
 	}
+	if !r.visible(job) {
+		return models.Job{}, newError(ErrNotFound, CodeJobNotFound, "job not found", nil)
+	}
 	return job, nil
 }
 
 func (r NewService) ViewJobByCompanyId(ctx context.Context, cId int) ([]models.Job, error) {
-	jobs, err := r.rp.ViewJobById(cId)
+	jobs, err := r.jobs.companyJobs(ctx, cId, r.rp.ViewJobById)
 	if err != nil {
 		return []models.Job{}, err
 	}
-	return jobs, nil
+	if len(jobs) == 0 {
+		// A company without jobs and a missing company look alike
+		_, err = r.rp.GetCompanyByID(ctx, cId)
+		if errors.Is(err, repository.ErrNotFound) {
+			return []models.Job{}, newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+		}
+		if err != nil {
+			return []models.Job{}, err
+		}
+	}
+	return r.visibleJobs(jobs), nil
+}
+
+// visible reports whether a job is shown, the jobs of unverified companies
+// are hidden when the service is configured to.
+func (r NewService) visible(job models.Job) bool {
+	return !r.hideUnverified || job.Verified
+}
+
+func (r NewService) visibleJobs(jobs []models.Job) []models.Job {
+	if !r.hideUnverified {
+		return jobs
+	}
+	shown := []models.Job{}
+	for _, j := range jobs {
+		if j.Verified {
+			shown = append(shown, j)
+		}
+	}
+	return shown
 }
 
 func CompareCriteria(application models.JobApplication, job models.Job) (models.Applicant, error) {
@@ -167,85 +218,87 @@ func CompareCriteria(application models.JobApplication, job models.Job) (models.
 
 	return models.Applicant{}, err
 }
-func (r NewService) ApplyJob(valid_application []models.JobApplication) ([]models.Applicant, error) {
-	var users []models.Applicant
-	var jobModel models.Job
-	var wg sync.WaitGroup
-	rc := rediss.RedisClient()
-	ctx := context.Background()
-	userChan := make(chan models.Applicant, len(valid_application))
-	for _, application := range valid_application {
-		wg.Add(1)
-		go func(application models.JobApplication) {
-			defer wg.Done()
-			RedisKey := strconv.Itoa(application.JobId)
-			retrievedJob, err := rc.Get(ctx, RedisKey).Result()
-			//if err == redis.Nil {
-			// job, err := r.rp.Process(application.JobId)
-			// if err != nil {
-			// 	return
-			// }
-			// jobJSON, err := json.Marshal(job)
-			// if err != nil {
-			// 	log.Error().Msgf("1 %v", err)
-			// }
-			// err = rc.Set(ctx, RedisKey, jobJSON, time.Hour).Err()
-			// if err != nil {
-			// 	log.Error().Msgf("2 %v", err)
-			// 	return
-			// }
-			// retrievedJob, err = rc.Get(ctx, RedisKey).Result()
-			// if err != nil {
-			// 	log.Error().Msgf("3 %v", err)
-			// 	return
-			// }
 
-			// }
-			if err != nil {
-				job, err := r.rp.Process(application.JobId)
-				if err != nil {
-					log.Error().Msgf("%v", err)
-					return
-				}
-				jobJSON, err := json.Marshal(job)
-				if err != nil {
-					log.Error().Msgf("1 %v", err)
-				}
-				err = rc.Set(ctx, RedisKey, jobJSON, time.Hour).Err()
-				if err != nil {
-					log.Error().Msgf("2 %v", err)
-					return
-				}
-				retrievedJob, err = rc.Get(ctx, RedisKey).Result()
-				if err != nil {
-					log.Error().Msgf("3 %v", err)
-					return
-				}
+// ApplyJob matches the applications against the criteria of their jobs and
+// returns an outcome for every application in the order of the
+// applications. It stops when ctx is done, e.g. because the client went
+// away.
+func (r NewService) ApplyJob(ctx context.Context, valid_application []models.JobApplication) ([]models.ApplicationOutcome, error) {
+	return r.matchApplications(ctx, valid_application, 0)
+}
+
+// matchApplications loads every distinct job once and matches its
+// applications, at most applyWorkers jobs at a time. first is the position
+// of the first application in its batch.
+func (r NewService) matchApplications(ctx context.Context, applications []models.JobApplication, first int) ([]models.ApplicationOutcome, error) {
+	byJob := map[int][]int{}
+	var jobIDs []int
+	for i, a := range applications {
+		if _, ok := byJob[a.JobId]; !ok {
+			jobIDs = append(jobIDs, a.JobId)
+		}
+		byJob[a.JobId] = append(byJob[a.JobId], i)
+	}
+
+	outcomes := make([]models.ApplicationOutcome, len(applications))
+	var g errgroup.Group
+	g.SetLimit(r.workers())
+	for _, jId := range jobIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
 			}
-			err = json.Unmarshal([]byte(retrievedJob), &jobModel)
-			if err != nil {
-				log.Error().Msgf("%v", err)
-				return
+			job, err := r.job(ctx, jId)
+			if err == nil && !r.visible(job) {
+				err = repository.ErrNotFound
 			}
-
-			user, err := CompareCriteria(application, jobModel)
-			if err != nil {
-				log.Error().Err(err).Msgf("error while comparing the %s applicartion with job criteria", application.Name)
-				return
+			for _, i := range byJob[jId] {
+				outcomes[i] = matchApplication(applications[i], first+i, job, err)
 			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return outcomes, nil
+}
 
-			userChan <- user
-		}(application)
+// defaultApplyWorkers is used when no number of workers was configured.
+const defaultApplyWorkers = 8
+
+func (r NewService) workers() int {
+	if r.applyWorkers > 0 {
+		return r.applyWorkers
 	}
-	go func() {
-		wg.Wait()
-		close(userChan)
-	}()
+	return defaultApplyWorkers
+}
 
-	for user := range userChan {
-		users = append(users, user)
+// matchApplication compares one application with its job, loadErr is the
+// error loading the job failed with.
+func matchApplication(a models.JobApplication, position int, job models.Job, loadErr error) models.ApplicationOutcome {
+	o := models.ApplicationOutcome{Position: position, Name: a.Name, Email: a.Email}
+	if loadErr != nil {
+		log.Error().Err(loadErr).Int("job", a.JobId).Msg("loading job")
+		o.Outcome = models.OutcomeFailed
+		o.Error = "job could not be loaded"
+		if errors.Is(loadErr, repository.ErrNotFound) {
+			o.Error = "job not found"
+		}
+		return o
+	}
+	_, err := CompareCriteria(a, job)
+	if err != nil {
+		log.Info().Msgf("application of %s doesn't meet the job criteria", a.Name)
+		o.Outcome = models.OutcomeRejected
+		return o
 	}
-	return users, nil
+	o.Outcome = models.OutcomeMatched
+	return o
 }
 
 // function to check the slices
@@ -3,15 +3,20 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"job-portal/internal/authstate"
+	"job-portal/internal/cache"
 	"job-portal/internal/models"
 	"job-portal/internal/repository"
 	"reflect"
 	"testing"
 
 	gomock "go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
 )
 
 func TestNewService_CreateJob(t *testing.T) {
+	owner, other := uint(1), uint(2)
 	type args struct {
 		ctx context.Context
 		nj  models.NewJob
@@ -21,10 +26,29 @@ func TestNewService_CreateJob(t *testing.T) {
 		name string
 		//r                NewService
 		args             args
+		lockErr          error
+		company          models.Company
 		want             models.Job
 		wantErr          bool
+		wantCode         string
 		mockRepoResponse func() (models.Job, error)
 	}{
+		{
+			name:     "missing company",
+			args:     args{ctx: context.Background(), nj: models.NewJob{Title: "software developer"}, cId: 24},
+			lockErr:  fmt.Errorf("locking company 24: %w", repository.ErrNotFound),
+			want:     models.Job{},
+			wantErr:  true,
+			wantCode: CodeCompanyNotFound,
+		},
+		{
+			name:     "not the owner",
+			args:     args{ctx: context.Background(), nj: models.NewJob{Title: "software developer"}, cId: 24},
+			company:  models.Company{ID: 24, OwnerID: &other},
+			want:     models.Job{},
+			wantErr:  true,
+			wantCode: CodeNotCompanyOwner,
+		},
 		{
 			name: "error in creating job",
 			args: args{
@@ -34,8 +58,10 @@ func TestNewService_CreateJob(t *testing.T) {
 					Description: "develop mobile applications",
 					CompanyID:   24,
 				},
+				cId: 24,
 			},
-			want: models.Job{},
+			company: models.Company{ID: 24, OwnerID: &owner},
+			want:    models.Job{},
 			mockRepoResponse: func() (models.Job, error) {
 				return models.Job{}, errors.New("error in creating job")
 			},
@@ -52,7 +78,9 @@ func TestNewService_CreateJob(t *testing.T) {
 					Description: "develop mobile applications",
 					CompanyID:   24,
 				},
+				cId: 24,
 			},
+			company: models.Company{ID: 24, OwnerID: &owner},
 
 			want: models.Job{
 				Title:       "software developer",
@@ -74,16 +102,22 @@ func TestNewService_CreateJob(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mc := gomock.NewController(t)
 			mockRepo := repository.NewMockRepository(mc)
+			expectTx(mockRepo)
+			mockRepo.EXPECT().LockCompany(tt.args.ctx, uint(tt.args.cId)).Return(tt.lockErr)
+			if tt.lockErr == nil {
+				mockRepo.EXPECT().GetCompanyByID(tt.args.ctx, tt.args.cId).Return(tt.company, nil)
+			}
 			if tt.mockRepoResponse != nil {
 				mockRepo.EXPECT().CreateJ(tt.args.ctx, tt.args.nj, tt.args.cId).Return(tt.mockRepoResponse()).AnyTimes()
 			}
 
-			s := NewServiceStore(mockRepo)
-			got, err := s.CreateJob(tt.args.ctx, tt.args.nj, tt.args.cId)
+			s := NewServiceStore(mockRepo, cache.NewMemory(), authstate.NewMemory())
+			got, err := s.CreateJob(tt.args.ctx, tt.args.nj, tt.args.cId, owner)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewService.CreateJob() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			assert.Equal(t, tt.wantCode, errCode(err))
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewService.CreateJob() = %v, want %v", got, tt.want)
 			}
@@ -137,11 +171,11 @@ func TestNewService_ViewJob(t *testing.T) {
 			mc := gomock.NewController(t)
 			mockRepo := repository.NewMockRepository(mc)
 			if tt.mockRepoResponse != nil {
-				mockRepo.EXPECT().ViewJobs().Return(tt.mockRepoResponse()).AnyTimes()
+				mockRepo.EXPECT().ViewJobs(gomock.Any()).Return(tt.mockRepoResponse()).AnyTimes()
 
 			}
 
-			s := NewServiceStore(mockRepo)
+			s := NewServiceStore(mockRepo, cache.NewMemory(), authstate.NewMemory())
 
 			got, err := s.ViewJob(tt.args.ctx)
 			if (err != nil) != tt.wantErr {
@@ -167,6 +201,7 @@ func TestNewService_GetJobInfoByID(t *testing.T) {
 		args             args
 		want             models.Job
 		wantErr          bool
+		wantErrIs        error
 		mockRepoResponse func() (models.Job, error)
 	}{
 		{
@@ -180,6 +215,18 @@ func TestNewService_GetJobInfoByID(t *testing.T) {
 				return models.Job{}, errors.New("test error")
 			},
 		},
+		{
+			name: "job not found",
+			want: models.Job{},
+			args: args{
+				jId: 12,
+			},
+			wantErr:   true,
+			wantErrIs: ErrNotFound,
+			mockRepoResponse: func() (models.Job, error) {
+				return models.Job{}, fmt.Errorf("fetching job 12: %w", repository.ErrNotFound)
+			},
+		},
 		{
 			name: "success",
 			args: args{
@@ -206,15 +253,18 @@ func TestNewService_GetJobInfoByID(t *testing.T) {
 			mockRepo := repository.NewMockRepository(mc)
 
 			if tt.mockRepoResponse != nil {
-				mockRepo.EXPECT().GetJobById(tt.args.jId).Return(tt.mockRepoResponse()).AnyTimes()
+				mockRepo.EXPECT().GetJobById(gomock.Any(), tt.args.jId).Return(tt.mockRepoResponse()).AnyTimes()
 			}
 
-			s := NewServiceStore(mockRepo)
-			got, err := s.GetJobInfoByID(tt.args.ctx, tt.args.jId)
+			s := NewServiceStore(mockRepo, cache.NewMemory(), authstate.NewMemory())
+			got, err := s.GetJobInfoByID(context.Background(), tt.args.jId)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewService.GetJobInfoByID() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("NewService.GetJobInfoByID() error = %v, want %v", err, tt.wantErrIs)
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewService.GetJobInfoByID() = %v, want %v", got, tt.want)
 			}
@@ -283,10 +333,10 @@ func TestNewService_ViewJobByCompanyId(t *testing.T) {
 			mockRepo := repository.NewMockRepository(mc)
 
 			if tt.mockRepoResponse != nil {
-				mockRepo.EXPECT().ViewJobById(tt.args.cId).Return(tt.mockRepoResponse()).AnyTimes()
+				mockRepo.EXPECT().ViewJobById(gomock.Any(), tt.args.cId).Return(tt.mockRepoResponse()).AnyTimes()
 			}
-			s := NewServiceStore(mockRepo)
-			got, err := s.ViewJobByCompanyId(tt.args.ctx, tt.args.cId)
+			s := NewServiceStore(mockRepo, cache.NewMemory(), authstate.NewMemory())
+			got, err := s.ViewJobByCompanyId(context.Background(), tt.args.cId)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewService.ViewJobByCompanyId() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -298,6 +348,30 @@ func TestNewService_ViewJobByCompanyId(t *testing.T) {
 	}
 }
 
+func TestNewService_ViewJobByCompanyId_WithoutJobs(t *testing.T) {
+	tests := []struct {
+		name     string
+		getErr   error
+		wantCode string
+	}{
+		{name: "company without jobs"},
+		{name: "missing company", getErr: fmt.Errorf("fetching company 12: %w", repository.ErrNotFound), wantCode: CodeCompanyNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ms := repository.NewMockRepository(gomock.NewController(t))
+			s := NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory())
+			ms.EXPECT().ViewJobById(gomock.Any(), 12).Return([]models.Job{}, nil)
+			ms.EXPECT().GetCompanyByID(ctx, 12).Return(models.Company{ID: 12}, tt.getErr)
+
+			jobs, err := s.ViewJobByCompanyId(ctx, 12)
+			assert.Equal(t, tt.wantCode, errCode(err))
+			assert.Equal(t, 0, len(jobs))
+		})
+	}
+}
+
 func TestNewService_ApplyJob(t *testing.T) {
 	type args struct {
 		application []models.JobApplication
@@ -307,7 +381,7 @@ func TestNewService_ApplyJob(t *testing.T) {
 		name string
 		//r       NewService
 		args         args
-		want         []models.Applicant
+		want         []models.ApplicationOutcome
 		wantErr      bool
 		mockResponse func() (models.Job, error)
 	}{
@@ -360,7 +434,11 @@ func TestNewService_ApplyJob(t *testing.T) {
 				},
 				jId: 1,
 			},
-			want:    []models.Applicant{{Name: "vishnu", Email: "vishnu@gmail.com", Age: 24}, {Name: "krishna", Email: "krishna@gmail.com", Age: 24}},
+			want: []models.ApplicationOutcome{
+				{Position: 0, Name: "vishnu", Email: "vishnu@gmail.com", Outcome: models.OutcomeMatched},
+				{Position: 1, Name: "krishna", Email: "krishna@gmail.com", Outcome: models.OutcomeMatched},
+				{Position: 2, Name: "vikram", Email: "vikram@gmail.com", Outcome: models.OutcomeRejected},
+			},
 			wantErr: false,
 			mockResponse: func() (models.Job, error) {
 				return models.Job{
@@ -420,8 +498,13 @@ func TestNewService_ApplyJob(t *testing.T) {
 				},
 				jId: 1,
 			},
-			want:    nil,
-			wantErr: true,
+			// Every application reports the failure instead of failing the
+			// whole batch
+			want: []models.ApplicationOutcome{
+				{Position: 0, Name: "vishnu", Email: "vishnu@gmail.com", Outcome: models.OutcomeFailed, Error: "job could not be loaded"},
+				{Position: 1, Name: "vikram", Email: "vikram@gmail.com", Outcome: models.OutcomeFailed, Error: "job could not be loaded"},
+			},
+			wantErr: false,
 			mockResponse: func() (models.Job, error) {
 				return models.Job{}, errors.New("")
 			},
@@ -461,7 +544,10 @@ func TestNewService_ApplyJob(t *testing.T) {
 				},
 				jId: 1,
 			},
-			want:    []models.Applicant{{Name: "vishnu", Email: "vishnu@gmail.com", Age: 24}},
+			want: []models.ApplicationOutcome{
+				{Position: 0, Name: "vishnu", Email: "vishnu@gmail.com", Outcome: models.OutcomeMatched},
+				{Position: 1, Name: "krishna", Email: "krishna@gmail.com", Outcome: models.OutcomeRejected},
+			},
 			wantErr: false,
 			mockResponse: func() (models.Job, error) {
 				return models.Job{
@@ -518,7 +604,10 @@ func TestNewService_ApplyJob(t *testing.T) {
 				},
 				jId: 1,
 			},
-			want:    []models.Applicant{{Name: "vishnu", Email: "vishnu@gmail.com", Age: 24}},
+			want: []models.ApplicationOutcome{
+				{Position: 0, Name: "vishnu", Email: "vishnu@gmail.com", Outcome: models.OutcomeMatched},
+				{Position: 1, Name: "krishna", Email: "krishna@gmail.com", Outcome: models.OutcomeRejected},
+			},
 			wantErr: false,
 			mockResponse: func() (models.Job, error) {
 				return models.Job{
@@ -575,7 +664,10 @@ func TestNewService_ApplyJob(t *testing.T) {
 				},
 				jId: 1,
 			},
-			want:    []models.Applicant{{Name: "vishnu", Email: "vishnu@gmail.com", Age: 24}},
+			want: []models.ApplicationOutcome{
+				{Position: 0, Name: "vishnu", Email: "vishnu@gmail.com", Outcome: models.OutcomeMatched},
+				{Position: 1, Name: "krishna", Email: "krishna@gmail.com", Outcome: models.OutcomeRejected},
+			},
 			wantErr: false,
 			mockResponse: func() (models.Job, error) {
 				return models.Job{
@@ -603,11 +695,11 @@ func TestNewService_ApplyJob(t *testing.T) {
 			mc := gomock.NewController(t)
 			ms := repository.NewMockRepository(mc)
 			if tt.mockResponse != nil {
-				ms.EXPECT().Process(gomock.Any()).Return(tt.mockResponse()).AnyTimes()
+				ms.EXPECT().GetJobById(gomock.Any(), gomock.Any()).Return(tt.mockResponse()).AnyTimes()
 			}
-			r := NewServiceStore(ms)
+			r := NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory())
 
-			got, err := r.ApplyJob(tt.args.application)
+			got, err := r.ApplyJob(context.Background(), tt.args.application)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewService.ApplyJob() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -618,3 +710,62 @@ func TestNewService_ApplyJob(t *testing.T) {
 		})
 	}
 }
+
+func TestNewService_ApplyJobCachesJobs(t *testing.T) {
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	ms.EXPECT().GetJobById(gomock.Any(), 1).Return(models.Job{ID: 1, Budget: 500000}, nil).Times(1)
+
+	c := cache.NewMemory()
+	r := NewServiceStore(ms, c, authstate.NewMemory())
+	application := []models.JobApplication{{Name: "vishnu", JobId: 1}}
+	for i := 0; i < 2; i++ {
+		_, err := r.ApplyJob(context.Background(), application)
+		if err != nil {
+			t.Fatalf("NewService.ApplyJob() error = %v", err)
+		}
+	}
+
+	d, err := c.TTL(context.Background(), jobKey(1))
+	if err != nil || d <= 0 {
+		t.Errorf("job not cached with a ttl: %v, %v", d, err)
+	}
+	// Viewing the job reads the same entry
+	job, err := r.GetJobInfoByID(context.Background(), 1)
+	if err != nil || job.Budget != 500000 {
+		t.Errorf("NewService.GetJobInfoByID() = %v, %v", job, err)
+	}
+}
+
+func TestNewService_ApplyJobLoadsEachJobOnce(t *testing.T) {
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	// Without a working cache only the batch itself avoids repeated loads
+	ms.EXPECT().GetJobById(gomock.Any(), 1).Return(models.Job{ID: 1}, nil).Times(1)
+	ms.EXPECT().GetJobById(gomock.Any(), 2).Return(models.Job{}, repository.ErrNotFound).Times(1)
+
+	r := NewServiceStore(ms, failingCache{}, authstate.NewMemory(), WithApplyWorkers(2))
+	got, err := r.ApplyJob(context.Background(), []models.JobApplication{
+		{Name: "vishnu", JobId: 1}, {Name: "ravi", JobId: 2}, {Name: "krishna", JobId: 1}, {Name: "vikram", JobId: 2},
+	})
+	if err != nil {
+		t.Fatalf("NewService.ApplyJob() error = %v", err)
+	}
+	if len(got) != 4 || got[2].Name != "krishna" || got[3].Error != "job not found" {
+		t.Errorf("NewService.ApplyJob() = %v", got)
+	}
+}
+
+func TestNewService_ApplyJobCancelled(t *testing.T) {
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	r := NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory())
+
+	// The client went away before any job was loaded
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := r.ApplyJob(ctx, []models.JobApplication{{Name: "vishnu", JobId: 1}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("NewService.ApplyJob() error = %v, want %v", err, context.Canceled)
+	}
+}
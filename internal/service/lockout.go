@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"job-portal/internal/authstate"
+	"time"
+)
+
+// Failed logins are counted per account and per client address. Reaching
+// the limit locks the subject out, every further lockout within
+// lockoutMemory doubles the duration up to maxLockout.
+const (
+	accountFailureLimit = 5
+	ipFailureLimit      = 20
+	failureWindow       = 15 * time.Minute
+	baseLockout         = time.Minute
+	maxLockout          = 24 * time.Hour
+	lockoutMemory       = 24 * time.Hour
+)
+
+// lockout scopes, each has its own limit and keys.
+const (
+	scopeAccount = "account"
+	scopeIP      = "ip"
+)
+
+func lockSubject(scope, id string) string {
+	return scope + ":" + id
+}
+
+// lockedFor returns how long the subject stays locked, 0 when it isn't.
+func lockedFor(ctx context.Context, st authstate.Store, scope, id string) (time.Duration, error) {
+	return st.LockedFor(ctx, lockSubject(scope, id))
+}
+
+// recordFailure counts a failed login against the subject and returns the
+// lockout it triggered, 0 if the limit wasn't reached.
+func recordFailure(ctx context.Context, st authstate.Store, scope, id string, limit int) (time.Duration, error) {
+	d, err := st.RecordFailure(ctx, lockSubject(scope, id), authstate.Policy{
+		Limit: limit, Window: failureWindow, Base: baseLockout, Max: maxLockout, Memory: lockoutMemory,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("counting %s failure: %w", scope, err)
+	}
+	return d, nil
+}
+
+// clearFailures forgets the failures of a subject after a successful login.
+// Previous lockouts still count towards the backoff.
+func clearFailures(ctx context.Context, st authstate.Store, scope, id string) error {
+	return st.ClearFailures(ctx, lockSubject(scope, id))
+}
+
+// unlock lifts the lockout of a subject and resets its backoff.
+func unlock(ctx context.Context, st authstate.Store, scope, id string) error {
+	return st.Unlock(ctx, lockSubject(scope, id))
+}
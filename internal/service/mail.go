@@ -0,0 +1,56 @@
+package service
+
+import (
+	"fmt"
+	"net/smtp"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Mailer delivers a plain text email.
+type Mailer func(to, subject, body string) error
+
+// mail sends through the mailer of the service, or SMTP when it has none.
+func (r NewService) mail(to, subject, body string) error {
+	if r.mailer != nil {
+		return r.mailer(to, subject, body)
+	}
+	return sendMail(to, subject, body)
+}
+
+// notify sends an email the caller doesn't wait for, a slow mail server
+// mustn't hold up the request that caused it. Failures are only logged.
+func (r NewService) notify(to, subject, body string) {
+	if r.notifications != nil {
+		r.notifications.Add(1)
+	}
+	go func() {
+		if r.notifications != nil {
+			defer r.notifications.Done()
+		}
+		err := r.mail(to, subject, body)
+		if err != nil {
+			log.Error().Err(err).Str("subject", subject).Msg("sending notification")
+		}
+	}()
+}
+
+// sendMail delivers a plain text email. It is a variable so tests can
+// capture mails instead of talking to the SMTP server.
+var sendMail = func(to, subject, body string) error {
+	from := "vishnuvirat693@gmail.com"
+	password := "xjve lhkn iyhg mkco"
+
+	// SMTP server and port
+	smtpServer := "smtp.gmail.com"
+	smtpPort := 587
+
+	// Set up authentication information
+	auth := smtp.PlainAuth("", from, password, smtpServer)
+
+	// Compose the email
+	message := fmt.Sprintf("Subject: %s\r\n\r\n%s", subject, body)
+
+	// Connect to the SMTP server
+	return smtp.SendMail(fmt.Sprintf("%s:%d", smtpServer, smtpPort), auth, from, []string{to}, []byte(message))
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"job-portal/internal/imaging"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"job-portal/internal/storage"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultMaxUpload bounds the size of an uploaded file.
+	DefaultMaxUpload = 5 << 20
+	// maxPixels bounds the dimensions of uploaded images, a small file can
+	// decode into a huge bitmap.
+	maxPixels = 40_000_000
+	// defaultURLTTL is how long links to files stay valid.
+	defaultURLTTL = 15 * time.Minute
+)
+
+// thumbnailSizes are the squares images are scaled down to fit, by name.
+var thumbnailSizes = []struct {
+	name string
+	size int
+}{
+	{"small", 128},
+	{"medium", 512},
+}
+
+var extensions = map[string]string{imaging.PNG: ".png", imaging.JPEG: ".jpg", imaging.WebP: ".webp"}
+
+// UploadCompanyMedia stores an image of a company with its thumbnails. Only
+// the owner may upload, a new logo replaces the previous one.
+func (r NewService) UploadCompanyMedia(ctx context.Context, companyID uint, userID uint, kind string, rd io.Reader) (models.CompanyMedia, error) {
+	if r.files == nil {
+		return models.CompanyMedia{}, errors.New("file storage isn't configured")
+	}
+	_, err := r.ownCompany(ctx, companyID, userID)
+	if err != nil {
+		return models.CompanyMedia{}, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(rd, r.maxUpload+1))
+	if err != nil {
+		return models.CompanyMedia{}, fmt.Errorf("reading upload: %w", err)
+	}
+	if int64(len(data)) > r.maxUpload {
+		return models.CompanyMedia{}, newError(ErrTooLarge, CodeFileTooLarge,
+			fmt.Sprintf("files can't be larger than %d bytes", r.maxUpload), nil)
+	}
+	img, format, err := imaging.Decode(data, maxPixels)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		return models.CompanyMedia{}, newError(ErrTooLarge, CodeFileTooLarge,
+			fmt.Sprintf("images can't have more than %d pixels", maxPixels), err)
+	}
+	if err != nil {
+		return models.CompanyMedia{}, newError(ErrUnsupportedMedia, CodeUnsupportedMedia,
+			"only png, jpeg and webp images can be uploaded", err)
+	}
+
+	m := models.CompanyMedia{
+		CompanyID:   companyID,
+		Kind:        kind,
+		Key:         fmt.Sprintf("companies/%d/%s-%s%s", companyID, kind, randomName(), extensions[format]),
+		ContentType: imaging.ContentType(format),
+		Size:        int64(len(data)),
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+	}
+	keys, err := r.storeImage(ctx, m.Key, data, img, format)
+	if err != nil {
+		return models.CompanyMedia{}, err
+	}
+
+	var replaced []models.CompanyMedia
+	err = r.inTx(ctx, func(r NewService) error {
+		if kind == models.MediaLogo {
+			// The lock keeps concurrent uploads from both keeping their logo
+			err := r.rp.LockCompany(ctx, companyID)
+			if err != nil {
+				return err
+			}
+			media, err := r.rp.CompanyMedia(ctx, companyID)
+			if err != nil {
+				return err
+			}
+			for _, old := range media {
+				if old.Kind != models.MediaLogo {
+					continue
+				}
+				_, err = r.rp.DeleteCompanyMedia(ctx, companyID, old.ID)
+				if err != nil {
+					return err
+				}
+				replaced = append(replaced, old)
+			}
+		}
+		m, err = r.rp.AddCompanyMedia(ctx, m)
+		return err
+	})
+	if err != nil {
+		r.removeFiles(ctx, keys)
+		if errors.Is(err, gorm.ErrForeignKeyViolated) || errors.Is(err, repository.ErrNotFound) {
+			return models.CompanyMedia{}, newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+		}
+		return models.CompanyMedia{}, err
+	}
+	for _, old := range replaced {
+		r.removeFiles(ctx, mediaKeys(old))
+	}
+	return r.signMedia(m), nil
+}
+
+// storeImage stores the original and its thumbnails and returns their keys.
+// Nothing is left behind when one fails.
+func (r NewService) storeImage(ctx context.Context, key string, data []byte, img image.Image, format string) ([]string, error) {
+	keys := []string{key}
+	err := r.files.Put(ctx, key, bytes.NewReader(data), imaging.ContentType(format))
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range thumbnailSizes {
+		var buf bytes.Buffer
+		thumbFormat, err := imaging.Encode(&buf, imaging.Fit(img, t.size), format)
+		if err != nil {
+			r.removeFiles(ctx, keys)
+			return nil, fmt.Errorf("encoding %s thumbnail: %w", t.name, err)
+		}
+		tk := thumbnailKey(key, t.name)
+		err = r.files.Put(ctx, tk, &buf, imaging.ContentType(thumbFormat))
+		if err != nil {
+			r.removeFiles(ctx, keys)
+			return nil, err
+		}
+		keys = append(keys, tk)
+	}
+	return keys, nil
+}
+
+// thumbnailKey derives the key of a thumbnail from the key of its original.
+// Thumbnails of JPEGs are JPEGs, every other one is a PNG.
+func thumbnailKey(key string, name string) string {
+	ext := path.Ext(key)
+	thumbExt := extensions[imaging.PNG]
+	if ext == extensions[imaging.JPEG] {
+		thumbExt = ext
+	}
+	return strings.TrimSuffix(key, ext) + "-" + name + thumbExt
+}
+
+// mediaKeys returns the keys of the original and the thumbnails of m.
+func mediaKeys(m models.CompanyMedia) []string {
+	keys := []string{m.Key}
+	for _, t := range thumbnailSizes {
+		keys = append(keys, thumbnailKey(m.Key, t.name))
+	}
+	return keys
+}
+
+// removeFiles deletes files nothing refers to anymore. They only take up
+// space, so a failure is only logged.
+func (r NewService) removeFiles(ctx context.Context, keys []string) {
+	err := r.files.Delete(ctx, keys...)
+	if err != nil {
+		log.Error().Err(err).Strs("keys", keys).Msg("deleting files")
+	}
+}
+
+// signMedia fills in the links to the files of m.
+func (r NewService) signMedia(m models.CompanyMedia) models.CompanyMedia {
+	if r.signer == nil {
+		return m
+	}
+	m.URL = r.signer.URL(m.Key, r.urlTTL)
+	m.Thumbnails = map[string]string{}
+	for _, t := range thumbnailSizes {
+		m.Thumbnails[t.name] = r.signer.URL(thumbnailKey(m.Key, t.name), r.urlTTL)
+	}
+	return m
+}
+
+// ListCompanyMedia returns the media of a company with fresh links.
+func (r NewService) ListCompanyMedia(ctx context.Context, companyID uint) ([]models.CompanyMedia, error) {
+	_, err := r.rp.GetCompanyByID(ctx, int(companyID))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	media, err := r.rp.CompanyMedia(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range media {
+		media[i] = r.signMedia(media[i])
+	}
+	return media, nil
+}
+
+// DeleteCompanyMedia deletes an image of a company with its thumbnails.
+// Only the owner may.
+func (r NewService) DeleteCompanyMedia(ctx context.Context, companyID uint, userID uint, mediaID uint) error {
+	_, err := r.ownCompany(ctx, companyID, userID)
+	if err != nil {
+		return err
+	}
+	m, err := r.rp.DeleteCompanyMedia(ctx, companyID, mediaID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return newError(ErrNotFound, CodeMediaNotFound, "media not found", err)
+	}
+	if err != nil {
+		return err
+	}
+	if r.files != nil {
+		r.removeFiles(ctx, mediaKeys(m))
+	}
+	return nil
+}
+
+// OpenFile returns the file a signed link points to. The caller closes its
+// body.
+func (r NewService) OpenFile(ctx context.Context, key, expires, signature string) (storage.Object, error) {
+	if r.files == nil || r.signer == nil {
+		return storage.Object{}, errors.New("file storage isn't configured")
+	}
+	err := r.signer.Verify(key, expires, signature)
+	if err != nil {
+		return storage.Object{}, newError(ErrForbidden, CodeInvalidSignature, "the link is invalid or has expired", err)
+	}
+	obj, err := r.files.Open(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return storage.Object{}, newError(ErrNotFound, CodeFileNotFound, "file not found", err)
+	}
+	if err != nil {
+		return storage.Object{}, err
+	}
+	return obj, nil
+}
+
+// randomName returns a name nobody can guess, so replaced files never share
+// a key with the files they replace.
+func randomName() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"job-portal/internal/cache"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"job-portal/internal/storage"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func pngOf(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// stored reports whether a file is kept under key.
+func stored(t *testing.T, files storage.Storage, key string) bool {
+	t.Helper()
+	obj, err := files.Open(context.Background(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj.Body.Close()
+	return true
+}
+
+func TestNewService_UploadCompanyMedia(t *testing.T) {
+	ctx := context.Background()
+	owner := uint(1)
+	oldLogo := models.CompanyMedia{ID: 2, CompanyID: 7, Kind: models.MediaLogo, Key: "companies/7/logo-old.png"}
+	tests := []struct {
+		name     string
+		data     []byte
+		kind     string
+		setup    func(ms *repository.MockRepository)
+		wantCode string
+		wantErr  bool
+	}{
+		{
+			name:     "too large",
+			data:     make([]byte, 2<<10),
+			kind:     models.MediaPhoto,
+			wantCode: CodeFileTooLarge,
+			wantErr:  true,
+		},
+		{
+			name:     "not an image",
+			data:     []byte("<svg onload=alert(1)>"),
+			kind:     models.MediaPhoto,
+			wantCode: CodeUnsupportedMedia,
+			wantErr:  true,
+		},
+		{
+			name: "insert fails",
+			data: pngOf(t, 20, 10),
+			kind: models.MediaPhoto,
+			setup: func(ms *repository.MockRepository) {
+				ms.EXPECT().AddCompanyMedia(ctx, gomock.Any()).Return(models.CompanyMedia{}, errors.New("db down"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "company deleted meanwhile",
+			data: pngOf(t, 20, 10),
+			kind: models.MediaLogo,
+			setup: func(ms *repository.MockRepository) {
+				ms.EXPECT().LockCompany(ctx, uint(7)).Return(fmt.Errorf("locking company 7: %w", repository.ErrNotFound))
+			},
+			wantCode: CodeCompanyNotFound,
+			wantErr:  true,
+		},
+		{
+			name: "new logo replaces the old one",
+			data: pngOf(t, 20, 10),
+			kind: models.MediaLogo,
+			setup: func(ms *repository.MockRepository) {
+				ms.EXPECT().LockCompany(ctx, uint(7)).Return(nil)
+				ms.EXPECT().CompanyMedia(ctx, uint(7)).Return([]models.CompanyMedia{oldLogo, {ID: 3, Kind: models.MediaPhoto}}, nil)
+				ms.EXPECT().DeleteCompanyMedia(ctx, uint(7), uint(2)).Return(oldLogo, nil)
+				ms.EXPECT().AddCompanyMedia(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, m models.CompanyMedia) (models.CompanyMedia, error) {
+					m.ID = 4
+					return m, nil
+				})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := repository.NewMockRepository(gomock.NewController(t))
+			dir := t.TempDir()
+			files, err := storage.NewLocal(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range mediaKeys(oldLogo) {
+				assert.Equal(t, nil, files.Put(ctx, key, bytes.NewReader(pngOf(t, 1, 1)), "image/png"))
+			}
+			s := &NewService{rp: ms, jobs: newJobCache(cache.NewMemory(), 0), maxUpload: 1 << 10}
+			WithStorage(files, storage.NewSigner([]byte("secret"), "/api/files/"), time.Minute)(s)
+			expectTx(ms)
+			ms.EXPECT().GetCompanyByID(ctx, 7).Return(models.Company{ID: 7, OwnerID: &owner}, nil)
+			if tt.setup != nil {
+				tt.setup(ms)
+			}
+
+			m, err := s.UploadCompanyMedia(ctx, 7, owner, tt.kind, bytes.NewReader(tt.data))
+			if tt.wantErr {
+				assert.NotEqual(t, nil, err)
+				assert.Equal(t, tt.wantCode, errCode(err))
+				// Nothing is left behind but the files of the old logo
+				left, err := os.ReadDir(filepath.Join(dir, "companies", "7"))
+				assert.Equal(t, nil, err)
+				assert.Equal(t, len(mediaKeys(oldLogo)), len(left))
+				return
+			}
+			assert.Equal(t, nil, err)
+			assert.Equal(t, "image/png", m.ContentType)
+			assert.Equal(t, 20, m.Width)
+			assert.Equal(t, 10, m.Height)
+			assert.Equal(t, 2, len(m.Thumbnails))
+			for _, key := range mediaKeys(m) {
+				assert.Equal(t, true, stored(t, files, key))
+			}
+			for _, key := range mediaKeys(oldLogo) {
+				assert.Equal(t, false, stored(t, files, key))
+			}
+		})
+	}
+}
+
+func TestNewService_UploadCompanyMedia_NotOwner(t *testing.T) {
+	ctx := context.Background()
+	ms := repository.NewMockRepository(gomock.NewController(t))
+	files, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &NewService{rp: ms, files: files, maxUpload: DefaultMaxUpload}
+	owner := uint(1)
+	ms.EXPECT().GetCompanyByID(ctx, 7).Return(models.Company{ID: 7, OwnerID: &owner}, nil)
+
+	_, err = s.UploadCompanyMedia(ctx, 7, 2, models.MediaLogo, bytes.NewReader(pngOf(t, 1, 1)))
+	assert.Equal(t, CodeNotCompanyOwner, errCode(err))
+}
+
+func TestNewService_OpenFile(t *testing.T) {
+	ctx := context.Background()
+	files, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, nil, files.Put(ctx, "companies/7/logo.png", bytes.NewReader([]byte("png")), "image/png"))
+	signer := storage.NewSigner([]byte("secret"), "")
+	s := &NewService{files: files, signer: signer}
+
+	sign := func(key string, ttl time.Duration) (string, string) {
+		u, err := url.Parse(signer.URL(key, ttl))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.Query().Get("expires"), u.Query().Get("signature")
+	}
+
+	expires, signature := sign("companies/7/logo.png", time.Minute)
+	obj, err := s.OpenFile(ctx, "companies/7/logo.png", expires, signature)
+	assert.Equal(t, nil, err)
+	obj.Body.Close()
+	assert.Equal(t, int64(3), obj.Size)
+
+	_, err = s.OpenFile(ctx, "companies/8/logo.png", expires, signature)
+	assert.Equal(t, CodeInvalidSignature, errCode(err))
+	expires, signature = sign("companies/7/logo.png", -time.Second)
+	_, err = s.OpenFile(ctx, "companies/7/logo.png", expires, signature)
+	assert.Equal(t, CodeInvalidSignature, errCode(err))
+	expires, signature = sign("companies/7/gone.png", time.Minute)
+	_, err = s.OpenFile(ctx, "companies/7/gone.png", expires, signature)
+	assert.Equal(t, CodeFileNotFound, errCode(err))
+}
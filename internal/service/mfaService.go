@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"job-portal/internal/auth"
+	"job-portal/internal/mfa"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// mfaIssuer names the account in authenticator apps.
+	mfaIssuer = "Job Portal"
+	// recoveryCodeCount codes are handed out on every enrollment and
+	// regeneration.
+	recoveryCodeCount = 10
+	// mfaFailureLimit wrong second factors lock the second step of the
+	// login, with the same backoff as password failures.
+	mfaFailureLimit = 5
+	// pendingTTL is how long the password step of a login stays valid,
+	// enrollment gets more time to set up the authenticator.
+	pendingTTL       = 5 * time.Minute
+	pendingEnrollTTL = 15 * time.Minute
+)
+
+// scopeMFA counts wrong second factors per user.
+const scopeMFA = "mfa"
+
+// pendingClaims returns the claims of a token that only allows finishing the
+// login of u, or nil when u logs in with the password alone.
+func (r NewService) pendingClaims(ctx context.Context, u models.User) (*jwt.RegisteredClaims, error) {
+	aud := jwt.ClaimStrings{auth.MFAPendingAudience}
+	ttl := pendingTTL
+	if !u.MFAEnabled {
+		required, err := r.companyRequiresMFA(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+		aud = append(aud, auth.MFAEnrollAudience)
+		ttl = pendingEnrollTTL
+	}
+	return &jwt.RegisteredClaims{
+		Issuer:    "job-portal-api",
+		Subject:   strconv.FormatUint(uint64(u.ID), 10),
+		Audience:  aud,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}, nil
+}
+
+func (r NewService) companyRequiresMFA(ctx context.Context, u models.User) (bool, error) {
+	if u.CompanyID == nil {
+		return false, nil
+	}
+	c, err := r.rp.GetCompanyByID(ctx, int(*u.CompanyID))
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading mfa policy: %w", err)
+	}
+	return c.RequireMFA, nil
+}
+
+// CompleteMFALogin finishes a login that passed the password step with a
+// TOTP code or a recovery code and returns the claims of a full token.
+func (r NewService) CompleteMFALogin(ctx context.Context, userID uint, code string, ip string) (jwt.RegisteredClaims, error) {
+	u, err := r.user(ctx, userID)
+	if err != nil {
+		return jwt.RegisteredClaims{}, err
+	}
+	if !u.MFAEnabled {
+		return jwt.RegisteredClaims{}, newError(ErrForbidden, CodeMFANotEnrolled, "enroll an authenticator to complete the login", nil)
+	}
+	err = r.checkSecondFactor(ctx, u, code, ip)
+	if err != nil {
+		return jwt.RegisteredClaims{}, err
+	}
+	r.audit(ctx, models.AuditEvent{Event: models.AuditLoginSucceeded, Email: u.Email, IP: ip, ActorID: strconv.FormatUint(uint64(u.ID), 10), Detail: "second factor"})
+	return repository.UserClaims(u), nil
+}
+
+// EnrollTOTP starts an enrollment with a new secret. The secret only becomes
+// active once ConfirmTOTP saw a code generated from it.
+func (r NewService) EnrollTOTP(ctx context.Context, userID uint) (models.TOTPEnrollment, error) {
+	u, err := r.user(ctx, userID)
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	if u.MFAEnabled {
+		return models.TOTPEnrollment{}, newError(ErrConflict, CodeMFAEnabled, "an authenticator is already enrolled", nil)
+	}
+	secret, err := mfa.NewSecret()
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	err = r.rp.SetTOTPPendingSecret(ctx, u.ID, secret)
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	return models.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: mfa.ProvisioningURI(mfaIssuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP activates the enrolled secret once code matches it and hands
+// out recovery codes. The returned claims are those of a full token, for
+// users whose login waited for the enrollment.
+func (r NewService) ConfirmTOTP(ctx context.Context, userID uint, code string) (models.RecoveryCodes, jwt.RegisteredClaims, error) {
+	u, err := r.user(ctx, userID)
+	if err != nil {
+		return models.RecoveryCodes{}, jwt.RegisteredClaims{}, err
+	}
+	if u.MFAEnabled {
+		return models.RecoveryCodes{}, jwt.RegisteredClaims{}, newError(ErrConflict, CodeMFAEnabled, "an authenticator is already enrolled", nil)
+	}
+	if u.TOTPPendingSecret == "" {
+		return models.RecoveryCodes{}, jwt.RegisteredClaims{}, newError(ErrConflict, CodeMFANotEnrolled, "start an enrollment first", nil)
+	}
+	step, ok := mfa.Validate(u.TOTPPendingSecret, code, time.Now())
+	if !ok {
+		return models.RecoveryCodes{}, jwt.RegisteredClaims{}, newError(ErrValidation, CodeInvalidMFACode, "code doesn't match the enrolled authenticator", nil)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return models.RecoveryCodes{}, jwt.RegisteredClaims{}, err
+	}
+	err = r.rp.EnableTOTP(ctx, u.ID, u.TOTPPendingSecret, step, hashes)
+	if err != nil {
+		return models.RecoveryCodes{}, jwt.RegisteredClaims{}, err
+	}
+	r.audit(ctx, models.AuditEvent{Event: models.AuditMFAEnabled, Email: u.Email, ActorID: strconv.FormatUint(uint64(u.ID), 10)})
+
+	u.MFAEnabled = true
+	return models.RecoveryCodes{Codes: codes}, repository.UserClaims(u), nil
+}
+
+// DisableTOTP removes the second factor after checking a current code. Members
+// of companies that require MFA can't turn it off.
+func (r NewService) DisableTOTP(ctx context.Context, userID uint, code string, ip string) error {
+	u, err := r.user(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !u.MFAEnabled {
+		return newError(ErrConflict, CodeMFANotEnrolled, "no authenticator is enrolled", nil)
+	}
+	required, err := r.companyRequiresMFA(ctx, u)
+	if err != nil {
+		return err
+	}
+	if required {
+		return newError(ErrForbidden, CodeMFARequired, "your company requires a second factor", nil)
+	}
+	err = r.checkSecondFactor(ctx, u, code, ip)
+	if err != nil {
+		return err
+	}
+	err = r.rp.DisableTOTP(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	r.audit(ctx, models.AuditEvent{Event: models.AuditMFADisabled, Email: u.Email, IP: ip, ActorID: strconv.FormatUint(uint64(u.ID), 10)})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a
+// current code.
+func (r NewService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string, ip string) (models.RecoveryCodes, error) {
+	u, err := r.user(ctx, userID)
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	if !u.MFAEnabled {
+		return models.RecoveryCodes{}, newError(ErrConflict, CodeMFANotEnrolled, "no authenticator is enrolled", nil)
+	}
+	err = r.checkSecondFactor(ctx, u, code, ip)
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	err = r.rp.ReplaceRecoveryCodes(ctx, u.ID, hashes)
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	return models.RecoveryCodes{Codes: codes}, nil
+}
+
+// SetCompanyMFAPolicy sets whether the members of a company have to log in
+// with a second factor. Only its owner may change it.
+func (r NewService) SetCompanyMFAPolicy(ctx context.Context, companyID uint, userID uint, required bool) error {
+	// A policy change is never left without its audit event
+	return r.inTx(ctx, func(r NewService) error {
+		_, err := r.ownCompany(ctx, companyID, userID)
+		if err != nil {
+			return err
+		}
+		err = r.rp.SetCompanyMFA(ctx, companyID, required)
+		if errors.Is(err, repository.ErrNotFound) {
+			return newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+		}
+		if err != nil {
+			return err
+		}
+		return r.rp.RecordAuditEvent(ctx, models.AuditEvent{Event: models.AuditMFAPolicy,
+			ActorID: strconv.FormatUint(uint64(userID), 10),
+			Detail:  fmt.Sprintf("company %d requires mfa: %t", companyID, required)})
+	})
+}
+
+func (r NewService) user(ctx context.Context, userID uint) (models.User, error) {
+	u, err := r.rp.GetUserByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.User{}, newError(ErrNotFound, CodeUserNotFound, "user not found", err)
+	}
+	return u, err
+}
+
+// checkSecondFactor accepts a TOTP code of a step later than the last one
+// used, or an unused recovery code. Wrong codes are counted and lock the
+// second factor of the user for a while.
+func (r NewService) checkSecondFactor(ctx context.Context, u models.User, code string, ip string) error {
+	id := strconv.FormatUint(uint64(u.ID), 10)
+	audit := models.AuditEvent{Email: u.Email, IP: ip, ActorID: id}
+
+	d, err := lockedFor(ctx, r.attempts, scopeMFA, id)
+	if err != nil {
+		log.Error().Err(err).Msg("checking mfa lockout")
+	}
+	if d > 0 {
+		return newError(ErrTooManyRequests, CodeMFALocked,
+			fmt.Sprintf("too many wrong codes, try again in %s", d.Round(time.Second)), nil)
+	}
+
+	ok, recovery, err := r.matchSecondFactor(ctx, u, code)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if err := clearFailures(ctx, r.attempts, scopeMFA, id); err != nil {
+			log.Error().Err(err).Msg("clearing mfa failures")
+		}
+		if recovery {
+			audit.Event = models.AuditRecoveryUsed
+			r.audit(ctx, audit)
+		}
+		return nil
+	}
+
+	audit.Event = models.AuditMFAFailed
+	r.audit(ctx, audit)
+	_, err = recordFailure(ctx, r.attempts, scopeMFA, id, mfaFailureLimit)
+	if err != nil {
+		log.Error().Err(err).Msg("counting mfa failure")
+	}
+	return newError(ErrUnauthorized, CodeInvalidMFACode, "invalid or already used code", nil)
+}
+
+func (r NewService) matchSecondFactor(ctx context.Context, u models.User, code string) (ok bool, recovery bool, err error) {
+	if mfa.IsCode(code) {
+		step, ok := mfa.Validate(u.TOTPSecret, code, time.Now())
+		if !ok {
+			return false, false, nil
+		}
+		// Refuses replays of a code, also within its own time step
+		ok, err = r.rp.AdvanceTOTPStep(ctx, u.ID, step)
+		return ok, false, err
+	}
+	ok, err = r.rp.UseRecoveryCode(ctx, u.ID, hashRecoveryCode(code))
+	return ok, true, err
+}
+
+// newRecoveryCodes returns fresh codes and the hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := mfa.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = hashRecoveryCode(c)
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode uses a plain sha256, unlike passwords the codes are random
+// with about 50 bits of entropy, and a deterministic hash lets the database
+// find the code.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(mfa.NormalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
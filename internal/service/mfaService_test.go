@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"job-portal/internal/auth"
+	"job-portal/internal/mfa"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	gomock "go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// expectTx runs transactions of the service on the mock itself.
+func expectTx(ms *repository.MockRepository) {
+	ms.EXPECT().WithTx(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fn func(repository.Repository) error) error {
+			return fn(ms)
+		}).AnyTimes()
+}
+
+func newMFAUser(t *testing.T) models.User {
+	secret, err := mfa.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.User{Email: "vishnu@gmail.com", MFAEnabled: true, TOTPSecret: secret}
+}
+
+func currentCode(t *testing.T, secret string) string {
+	code, err := mfa.Code(secret, mfa.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestNewService_AuthenticateMFA(t *testing.T) {
+	companyID := uint(7)
+	tests := []struct {
+		name        string
+		user        models.User
+		company     models.Company
+		wantPending bool
+		wantEnroll  bool
+	}{
+		{
+			name: "no second factor",
+			user: models.User{},
+		},
+		{
+			name:        "enrolled user",
+			user:        models.User{MFAEnabled: true},
+			wantPending: true,
+		},
+		{
+			name:        "company requires mfa",
+			user:        models.User{CompanyID: &companyID},
+			company:     models.Company{RequireMFA: true},
+			wantPending: true,
+			wantEnroll:  true,
+		},
+		{
+			name:    "company doesn't require mfa",
+			user:    models.User{CompanyID: &companyID},
+			company: models.Company{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mc := gomock.NewController(t)
+			ms := repository.NewMockRepository(mc)
+			_, st := newTestStore(t)
+			ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil).AnyTimes()
+			ms.EXPECT().AuthenticateUser(ctx, "vishnu@gmail.com", "Secret123").
+				Return(jwt.RegisteredClaims{Subject: "1", Audience: jwt.ClaimStrings{"students"}}, nil)
+			ms.EXPECT().GetUserByID(ctx, uint(1)).Return(tt.user, nil)
+			ms.EXPECT().GetCompanyByID(gomock.Any(), int(companyID)).Return(tt.company, nil).AnyTimes()
+
+			s := &NewService{rp: ms, attempts: st}
+			c, err := s.Authenticate(ctx, "vishnu@gmail.com", "Secret123", "10.0.0.1")
+			assert.Equal(t, nil, err)
+			assert.Equal(t, tt.wantPending, auth.IsMFAPending(c))
+			assert.Equal(t, tt.wantPending && tt.wantEnroll, auth.IsMFAPending(c) && auth.CanEnrollMFA(c))
+		})
+	}
+}
+
+func TestNewService_CompleteMFALogin(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	_, st := newTestStore(t)
+	s := &NewService{rp: ms, attempts: st}
+
+	u := newMFAUser(t)
+	u.ID = 1
+	code := currentCode(t, u.TOTPSecret)
+	ms.EXPECT().GetUserByID(ctx, uint(1)).Return(u, nil).AnyTimes()
+	ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil).AnyTimes()
+	gomock.InOrder(
+		ms.EXPECT().AdvanceTOTPStep(ctx, uint(1), gomock.Any()).Return(true, nil),
+		// The same code again is a replay
+		ms.EXPECT().AdvanceTOTPStep(ctx, uint(1), gomock.Any()).Return(false, nil),
+	)
+
+	c, err := s.CompleteMFALogin(ctx, 1, code, "10.0.0.1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "1", c.Subject)
+	assert.Equal(t, false, auth.IsMFAPending(c))
+
+	_, err = s.CompleteMFALogin(ctx, 1, code, "10.0.0.1")
+	assert.Equal(t, CodeInvalidMFACode, errCode(err))
+	assert.Equal(t, true, errors.Is(err, ErrUnauthorized))
+
+	// Recovery codes are matched by their normalized hash
+	ms.EXPECT().UseRecoveryCode(ctx, uint(1), hashRecoveryCode("abcde-fghjk")).Return(true, nil)
+	_, err = s.CompleteMFALogin(ctx, 1, " ABCDE-FGHJK ", "10.0.0.1")
+	assert.Equal(t, nil, err)
+}
+
+func TestNewService_CompleteMFALogin_MissingUser(t *testing.T) {
+	ctx := context.Background()
+	ms := repository.NewMockRepository(gomock.NewController(t))
+	s := &NewService{rp: ms}
+	ms.EXPECT().GetUserByID(ctx, uint(999)).Return(models.User{}, fmt.Errorf("fetching user 999: %w", repository.ErrNotFound))
+
+	_, err := s.CompleteMFALogin(ctx, 999, "123456", "10.0.0.1")
+	assert.Equal(t, CodeUserNotFound, errCode(err))
+	assert.Equal(t, true, errors.Is(err, ErrNotFound))
+}
+
+func TestNewService_CompleteMFALoginLockout(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	_, st := newTestStore(t)
+	s := &NewService{rp: ms, attempts: st}
+
+	u := newMFAUser(t)
+	u.ID = 1
+	ms.EXPECT().GetUserByID(ctx, uint(1)).Return(u, nil).AnyTimes()
+	ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil).AnyTimes()
+	ms.EXPECT().UseRecoveryCode(ctx, uint(1), gomock.Any()).Return(false, nil).Times(mfaFailureLimit)
+
+	for i := 0; i < mfaFailureLimit; i++ {
+		_, err := s.CompleteMFALogin(ctx, 1, "wrong-code", "10.0.0.1")
+		assert.Equal(t, CodeInvalidMFACode, errCode(err))
+	}
+
+	// Even a valid code is refused while locked
+	_, err := s.CompleteMFALogin(ctx, 1, currentCode(t, u.TOTPSecret), "10.0.0.1")
+	assert.Equal(t, CodeMFALocked, errCode(err))
+	assert.Equal(t, true, errors.Is(err, ErrTooManyRequests))
+}
+
+func TestNewService_EnrollTOTP(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	s := &NewService{rp: ms}
+
+	u := models.User{Email: "vishnu@gmail.com"}
+	u.ID = 1
+	var secret string
+	ms.EXPECT().GetUserByID(ctx, uint(1)).DoAndReturn(func(context.Context, uint) (models.User, error) {
+		u.TOTPPendingSecret = secret
+		return u, nil
+	}).AnyTimes()
+	ms.EXPECT().SetTOTPPendingSecret(ctx, uint(1), gomock.Any()).DoAndReturn(func(_ context.Context, _ uint, s string) error {
+		secret = s
+		return nil
+	})
+	ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil).AnyTimes()
+
+	_, _, err := s.ConfirmTOTP(ctx, 1, "123456")
+	assert.Equal(t, CodeMFANotEnrolled, errCode(err))
+
+	e, err := s.EnrollTOTP(ctx, 1)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, secret, e.Secret)
+	assert.Equal(t, true, strings.HasPrefix(e.ProvisioningURI, "otpauth://totp/Job%20Portal:vishnu@gmail.com?"))
+
+	wrong := "000000"
+	if currentCode(t, secret) == wrong {
+		wrong = "000001"
+	}
+	_, _, err = s.ConfirmTOTP(ctx, 1, wrong)
+	assert.Equal(t, CodeInvalidMFACode, errCode(err))
+
+	var hashes []string
+	ms.EXPECT().EnableTOTP(ctx, uint(1), secret, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uint, _ string, _ int64, h []string) error {
+			hashes = h
+			return nil
+		})
+	rc, c, err := s.ConfirmTOTP(ctx, 1, currentCode(t, secret))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, recoveryCodeCount, len(rc.Codes))
+	assert.Equal(t, hashRecoveryCode(rc.Codes[0]), hashes[0])
+	assert.Equal(t, "1", c.Subject)
+}
+
+func TestNewService_DisableTOTP(t *testing.T) {
+	companyID := uint(7)
+	tests := []struct {
+		name     string
+		company  models.Company
+		wantCode string
+	}{
+		{
+			name:    "disabled",
+			company: models.Company{},
+		},
+		{
+			name:     "company requires mfa",
+			company:  models.Company{RequireMFA: true},
+			wantCode: CodeMFARequired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mc := gomock.NewController(t)
+			ms := repository.NewMockRepository(mc)
+			_, st := newTestStore(t)
+			s := &NewService{rp: ms, attempts: st}
+
+			u := newMFAUser(t)
+			u.ID = 1
+			u.CompanyID = &companyID
+			ms.EXPECT().GetUserByID(ctx, uint(1)).Return(u, nil)
+			ms.EXPECT().GetCompanyByID(gomock.Any(), int(companyID)).Return(tt.company, nil)
+			ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil).AnyTimes()
+			ms.EXPECT().AdvanceTOTPStep(ctx, uint(1), gomock.Any()).Return(true, nil).AnyTimes()
+			ms.EXPECT().DisableTOTP(ctx, uint(1)).Return(nil).AnyTimes()
+
+			err := s.DisableTOTP(ctx, 1, currentCode(t, u.TOTPSecret), "10.0.0.1")
+			assert.Equal(t, tt.wantCode, errCode(err))
+		})
+	}
+}
+
+func TestNewService_SetCompanyMFAPolicy(t *testing.T) {
+	owner, member := uint(1), uint(2)
+	tests := []struct {
+		name     string
+		company  models.Company
+		getErr   error
+		wantCode string
+	}{
+		{
+			name:    "owner",
+			company: models.Company{ID: 7, OwnerID: &owner},
+		},
+		{
+			name:     "member who doesn't own it",
+			company:  models.Company{ID: 7, OwnerID: &member},
+			wantCode: CodeNotCompanyOwner,
+		},
+		{
+			name:     "without owner",
+			company:  models.Company{ID: 7},
+			wantCode: CodeNotCompanyOwner,
+		},
+		{
+			name:     "missing company",
+			getErr:   fmt.Errorf("fetching company 7: %w", repository.ErrNotFound),
+			wantCode: CodeCompanyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mc := gomock.NewController(t)
+			ms := repository.NewMockRepository(mc)
+			s := &NewService{rp: ms}
+
+			expectTx(ms)
+			ms.EXPECT().GetCompanyByID(ctx, 7).Return(tt.company, tt.getErr)
+			if tt.wantCode == "" {
+				ms.EXPECT().SetCompanyMFA(ctx, uint(7), true).Return(nil)
+				ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil)
+			}
+
+			err := s.SetCompanyMFAPolicy(ctx, 7, owner, true)
+			assert.Equal(t, tt.wantCode, errCode(err))
+		})
+	}
+}
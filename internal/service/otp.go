@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"job-portal/internal/authstate"
+	"job-portal/internal/models"
+	"math/big"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// otpMaxAttempts wrong guesses lock the otp until a new one is issued.
+	otpMaxAttempts = 5
+	// otpResendCooldown is the minimum time between two otps for an email.
+	otpResendCooldown = time.Minute
+)
+
+// otpPurpose keeps otps issued for different flows apart, an otp sent to
+// reset a password can't verify an email and the other way round.
+type otpPurpose struct {
+	name string
+	// ttl is how long an issued otp can be redeemed.
+	ttl time.Duration
+}
+
+var (
+	otpPasswordReset     = otpPurpose{name: "reset", ttl: 5 * time.Minute}
+	otpEmailVerification = otpPurpose{name: "verify", ttl: 24 * time.Hour}
+	// otpCompanyVerification proves an address on the domain of a company.
+	otpCompanyVerification = otpPurpose{name: "company", ttl: time.Hour}
+)
+
+func otpKey(p otpPurpose, email string) string {
+	return "otp:" + p.name + ":" + models.NormalizeEmail(email)
+}
+
+func otpCooldownKey(p otpPurpose, email string) string {
+	return "otp:" + p.name + ":cooldown:" + models.NormalizeEmail(email)
+}
+
+// issueOTP generates an otp for email and stores its hash, replacing any otp
+// issued before for the same purpose. It refuses to issue another one within
+// otpResendCooldown.
+func issueOTP(ctx context.Context, st authstate.Store, p otpPurpose, email string) (string, error) {
+	ok, err := st.StartCooldown(ctx, otpCooldownKey(p, email), otpResendCooldown)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", newError(ErrTooManyRequests, CodeOTPCooldown, "an otp was sent recently, wait before requesting another one", nil)
+	}
+
+	otp, err := generateOTP()
+	if err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(otp), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hashing otp: %w", err)
+	}
+
+	err = st.PutOTP(ctx, otpKey(p, email), hash, p.ttl)
+	if err != nil {
+		return "", err
+	}
+	return otp, nil
+}
+
+// revokeOTP drops the otp and the cooldown, used when the otp never reached
+// the user.
+func revokeOTP(ctx context.Context, st authstate.Store, p otpPurpose, email string) error {
+	_, err := st.Delete(ctx, otpKey(p, email), otpCooldownKey(p, email))
+	return err
+}
+
+// redeemOTP checks otp against the one issued for email and invalidates it on
+// success so it can't be used twice. Every attempt is counted before the
+// comparison and the otp is locked after otpMaxAttempts.
+func redeemOTP(ctx context.Context, st authstate.Store, p otpPurpose, email, otp string) error {
+	key := otpKey(p, email)
+	invalid := newError(ErrValidation, CodeInvalidOTP, "otp is invalid or has expired", nil)
+
+	hash, attempts, err := st.AttemptOTP(ctx, key)
+	if errors.Is(err, authstate.ErrNoOTP) {
+		return invalid
+	}
+	if err != nil {
+		return err
+	}
+	if attempts > otpMaxAttempts {
+		return newError(ErrTooManyRequests, CodeOTPLocked, "too many wrong attempts, request a new otp", nil)
+	}
+
+	if bcrypt.CompareHashAndPassword(hash, []byte(otp)) != nil {
+		return invalid
+	}
+
+	// Only the request that actually deletes the otp may use it, a concurrent
+	// request with the same otp finds nothing to delete.
+	n, err := st.Delete(ctx, key)
+	if err != nil {
+		return fmt.Errorf("invalidating otp: %w", err)
+	}
+	if n == 0 {
+		return invalid
+	}
+	return nil
+}
+
+// generateOTP returns a random 6 digit code.
+func generateOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("generating otp: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
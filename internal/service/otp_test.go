@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"job-portal/internal/authstate"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func newTestStore(t *testing.T) (*miniredis.Miniredis, authstate.Store) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return mr, authstate.NewRedis(rdb)
+}
+
+func errCode(err error) string {
+	var se *Error
+	if errors.As(err, &se) {
+		return se.Code
+	}
+	return ""
+}
+
+func TestGenerateOTP(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		otp, err := generateOTP()
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 6, len(otp))
+	}
+}
+
+func TestOTP_HashedAndSingleUse(t *testing.T) {
+	ctx := context.Background()
+	mr, st := newTestStore(t)
+
+	otp, err := issueOTP(ctx, st, otpPasswordReset, "Vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+
+	// Only a hash of the otp is stored
+	stored := mr.HGet("otp:reset:vishnu@gmail.com", "hash")
+	assert.NotEqual(t, "", stored)
+	assert.NotEqual(t, otp, stored)
+
+	err = redeemOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com", otp)
+	assert.Equal(t, nil, err)
+
+	err = redeemOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com", otp)
+	assert.Equal(t, CodeInvalidOTP, errCode(err))
+}
+
+func TestOTP_Expires(t *testing.T) {
+	ctx := context.Background()
+	mr, st := newTestStore(t)
+
+	otp, err := issueOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+
+	mr.FastForward(otpPasswordReset.ttl)
+	err = redeemOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com", otp)
+	assert.Equal(t, CodeInvalidOTP, errCode(err))
+	assert.Equal(t, false, mr.Exists("otp:reset:vishnu@gmail.com"))
+}
+
+func TestOTP_LockedAfterWrongAttempts(t *testing.T) {
+	ctx := context.Background()
+	mr, st := newTestStore(t)
+
+	otp, err := issueOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+	wrong := "000000"
+	if otp == wrong {
+		wrong = "000001"
+	}
+
+	for i := 0; i < otpMaxAttempts; i++ {
+		err = redeemOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com", wrong)
+		assert.Equal(t, CodeInvalidOTP, errCode(err))
+	}
+
+	// Even the right otp is refused once it is locked
+	err = redeemOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com", otp)
+	assert.Equal(t, CodeOTPLocked, errCode(err))
+	assert.Equal(t, true, errors.Is(err, ErrTooManyRequests))
+
+	// A new otp starts with a clean slate
+	mr.FastForward(otpResendCooldown)
+	otp, err = issueOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+	err = redeemOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com", otp)
+	assert.Equal(t, nil, err)
+}
+
+func TestOTP_ResendCooldown(t *testing.T) {
+	ctx := context.Background()
+	mr, st := newTestStore(t)
+
+	_, err := issueOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+
+	_, err = issueOTP(ctx, st, otpPasswordReset, "VISHNU@gmail.com")
+	assert.Equal(t, CodeOTPCooldown, errCode(err))
+
+	mr.FastForward(otpResendCooldown)
+	_, err = issueOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+
+	// Revoking an unsent otp lifts the cooldown
+	err = revokeOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+	_, err = issueOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+}
+
+func TestOTP_PurposesAreSeparate(t *testing.T) {
+	ctx := context.Background()
+	_, st := newTestStore(t)
+
+	otp, err := issueOTP(ctx, st, otpEmailVerification, "vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+
+	// The cooldown of one purpose doesn't hold back the other
+	_, err = issueOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+
+	err = redeemOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com", otp)
+	assert.Equal(t, CodeInvalidOTP, errCode(err))
+	err = redeemOTP(ctx, st, otpEmailVerification, "vishnu@gmail.com", otp)
+	assert.Equal(t, nil, err)
+}
@@ -2,32 +2,218 @@ package service
 
 import (
 	"context"
+	"io"
+	"job-portal/internal/authstate"
+	"job-portal/internal/cache"
+	"job-portal/internal/invalidation"
 	"job-portal/internal/models"
 	"job-portal/internal/repository"
+	"job-portal/internal/storage"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type NewService struct {
 	rp repository.Repository
+	// cache keeps data read often, like the jobs applications are matched
+	// against.
+	cache cache.Cache
+	// jobs reads jobs through cache.
+	jobs *jobCache
+	// bus tells the other instances which cached data changed, it is nil
+	// when only one instance runs.
+	bus invalidation.Publisher
+	// attempts keeps otps and login lockouts.
+	attempts authstate.Store
+	// mailer sends the emails, nil sends them through SMTP.
+	mailer Mailer
+	// notifications counts the emails sent in the background that haven't
+	// been delivered yet.
+	notifications *sync.WaitGroup
+	// verifiedLogin refuses logins until the email is verified.
+	verifiedLogin bool
+	// hideUnverified hides the jobs of companies without the verified
+	// badge.
+	hideUnverified bool
+	// taskWorkers is the number of tasks processed at the same time, tasks
+	// can't be submitted without workers.
+	taskWorkers int
+	// applyWorkers is the number of jobs loaded and matched at the same time
+	// for one batch of applications.
+	applyWorkers int
+	tasks        chan string
+	// queued are the ids waiting in tasks, none of them is queued twice.
+	queued *queuedTasks
+	// instance tells the instances apart that claim tasks.
+	instance string
+	// work stops the task workers once it is done.
+	work context.Context
+	// files keeps uploaded media, links to them are signed by signer and
+	// expire after urlTTL.
+	files     storage.Storage
+	signer    *storage.Signer
+	urlTTL    time.Duration
+	maxUpload int64
+}
+
+// WithInvalidation publishes changes of cached data on the bus so every
+// instance evicts its local copies.
+func WithInvalidation(p invalidation.Publisher) Option {
+	return func(s *NewService) {
+		s.bus = p
+	}
+}
+
+// WithApplyWorkers matches the applications of n jobs at the same time, zero
+// keeps the default.
+func WithApplyWorkers(n int) Option {
+	return func(s *NewService) {
+		s.applyWorkers = n
+	}
+}
+
+// WithTaskWorkers processes submitted tasks with n workers. Unfinished tasks
+// are resumed as soon as the service is created.
+func WithTaskWorkers(n int) Option {
+	return func(s *NewService) {
+		s.taskWorkers = n
+	}
+}
+
+// WithWorkContext stops the task workers once ctx is done. A task that was
+// interrupted is resumed when its lease ends.
+func WithWorkContext(ctx context.Context) Option {
+	return func(s *NewService) {
+		s.work = ctx
+	}
+}
+
+// WithMailer sends the emails of the service with m instead of SMTP.
+func WithMailer(m Mailer) Option {
+	return func(s *NewService) {
+		s.mailer = m
+	}
+}
+
+// WithStorage keeps uploaded files in st, links to them are signed by
+// signer and valid for ttl. Without storage uploads fail.
+func WithStorage(st storage.Storage, signer *storage.Signer, ttl time.Duration) Option {
+	return func(s *NewService) {
+		s.files = st
+		s.signer = signer
+		if ttl > 0 {
+			s.urlTTL = ttl
+		}
+	}
+}
+
+// WithUploadLimit refuses uploads larger than n bytes, zero keeps the
+// default.
+func WithUploadLimit(n int64) Option {
+	return func(s *NewService) {
+		if n > 0 {
+			s.maxUpload = n
+		}
+	}
+}
+
+// Option configures optional policies of the service.
+type Option func(*NewService)
+
+// WithVerifiedLogin refuses logins of users who haven't verified their email.
+func WithVerifiedLogin(required bool) Option {
+	return func(s *NewService) {
+		s.verifiedLogin = required
+	}
+}
+
+// WithHiddenUnverified hides the jobs of unverified companies from listings
+// and applications.
+func WithHiddenUnverified(hide bool) Option {
+	return func(s *NewService) {
+		s.hideUnverified = hide
+	}
+}
+
+// WithJobCacheTTL sets how long jobs stay cached, zero keeps the default.
+func WithJobCacheTTL(ttl time.Duration) Option {
+	return func(s *NewService) {
+		if ttl > 0 {
+			s.jobs.ttl = ttl
+		}
+	}
 }
 
 //go:generate mockgen -source=service.go -destination=service_mock.go -package=service
 type Service interface {
 	CreateUser(ctx context.Context, nu models.NewUser) (models.User, error)
-	Authenticate(ctx context.Context, email string, password string) (jwt.RegisteredClaims, error)
-	CreateJob(ctx context.Context, nj models.NewJob, cId int) (models.Job, error)
+	Authenticate(ctx context.Context, email string, password string, ip string) (jwt.RegisteredClaims, error)
+	TokenRevoked(ctx context.Context, c jwt.RegisteredClaims) (bool, error)
+	UnlockAccount(ctx context.Context, u models.Unlock, actorID string) error
+	VerifyEmail(ctx context.Context, v models.VerifyEmail) error
+	ResendVerification(ctx context.Context, email string) error
+	CompleteMFALogin(ctx context.Context, userID uint, code string, ip string) (jwt.RegisteredClaims, error)
+	EnrollTOTP(ctx context.Context, userID uint) (models.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID uint, code string) (models.RecoveryCodes, jwt.RegisteredClaims, error)
+	DisableTOTP(ctx context.Context, userID uint, code string, ip string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, code string, ip string) (models.RecoveryCodes, error)
+	SetCompanyMFAPolicy(ctx context.Context, companyID uint, userID uint, required bool) error
+	CreateJob(ctx context.Context, nj models.NewJob, cId int, userID uint) (models.Job, error)
 	ViewJob(ctx context.Context) ([]models.Job, error)
 	GetJobInfoByID(ctx context.Context, jId int) (models.Job, error)
 	ViewJobByCompanyId(ctx context.Context, cId int) ([]models.Job, error)
-	CreateCompany(ctx context.Context, ni models.NewCompany) (models.Company, error)
-	ViewCompany(ctx context.Context) ([]models.Company, error)
+	CreateCompany(ctx context.Context, ni models.NewCompany, userID uint) (models.Company, error)
+	ViewCompany(ctx context.Context, q models.CompanyQuery) (models.CompanyPage, error)
 	GetCompanyInfoByID(ctx context.Context, uid int) (models.Company, error)
-	ApplyJob(application []models.JobApplication) ([]models.Applicant, error)
-	CheckEmail(e string) (bool, error)
-	UpdatePassword(np models.Reset) (bool, error)
+	UpdateCompany(ctx context.Context, companyID uint, userID uint, p models.CompanyProfile) (models.Company, error)
+	PatchCompany(ctx context.Context, companyID uint, userID uint, cp models.CompanyPatch) (models.Company, error)
+	DeleteCompany(ctx context.Context, companyID uint, userID uint) error
+	UploadCompanyMedia(ctx context.Context, companyID uint, userID uint, kind string, r io.Reader) (models.CompanyMedia, error)
+	ListCompanyMedia(ctx context.Context, companyID uint) ([]models.CompanyMedia, error)
+	DeleteCompanyMedia(ctx context.Context, companyID uint, userID uint, mediaID uint) error
+	OpenFile(ctx context.Context, key, expires, signature string) (storage.Object, error)
+	RequestCompanyVerification(ctx context.Context, companyID uint, userID uint, email string) (models.CompanyVerification, error)
+	ConfirmCompanyVerification(ctx context.Context, companyID uint, userID uint, code string) (models.CompanyVerification, error)
+	GetCompanyVerification(ctx context.Context, companyID uint, userID uint) (models.CompanyVerification, error)
+	ListCompanyVerifications(ctx context.Context, status string) ([]models.CompanyVerification, error)
+	ReviewCompanyVerification(ctx context.Context, id uint, approve bool, reason string, actorID string) (models.CompanyVerification, error)
+	ApplyJob(ctx context.Context, application []models.JobApplication) ([]models.ApplicationOutcome, error)
+	SubmitApplications(ctx context.Context, userID uint, applications []models.JobApplication) (models.Task, error)
+	GetTask(ctx context.Context, id string, userID uint) (models.Task, error)
+	CheckEmail(ctx context.Context, e string) (bool, error)
+	UpdatePassword(ctx context.Context, np models.Reset) (bool, error)
+}
+
+// inTx runs fn with a copy of the service whose repository calls share one
+// transaction.
+func (r NewService) inTx(ctx context.Context, fn func(NewService) error) error {
+	return r.rp.WithTx(ctx, func(rp repository.Repository) error {
+		tx := r
+		tx.rp = rp
+		return fn(tx)
+	})
 }
 
-func NewServiceStore(s repository.Repository) Service {
-	return &NewService{rp: s}
+// NewServiceStore builds the service on shared clients, c and as are created
+// once in main.
+func NewServiceStore(s repository.Repository, c cache.Cache, as authstate.Store, opts ...Option) Service {
+	if as == nil {
+		panic("service: no store for otps and lockouts")
+	}
+	ns := &NewService{rp: s, cache: c, jobs: newJobCache(c, defaultJobCacheTTL), attempts: as,
+		urlTTL: defaultURLTTL, maxUpload: DefaultMaxUpload, notifications: new(sync.WaitGroup)}
+	for _, opt := range opts {
+		opt(ns)
+	}
+	if ns.taskWorkers > 0 {
+		ns.instance = uuid.NewString()
+		if ns.work == nil {
+			ns.work = context.Background()
+		}
+		ns.startTasks(ns.work)
+	}
+	return ns
 }
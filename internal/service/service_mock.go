@@ -10,7 +10,9 @@ package service
 
 import (
 	context "context"
+	io "io"
 	models "job-portal/internal/models"
+	storage "job-portal/internal/storage"
 	reflect "reflect"
 
 	jwt "github.com/golang-jwt/jwt/v5"
@@ -41,78 +43,124 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // ApplyJob mocks base method.
-func (m *MockService) ApplyJob(application []models.JobApplication) ([]models.Applicant, error) {
+func (m *MockService) ApplyJob(ctx context.Context, application []models.JobApplication) ([]models.ApplicationOutcome, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ApplyJob", application)
-	ret0, _ := ret[0].([]models.Applicant)
+	ret := m.ctrl.Call(m, "ApplyJob", ctx, application)
+	ret0, _ := ret[0].([]models.ApplicationOutcome)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ApplyJob indicates an expected call of ApplyJob.
-func (mr *MockServiceMockRecorder) ApplyJob(application any) *gomock.Call {
+func (mr *MockServiceMockRecorder) ApplyJob(ctx, application any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyJob", reflect.TypeOf((*MockService)(nil).ApplyJob), application)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyJob", reflect.TypeOf((*MockService)(nil).ApplyJob), ctx, application)
 }
 
 // Authenticate mocks base method.
-func (m *MockService) Authenticate(ctx context.Context, email, password string) (jwt.RegisteredClaims, error) {
+func (m *MockService) Authenticate(ctx context.Context, email, password, ip string) (jwt.RegisteredClaims, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Authenticate", ctx, email, password)
+	ret := m.ctrl.Call(m, "Authenticate", ctx, email, password, ip)
 	ret0, _ := ret[0].(jwt.RegisteredClaims)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Authenticate indicates an expected call of Authenticate.
-func (mr *MockServiceMockRecorder) Authenticate(ctx, email, password any) *gomock.Call {
+func (mr *MockServiceMockRecorder) Authenticate(ctx, email, password, ip any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockService)(nil).Authenticate), ctx, email, password)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockService)(nil).Authenticate), ctx, email, password, ip)
 }
 
 // CheckEmail mocks base method.
-func (m *MockService) CheckEmail(e string) (bool, error) {
+func (m *MockService) CheckEmail(ctx context.Context, e string) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CheckEmail", e)
+	ret := m.ctrl.Call(m, "CheckEmail", ctx, e)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CheckEmail indicates an expected call of CheckEmail.
-func (mr *MockServiceMockRecorder) CheckEmail(e any) *gomock.Call {
+func (mr *MockServiceMockRecorder) CheckEmail(ctx, e any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckEmail", reflect.TypeOf((*MockService)(nil).CheckEmail), e)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckEmail", reflect.TypeOf((*MockService)(nil).CheckEmail), ctx, e)
+}
+
+// CompleteMFALogin mocks base method.
+func (m *MockService) CompleteMFALogin(ctx context.Context, userID uint, code, ip string) (jwt.RegisteredClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteMFALogin", ctx, userID, code, ip)
+	ret0, _ := ret[0].(jwt.RegisteredClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteMFALogin indicates an expected call of CompleteMFALogin.
+func (mr *MockServiceMockRecorder) CompleteMFALogin(ctx, userID, code, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteMFALogin", reflect.TypeOf((*MockService)(nil).CompleteMFALogin), ctx, userID, code, ip)
+}
+
+// ConfirmCompanyVerification mocks base method.
+func (m *MockService) ConfirmCompanyVerification(ctx context.Context, companyID, userID uint, code string) (models.CompanyVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmCompanyVerification", ctx, companyID, userID, code)
+	ret0, _ := ret[0].(models.CompanyVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmCompanyVerification indicates an expected call of ConfirmCompanyVerification.
+func (mr *MockServiceMockRecorder) ConfirmCompanyVerification(ctx, companyID, userID, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmCompanyVerification", reflect.TypeOf((*MockService)(nil).ConfirmCompanyVerification), ctx, companyID, userID, code)
+}
+
+// ConfirmTOTP mocks base method.
+func (m *MockService) ConfirmTOTP(ctx context.Context, userID uint, code string) (models.RecoveryCodes, jwt.RegisteredClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmTOTP", ctx, userID, code)
+	ret0, _ := ret[0].(models.RecoveryCodes)
+	ret1, _ := ret[1].(jwt.RegisteredClaims)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ConfirmTOTP indicates an expected call of ConfirmTOTP.
+func (mr *MockServiceMockRecorder) ConfirmTOTP(ctx, userID, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmTOTP", reflect.TypeOf((*MockService)(nil).ConfirmTOTP), ctx, userID, code)
 }
 
 // CreateCompany mocks base method.
-func (m *MockService) CreateCompany(ctx context.Context, ni models.NewCompany) (models.Company, error) {
+func (m *MockService) CreateCompany(ctx context.Context, ni models.NewCompany, userID uint) (models.Company, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateCompany", ctx, ni)
+	ret := m.ctrl.Call(m, "CreateCompany", ctx, ni, userID)
 	ret0, _ := ret[0].(models.Company)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateCompany indicates an expected call of CreateCompany.
-func (mr *MockServiceMockRecorder) CreateCompany(ctx, ni any) *gomock.Call {
+func (mr *MockServiceMockRecorder) CreateCompany(ctx, ni, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCompany", reflect.TypeOf((*MockService)(nil).CreateCompany), ctx, ni)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCompany", reflect.TypeOf((*MockService)(nil).CreateCompany), ctx, ni, userID)
 }
 
 // CreateJob mocks base method.
-func (m *MockService) CreateJob(ctx context.Context, nj models.NewJob, cId int) (models.Job, error) {
+func (m *MockService) CreateJob(ctx context.Context, nj models.NewJob, cId int, userID uint) (models.Job, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateJob", ctx, nj, cId)
+	ret := m.ctrl.Call(m, "CreateJob", ctx, nj, cId, userID)
 	ret0, _ := ret[0].(models.Job)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateJob indicates an expected call of CreateJob.
-func (mr *MockServiceMockRecorder) CreateJob(ctx, nj, cId any) *gomock.Call {
+func (mr *MockServiceMockRecorder) CreateJob(ctx, nj, cId, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockService)(nil).CreateJob), ctx, nj, cId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockService)(nil).CreateJob), ctx, nj, cId, userID)
 }
 
 // CreateUser mocks base method.
@@ -130,6 +178,63 @@ func (mr *MockServiceMockRecorder) CreateUser(ctx, nu any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockService)(nil).CreateUser), ctx, nu)
 }
 
+// DeleteCompany mocks base method.
+func (m *MockService) DeleteCompany(ctx context.Context, companyID, userID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCompany", ctx, companyID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCompany indicates an expected call of DeleteCompany.
+func (mr *MockServiceMockRecorder) DeleteCompany(ctx, companyID, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCompany", reflect.TypeOf((*MockService)(nil).DeleteCompany), ctx, companyID, userID)
+}
+
+// DeleteCompanyMedia mocks base method.
+func (m *MockService) DeleteCompanyMedia(ctx context.Context, companyID, userID, mediaID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCompanyMedia", ctx, companyID, userID, mediaID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteCompanyMedia indicates an expected call of DeleteCompanyMedia.
+func (mr *MockServiceMockRecorder) DeleteCompanyMedia(ctx, companyID, userID, mediaID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCompanyMedia", reflect.TypeOf((*MockService)(nil).DeleteCompanyMedia), ctx, companyID, userID, mediaID)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockService) DisableTOTP(ctx context.Context, userID uint, code, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, userID, code, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockServiceMockRecorder) DisableTOTP(ctx, userID, code, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockService)(nil).DisableTOTP), ctx, userID, code, ip)
+}
+
+// EnrollTOTP mocks base method.
+func (m *MockService) EnrollTOTP(ctx context.Context, userID uint) (models.TOTPEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTOTP", ctx, userID)
+	ret0, _ := ret[0].(models.TOTPEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTOTP indicates an expected call of EnrollTOTP.
+func (mr *MockServiceMockRecorder) EnrollTOTP(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTOTP", reflect.TypeOf((*MockService)(nil).EnrollTOTP), ctx, userID)
+}
+
 // GetCompanyInfoByID mocks base method.
 func (m *MockService) GetCompanyInfoByID(ctx context.Context, uid int) (models.Company, error) {
 	m.ctrl.T.Helper()
@@ -145,6 +250,21 @@ func (mr *MockServiceMockRecorder) GetCompanyInfoByID(ctx, uid any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompanyInfoByID", reflect.TypeOf((*MockService)(nil).GetCompanyInfoByID), ctx, uid)
 }
 
+// GetCompanyVerification mocks base method.
+func (m *MockService) GetCompanyVerification(ctx context.Context, companyID, userID uint) (models.CompanyVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCompanyVerification", ctx, companyID, userID)
+	ret0, _ := ret[0].(models.CompanyVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCompanyVerification indicates an expected call of GetCompanyVerification.
+func (mr *MockServiceMockRecorder) GetCompanyVerification(ctx, companyID, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompanyVerification", reflect.TypeOf((*MockService)(nil).GetCompanyVerification), ctx, companyID, userID)
+}
+
 // GetJobInfoByID mocks base method.
 func (m *MockService) GetJobInfoByID(ctx context.Context, jId int) (models.Job, error) {
 	m.ctrl.T.Helper()
@@ -160,34 +280,270 @@ func (mr *MockServiceMockRecorder) GetJobInfoByID(ctx, jId any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobInfoByID", reflect.TypeOf((*MockService)(nil).GetJobInfoByID), ctx, jId)
 }
 
+// GetTask mocks base method.
+func (m *MockService) GetTask(ctx context.Context, id string, userID uint) (models.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTask", ctx, id, userID)
+	ret0, _ := ret[0].(models.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTask indicates an expected call of GetTask.
+func (mr *MockServiceMockRecorder) GetTask(ctx, id, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTask", reflect.TypeOf((*MockService)(nil).GetTask), ctx, id, userID)
+}
+
+// ListCompanyMedia mocks base method.
+func (m *MockService) ListCompanyMedia(ctx context.Context, companyID uint) ([]models.CompanyMedia, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCompanyMedia", ctx, companyID)
+	ret0, _ := ret[0].([]models.CompanyMedia)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCompanyMedia indicates an expected call of ListCompanyMedia.
+func (mr *MockServiceMockRecorder) ListCompanyMedia(ctx, companyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCompanyMedia", reflect.TypeOf((*MockService)(nil).ListCompanyMedia), ctx, companyID)
+}
+
+// ListCompanyVerifications mocks base method.
+func (m *MockService) ListCompanyVerifications(ctx context.Context, status string) ([]models.CompanyVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCompanyVerifications", ctx, status)
+	ret0, _ := ret[0].([]models.CompanyVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCompanyVerifications indicates an expected call of ListCompanyVerifications.
+func (mr *MockServiceMockRecorder) ListCompanyVerifications(ctx, status any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCompanyVerifications", reflect.TypeOf((*MockService)(nil).ListCompanyVerifications), ctx, status)
+}
+
+// OpenFile mocks base method.
+func (m *MockService) OpenFile(ctx context.Context, key, expires, signature string) (storage.Object, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenFile", ctx, key, expires, signature)
+	ret0, _ := ret[0].(storage.Object)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenFile indicates an expected call of OpenFile.
+func (mr *MockServiceMockRecorder) OpenFile(ctx, key, expires, signature any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenFile", reflect.TypeOf((*MockService)(nil).OpenFile), ctx, key, expires, signature)
+}
+
+// PatchCompany mocks base method.
+func (m *MockService) PatchCompany(ctx context.Context, companyID, userID uint, cp models.CompanyPatch) (models.Company, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchCompany", ctx, companyID, userID, cp)
+	ret0, _ := ret[0].(models.Company)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchCompany indicates an expected call of PatchCompany.
+func (mr *MockServiceMockRecorder) PatchCompany(ctx, companyID, userID, cp any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchCompany", reflect.TypeOf((*MockService)(nil).PatchCompany), ctx, companyID, userID, cp)
+}
+
+// RegenerateRecoveryCodes mocks base method.
+func (m *MockService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code, ip string) (models.RecoveryCodes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegenerateRecoveryCodes", ctx, userID, code, ip)
+	ret0, _ := ret[0].(models.RecoveryCodes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegenerateRecoveryCodes indicates an expected call of RegenerateRecoveryCodes.
+func (mr *MockServiceMockRecorder) RegenerateRecoveryCodes(ctx, userID, code, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegenerateRecoveryCodes", reflect.TypeOf((*MockService)(nil).RegenerateRecoveryCodes), ctx, userID, code, ip)
+}
+
+// RequestCompanyVerification mocks base method.
+func (m *MockService) RequestCompanyVerification(ctx context.Context, companyID, userID uint, email string) (models.CompanyVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestCompanyVerification", ctx, companyID, userID, email)
+	ret0, _ := ret[0].(models.CompanyVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestCompanyVerification indicates an expected call of RequestCompanyVerification.
+func (mr *MockServiceMockRecorder) RequestCompanyVerification(ctx, companyID, userID, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestCompanyVerification", reflect.TypeOf((*MockService)(nil).RequestCompanyVerification), ctx, companyID, userID, email)
+}
+
+// ResendVerification mocks base method.
+func (m *MockService) ResendVerification(ctx context.Context, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendVerification", ctx, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResendVerification indicates an expected call of ResendVerification.
+func (mr *MockServiceMockRecorder) ResendVerification(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerification", reflect.TypeOf((*MockService)(nil).ResendVerification), ctx, email)
+}
+
+// ReviewCompanyVerification mocks base method.
+func (m *MockService) ReviewCompanyVerification(ctx context.Context, id uint, approve bool, reason, actorID string) (models.CompanyVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewCompanyVerification", ctx, id, approve, reason, actorID)
+	ret0, _ := ret[0].(models.CompanyVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviewCompanyVerification indicates an expected call of ReviewCompanyVerification.
+func (mr *MockServiceMockRecorder) ReviewCompanyVerification(ctx, id, approve, reason, actorID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewCompanyVerification", reflect.TypeOf((*MockService)(nil).ReviewCompanyVerification), ctx, id, approve, reason, actorID)
+}
+
+// SetCompanyMFAPolicy mocks base method.
+func (m *MockService) SetCompanyMFAPolicy(ctx context.Context, companyID, userID uint, required bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCompanyMFAPolicy", ctx, companyID, userID, required)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCompanyMFAPolicy indicates an expected call of SetCompanyMFAPolicy.
+func (mr *MockServiceMockRecorder) SetCompanyMFAPolicy(ctx, companyID, userID, required any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCompanyMFAPolicy", reflect.TypeOf((*MockService)(nil).SetCompanyMFAPolicy), ctx, companyID, userID, required)
+}
+
+// SubmitApplications mocks base method.
+func (m *MockService) SubmitApplications(ctx context.Context, userID uint, applications []models.JobApplication) (models.Task, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitApplications", ctx, userID, applications)
+	ret0, _ := ret[0].(models.Task)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitApplications indicates an expected call of SubmitApplications.
+func (mr *MockServiceMockRecorder) SubmitApplications(ctx, userID, applications any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitApplications", reflect.TypeOf((*MockService)(nil).SubmitApplications), ctx, userID, applications)
+}
+
+// TokenRevoked mocks base method.
+func (m *MockService) TokenRevoked(ctx context.Context, c jwt.RegisteredClaims) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TokenRevoked", ctx, c)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TokenRevoked indicates an expected call of TokenRevoked.
+func (mr *MockServiceMockRecorder) TokenRevoked(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TokenRevoked", reflect.TypeOf((*MockService)(nil).TokenRevoked), ctx, c)
+}
+
+// UnlockAccount mocks base method.
+func (m *MockService) UnlockAccount(ctx context.Context, u models.Unlock, actorID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockAccount", ctx, u, actorID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockAccount indicates an expected call of UnlockAccount.
+func (mr *MockServiceMockRecorder) UnlockAccount(ctx, u, actorID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockAccount", reflect.TypeOf((*MockService)(nil).UnlockAccount), ctx, u, actorID)
+}
+
+// UpdateCompany mocks base method.
+func (m *MockService) UpdateCompany(ctx context.Context, companyID, userID uint, p models.CompanyProfile) (models.Company, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCompany", ctx, companyID, userID, p)
+	ret0, _ := ret[0].(models.Company)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateCompany indicates an expected call of UpdateCompany.
+func (mr *MockServiceMockRecorder) UpdateCompany(ctx, companyID, userID, p any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCompany", reflect.TypeOf((*MockService)(nil).UpdateCompany), ctx, companyID, userID, p)
+}
+
 // UpdatePassword mocks base method.
-func (m *MockService) UpdatePassword(np models.Reset) (bool, error) {
+func (m *MockService) UpdatePassword(ctx context.Context, np models.Reset) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdatePassword", np)
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, np)
 	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdatePassword indicates an expected call of UpdatePassword.
-func (mr *MockServiceMockRecorder) UpdatePassword(np any) *gomock.Call {
+func (mr *MockServiceMockRecorder) UpdatePassword(ctx, np any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockService)(nil).UpdatePassword), ctx, np)
+}
+
+// UploadCompanyMedia mocks base method.
+func (m *MockService) UploadCompanyMedia(ctx context.Context, companyID, userID uint, kind string, r io.Reader) (models.CompanyMedia, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadCompanyMedia", ctx, companyID, userID, kind, r)
+	ret0, _ := ret[0].(models.CompanyMedia)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadCompanyMedia indicates an expected call of UploadCompanyMedia.
+func (mr *MockServiceMockRecorder) UploadCompanyMedia(ctx, companyID, userID, kind, r any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadCompanyMedia", reflect.TypeOf((*MockService)(nil).UploadCompanyMedia), ctx, companyID, userID, kind, r)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockService) VerifyEmail(ctx context.Context, v models.VerifyEmail) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, v)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockServiceMockRecorder) VerifyEmail(ctx, v any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockService)(nil).UpdatePassword), np)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockService)(nil).VerifyEmail), ctx, v)
 }
 
 // ViewCompany mocks base method.
-func (m *MockService) ViewCompany(ctx context.Context) ([]models.Company, error) {
+func (m *MockService) ViewCompany(ctx context.Context, q models.CompanyQuery) (models.CompanyPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ViewCompany", ctx)
-	ret0, _ := ret[0].([]models.Company)
+	ret := m.ctrl.Call(m, "ViewCompany", ctx, q)
+	ret0, _ := ret[0].(models.CompanyPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ViewCompany indicates an expected call of ViewCompany.
-func (mr *MockServiceMockRecorder) ViewCompany(ctx any) *gomock.Call {
+func (mr *MockServiceMockRecorder) ViewCompany(ctx, q any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ViewCompany", reflect.TypeOf((*MockService)(nil).ViewCompany), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ViewCompany", reflect.TypeOf((*MockService)(nil).ViewCompany), ctx, q)
 }
 
 // ViewJob mocks base method.
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// taskQueueSize bounds the tasks waiting for a worker.
+const taskQueueSize = 1024
+
+// taskChunk is how many applications are matched before their outcomes are
+// stored, a restart repeats at most one chunk.
+const taskChunk = 100
+
+// taskLease is how long a task stays with the instance that claimed it
+// without progress, after that another instance may resume it. Unfinished
+// tasks are looked for every taskRescan.
+const (
+	taskLease  = 2 * time.Minute
+	taskRescan = time.Minute
+)
+
+// A failing step of a task is attempted taskAttempts times, the wait
+// between attempts starts at taskRetryDelay and doubles.
+const taskAttempts = 4
+
+var taskRetryDelay = time.Second
+
+// SubmitApplications stores the applications as a task and queues it for
+// the workers.
+func (r NewService) SubmitApplications(ctx context.Context, userID uint, applications []models.JobApplication) (models.Task, error) {
+	if r.tasks == nil {
+		return models.Task{}, errors.New("no task workers configured")
+	}
+	if len(r.tasks) == cap(r.tasks) {
+		return models.Task{}, newError(ErrTooManyRequests, CodeTaskQueueFull, "too many tasks are waiting, try again later", nil)
+	}
+	b, err := json.Marshal(applications)
+	if err != nil {
+		return models.Task{}, err
+	}
+	t, err := r.rp.CreateTask(ctx, models.Task{
+		ID:           uuid.NewString(),
+		UserID:       userID,
+		Status:       models.TaskPending,
+		Total:        len(applications),
+		Applications: b,
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	r.queued.add(t.ID)
+	select {
+	case r.tasks <- t.ID:
+	default:
+		// Filled up since the check above
+		r.queued.remove(t.ID)
+		err = r.rp.FinishTask(ctx, t.ID, models.TaskFailed, "task queue full")
+		if err != nil {
+			log.Error().Err(err).Str("task", t.ID).Msg("failing task")
+		}
+		return models.Task{}, newError(ErrTooManyRequests, CodeTaskQueueFull, "too many tasks are waiting, try again later", nil)
+	}
+	t.Applications = nil
+	return t, nil
+}
+
+// GetTask returns the progress and the outcomes of a task. Tasks of other
+// users don't exist for the caller.
+func (r NewService) GetTask(ctx context.Context, id string, userID uint) (models.Task, error) {
+	t, err := r.rp.GetTask(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && t.UserID != userID) {
+		return models.Task{}, newError(ErrNotFound, CodeTaskNotFound, "task not found", err)
+	}
+	if err != nil {
+		return models.Task{}, err
+	}
+	return t, nil
+}
+
+// startTasks starts the workers and keeps queueing the tasks nobody runs,
+// those a previous run left unfinished and those of instances that stopped.
+// Everything stops once ctx is done.
+func (r *NewService) startTasks(ctx context.Context) {
+	started := time.Now()
+	r.tasks = make(chan string, taskQueueSize)
+	r.queued = &queuedTasks{ids: map[string]bool{}}
+	for i := 0; i < r.taskWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-r.tasks:
+					if ctx.Err() != nil {
+						return
+					}
+					r.queued.remove(id)
+					r.runTask(ctx, id)
+				}
+			}
+		}()
+	}
+	go func() {
+		for {
+			r.queueAbandoned(ctx, started)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(taskRescan):
+			}
+		}
+	}()
+}
+
+// queueAbandoned queues the unfinished tasks that seem to have no owner and
+// aren't waiting in the queue already.
+func (r NewService) queueAbandoned(ctx context.Context, started time.Time) {
+	tasks, err := r.rp.UnfinishedTasks(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("resuming tasks")
+		return
+	}
+	now := time.Now()
+	for _, t := range tasks {
+		if !abandoned(t, started, now) || !r.queued.add(t.ID) {
+			continue
+		}
+		log.Info().Str("task", t.ID).Int("processed", t.Processed).Msg("resuming task")
+		select {
+		case r.tasks <- t.ID:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// queuedTasks are the ids of the tasks waiting for a worker.
+type queuedTasks struct {
+	mu  sync.Mutex
+	ids map[string]bool
+}
+
+// add marks id as queued, it reports false when it already was.
+func (q *queuedTasks) add(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ids[id] {
+		return false
+	}
+	q.ids[id] = true
+	return true
+}
+
+// remove marks id as taken by a worker, it may be queued again.
+func (q *queuedTasks) remove(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.ids, id)
+}
+
+// abandoned reports whether nobody seems to run t. Its lease ended, or it
+// was never claimed and either predates this instance or has waited longer
+// than a lease in the queue of another one.
+func abandoned(t models.Task, started, now time.Time) bool {
+	if t.LeaseUntil != nil {
+		return t.LeaseUntil.Before(now)
+	}
+	return t.CreatedAt.Before(started) || t.CreatedAt.Before(now.Add(-taskLease))
+}
+
+// runTask matches the applications of a task that have no outcome yet. It
+// claims the task first and extends the lease before every chunk, so only
+// one instance runs it. Failing steps are retried before the task fails,
+// a task interrupted by a shutdown is resumed once its lease ends.
+func (r NewService) runTask(ctx context.Context, id string) {
+	ok, err := r.rp.ClaimTask(ctx, id, r.instance, time.Now().Add(taskLease))
+	if err != nil {
+		log.Error().Err(err).Str("task", id).Msg("claiming task")
+		return
+	}
+	if !ok {
+		return
+	}
+
+	var t models.Task
+	ok = r.retryTask(ctx, id, "task could not be loaded", func() error {
+		var err error
+		t, err = r.rp.GetTask(ctx, id)
+		return err
+	})
+	if !ok {
+		return
+	}
+	var applications []models.JobApplication
+	err = json.Unmarshal(t.Applications, &applications)
+	if err != nil {
+		log.Error().Err(err).Str("task", id).Msg("decoding applications")
+		r.finishTask(ctx, id, models.TaskFailed, "applications could not be read")
+		return
+	}
+
+	for start := t.Processed; start < len(applications); start += taskChunk {
+		end := min(start+taskChunk, len(applications))
+		lost := false
+		ok := r.retryTask(ctx, id, "progress could not be stored", func() error {
+			held, err := r.rp.ExtendTask(ctx, id, r.instance, time.Now().Add(taskLease))
+			if err != nil {
+				return err
+			}
+			if !held {
+				lost = true
+				return nil
+			}
+			outcomes, err := r.matchApplications(ctx, applications[start:end], start)
+			if err != nil {
+				return err
+			}
+			items := make([]models.TaskItem, len(outcomes))
+			for i, o := range outcomes {
+				items[i] = models.TaskItem{TaskID: id, ApplicationOutcome: o}
+			}
+			return r.rp.RecordTaskItems(ctx, id, items)
+		})
+		if lost {
+			log.Warn().Str("task", id).Msg("task taken over by another instance")
+			return
+		}
+		if !ok {
+			return
+		}
+	}
+	r.finishTask(ctx, id, models.TaskDone, "")
+}
+
+// retryTask runs step until it succeeds, waiting longer after every
+// failure. It fails the task with reason once taskAttempts ran out and
+// reports whether the step succeeded. Nothing is failed when ctx ends.
+func (r NewService) retryTask(ctx context.Context, id string, reason string, step func() error) bool {
+	delay := taskRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := step()
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		log.Error().Err(err).Str("task", id).Int("attempt", attempt).Msg("running task")
+		if attempt == taskAttempts {
+			r.finishTask(ctx, id, models.TaskFailed, reason)
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (r NewService) finishTask(ctx context.Context, id string, status string, reason string) {
+	err := r.rp.FinishTask(ctx, id, status, reason)
+	if err != nil {
+		log.Error().Err(err).Str("task", id).Msg("finishing task")
+	}
+}
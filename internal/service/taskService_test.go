@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"job-portal/internal/authstate"
+	"job-portal/internal/cache"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gomock "go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// waitFinished returns the status a task finished with.
+func waitFinished(t *testing.T, finished <-chan string) string {
+	t.Helper()
+	select {
+	case s := <-finished:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("task didn't finish")
+		return ""
+	}
+}
+
+// workContext stops the task workers of a test once it ends.
+func workContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestNewService_SubmitApplications(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	ms.EXPECT().UnfinishedTasks(gomock.Any()).Return(nil, nil)
+	s := NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory(), WithTaskWorkers(2), WithWorkContext(workContext(t)))
+
+	applications := []models.JobApplication{
+		{Name: "vishnu", Email: "vishnu@gmail.com", JobId: 1},
+		{Name: "ravi", Email: "ravi@gmail.com", JobId: 2},
+	}
+	var task models.Task
+	ms.EXPECT().CreateTask(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, t models.Task) (models.Task, error) {
+		task = t
+		return t, nil
+	})
+	ms.EXPECT().GetTask(gomock.Any(), gomock.Any()).DoAndReturn(func(context.Context, string) (models.Task, error) {
+		return task, nil
+	})
+	ms.EXPECT().ClaimTask(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+	ms.EXPECT().ExtendTask(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+	ms.EXPECT().GetJobById(gomock.Any(), 1).Return(models.Job{ID: 1}, nil)
+	ms.EXPECT().GetJobById(gomock.Any(), 2).Return(models.Job{}, repository.ErrNotFound)
+	var items []models.TaskItem
+	ms.EXPECT().RecordTaskItems(gomock.Any(), gomock.Any(), gomock.Len(2)).
+		DoAndReturn(func(_ context.Context, _ string, it []models.TaskItem) error {
+			items = it
+			return nil
+		})
+	finished := make(chan string, 1)
+	ms.EXPECT().FinishTask(gomock.Any(), gomock.Any(), gomock.Any(), "").
+		DoAndReturn(func(_ context.Context, _ string, status string, _ string) error {
+			finished <- status
+			return nil
+		})
+
+	got, err := s.SubmitApplications(ctx, 1, applications)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, models.TaskPending, got.Status)
+	assert.Equal(t, 2, got.Total)
+	assert.Equal(t, uint(1), task.UserID)
+
+	assert.Equal(t, models.TaskDone, waitFinished(t, finished))
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, 1, items[1].Position)
+	assert.Equal(t, models.OutcomeFailed, items[1].Outcome)
+	assert.Equal(t, "job not found", items[1].Error)
+}
+
+func TestNewService_ResumeTasks(t *testing.T) {
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+
+	b, _ := json.Marshal([]models.JobApplication{
+		{Name: "vishnu", JobId: 1},
+		{Name: "ravi", JobId: 1},
+	})
+	// The first application was processed before the restart
+	task := models.Task{ID: "3f1c", Status: models.TaskRunning, Total: 2, Processed: 1, Applications: b,
+		CreatedAt: time.Now().Add(-time.Minute)}
+	ms.EXPECT().UnfinishedTasks(gomock.Any()).Return([]models.Task{task}, nil)
+	ms.EXPECT().ClaimTask(gomock.Any(), "3f1c", gomock.Any(), gomock.Any()).Return(true, nil)
+	ms.EXPECT().ExtendTask(gomock.Any(), "3f1c", gomock.Any(), gomock.Any()).Return(true, nil)
+	ms.EXPECT().GetTask(gomock.Any(), "3f1c").Return(task, nil)
+	ms.EXPECT().GetJobById(gomock.Any(), 1).Return(models.Job{ID: 1}, nil)
+	var items []models.TaskItem
+	ms.EXPECT().RecordTaskItems(gomock.Any(), "3f1c", gomock.Len(1)).
+		DoAndReturn(func(_ context.Context, _ string, it []models.TaskItem) error {
+			items = it
+			return nil
+		})
+	finished := make(chan string, 1)
+	ms.EXPECT().FinishTask(gomock.Any(), "3f1c", gomock.Any(), "").
+		DoAndReturn(func(_ context.Context, _ string, status string, _ string) error {
+			finished <- status
+			return nil
+		})
+
+	NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory(), WithTaskWorkers(1), WithWorkContext(workContext(t)))
+	assert.Equal(t, models.TaskDone, waitFinished(t, finished))
+	assert.Equal(t, 1, items[0].Position)
+	assert.Equal(t, "ravi", items[0].Name)
+}
+
+func TestNewService_QueueAbandonedOnce(t *testing.T) {
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	now := time.Now()
+	ended := now.Add(-time.Second)
+	tasks := []models.Task{{ID: "3f1c", CreatedAt: now.Add(-time.Hour), LeaseUntil: &ended}, {ID: "9a2b", CreatedAt: now}}
+	ms.EXPECT().UnfinishedTasks(gomock.Any()).Return(tasks, nil).Times(2)
+	s := NewService{rp: ms, tasks: make(chan string, 4), queued: &queuedTasks{ids: map[string]bool{}}}
+
+	// A task still waiting isn't queued again by the next rescan
+	s.queueAbandoned(context.Background(), now)
+	s.queueAbandoned(context.Background(), now)
+	assert.Equal(t, 1, len(s.tasks))
+	id := <-s.tasks
+	assert.Equal(t, "3f1c", id)
+
+	// Once a worker took it, it may be queued again
+	s.queued.remove(id)
+	ms.EXPECT().UnfinishedTasks(gomock.Any()).Return(tasks, nil)
+	s.queueAbandoned(context.Background(), now)
+	assert.Equal(t, 1, len(s.tasks))
+}
+
+func TestAbandoned(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-time.Hour)
+	ended, running := now.Add(-time.Second), now.Add(time.Second)
+	tests := []struct {
+		name string
+		task models.Task
+		want bool
+	}{
+		{name: "lease ended", task: models.Task{CreatedAt: now, LeaseUntil: &ended}, want: true},
+		{name: "lease running", task: models.Task{CreatedAt: started.Add(-time.Hour), LeaseUntil: &running}},
+		{name: "left by a previous run", task: models.Task{CreatedAt: started.Add(-time.Second)}, want: true},
+		{name: "just submitted", task: models.Task{CreatedAt: now.Add(-time.Second)}},
+		{name: "waited longer than a lease", task: models.Task{CreatedAt: now.Add(-taskLease - time.Second)}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, abandoned(tt.task, started, now))
+		})
+	}
+}
+
+// newUnfinishedTask stores a task a previous run left unfinished, its
+// applications are for jobs that don't exist.
+func newUnfinishedTask(t *testing.T, rp repository.Repository, n int) models.Task {
+	applications := make([]models.JobApplication, n)
+	for i := range applications {
+		applications[i] = models.JobApplication{Name: "vishnu", JobId: 42}
+	}
+	b, _ := json.Marshal(applications)
+	task, err := rp.CreateTask(context.Background(), models.Task{ID: "3f1c", UserID: 1, Status: models.TaskPending,
+		Total: n, Applications: b, CreatedAt: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+// waitTask polls the task until it finished.
+func waitTask(t *testing.T, rp repository.Repository, id string) models.Task {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		task, err := rp.GetTask(context.Background(), id)
+		if err == nil && (task.Status == models.TaskDone || task.Status == models.TaskFailed) {
+			return task
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("task didn't finish")
+	return models.Task{}
+}
+
+// slowJobs takes a while to load every job, so runs of a task overlap. It
+// counts the runs by the tasks they load.
+type slowJobs struct {
+	*repository.Memory
+	runs *atomic.Int32
+}
+
+func (s slowJobs) GetJobById(ctx context.Context, jId int) (models.Job, error) {
+	time.Sleep(20 * time.Millisecond)
+	return s.Memory.GetJobById(ctx, jId)
+}
+
+func (s slowJobs) GetTask(ctx context.Context, id string) (models.Task, error) {
+	s.runs.Add(1)
+	return s.Memory.GetTask(ctx, id)
+}
+
+func TestNewService_TaskRunsOnOneInstance(t *testing.T) {
+	rp := slowJobs{Memory: repository.NewMemory(), runs: new(atomic.Int32)}
+	task := newUnfinishedTask(t, rp, 2*taskChunk)
+
+	// Both instances find the task at the same time
+	ctx := workContext(t)
+	for i := 0; i < 2; i++ {
+		NewServiceStore(rp, cache.NewMemory(), authstate.NewMemory(), WithTaskWorkers(2), WithWorkContext(ctx))
+	}
+	got := waitTask(t, rp.Memory, task.ID)
+	assert.Equal(t, models.TaskDone, got.Status)
+	assert.Equal(t, 2*taskChunk, got.Processed)
+	assert.Equal(t, 2*taskChunk, len(got.Items))
+	assert.Equal(t, int32(1), rp.runs.Load())
+}
+
+func TestNewService_TaskFailsAfterRetries(t *testing.T) {
+	prev := taskRetryDelay
+	taskRetryDelay = time.Millisecond
+	t.Cleanup(func() { taskRetryDelay = prev })
+
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	b, _ := json.Marshal([]models.JobApplication{{Name: "vishnu", JobId: 1}})
+	task := models.Task{ID: "3f1c", Status: models.TaskPending, Total: 1, Applications: b, CreatedAt: time.Now().Add(-time.Minute)}
+	ms.EXPECT().UnfinishedTasks(gomock.Any()).Return([]models.Task{task}, nil)
+	ms.EXPECT().ClaimTask(gomock.Any(), "3f1c", gomock.Any(), gomock.Any()).Return(true, nil)
+	ms.EXPECT().GetTask(gomock.Any(), "3f1c").Return(task, nil)
+	ms.EXPECT().ExtendTask(gomock.Any(), "3f1c", gomock.Any(), gomock.Any()).Return(true, nil).Times(taskAttempts)
+	ms.EXPECT().GetJobById(gomock.Any(), 1).Return(models.Job{ID: 1}, nil)
+	ms.EXPECT().RecordTaskItems(gomock.Any(), "3f1c", gomock.Len(1)).Return(errors.New("connection refused")).Times(taskAttempts)
+	finished := make(chan string, 1)
+	ms.EXPECT().FinishTask(gomock.Any(), "3f1c", models.TaskFailed, "progress could not be stored").
+		DoAndReturn(func(_ context.Context, _ string, status string, _ string) error {
+			finished <- status
+			return nil
+		})
+
+	NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory(), WithTaskWorkers(1), WithWorkContext(workContext(t)))
+	assert.Equal(t, models.TaskFailed, waitFinished(t, finished))
+}
+
+func TestNewService_TaskWorkersStop(t *testing.T) {
+	rp := repository.NewMemory()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewServiceStore(rp, cache.NewMemory(), authstate.NewMemory(), WithTaskWorkers(1), WithWorkContext(ctx))
+	cancel()
+	// Give the workers time to notice
+	time.Sleep(50 * time.Millisecond)
+
+	task, err := s.SubmitApplications(context.Background(), 1, []models.JobApplication{{Name: "vishnu", JobId: 42}})
+	assert.Equal(t, nil, err)
+	time.Sleep(50 * time.Millisecond)
+	got, err := rp.GetTask(context.Background(), task.ID)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, models.TaskPending, got.Status)
+}
+
+func TestNewService_GetTask(t *testing.T) {
+	tests := []struct {
+		name     string
+		task     models.Task
+		err      error
+		wantCode string
+	}{
+		{
+			name: "own task",
+			task: models.Task{ID: "3f1c", UserID: 1},
+		},
+		{
+			name:     "task of another user",
+			task:     models.Task{ID: "3f1c", UserID: 2},
+			wantCode: CodeTaskNotFound,
+		},
+		{
+			name:     "unknown task",
+			err:      repository.ErrNotFound,
+			wantCode: CodeTaskNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mc := gomock.NewController(t)
+			ms := repository.NewMockRepository(mc)
+			ms.EXPECT().GetTask(ctx, "3f1c").Return(tt.task, tt.err)
+
+			s := NewServiceStore(ms, cache.NewMemory(), authstate.NewMemory())
+			_, err := s.GetTask(ctx, "3f1c", 1)
+			assert.Equal(t, tt.wantCode, errCode(err))
+		})
+	}
+}
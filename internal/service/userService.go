@@ -2,118 +2,263 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"job-portal/cmd/rediss"
+	"job-portal/internal/auth"
 	"job-portal/internal/models"
-	"math/rand"
-	"net/smtp"
+	"job-portal/internal/repository"
 	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 func (r NewService) CreateUser(ctx context.Context, nu models.NewUser) (models.User, error) {
 	user, err := r.rp.CreateU(ctx, nu)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return models.User{}, newError(ErrConflict, CodeEmailExists, "an account with this email already exists", err)
+	}
 	if err != nil {
 		return models.User{}, err
 	}
+	// The account exists either way, a code that couldn't be sent can be
+	// requested again through ResendVerification
+	err = r.sendVerification(ctx, user.Email)
+	if err != nil {
+		log.Error().Err(err).Msg("sending verification code")
+	}
 	return user, nil
 }
-func (r NewService) Authenticate(ctx context.Context, email string, password string) (jwt.RegisteredClaims, error) {
-	c, err := r.rp.AuthenticateUser(ctx, email, password)
+
+// VerifyEmail marks the user as verified when the code matches the one sent
+// to the email.
+func (r NewService) VerifyEmail(ctx context.Context, v models.VerifyEmail) error {
+	err := redeemOTP(ctx, r.attempts, otpEmailVerification, v.Email, v.Code)
 	if err != nil {
-		return jwt.RegisteredClaims{}, err
+		return err
 	}
-	return c, nil
+	err = r.rp.MarkUserVerified(ctx, v.Email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return newError(ErrNotFound, CodeEmailNotRegistered, "given email is not registered with job portal", err)
+	}
+	return err
 }
 
-func (r NewService) CheckEmail(e string) (bool, error) {
-	b, err := r.rp.CheckUserEmail(e)
+// ResendVerification sends a new verification code, replacing the previous
+// one.
+func (r NewService) ResendVerification(ctx context.Context, email string) error {
+	u, err := r.rp.GetUserByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		return newError(ErrNotFound, CodeEmailNotRegistered, "given email is not registered with job portal", err)
+	}
 	if err != nil {
-		return false, err
+		return err
 	}
-	if b {
-		otp := generateOTP()
-		otpStr := strconv.Itoa(otp)
+	if u.Verified {
+		return newError(ErrConflict, CodeEmailVerified, "email is already verified", nil)
+	}
+	return r.sendVerification(ctx, u.Email)
+}
 
-		from := "vishnuvirat693@gmail.com"
-		password := "xjve lhkn iyhg mkco"
+func (r NewService) sendVerification(ctx context.Context, email string) error {
+	code, err := issueOTP(ctx, r.attempts, otpEmailVerification, email)
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Your job portal verification code is %s. It is valid for %s.", code, otpEmailVerification.ttl)
+	err = r.mail(email, "Verify your job portal email", body)
+	if err != nil {
+		if rerr := revokeOTP(ctx, r.attempts, otpEmailVerification, email); rerr != nil {
+			log.Error().Err(rerr).Msg("revoking unsent verification code")
+		}
+		return fmt.Errorf("sending verification email: %w", err)
+	}
+	return nil
+}
 
-		// Recipient's email address
-		to := e
+// Authenticate checks the credentials of a login attempt made from ip. Failed
+// attempts are counted against the account and the address, either one
+// gets locked out for a while once it has failed too often.
+func (r NewService) Authenticate(ctx context.Context, email string, password string, ip string) (jwt.RegisteredClaims, error) {
+	account := models.NormalizeEmail(email)
+	audit := models.AuditEvent{Email: account, IP: ip}
 
-		// SMTP server and port
-		smtpServer := "smtp.gmail.com"
-		smtpPort := 587
+	for _, l := range []struct{ scope, id string }{{scopeAccount, account}, {scopeIP, ip}} {
+		d, err := lockedFor(ctx, r.attempts, l.scope, l.id)
+		if err != nil {
+			// Logins keep working when the lockout state can't be read
+			log.Error().Err(err).Msg("checking login lockout")
+			continue
+		}
+		if d > 0 {
+			audit.Event, audit.Detail = models.AuditLoginBlocked, l.scope+" locked"
+			r.audit(ctx, audit)
+			return jwt.RegisteredClaims{}, newError(ErrTooManyRequests, CodeAccountLocked,
+				fmt.Sprintf("too many failed logins, try again in %s", d.Round(time.Second)), nil)
+		}
+	}
 
-		// Message
-		subject := "opt regarding job portal"
-		body := fmt.Sprintf("one time password is:%s", otpStr)
+	c, err := r.rp.AuthenticateUser(ctx, email, password)
+	if err == nil {
+		if err := clearFailures(ctx, r.attempts, scopeAccount, account); err != nil {
+			log.Error().Err(err).Msg("clearing failed logins")
+		}
+		if r.verifiedLogin && !auth.IsVerified(c) {
+			audit.Event, audit.ActorID, audit.Detail = models.AuditLoginBlocked, c.Subject, "email not verified"
+			r.audit(ctx, audit)
+			return jwt.RegisteredClaims{}, newError(ErrForbidden, CodeEmailNotVerified, "verify your email before logging in", nil)
+		}
 
-		// Set up authentication information
-		auth := smtp.PlainAuth("", from, password, smtpServer)
+		pending, err := r.loginPending(ctx, c)
+		if err != nil {
+			return jwt.RegisteredClaims{}, err
+		}
+		audit.Event, audit.ActorID = models.AuditLoginSucceeded, c.Subject
+		if pending != nil {
+			audit.Detail = "second factor pending"
+			r.audit(ctx, audit)
+			return *pending, nil
+		}
+		r.audit(ctx, audit)
+		return c, nil
+	}
 
-		// Compose the email
-		message := fmt.Sprintf("Subject: %s\r\n\r\n%s", subject, body)
+	unknown := errors.Is(err, repository.ErrNotFound)
+	if !unknown && !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return jwt.RegisteredClaims{}, err
+	}
+	audit.Event = models.AuditLoginFailed
+	r.audit(ctx, audit)
 
-		// Connect to the SMTP server
-		err := smtp.SendMail(fmt.Sprintf("%s:%d", smtpServer, smtpPort), auth, from, []string{to}, []byte(message))
-		if err != nil {
-			fmt.Println("Error sending email:", err)
-			return false, err
+	// Unknown emails are counted too so a lockout doesn't reveal which
+	// accounts exist
+	d, ferr := recordFailure(ctx, r.attempts, scopeAccount, account, accountFailureLimit)
+	if ferr != nil {
+		log.Error().Err(ferr).Msg("counting failed login")
+	}
+	if d > 0 {
+		audit.Event, audit.Detail = models.AuditAccountLocked, "locked for "+d.String()
+		r.audit(ctx, audit)
+		if !unknown {
+			r.notifyLocked(account, d)
 		}
+	}
 
-		fmt.Println("Email sent successfully.")
-		rc := rediss.RedisClient()
-		ctx := context.Background()
-		err = rc.Set(ctx, e, otpStr, 5*time.Minute).Err()
+	d, ferr = recordFailure(ctx, r.attempts, scopeIP, ip, ipFailureLimit)
+	if ferr != nil {
+		log.Error().Err(ferr).Msg("counting failed login")
+	}
+	if d > 0 {
+		audit.Event, audit.Detail = models.AuditIPLocked, "locked for "+d.String()
+		r.audit(ctx, audit)
+	}
+
+	return jwt.RegisteredClaims{}, newError(ErrUnauthorized, CodeInvalidCredentials, "invalid email or password", err)
+}
+
+// loginPending returns the claims of a token that still waits for a second
+// factor, or nil when the password login is complete.
+func (r NewService) loginPending(ctx context.Context, c jwt.RegisteredClaims) (*jwt.RegisteredClaims, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing subject %q: %w", c.Subject, err)
+	}
+	u, err := r.user(ctx, uint(id))
+	if err != nil {
+		return nil, err
+	}
+	return r.pendingClaims(ctx, u)
+}
+
+// UnlockAccount lifts the lockout of an account, and of an address when one
+// is given, on behalf of the admin actorID.
+func (r NewService) UnlockAccount(ctx context.Context, u models.Unlock, actorID string) error {
+	account := models.NormalizeEmail(u.Email)
+	err := unlock(ctx, r.attempts, scopeAccount, account)
+	if err != nil {
+		return fmt.Errorf("unlocking account: %w", err)
+	}
+	if u.IP != "" {
+		err = unlock(ctx, r.attempts, scopeIP, u.IP)
 		if err != nil {
-			log.Error().Msgf("%v", err)
-			return false, err
+			return fmt.Errorf("unlocking ip: %w", err)
 		}
-		r, _ := rc.Get(ctx, e).Result()
-		fmt.Println(r)
-		return true, nil
+	}
+	r.audit(ctx, models.AuditEvent{Event: models.AuditAccountUnlocked, Email: account, IP: u.IP, ActorID: actorID})
+	return nil
+}
 
+// audit records an event. A failing audit log is reported but never stops
+// the request.
+func (r NewService) audit(ctx context.Context, e models.AuditEvent) {
+	err := r.rp.RecordAuditEvent(ctx, e)
+	if err != nil {
+		log.Error().Err(err).Str("event", e.Event).Msg("recording audit event")
 	}
-	return false, err
 }
-func (r NewService) UpdatePassword(np models.Reset) (bool, error) {
-	rcx := rediss.RedisClient()
-	ctx := context.Background()
-	otp, err := rcx.Get(ctx, np.Email).Result()
-	if err != nil || otp != np.Otp {
-		return false, err
+
+func (r NewService) notifyLocked(email string, d time.Duration) {
+	body := fmt.Sprintf("Your job portal account was locked for %s after too many failed login attempts. "+
+		"If this wasn't you, reset your password once the lock expires.", d.Round(time.Second))
+	r.notify(email, "Your job portal account was locked", body)
+}
+
+func (r NewService) CheckEmail(ctx context.Context, e string) (bool, error) {
+	b, err := r.rp.CheckUserEmail(ctx, e)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, newError(ErrNotFound, CodeEmailNotRegistered, "given email is not registered with job portal", err)
 	}
-	b, err := r.rp.UpdateUserPassword(np)
 	if err != nil {
 		return false, err
 	}
 	if b {
-		from := "vishnuvirat693@gmail.com"
-		password := "xjve lhkn iyhg mkco"
-
-		// Recipient's email address
-		to := np.Email
-
-		// SMTP server and port
-		smtpServer := "smtp.gmail.com"
-		smtpPort := 587
+		otpStr, err := issueOTP(ctx, r.attempts, otpPasswordReset, e)
+		if err != nil {
+			return false, err
+		}
 
-		// Message
-		subject := "Regarding recent password update"
-		body := "Successfully reset your password for the job portal api"
+		err = r.mail(e, "opt regarding job portal", fmt.Sprintf("one time password is:%s", otpStr))
+		if err != nil {
+			fmt.Println("Error sending email:", err)
+			// The otp never reached the user, let them ask for another one
+			if rerr := revokeOTP(ctx, r.attempts, otpPasswordReset, e); rerr != nil {
+				log.Error().Err(rerr).Msg("revoking unsent otp")
+			}
+			return false, err
+		}
 
-		// Set up authentication information
-		auth := smtp.PlainAuth("", from, password, smtpServer)
+		fmt.Println("Email sent successfully.")
+		return true, nil
 
-		// Compose the email
-		message := fmt.Sprintf("Subject: %s\r\n\r\n%s", subject, body)
+	}
+	return false, err
+}
 
-		// Connect to the SMTP server
-		err := smtp.SendMail(fmt.Sprintf("%s:%d", smtpServer, smtpPort), auth, from, []string{to}, []byte(message))
+// UpdatePassword sets a new password once the otp of a password reset is
+// redeemed. The otp is redeemed last in the transaction, a failing write
+// leaves it usable and a wrong or used otp rolls the write back.
+func (r NewService) UpdatePassword(ctx context.Context, np models.Reset) (bool, error) {
+	var b bool
+	err := r.inTx(ctx, func(r NewService) error {
+		var err error
+		b, err = r.rp.UpdateUserPassword(ctx, np)
+		if err != nil {
+			return err
+		}
+		err = r.rp.RecordAuditEvent(ctx, models.AuditEvent{Event: models.AuditPasswordReset, Email: models.NormalizeEmail(np.Email)})
+		if err != nil {
+			return err
+		}
+		return redeemOTP(ctx, r.attempts, otpPasswordReset, np.Email, np.Otp)
+	})
+	if err != nil {
+		return false, err
+	}
+	if b {
+		err := r.mail(np.Email, "Regarding recent password update", "Successfully reset your password for the job portal api")
 		if err != nil {
 			fmt.Println("Error sending email:", err)
 			return false, err
@@ -126,10 +271,28 @@ func (r NewService) UpdatePassword(np models.Reset) (bool, error) {
 	}
 	return false, err
 }
-func generateOTP() int {
-	rand.Seed(time.Now().UnixNano())
 
-	// Generate a random 6-digit number
-	otp := rand.Intn(900000) + 100000
-	return otp
+// TokenRevoked reports whether the password of the user of a token was reset
+// after the token was issued, such tokens stop working before they expire.
+// iat only has a precision of seconds, tokens issued in the second of a
+// reset stay valid. Tokens of users that are gone are left to the handlers.
+func (r NewService) TokenRevoked(ctx context.Context, c jwt.RegisteredClaims) (bool, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return true, nil
+	}
+	u, err := r.rp.GetUserByID(ctx, uint(id))
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if u.PasswordChangedAt == nil {
+		return false, nil
+	}
+	if c.IssuedAt == nil {
+		return true, nil
+	}
+	return c.IssuedAt.Before(u.PasswordChangedAt.Truncate(time.Second)), nil
 }
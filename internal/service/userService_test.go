@@ -3,13 +3,21 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"job-portal/internal/auth"
 	"job-portal/internal/models"
 	"job-portal/internal/repository"
 	"reflect"
+	"regexp"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	gomock "go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/go-playground/assert.v1"
+	"gorm.io/gorm"
 )
 
 func TestNewService_CreateUser(t *testing.T) {
@@ -41,6 +49,22 @@ func TestNewService_CreateUser(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "email already registered",
+			args: args{
+				ctx: context.Background(),
+				nu: models.NewUser{
+					Name:     "vishnu",
+					Email:    "Vishnu@gmail.com",
+					Password: "1234",
+				},
+			},
+			want: models.User{},
+			mockRepoResponse: func() (models.User, error) {
+				return models.User{}, gorm.ErrDuplicatedKey
+			},
+			wantErr: true,
+		},
 		{
 			name: "success",
 			args: args{
@@ -72,8 +96,10 @@ func TestNewService_CreateUser(t *testing.T) {
 			if tt.mockRepoResponse != nil {
 				mockRepo.EXPECT().CreateU(tt.args.ctx, tt.args.nu).Return(tt.mockRepoResponse()).AnyTimes()
 			}
+			captureMail(t)
 
-			s := NewServiceStore(mockRepo)
+			_, st := newTestStore(t)
+			s := &NewService{rp: mockRepo, attempts: st}
 			got, err := s.CreateUser(tt.args.ctx, tt.args.nu)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewService.CreateUser() error = %v, wantErr %v", err, tt.wantErr)
@@ -113,6 +139,19 @@ func TestNewService_Authenticate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "wrong password",
+			args: args{
+				ctx:      context.Background(),
+				email:    "vishnu@gmail.com",
+				password: "1234",
+			},
+			want: jwt.RegisteredClaims{},
+			mockRepoResponse: func() (jwt.RegisteredClaims, error) {
+				return jwt.RegisteredClaims{}, bcrypt.ErrMismatchedHashAndPassword
+			},
+			wantErr: true,
+		},
 		{
 			name: "success",
 			args: args{
@@ -123,7 +162,7 @@ func TestNewService_Authenticate(t *testing.T) {
 			want: jwt.RegisteredClaims{
 				ID:        "123",
 				Issuer:    "user",
-				Subject:   "login",
+				Subject:   "1",
 				ExpiresAt: &jwt.NumericDate{},
 				NotBefore: &jwt.NumericDate{},
 				IssuedAt:  &jwt.NumericDate{},
@@ -133,7 +172,7 @@ func TestNewService_Authenticate(t *testing.T) {
 				return jwt.RegisteredClaims{
 					ID:        "123",
 					Issuer:    "user",
-					Subject:   "login",
+					Subject:   "1",
 					ExpiresAt: &jwt.NumericDate{},
 					NotBefore: &jwt.NumericDate{},
 					IssuedAt:  &jwt.NumericDate{},
@@ -150,9 +189,12 @@ func TestNewService_Authenticate(t *testing.T) {
 			if tt.mockRepoResponse != nil {
 				ms.EXPECT().AuthenticateUser(tt.args.ctx, tt.args.email, tt.args.password).Return(tt.mockRepoResponse()).AnyTimes()
 			}
+			ms.EXPECT().RecordAuditEvent(tt.args.ctx, gomock.Any()).Return(nil).AnyTimes()
+			ms.EXPECT().GetUserByID(tt.args.ctx, uint(1)).Return(models.User{}, nil).AnyTimes()
 
-			s := NewServiceStore(ms)
-			got, err := s.Authenticate(tt.args.ctx, tt.args.email, tt.args.password)
+			_, st := newTestStore(t)
+			s := &NewService{rp: ms, attempts: st}
+			got, err := s.Authenticate(tt.args.ctx, tt.args.email, tt.args.password, "10.0.0.1")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewService.Authenticate() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -163,3 +205,270 @@ func TestNewService_Authenticate(t *testing.T) {
 		})
 	}
 }
+
+func TestNewService_AuthenticateLockout(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	mr, st := newTestStore(t)
+	s := &NewService{rp: ms, attempts: st, notifications: new(sync.WaitGroup)}
+
+	var events []string
+	ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, e models.AuditEvent) error {
+		events = append(events, e.Event)
+		return nil
+	}).AnyTimes()
+	ms.EXPECT().AuthenticateUser(ctx, "vishnu@gmail.com", "wrong").
+		Return(jwt.RegisteredClaims{}, bcrypt.ErrMismatchedHashAndPassword).Times(accountFailureLimit * 2)
+	ms.EXPECT().AuthenticateUser(ctx, "vishnu@gmail.com", "Secret123").
+		Return(jwt.RegisteredClaims{Subject: "1"}, nil).Times(1)
+	ms.EXPECT().GetUserByID(ctx, uint(1)).Return(models.User{}, nil).Times(1)
+
+	mails := captureMail(t)
+	t.Cleanup(s.notifications.Wait)
+
+	for i := 0; i < accountFailureLimit; i++ {
+		_, err := s.Authenticate(ctx, "vishnu@gmail.com", "wrong", "10.0.0.1")
+		assert.Equal(t, CodeInvalidCredentials, errCode(err))
+	}
+	// The notification doesn't hold up the login
+	s.notifications.Wait()
+	assert.Equal(t, 1, len(*mails))
+	assert.Equal(t, "vishnu@gmail.com", (*mails)[0].to)
+	assert.Equal(t, models.AuditAccountLocked, events[len(events)-1])
+
+	// Even the right password is refused while the account is locked, the
+	// email is matched case insensitively
+	_, err := s.Authenticate(ctx, "Vishnu@gmail.com", "Secret123", "10.0.0.2")
+	assert.Equal(t, CodeAccountLocked, errCode(err))
+	assert.Equal(t, true, errors.Is(err, ErrTooManyRequests))
+	assert.Equal(t, models.AuditLoginBlocked, events[len(events)-1])
+
+	// The second lockout lasts twice as long
+	mr.FastForward(baseLockout)
+	for i := 0; i < accountFailureLimit; i++ {
+		_, err = s.Authenticate(ctx, "vishnu@gmail.com", "wrong", "10.0.0.1")
+		assert.Equal(t, CodeInvalidCredentials, errCode(err))
+	}
+	assert.Equal(t, 2*baseLockout, mr.TTL("login:lock:account:vishnu@gmail.com"))
+
+	// An admin can lift the lockout early
+	err = s.UnlockAccount(ctx, models.Unlock{Email: "vishnu@gmail.com"}, "42")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, models.AuditAccountUnlocked, events[len(events)-1])
+
+	claims, err := s.Authenticate(ctx, "vishnu@gmail.com", "Secret123", "10.0.0.1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "1", claims.Subject)
+	assert.Equal(t, models.AuditLoginSucceeded, events[len(events)-1])
+}
+
+func TestNewService_AuthenticateIPLockout(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	_, st := newTestStore(t)
+	s := &NewService{rp: ms, attempts: st}
+
+	ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil).AnyTimes()
+	ms.EXPECT().AuthenticateUser(ctx, gomock.Any(), gomock.Any()).
+		Return(jwt.RegisteredClaims{}, repository.ErrNotFound).Times(ipFailureLimit)
+
+	mails := captureMail(t)
+
+	// Spraying different emails from one address locks the address
+	for i := 0; i < ipFailureLimit; i++ {
+		_, err := s.Authenticate(ctx, fmt.Sprintf("user%d@gmail.com", i), "wrong", "10.0.0.1")
+		assert.Equal(t, CodeInvalidCredentials, errCode(err))
+	}
+	_, err := s.Authenticate(ctx, "vishnu@gmail.com", "Secret123", "10.0.0.1")
+	assert.Equal(t, CodeAccountLocked, errCode(err))
+	// Unknown accounts are never notified
+	assert.Equal(t, 0, len(*mails))
+
+	err = s.UnlockAccount(ctx, models.Unlock{Email: "vishnu@gmail.com", IP: "10.0.0.1"}, "42")
+	assert.Equal(t, nil, err)
+	locked, err := lockedFor(ctx, st, scopeIP, "10.0.0.1")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, time.Duration(0), locked)
+}
+
+type mail struct {
+	to, subject, body string
+}
+
+// captureMail records the mails sent during the test instead of sending them.
+func captureMail(t *testing.T) *[]mail {
+	var mails []mail
+	orig := sendMail
+	t.Cleanup(func() { sendMail = orig })
+	sendMail = func(to, subject, body string) error {
+		mails = append(mails, mail{to: to, subject: subject, body: body})
+		return nil
+	}
+	return &mails
+}
+
+func TestNewService_VerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	mr, st := newTestStore(t)
+	s := &NewService{rp: ms, attempts: st}
+	mails := captureMail(t)
+
+	nu := models.NewUser{Name: "vishnu", Email: "vishnu@gmail.com", Password: "Secret123"}
+	ms.EXPECT().CreateU(ctx, nu).Return(models.User{Name: "vishnu", Email: "vishnu@gmail.com"}, nil).Times(1)
+	_, err := s.CreateUser(ctx, nu)
+	assert.Equal(t, nil, err)
+
+	// The code is only in the mail, redis holds its hash
+	assert.Equal(t, 1, len(*mails))
+	code := regexp.MustCompile(`\d{6}`).FindString((*mails)[0].body)
+	assert.NotEqual(t, "", code)
+	assert.NotEqual(t, code, mr.HGet("otp:verify:vishnu@gmail.com", "hash"))
+
+	err = s.VerifyEmail(ctx, models.VerifyEmail{Email: "vishnu@gmail.com", Code: "not the code"})
+	assert.Equal(t, CodeInvalidOTP, errCode(err))
+
+	ms.EXPECT().MarkUserVerified(ctx, "vishnu@gmail.com").Return(nil).Times(1)
+	err = s.VerifyEmail(ctx, models.VerifyEmail{Email: "vishnu@gmail.com", Code: code})
+	assert.Equal(t, nil, err)
+
+	// Codes are single use
+	err = s.VerifyEmail(ctx, models.VerifyEmail{Email: "vishnu@gmail.com", Code: code})
+	assert.Equal(t, CodeInvalidOTP, errCode(err))
+}
+
+func TestNewService_UpdatePassword(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	_, st := newTestStore(t)
+	s := &NewService{rp: ms, attempts: st}
+	mails := captureMail(t)
+	expectTx(ms)
+
+	otp, err := issueOTP(ctx, st, otpPasswordReset, "vishnu@gmail.com")
+	assert.Equal(t, nil, err)
+	np := models.Reset{Email: "vishnu@gmail.com", Otp: otp, NewPassword: "Changed#123"}
+
+	// A failing write leaves the otp usable
+	ms.EXPECT().UpdateUserPassword(ctx, np).Return(false, errors.New("connection refused"))
+	_, err = s.UpdatePassword(ctx, np)
+	assert.NotEqual(t, nil, err)
+
+	ms.EXPECT().UpdateUserPassword(ctx, np).Return(true, nil)
+	ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil)
+	ok, err := s.UpdatePassword(ctx, np)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, 1, len(*mails))
+
+	// A used otp rolls the write back
+	ms.EXPECT().UpdateUserPassword(ctx, np).Return(true, nil)
+	ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil)
+	_, err = s.UpdatePassword(ctx, np)
+	assert.Equal(t, CodeInvalidOTP, errCode(err))
+	assert.Equal(t, 1, len(*mails))
+}
+
+func TestNewService_ResendVerification(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      models.User
+		repoErr   error
+		wantCode  string
+		wantMails int
+	}{
+		{
+			name:     "unknown email",
+			repoErr:  fmt.Errorf("fetching user: %w", repository.ErrNotFound),
+			wantCode: CodeEmailNotRegistered,
+		},
+		{
+			name:     "already verified",
+			user:     models.User{Email: "vishnu@gmail.com", Verified: true},
+			wantCode: CodeEmailVerified,
+		},
+		{
+			name:      "success",
+			user:      models.User{Email: "vishnu@gmail.com"},
+			wantMails: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mc := gomock.NewController(t)
+			ms := repository.NewMockRepository(mc)
+			ms.EXPECT().GetUserByEmail(ctx, "vishnu@gmail.com").Return(tt.user, tt.repoErr).Times(1)
+			_, st := newTestStore(t)
+			mails := captureMail(t)
+
+			s := &NewService{rp: ms, attempts: st}
+			err := s.ResendVerification(ctx, "vishnu@gmail.com")
+			assert.Equal(t, tt.wantCode, errCode(err))
+			assert.Equal(t, tt.wantMails, len(*mails))
+		})
+	}
+}
+
+func TestNewService_AuthenticateVerifiedLogin(t *testing.T) {
+	ctx := context.Background()
+	mc := gomock.NewController(t)
+	ms := repository.NewMockRepository(mc)
+	_, st := newTestStore(t)
+	ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil).AnyTimes()
+	ms.EXPECT().AuthenticateUser(ctx, "new@gmail.com", "Secret123").
+		Return(jwt.RegisteredClaims{Subject: "1", Audience: jwt.ClaimStrings{"students"}}, nil).AnyTimes()
+	ms.EXPECT().AuthenticateUser(ctx, "verified@gmail.com", "Secret123").
+		Return(jwt.RegisteredClaims{Subject: "2", Audience: jwt.ClaimStrings{"students", auth.VerifiedAudience}}, nil).AnyTimes()
+	ms.EXPECT().GetUserByID(ctx, gomock.Any()).Return(models.User{}, nil).AnyTimes()
+
+	s := &NewService{rp: ms, attempts: st}
+	_, err := s.Authenticate(ctx, "new@gmail.com", "Secret123", "10.0.0.1")
+	assert.Equal(t, nil, err)
+
+	WithVerifiedLogin(true)(s)
+	_, err = s.Authenticate(ctx, "new@gmail.com", "Secret123", "10.0.0.1")
+	assert.Equal(t, CodeEmailNotVerified, errCode(err))
+	assert.Equal(t, true, errors.Is(err, ErrForbidden))
+
+	_, err = s.Authenticate(ctx, "verified@gmail.com", "Secret123", "10.0.0.1")
+	assert.Equal(t, nil, err)
+}
+
+func TestNewService_TokenRevoked(t *testing.T) {
+	changed := time.Now().Add(-time.Minute)
+	issued := func(at time.Time) *jwt.NumericDate { return jwt.NewNumericDate(at) }
+	tests := []struct {
+		name    string
+		claims  jwt.RegisteredClaims
+		user    models.User
+		err     error
+		want    bool
+		wantErr bool
+	}{
+		{name: "password never reset", claims: jwt.RegisteredClaims{Subject: "1", IssuedAt: issued(changed.Add(-time.Hour))}},
+		{name: "issued before the reset", claims: jwt.RegisteredClaims{Subject: "1", IssuedAt: issued(changed.Add(-time.Second))},
+			user: models.User{PasswordChangedAt: &changed}, want: true},
+		{name: "issued in the second of the reset", claims: jwt.RegisteredClaims{Subject: "1", IssuedAt: issued(changed)},
+			user: models.User{PasswordChangedAt: &changed}},
+		{name: "issued after the reset", claims: jwt.RegisteredClaims{Subject: "1", IssuedAt: issued(changed.Add(time.Second))},
+			user: models.User{PasswordChangedAt: &changed}},
+		{name: "without issue time", claims: jwt.RegisteredClaims{Subject: "1"}, user: models.User{PasswordChangedAt: &changed}, want: true},
+		{name: "user is gone", claims: jwt.RegisteredClaims{Subject: "1"}, err: repository.ErrNotFound},
+		{name: "database down", claims: jwt.RegisteredClaims{Subject: "1"}, err: errors.New("connection refused"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := repository.NewMockRepository(gomock.NewController(t))
+			ms.EXPECT().GetUserByID(gomock.Any(), uint(1)).Return(tt.user, tt.err)
+			s := &NewService{rp: ms}
+			got, err := s.TokenRevoked(context.Background(), tt.claims)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RequestCompanyVerification mails a code to an address on the domain of the
+// company website. Only the owner may ask, a new request replaces one whose
+// code wasn't confirmed yet.
+func (r NewService) RequestCompanyVerification(ctx context.Context, companyID uint, userID uint, email string) (models.CompanyVerification, error) {
+	c, err := r.ownCompany(ctx, companyID, userID)
+	if err != nil {
+		return models.CompanyVerification{}, err
+	}
+	if c.Verified {
+		return models.CompanyVerification{}, newError(ErrConflict, CodeCompanyVerified, "the company is already verified", nil)
+	}
+	domain := websiteDomain(c.Website)
+	if domain == "" {
+		return models.CompanyVerification{}, newError(ErrValidation, CodeWebsiteMissing,
+			"add the website of the company before asking for verification", nil)
+	}
+	email = models.NormalizeEmail(email)
+	if !onDomain(email, domain) {
+		return models.CompanyVerification{}, newError(ErrValidation, CodeEmailNotOnDomain,
+			fmt.Sprintf("the email has to be an address on %s", domain), nil)
+	}
+
+	v, err := r.rp.LatestCompanyVerification(ctx, companyID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return models.CompanyVerification{}, err
+	}
+	if v.Status == models.VerificationPending {
+		return models.CompanyVerification{}, newError(ErrConflict, CodeVerificationPending, "the company is already waiting for review", nil)
+	}
+	if v.Status != models.VerificationEmailSent {
+		v = models.CompanyVerification{CompanyID: companyID}
+	}
+	v.RequestedBy, v.Email, v.Status = userID, email, models.VerificationEmailSent
+
+	code, err := issueOTP(ctx, r.attempts, otpCompanyVerification, email)
+	if err != nil {
+		return models.CompanyVerification{}, err
+	}
+	v, err = r.rp.SaveCompanyVerification(ctx, v)
+	if err == nil {
+		body := fmt.Sprintf("Your code to verify %s on the job portal is %s. It is valid for %s.", c.Name, code, otpCompanyVerification.ttl)
+		err = r.mail(email, "Verify your company on the job portal", body)
+		if err != nil {
+			err = fmt.Errorf("sending company verification email: %w", err)
+		}
+	}
+	if err != nil {
+		// The code is useless without the request or the email, a new one
+		// can be asked for right away
+		if rerr := revokeOTP(ctx, r.attempts, otpCompanyVerification, email); rerr != nil {
+			log.Error().Err(rerr).Msg("revoking unsent company verification code")
+		}
+		return models.CompanyVerification{}, err
+	}
+	return v, nil
+}
+
+// ConfirmCompanyVerification checks the code mailed for the latest request,
+// the request then waits for an admin.
+func (r NewService) ConfirmCompanyVerification(ctx context.Context, companyID uint, userID uint, code string) (models.CompanyVerification, error) {
+	_, err := r.ownCompany(ctx, companyID, userID)
+	if err != nil {
+		return models.CompanyVerification{}, err
+	}
+	v, err := r.rp.LatestCompanyVerification(ctx, companyID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return models.CompanyVerification{}, err
+	}
+	if v.Status != models.VerificationEmailSent {
+		return models.CompanyVerification{}, newError(ErrNotFound, CodeVerificationNotFound,
+			"no verification code was requested for the company", err)
+	}
+	err = redeemOTP(ctx, r.attempts, otpCompanyVerification, v.Email, code)
+	if err != nil {
+		return models.CompanyVerification{}, err
+	}
+	now := time.Now()
+	v.Status, v.EmailVerifiedAt = models.VerificationPending, &now
+	return r.rp.SaveCompanyVerification(ctx, v)
+}
+
+// GetCompanyVerification returns the latest verification request of a
+// company to its owner.
+func (r NewService) GetCompanyVerification(ctx context.Context, companyID uint, userID uint) (models.CompanyVerification, error) {
+	_, err := r.ownCompany(ctx, companyID, userID)
+	if err != nil {
+		return models.CompanyVerification{}, err
+	}
+	v, err := r.rp.LatestCompanyVerification(ctx, companyID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.CompanyVerification{}, newError(ErrNotFound, CodeVerificationNotFound,
+			"the company never asked for verification", err)
+	}
+	return v, err
+}
+
+var verificationStatuses = []string{models.VerificationEmailSent, models.VerificationPending,
+	models.VerificationApproved, models.VerificationRejected}
+
+// ListCompanyVerifications returns the verification requests with the status
+// for admins, every request when it is empty.
+func (r NewService) ListCompanyVerifications(ctx context.Context, status string) ([]models.CompanyVerification, error) {
+	if status != "" && !slices.Contains(verificationStatuses, status) {
+		return nil, newError(ErrValidation, CodeInvalidStatus,
+			"status must be one of "+strings.Join(verificationStatuses, ", "), nil)
+	}
+	return r.rp.CompanyVerifications(ctx, status)
+}
+
+// ReviewCompanyVerification approves or rejects a request waiting for review
+// on behalf of the admin actorID. Approving gives the company its badge,
+// the owner is told either way.
+func (r NewService) ReviewCompanyVerification(ctx context.Context, id uint, approve bool, reason string, actorID string) (models.CompanyVerification, error) {
+	reason = strings.TrimSpace(reason)
+	if !approve && reason == "" {
+		return models.CompanyVerification{}, newError(ErrValidation, CodeReasonRequired, "tell the owner why the request is rejected", nil)
+	}
+
+	var v models.CompanyVerification
+	var jobs []models.Job
+	err := r.inTx(ctx, func(r NewService) error {
+		var err error
+		v, err = r.rp.GetCompanyVerification(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			return newError(ErrNotFound, CodeVerificationNotFound, "verification request not found", err)
+		}
+		if err != nil {
+			return err
+		}
+		if v.Status != models.VerificationPending {
+			return newError(ErrConflict, CodeVerificationReviewed, "only requests waiting for review can be approved or rejected", nil)
+		}
+
+		now := time.Now()
+		v.Status, v.ReviewedBy, v.ReviewedAt, v.Reason = models.VerificationRejected, actorID, &now, reason
+		event := models.AuditCompanyRejected
+		if approve {
+			v.Status, event = models.VerificationApproved, models.AuditCompanyVerified
+			c, err := r.rp.GetCompanyByID(ctx, int(v.CompanyID))
+			if errors.Is(err, repository.ErrNotFound) {
+				return newError(ErrNotFound, CodeCompanyNotFound, "company not found", err)
+			}
+			if err != nil {
+				return err
+			}
+			// The proof only counts for the website it was made for
+			if !onDomain(v.Email, websiteDomain(c.Website)) {
+				return newError(ErrConflict, CodeEmailNotOnDomain, "the website of the company changed since the request, reject it", nil)
+			}
+			err = r.rp.SetCompanyVerified(ctx, v.CompanyID, true)
+			if err != nil {
+				return err
+			}
+			jobs, err = r.rp.ViewJobById(ctx, int(v.CompanyID))
+			if err != nil {
+				return err
+			}
+		}
+		v, err = r.rp.SaveCompanyVerification(ctx, v)
+		if err != nil {
+			return err
+		}
+		return r.rp.RecordAuditEvent(ctx, models.AuditEvent{Event: event, Email: v.Email, ActorID: actorID,
+			Detail: fmt.Sprintf("company %d, request %d", v.CompanyID, v.ID)})
+	})
+	if err != nil {
+		return models.CompanyVerification{}, err
+	}
+	if approve {
+		r.companyJobsChanged(ctx, v.CompanyID, jobs)
+	}
+	r.notifyReview(ctx, v)
+	return v, nil
+}
+
+// revokeVerification takes the badge away from a company whose name or
+// website changed, the domain proven no longer vouches for it.
+func (r NewService) revokeVerification(ctx context.Context, c models.Company, userID uint) ([]models.Job, error) {
+	err := r.rp.SetCompanyVerified(ctx, c.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := r.rp.ViewJobById(ctx, int(c.ID))
+	if err != nil {
+		return nil, err
+	}
+	err = r.rp.RecordAuditEvent(ctx, models.AuditEvent{Event: models.AuditCompanyRevoked,
+		ActorID: strconv.FormatUint(uint64(userID), 10), Detail: fmt.Sprintf("company %d changed its name or website", c.ID)})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// notifyReview tells the owner who asked how the request was decided. The
+// decision stands without the email, so it is sent in the background.
+func (r NewService) notifyReview(ctx context.Context, v models.CompanyVerification) {
+	u, err := r.rp.GetUserByID(ctx, v.RequestedBy)
+	if err != nil {
+		log.Error().Err(err).Uint("user", v.RequestedBy).Msg("finding owner to notify of verification review")
+		return
+	}
+	subject, body := "Your company is verified", "An admin approved the verification of your company on the job portal."
+	if v.Status == models.VerificationRejected {
+		subject = "Your company verification was rejected"
+		body = "An admin rejected the verification of your company on the job portal: " + v.Reason
+	}
+	r.notify(u.Email, subject, body)
+}
+
+// websiteDomain returns the host of a website without a leading www, empty
+// when there is no usable website.
+func websiteDomain(website string) string {
+	u, err := url.Parse(website)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// onDomain reports whether email is an address on domain or one of its
+// subdomains.
+func onDomain(email, domain string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	host := strings.ToLower(email[at+1:])
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
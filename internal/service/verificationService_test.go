@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"job-portal/internal/cache"
+	"job-portal/internal/models"
+	"job-portal/internal/repository"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestOnDomain(t *testing.T) {
+	tests := []struct {
+		website string
+		email   string
+		want    bool
+	}{
+		{website: "https://www.Acme.com/about", email: "hr@acme.com", want: true},
+		{website: "https://acme.com", email: "hr@eu.acme.com", want: true},
+		{website: "https://acme.com", email: "hr@notacme.com", want: false},
+		{website: "https://acme.com", email: "hr@acme.com.evil.io", want: false},
+		{website: "", email: "hr@acme.com", want: false},
+		{website: "https://acme.com", email: "acme.com", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.website+" "+tt.email, func(t *testing.T) {
+			domain := websiteDomain(tt.website)
+			assert.Equal(t, tt.want, domain != "" && onDomain(tt.email, domain))
+		})
+	}
+}
+
+func TestNewService_RequestCompanyVerification(t *testing.T) {
+	ctx := context.Background()
+	owner := uint(1)
+	company := models.Company{ID: 7, Name: "acme", OwnerID: &owner, Website: "https://www.acme.com"}
+	tests := []struct {
+		name     string
+		company  models.Company
+		email    string
+		latest   models.CompanyVerification
+		wantCode string
+	}{
+		{
+			name:     "already verified",
+			company:  models.Company{ID: 7, OwnerID: &owner, Website: "https://acme.com", Verified: true},
+			email:    "hr@acme.com",
+			wantCode: CodeCompanyVerified,
+		},
+		{
+			name:     "without website",
+			company:  models.Company{ID: 7, OwnerID: &owner},
+			email:    "hr@acme.com",
+			wantCode: CodeWebsiteMissing,
+		},
+		{
+			name:     "email on another domain",
+			company:  company,
+			email:    "hr@gmail.com",
+			wantCode: CodeEmailNotOnDomain,
+		},
+		{
+			name:     "waiting for review",
+			company:  company,
+			email:    "hr@acme.com",
+			latest:   models.CompanyVerification{ID: 3, Status: models.VerificationPending},
+			wantCode: CodeVerificationPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := repository.NewMockRepository(gomock.NewController(t))
+			_, st := newTestStore(t)
+			s := &NewService{rp: ms, attempts: st}
+			ms.EXPECT().GetCompanyByID(ctx, 7).Return(tt.company, nil)
+			ms.EXPECT().LatestCompanyVerification(ctx, uint(7)).Return(tt.latest, nil).AnyTimes()
+
+			_, err := s.RequestCompanyVerification(ctx, 7, owner, tt.email)
+			assert.Equal(t, tt.wantCode, errCode(err))
+		})
+	}
+}
+
+func TestNewService_CompanyVerificationCode(t *testing.T) {
+	ctx := context.Background()
+	owner := uint(1)
+	ms := repository.NewMockRepository(gomock.NewController(t))
+	_, st := newTestStore(t)
+	s := &NewService{rp: ms, attempts: st}
+	mails := captureMail(t)
+	ms.EXPECT().GetCompanyByID(ctx, 7).Return(models.Company{ID: 7, Name: "acme", OwnerID: &owner, Website: "https://acme.com"}, nil).AnyTimes()
+
+	// A request whose code wasn't confirmed is sent again
+	sent := models.CompanyVerification{ID: 3, CompanyID: 7, Email: "old@acme.com", Status: models.VerificationEmailSent}
+	ms.EXPECT().LatestCompanyVerification(ctx, uint(7)).Return(sent, nil)
+	ms.EXPECT().SaveCompanyVerification(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, v models.CompanyVerification) (models.CompanyVerification, error) {
+			return v, nil
+		})
+	v, err := s.RequestCompanyVerification(ctx, 7, owner, "HR@acme.com")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, uint(3), v.ID)
+	assert.Equal(t, "hr@acme.com", v.Email)
+	assert.Equal(t, 1, len(*mails))
+	assert.Equal(t, "hr@acme.com", (*mails)[0].to)
+	code := regexp.MustCompile(`\d{6}`).FindString((*mails)[0].body)
+
+	ms.EXPECT().LatestCompanyVerification(ctx, uint(7)).Return(v, nil).Times(2)
+	_, err = s.ConfirmCompanyVerification(ctx, 7, owner, "not the code")
+	assert.Equal(t, CodeInvalidOTP, errCode(err))
+
+	ms.EXPECT().SaveCompanyVerification(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, v models.CompanyVerification) (models.CompanyVerification, error) {
+			return v, nil
+		})
+	v, err = s.ConfirmCompanyVerification(ctx, 7, owner, code)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, models.VerificationPending, v.Status)
+	assert.NotEqual(t, nil, v.EmailVerifiedAt)
+
+	// Nothing is left to confirm once the request waits for review
+	ms.EXPECT().LatestCompanyVerification(ctx, uint(7)).Return(v, nil)
+	_, err = s.ConfirmCompanyVerification(ctx, 7, owner, code)
+	assert.Equal(t, CodeVerificationNotFound, errCode(err))
+}
+
+func TestNewService_ReviewCompanyVerification(t *testing.T) {
+	ctx := context.Background()
+	pending := models.CompanyVerification{ID: 3, CompanyID: 7, RequestedBy: 1, Email: "hr@acme.com", Status: models.VerificationPending}
+	tests := []struct {
+		name     string
+		approve  bool
+		reason   string
+		setup    func(ms *repository.MockRepository)
+		wantCode string
+		want     string
+	}{
+		{
+			name:     "rejected without reason",
+			reason:   "  ",
+			wantCode: CodeReasonRequired,
+		},
+		{
+			name:    "missing request",
+			approve: true,
+			setup: func(ms *repository.MockRepository) {
+				ms.EXPECT().GetCompanyVerification(ctx, uint(3)).Return(models.CompanyVerification{}, repository.ErrNotFound)
+			},
+			wantCode: CodeVerificationNotFound,
+		},
+		{
+			name:    "already reviewed",
+			approve: true,
+			setup: func(ms *repository.MockRepository) {
+				ms.EXPECT().GetCompanyVerification(ctx, uint(3)).
+					Return(models.CompanyVerification{ID: 3, Status: models.VerificationRejected}, nil)
+			},
+			wantCode: CodeVerificationReviewed,
+		},
+		{
+			name:    "website changed since the request",
+			approve: true,
+			setup: func(ms *repository.MockRepository) {
+				ms.EXPECT().GetCompanyVerification(ctx, uint(3)).Return(pending, nil)
+				ms.EXPECT().GetCompanyByID(ctx, 7).Return(models.Company{ID: 7, Website: "https://other.io"}, nil)
+			},
+			wantCode: CodeEmailNotOnDomain,
+		},
+		{
+			name:    "approved",
+			approve: true,
+			setup: func(ms *repository.MockRepository) {
+				ms.EXPECT().GetCompanyVerification(ctx, uint(3)).Return(pending, nil)
+				ms.EXPECT().GetCompanyByID(ctx, 7).Return(models.Company{ID: 7, Website: "https://acme.com"}, nil)
+				ms.EXPECT().SetCompanyVerified(ctx, uint(7), true).Return(nil)
+				ms.EXPECT().ViewJobById(ctx, 7).Return([]models.Job{{ID: 5, CompanyID: 7}}, nil)
+				ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).
+					DoAndReturn(func(_ context.Context, e models.AuditEvent) error {
+						assert.Equal(t, models.AuditCompanyVerified, e.Event)
+						assert.Equal(t, "42", e.ActorID)
+						return nil
+					})
+			},
+			want: models.VerificationApproved,
+		},
+		{
+			name:   "rejected",
+			reason: "the website belongs to someone else",
+			setup: func(ms *repository.MockRepository) {
+				ms.EXPECT().GetCompanyVerification(ctx, uint(3)).Return(pending, nil)
+				ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).Return(nil)
+			},
+			want: models.VerificationRejected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := repository.NewMockRepository(gomock.NewController(t))
+			c := cache.NewMemory()
+			s := &NewService{rp: ms, jobs: newJobCache(c, 0), notifications: new(sync.WaitGroup)}
+			mails := captureMail(t)
+			expectTx(ms)
+			if tt.setup != nil {
+				tt.setup(ms)
+			}
+			if tt.want != "" {
+				ms.EXPECT().SaveCompanyVerification(ctx, gomock.Any()).
+					DoAndReturn(func(_ context.Context, v models.CompanyVerification) (models.CompanyVerification, error) {
+						return v, nil
+					})
+				ms.EXPECT().GetUserByID(ctx, uint(1)).Return(models.User{Email: "owner@gmail.com"}, nil)
+			}
+			assert.Equal(t, nil, c.Set(ctx, jobKey(5), []byte("{}"), time.Hour))
+
+			v, err := s.ReviewCompanyVerification(ctx, 3, tt.approve, tt.reason, "42")
+			s.notifications.Wait()
+			assert.Equal(t, tt.wantCode, errCode(err))
+			if tt.wantCode != "" {
+				assert.Equal(t, 0, len(*mails))
+				return
+			}
+			assert.Equal(t, tt.want, v.Status)
+			assert.Equal(t, "42", v.ReviewedBy)
+			assert.Equal(t, 1, len(*mails))
+			assert.Equal(t, "owner@gmail.com", (*mails)[0].to)
+			// The cached jobs of an approved company lose their stale badge
+			_, err = c.Get(ctx, jobKey(5))
+			assert.Equal(t, tt.approve, errors.Is(err, cache.ErrMiss))
+		})
+	}
+}
+
+func TestNewService_PatchCompany_RevokesVerification(t *testing.T) {
+	ctx := context.Background()
+	owner := uint(1)
+	now := time.Now()
+	verified := models.Company{ID: 7, Name: "acme", OwnerID: &owner, Website: "https://acme.com", Verified: true, VerifiedAt: &now}
+	other, same := "https://acme.io", "https://www.acme.com/careers"
+	tests := []struct {
+		name    string
+		patch   models.CompanyPatch
+		revoked bool
+	}{
+		{name: "same domain", patch: models.CompanyPatch{Website: &same}},
+		{name: "other domain", patch: models.CompanyPatch{Website: &other}, revoked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := repository.NewMockRepository(gomock.NewController(t))
+			s := &NewService{rp: ms, jobs: newJobCache(cache.NewMemory(), 0)}
+			expectTx(ms)
+			ms.EXPECT().LockCompany(ctx, uint(7)).Return(nil)
+			ms.EXPECT().GetCompanyByID(ctx, 7).Return(verified, nil)
+			ms.EXPECT().UpdateCompany(ctx, uint(7), gomock.Any()).Return(verified, nil)
+			if tt.revoked {
+				ms.EXPECT().SetCompanyVerified(ctx, uint(7), false).Return(nil)
+				ms.EXPECT().ViewJobById(ctx, 7).Return(nil, nil)
+				ms.EXPECT().RecordAuditEvent(ctx, gomock.Any()).
+					DoAndReturn(func(_ context.Context, e models.AuditEvent) error {
+						assert.Equal(t, models.AuditCompanyRevoked, e.Event)
+						return nil
+					})
+			}
+
+			com, err := s.PatchCompany(ctx, 7, owner, tt.patch)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, !tt.revoked, com.Verified)
+		})
+	}
+}
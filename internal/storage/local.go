@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Local keeps files in a directory. Files are written to a temporary file
+// first, so readers never see half written ones.
+type Local struct {
+	root string
+}
+
+var _ Storage = (*Local)(nil)
+
+// NewLocal stores files below dir, creating it when missing.
+func NewLocal(dir string) (*Local, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, fmt.Errorf("creating storage directory: %w", err)
+	}
+	return &Local{root: dir}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	err := ValidKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, _ string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(p), 0o750)
+	if err != nil {
+		return fmt.Errorf("storing %s: %w", key, err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("storing %s: %w", key, err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, contextReader{ctx: ctx, r: r})
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("storing %s: %w", key, err)
+	}
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("storing %s: %w", key, err)
+	}
+	err = os.Rename(f.Name(), p)
+	if err != nil {
+		return fmt.Errorf("storing %s: %w", key, err)
+	}
+	return nil
+}
+
+func (l *Local) Open(_ context.Context, key string) (Object, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Object{}, fmt.Errorf("opening %s: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return Object{}, fmt.Errorf("opening %s: %w", key, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return Object{}, fmt.Errorf("opening %s: %w", key, err)
+	}
+	return Object{Body: f, Size: info.Size(), ContentType: contentType(key)}, nil
+}
+
+func (l *Local) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		p, err := l.path(key)
+		if err != nil {
+			return err
+		}
+		err = os.Remove(p)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("deleting %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// contextReader stops a copy once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	err := cr.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
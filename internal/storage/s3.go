@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ObjectAPI is the part of an S3 compatible client the storage needs, an
+// adapter around the SDK of the provider implements it. GetObject returns
+// ErrObjectNotFound for missing keys.
+type ObjectAPI interface {
+	PutObject(ctx context.Context, bucket, key string, body io.Reader, contentType string) error
+	GetObject(ctx context.Context, bucket, key string) (body io.ReadCloser, size int64, contentType string, err error)
+	DeleteObjects(ctx context.Context, bucket string, keys []string) error
+}
+
+// ErrObjectNotFound is returned by an ObjectAPI for missing keys.
+var ErrObjectNotFound = errors.New("no such key")
+
+// S3 keeps files in a bucket of an S3 compatible object store, every
+// instance sees the same files.
+type S3 struct {
+	client ObjectAPI
+	bucket string
+	// prefix is prepended to every key so a bucket can be shared.
+	prefix string
+}
+
+var _ Storage = (*S3)(nil)
+
+// NewS3 stores files in bucket below prefix, which may be empty.
+func NewS3(client ObjectAPI, bucket, prefix string) *S3 {
+	return &S3{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	err := ValidKey(key)
+	if err != nil {
+		return err
+	}
+	err = s.client.PutObject(ctx, s.bucket, s.prefix+key, r, contentType)
+	if err != nil {
+		return fmt.Errorf("storing %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (Object, error) {
+	err := ValidKey(key)
+	if err != nil {
+		return Object{}, err
+	}
+	body, size, ct, err := s.client.GetObject(ctx, s.bucket, s.prefix+key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return Object{}, fmt.Errorf("opening %s: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return Object{}, fmt.Errorf("opening %s: %w", key, err)
+	}
+	if ct == "" {
+		ct = contentType(key)
+	}
+	return Object{Body: body, Size: size, ContentType: ct}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		err := ValidKey(key)
+		if err != nil {
+			return err
+		}
+		full[i] = s.prefix + key
+	}
+	err := s.client.DeleteObjects(ctx, s.bucket, full)
+	if err != nil {
+		return fmt.Errorf("deleting %d files: %w", len(keys), err)
+	}
+	return nil
+}
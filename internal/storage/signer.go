@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidSignature is returned for download links that were tampered
+// with or have expired.
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// Signer creates download links that are valid for a while without a token,
+// so they work in img tags. Every instance serving the links needs the same
+// secret.
+type Signer struct {
+	secret []byte
+	// base is the path the key is appended to, e.g. /api/files/.
+	base string
+	now  func() time.Time
+}
+
+func NewSigner(secret []byte, base string) *Signer {
+	return &Signer{secret: secret, base: base, now: time.Now}
+}
+
+// URL returns a link to the file under key that expires after ttl.
+func (s *Signer) URL(key string, ttl time.Duration) string {
+	expires := strconv.FormatInt(s.now().Add(ttl).Unix(), 10)
+	q := url.Values{"expires": {expires}, "signature": {s.sign(key, expires)}}
+	return s.base + key + "?" + q.Encode()
+}
+
+// Verify checks the expires and signature parameters of a link to key.
+func (s *Signer) Verify(key, expires, signature string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+		return ErrInvalidSignature
+	}
+	if !s.now().Before(time.Unix(exp, 0)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *Signer) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,58 @@
+// Package storage keeps uploaded files, on the local filesystem for single
+// instance setups or in an S3 compatible bucket shared by every instance.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned for keys no file is stored under.
+var ErrNotFound = errors.New("file not found")
+
+// Storage stores files under slash separated keys such as
+// companies/7/logo.png.
+type Storage interface {
+	// Put stores the content of r under key, replacing what was there.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Open returns the file stored under key or ErrNotFound. The caller
+	// closes the body.
+	Open(ctx context.Context, key string) (Object, error)
+	// Delete removes the files, missing keys are ignored.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Object is a stored file being read.
+type Object struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+}
+
+// ValidKey reports whether key is relative, clean and free of parts that
+// could escape the root of the storage.
+func ValidKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) || path.Clean(key) != key {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "." || part == ".." {
+			return fmt.Errorf("invalid key %q", key)
+		}
+	}
+	return nil
+}
+
+// contentType guesses the type of a file from the extension of its key.
+func contentType(key string) string {
+	ct := mime.TypeByExtension(path.Ext(key))
+	if ct == "" {
+		return "application/octet-stream"
+	}
+	return ct
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// testStorage runs the same checks against every implementation.
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	_, err := s.Open(ctx, "companies/7/logo.png")
+	assert.Equal(t, true, errors.Is(err, ErrNotFound))
+
+	err = s.Put(ctx, "companies/7/logo.png", strings.NewReader("first"), "image/png")
+	assert.Equal(t, nil, err)
+	err = s.Put(ctx, "companies/7/logo.png", strings.NewReader("second"), "image/png")
+	assert.Equal(t, nil, err)
+	obj, err := s.Open(ctx, "companies/7/logo.png")
+	assert.Equal(t, nil, err)
+	data, _ := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	assert.Equal(t, "second", string(data))
+	assert.Equal(t, int64(6), obj.Size)
+	assert.Equal(t, "image/png", obj.ContentType)
+
+	err = s.Put(ctx, "../escape.png", strings.NewReader("x"), "image/png")
+	assert.NotEqual(t, nil, err)
+	_, err = s.Open(ctx, "/etc/passwd")
+	assert.NotEqual(t, nil, err)
+
+	err = s.Delete(ctx, "companies/7/logo.png", "companies/7/missing.png")
+	assert.Equal(t, nil, err)
+	_, err = s.Open(ctx, "companies/7/logo.png")
+	assert.Equal(t, true, errors.Is(err, ErrNotFound))
+	assert.Equal(t, nil, s.Delete(ctx))
+}
+
+func TestLocal(t *testing.T) {
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, l)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = l.Put(ctx, "companies/7/logo.png", strings.NewReader("x"), "image/png")
+	assert.Equal(t, true, errors.Is(err, context.Canceled))
+	_, err = l.Open(context.Background(), "companies/7/logo.png")
+	assert.Equal(t, true, errors.Is(err, ErrNotFound))
+}
+
+// bucket is an in-memory ObjectAPI.
+type bucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (b *bucket) PutObject(_ context.Context, name, key string, body io.Reader, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[name+"/"+key] = data
+	b.types[name+"/"+key] = contentType
+	return nil
+}
+
+func (b *bucket) GetObject(_ context.Context, name, key string) (io.ReadCloser, int64, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[name+"/"+key]
+	if !ok {
+		return nil, 0, "", ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), b.types[name+"/"+key], nil
+}
+
+func (b *bucket) DeleteObjects(_ context.Context, name string, keys []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range keys {
+		delete(b.objects, name+"/"+key)
+	}
+	return nil
+}
+
+func TestS3(t *testing.T) {
+	b := &bucket{objects: map[string][]byte{}, types: map[string]string{}}
+	testStorage(t, NewS3(b, "media", "uploads/"))
+
+	err := NewS3(b, "media", "uploads/").Put(context.Background(), "a.png", strings.NewReader("x"), "image/png")
+	assert.Equal(t, nil, err)
+	_, ok := b.objects["media/uploads/a.png"]
+	assert.Equal(t, true, ok)
+}
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{key: "companies/7/logo.png", valid: true},
+		{key: "logo.png", valid: true},
+		{key: ""},
+		{key: "/companies/7/logo.png"},
+		{key: "companies/../../etc/passwd"},
+		{key: "companies//logo.png"},
+		{key: "companies/7/"},
+		{key: `companies\7\logo.png`},
+		{key: ".."},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.valid, ValidKey(tt.key) == nil)
+	}
+}
+
+func TestSigner(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewSigner([]byte("secret"), "/api/files/")
+	s.now = func() time.Time { return now }
+
+	link := s.URL("companies/7/logo.png", time.Minute)
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "/api/files/companies/7/logo.png", u.Path)
+	expires, signature := u.Query().Get("expires"), u.Query().Get("signature")
+	assert.Equal(t, "1700000060", expires)
+	assert.Equal(t, nil, s.Verify("companies/7/logo.png", expires, signature))
+
+	assert.Equal(t, ErrInvalidSignature, s.Verify("companies/8/logo.png", expires, signature))
+	assert.Equal(t, ErrInvalidSignature, s.Verify("companies/7/logo.png", "1700000120", signature))
+	assert.Equal(t, ErrInvalidSignature, s.Verify("companies/7/logo.png", "soon", signature))
+	assert.Equal(t, ErrInvalidSignature, s.Verify("companies/7/logo.png", expires, ""))
+	other := NewSigner([]byte("other"), "/api/files/")
+	other.now = s.now
+	assert.Equal(t, ErrInvalidSignature, other.Verify("companies/7/logo.png", expires, signature))
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, ErrInvalidSignature, s.Verify("companies/7/logo.png", expires, signature))
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"job-portal/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "job-portal"
+
+// Tracer returns the tracer used for every span created by the application.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Init installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes pending spans and must be called
+// on shutdown.
+func Init(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("building trace resource %w", err)
+	}
+
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	var closer io.Closer
+
+	switch cfg.TraceExporter {
+	case "otlp":
+		exOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.OTLPInsecure {
+			exOpts = append(exOpts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(ctx, exOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating otlp exporter %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("creating stdout exporter %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case "file":
+		f, err := os.OpenFile(cfg.TraceFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening trace file %w", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("creating file exporter %w", err)
+		}
+		closer = f
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case "", "none":
+		// Spans are still created so trace ids are generated and propagated,
+		// they are just never exported.
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.TraceExporter)
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}
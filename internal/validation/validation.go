@@ -0,0 +1,174 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"job-portal/internal/models"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator"
+)
+
+// FieldError describes a single field that failed validation. Field is the
+// JSON name the client sent, Rule the validator tag that failed.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors is returned by Struct when one or more fields are invalid.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fe := range e {
+		parts = append(parts, fe.Field+" "+fe.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// WithPrefix qualifies every field name, used when validating the elements
+// of a request that is a JSON array.
+func (e Errors) WithPrefix(prefix string) Errors {
+	out := make(Errors, len(e))
+	for i, fe := range e {
+		fe.Field = prefix + fe.Field
+		out[i] = fe
+	}
+	return out
+}
+
+const minPasswordLen = 8
+
+// validate is shared by every handler, the validator caches struct metadata
+// so building it once matters.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	// Report the JSON name instead of the Go field name
+	v.RegisterTagNameFunc(jsonName)
+
+	if err := v.RegisterValidation("password", strongPassword); err != nil {
+		panic(err)
+	}
+	if err := v.RegisterValidation("size_band", sizeBand); err != nil {
+		panic(err)
+	}
+	// Patches clear an optional link with an empty string
+	v.RegisterAlias("optional_url", "len=0|url")
+	return v
+}
+
+// Struct validates s and returns Errors describing every invalid field, or
+// nil when s is valid.
+func Struct(s any) error {
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	t := reflect.TypeOf(s)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	out := make(Errors, 0, len(verrs))
+	for _, fe := range verrs {
+		out = append(out, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: message(t, fe),
+		})
+	}
+	return out
+}
+
+func message(t reflect.Type, fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "ip":
+		return "must be a valid ip address"
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte", "min":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "lte", "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "gtefield":
+		return fmt.Sprintf("must be greater than or equal to %s", fieldName(t, fe.Param()))
+	case "eqfield":
+		return fmt.Sprintf("must match %s", fieldName(t, fe.Param()))
+	case "url", "optional_url":
+		return "must be a valid url"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "size_band":
+		return fmt.Sprintf("must be one of %s", strings.Join(models.CompanySizeBands, ", "))
+	case "password":
+		return fmt.Sprintf("must be at least %d characters and contain an upper case letter, a lower case letter and a digit", minPasswordLen)
+	}
+	return fmt.Sprintf("failed on the '%s' rule", fe.Tag())
+}
+
+// fieldName resolves the JSON name of a sibling field referenced by a cross
+// field rule such as gtefield=Min_NP.
+func fieldName(t reflect.Type, name string) string {
+	if t.Kind() != reflect.Struct {
+		return name
+	}
+	f, ok := t.FieldByName(name)
+	if !ok {
+		return name
+	}
+	return jsonName(f)
+}
+
+func jsonName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+func strongPassword(fl validator.FieldLevel) bool {
+	p := fl.Field().String()
+	if len(p) < minPasswordLen {
+		return false
+	}
+	var upper, lower, digit bool
+	for _, r := range p {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// sizeBand accepts one of the company size bands or nothing.
+func sizeBand(fl validator.FieldLevel) bool {
+	b := fl.Field().String()
+	return b == "" || slices.Contains(models.CompanySizeBands, b)
+}
@@ -0,0 +1,89 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestStruct_Rules(t *testing.T) {
+	type account struct {
+		Password string `json:"password" validate:"password"`
+		Size     string `json:"size_band" validate:"size_band"`
+		Website  string `json:"website" validate:"optional_url"`
+	}
+	valid := account{Password: "Secret#123", Size: "11-50", Website: "https://acme.com"}
+	tests := []struct {
+		name  string
+		patch func(a *account)
+		rule  string
+	}{
+		{name: "valid", patch: func(a *account) {}},
+		{name: "password too short", patch: func(a *account) { a.Password = "Sec#1" }, rule: "password"},
+		{name: "password without upper case", patch: func(a *account) { a.Password = "secret#123" }, rule: "password"},
+		{name: "password without lower case", patch: func(a *account) { a.Password = "SECRET#123" }, rule: "password"},
+		{name: "password without digit", patch: func(a *account) { a.Password = "Secret#abc" }, rule: "password"},
+		{name: "unknown size band", patch: func(a *account) { a.Size = "12-40" }, rule: "size_band"},
+		{name: "no size band", patch: func(a *account) { a.Size = "" }},
+		{name: "invalid url", patch: func(a *account) { a.Website = "acme" }, rule: "optional_url"},
+		{name: "no url", patch: func(a *account) { a.Website = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := valid
+			tt.patch(&a)
+			err := Struct(a)
+			if tt.rule == "" {
+				assert.Equal(t, nil, err)
+				return
+			}
+			var verrs Errors
+			assert.Equal(t, true, errors.As(err, &verrs))
+			assert.Equal(t, 1, len(verrs))
+			assert.Equal(t, tt.rule, verrs[0].Rule)
+		})
+	}
+}
+
+func TestStruct_FieldNames(t *testing.T) {
+	type job struct {
+		Title  string `json:"title" validate:"required"`
+		Min_NP int    `json:"min_np" validate:"gte=0"`
+		Max_NP int    `json:"max_np" validate:"gtefield=Min_NP"`
+		Notes  string `validate:"max=3"`
+		Secret string `json:"-" validate:"required"`
+	}
+	err := Struct(&job{Min_NP: 30, Max_NP: 10, Notes: "long", Secret: "x"})
+	assert.Equal(t, Errors{
+		{Field: "title", Rule: "required", Message: "is required"},
+		{Field: "max_np", Rule: "gtefield", Message: "must be greater than or equal to min_np"},
+		{Field: "Notes", Rule: "max", Message: "must be at most 3"},
+	}, err)
+	assert.Equal(t, "title is required; max_np must be greater than or equal to min_np; Notes must be at most 3", err.Error())
+}
+
+func TestStruct_Messages(t *testing.T) {
+	type reset struct {
+		Email   string `json:"email" validate:"email"`
+		New     string `json:"new_password" validate:"password"`
+		Confirm string `json:"confirm_password" validate:"eqfield=New"`
+		Size    string `json:"size_band" validate:"size_band"`
+		Sort    string `json:"sort" validate:"oneof=name -name"`
+	}
+	err := Struct(reset{Email: "vishnu", New: "secret", Confirm: "other", Size: "huge", Sort: "size"})
+	assert.Equal(t, Errors{
+		{Field: "email", Rule: "email", Message: "must be a valid email address"},
+		{Field: "new_password", Rule: "password", Message: "must be at least 8 characters and contain an upper case letter, a lower case letter and a digit"},
+		{Field: "confirm_password", Rule: "eqfield", Message: "must match new_password"},
+		{Field: "size_band", Rule: "size_band", Message: "must be one of 1-10, 11-50, 51-200, 201-500, 501-1000, 1001-5000, 5001+"},
+		{Field: "sort", Rule: "oneof", Message: "must be one of name, -name"},
+	}, err)
+}
+
+func TestErrors_WithPrefix(t *testing.T) {
+	errs := Errors{{Field: "email", Rule: "email", Message: "must be a valid email address"}}
+	assert.Equal(t, "[2].email", errs.WithPrefix("[2].")[0].Field)
+	// The original errors are left alone
+	assert.Equal(t, "email", errs[0].Field)
+}